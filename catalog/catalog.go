@@ -0,0 +1,152 @@
+// Package catalog builds a catalog of the APIs in a generated Kong declarative file;
+// the services and their routes, with the owners and tags from the spec. It is meant
+// to feed developer portals and service catalogs, eg. Backstage.
+package catalog
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+
+	"sigs.k8s.io/yaml"
+)
+
+// Version is the version of the catalog format.
+const Version = "1"
+
+// Catalog describes the APIs generated from a spec.
+type Catalog struct {
+	CatalogVersion string    `json:"catalog_version"`
+	Title          string    `json:"title,omitempty"`
+	Version        string    `json:"version,omitempty"`
+	Owners         []Owner   `json:"owners"`
+	Tags           []string  `json:"tags"`
+	SpecChecksum   string    `json:"spec_checksum"`
+	Services       []Service `json:"services"`
+}
+
+// Owner is a contact for the API, from 'info.contact' in the spec.
+type Owner struct {
+	Name  string `json:"name,omitempty"`
+	Email string `json:"email,omitempty"`
+	URL   string `json:"url,omitempty"`
+}
+
+// Service is a generated Kong service, with its routes.
+type Service struct {
+	Name   string   `json:"name"`
+	Tags   []string `json:"tags"`
+	Routes []Route  `json:"routes"`
+}
+
+// Route is a generated Kong route.
+type Route struct {
+	Name    string   `json:"name"`
+	Paths   []string `json:"paths"`
+	Methods []string `json:"methods,omitempty"`
+}
+
+// specInfo is the part of the spec used for the catalog.
+type specInfo struct {
+	Info struct {
+		Title   string `json:"title"`
+		Version string `json:"version"`
+		Contact *Owner `json:"contact"`
+	} `json:"info"`
+	Tags []struct {
+		Name string `json:"name"`
+	} `json:"tags"`
+}
+
+// Checksum returns the checksum of the spec, as 'sha256:<hex digest>'.
+func Checksum(spec []byte) string {
+	sum := sha256.Sum256(spec)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// stringList returns a list of strings, from either a []string or a []interface{}.
+func stringList(value interface{}) []string {
+	switch list := value.(type) {
+	case []string:
+		return append([]string{}, list...)
+	case []interface{}:
+		result := make([]string, 0, len(list))
+		for _, item := range list {
+			if s, ok := item.(string); ok {
+				result = append(result, s)
+			}
+		}
+		return result
+	}
+	return nil
+}
+
+// New returns the catalog for the spec, and the Kong declarative file generated from
+// it. The title, owners and tags are taken from the spec if it is an OpenAPI spec (or
+// any JSON/YAML document with an 'info' object), otherwise they are left empty.
+func New(spec []byte, deckData map[string]interface{}) *Catalog {
+	c := &Catalog{
+		CatalogVersion: Version,
+		Owners:         []Owner{},
+		Tags:           []string{},
+		SpecChecksum:   Checksum(spec),
+		Services:       []Service{},
+	}
+
+	var info specInfo
+	if err := yaml.Unmarshal(spec, &info); err == nil {
+		c.Title = info.Info.Title
+		c.Version = info.Info.Version
+		if info.Info.Contact != nil && *info.Info.Contact != (Owner{}) {
+			c.Owners = append(c.Owners, *info.Info.Contact)
+		}
+		for _, tag := range info.Tags {
+			c.Tags = append(c.Tags, tag.Name)
+		}
+	}
+
+	services, _ := deckData["services"].([]interface{})
+	for _, s := range services {
+		service, ok := s.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		entry := Service{Tags: stringList(service["tags"]), Routes: []Route{}}
+		entry.Name, _ = service["name"].(string)
+		if entry.Tags == nil {
+			entry.Tags = []string{}
+		}
+		routes, _ := service["routes"].([]interface{})
+		for _, r := range routes {
+			route, ok := r.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			name, _ := route["name"].(string)
+			entry.Routes = append(entry.Routes, Route{
+				Name:    name,
+				Paths:   stringList(route["paths"]),
+				Methods: stringList(route["methods"]),
+			})
+		}
+		sort.Slice(entry.Routes, func(i, j int) bool { return entry.Routes[i].Name < entry.Routes[j].Name })
+		c.Services = append(c.Services, entry)
+	}
+	sort.Slice(c.Services, func(i, j int) bool { return c.Services[i].Name < c.Services[j].Name })
+	return c
+}
+
+// Marshal returns the catalog as indented JSON. Route paths are regexes, so HTML
+// characters are not escaped.
+func (c *Catalog) Marshal() ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(c); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
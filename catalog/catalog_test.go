@@ -0,0 +1,74 @@
+package catalog
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_New(t *testing.T) {
+	spec := []byte(`
+openapi: 3.0.3
+info:
+  title: Learn Service
+  version: 1.0.0
+  contact:
+    name: Team Learn
+    email: learn@example.com
+tags:
+  - name: learn
+`)
+	deckData := map[string]interface{}{
+		"services": []interface{}{
+			map[string]interface{}{
+				"name": "learn-service",
+				"tags": []string{"team-learn"},
+				"routes": []interface{}{
+					map[string]interface{}{
+						"name":    "learn-service_put",
+						"paths":   []string{"~/learn$"},
+						"methods": []string{"PUT"},
+					},
+					map[string]interface{}{
+						"name":    "learn-service_get",
+						"paths":   []interface{}{"~/learn$"},
+						"methods": []interface{}{"GET"},
+					},
+				},
+			},
+		},
+	}
+
+	c := New(spec, deckData)
+	assert.Equal(t, &Catalog{
+		CatalogVersion: Version,
+		Title:          "Learn Service",
+		Version:        "1.0.0",
+		Owners:         []Owner{{Name: "Team Learn", Email: "learn@example.com"}},
+		Tags:           []string{"learn"},
+		SpecChecksum:   Checksum(spec),
+		Services: []Service{{
+			Name: "learn-service",
+			Tags: []string{"team-learn"},
+			Routes: []Route{
+				{Name: "learn-service_get", Paths: []string{"~/learn$"}, Methods: []string{"GET"}},
+				{Name: "learn-service_put", Paths: []string{"~/learn$"}, Methods: []string{"PUT"}},
+			},
+		}},
+	}, c)
+}
+
+func Test_NewNonOpenAPI(t *testing.T) {
+	c := New([]byte("syntax = \"proto3\";"), map[string]interface{}{})
+	assert.Empty(t, c.Title)
+	assert.Equal(t, []Owner{}, c.Owners)
+	assert.Equal(t, []Service{}, c.Services)
+
+	data, err := c.Marshal()
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), `"owners": []`)
+}
+
+func Test_Checksum(t *testing.T) {
+	assert.Equal(t, "sha256:e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855", Checksum(nil))
+}
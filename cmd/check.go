@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Kong/fw/filebasics"
+	"github.com/Kong/fw/kongcompat"
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/yaml"
+)
+
+// Executes the CLI command "check"
+func executeCheck(cmd *cobra.Command, _ []string) error {
+	filenameIn, _ := cmd.Flags().GetString("input")
+	kongAddr, _ := cmd.Flags().GetString("kong-addr")
+	headers, _ := cmd.Flags().GetStringSlice("headers")
+
+	body, err := filebasics.ReadFile(filenameIn)
+	if err != nil {
+		return ioError(err)
+	}
+	var content map[string]interface{}
+	if err := yaml.Unmarshal(*body, &content); err != nil {
+		return fmt.Errorf("failed to parse input file '%s': %w", filenameIn, err)
+	}
+
+	info, err := kongcompat.FetchGatewayInfo(cmd.Context(), kongAddr, headers)
+	if err != nil {
+		return ioError(err)
+	}
+
+	problems := kongcompat.Check(content, info)
+	if len(problems) > 0 {
+		return fmt.Errorf("configuration is not compatible with Kong %s:\n  %s",
+			info.Version, strings.Join(problems, "\n  "))
+	}
+
+	fmt.Fprintf(cmd.OutOrStdout(), "configuration is compatible with Kong %s\n", info.Version)
+	return nil
+}
+
+// checkCmd represents the check command
+var checkCmd = &cobra.Command{
+	Use:   "check",
+	Short: "Check a Kong declarative file against a live Kong Gateway",
+	Long: `Check a Kong declarative file (eg. generated by 'fw convert') against a live
+Kong Gateway. The Admin API is queried for the Kong version and available plugins,
+and the file is validated against those (format version, plugins, route features).`,
+	Args: cobra.NoArgs,
+	RunE: executeCheck,
+}
+
+func init() {
+	rootCmd.AddCommand(checkCmd)
+	checkCmd.Flags().StringP("input", "i", "-", "Kong declarative file to check. Use - to read from stdin")
+	checkCmd.Flags().String("kong-addr", "http://localhost:8001", "address of the Kong Admin API")
+	checkCmd.Flags().StringSlice("headers", nil,
+		"headers to add to Admin API requests, in 'name:value' format, eg. 'Kong-Admin-Token:secret'")
+}
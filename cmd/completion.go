@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"github.com/Kong/fw/convertoas3"
+	"github.com/spf13/cobra"
+)
+
+// fixedCompletion returns a completion function offering a fixed list of values.
+func fixedCompletion(values ...string) func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+	return func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+		return values, cobra.ShellCompDirectiveNoFileComp
+	}
+}
+
+// registerCompletions registers the completions for flag values, in addition to the
+// commands and flags themselves, which are completed by the 'completion' command
+// provided by cobra (bash, zsh, fish and powershell).
+func registerCompletions() {
+	specExtensions := []string{"yaml", "yml", "json", "gz"}
+	_ = rootCmd.RegisterFlagCompletionFunc("log-level", fixedCompletion("debug", "info", "warn"))
+	_ = rootCmd.RegisterFlagCompletionFunc("log-format", fixedCompletion("text", "json"))
+	_ = rootCmd.RegisterFlagCompletionFunc("profile", completeProfiles)
+
+	_ = convertCmd.MarkFlagFilename("input",
+		append(specExtensions, "raml", "graphql", "gql", "proto", "pb", "protoset")...)
+	_ = convertCmd.MarkFlagFilename("output", specExtensions...)
+	_ = convertCmd.MarkFlagFilename("catalog", "json")
+	_ = convertCmd.MarkFlagFilename("coverage", "json")
+	_ = convertCmd.MarkFlagFilename("portal-doc", specExtensions...)
+	_ = convertCmd.MarkFlagFilename("components", specExtensions...)
+	_ = convertCmd.MarkFlagFilename("sarif", "sarif", "json")
+	_ = convertCmd.RegisterFlagCompletionFunc("format", fixedCompletion("yaml", "json"))
+	_ = convertCmd.RegisterFlagCompletionFunc("input-format", fixedCompletion(inputFormatNames()...))
+	_ = convertCmd.RegisterFlagCompletionFunc("upstream-algorithm",
+		fixedCompletion("round-robin", "least-connections", "consistent-hashing"))
+	_ = convertCmd.RegisterFlagCompletionFunc("acl-from",
+		fixedCompletion(convertoas3.ACLFromTags, convertoas3.ACLFromScopes))
+	_ = convertCmd.RegisterFlagCompletionFunc("secrets",
+		fixedCompletion(convertoas3.SecretsWarn, convertoas3.SecretsFail, convertoas3.SecretsIgnore))
+	_ = convertCmd.MarkFlagFilename("input-ca-cert", "pem", "crt")
+
+	_ = validateExtensionsCmd.MarkFlagFilename("input", specExtensions...)
+	_ = validateExtensionsCmd.MarkFlagFilename("sarif", "sarif", "json")
+	_ = validateExtensionsCmd.MarkFlagFilename("input-ca-cert", "pem", "crt")
+
+	_ = checkCmd.MarkFlagFilename("input", specExtensions...)
+
+	_ = stripCmd.MarkFlagFilename("input", specExtensions...)
+	_ = stripCmd.MarkFlagFilename("output", specExtensions...)
+	_ = stripCmd.RegisterFlagCompletionFunc("format", fixedCompletion("yaml", "json"))
+	_ = stripCmd.MarkFlagFilename("input-ca-cert", "pem", "crt")
+
+	_ = normalizeCmd.MarkFlagFilename("input", specExtensions...)
+	_ = normalizeCmd.MarkFlagFilename("output", specExtensions...)
+	_ = normalizeCmd.RegisterFlagCompletionFunc("format", fixedCompletion("yaml", "json"))
+	_ = normalizeCmd.MarkFlagFilename("input-ca-cert", "pem", "crt")
+
+	_ = idsCmd.MarkFlagFilename("input", append(specExtensions, "raml", "graphql", "gql", "proto", "pb", "protoset")...)
+	_ = idsCmd.MarkFlagFilename("components", specExtensions...)
+	_ = idsCmd.RegisterFlagCompletionFunc("format", fixedCompletion("text", "json"))
+	_ = idsCmd.RegisterFlagCompletionFunc("input-format", fixedCompletion(inputFormatNames()...))
+	_ = idsCmd.MarkFlagFilename("input-ca-cert", "pem", "crt")
+}
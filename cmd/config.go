@@ -0,0 +1,192 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	defaultConfigFile = ".fw.yaml"
+	envPrefix         = "FW_"
+)
+
+// configKeys are flags that cannot be set from the config file, the environment, or a
+// profile, only on the command line. The config and profiles files are read from the
+// current directory, so for a cloned repository, a '--policy' command from those would
+// run without the user asking for it.
+var configKeys = map[string]bool{
+	"config":        true,
+	"help":          true,
+	"policy":        true,
+	"profiles-file": true,
+}
+
+// readConfig reads the config file given by '--config', or the default config file
+// in the current directory, if present. Returns nil if there is no config file.
+func readConfig(cmd *cobra.Command) (map[string]interface{}, error) {
+	filename, _ := cmd.Flags().GetString("config")
+	if filename == "" {
+		filename = defaultConfigFile
+		if _, err := os.Stat(filename); errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+	}
+
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, ioError(fmt.Errorf("failed to read config file: %w", err))
+	}
+	var config map[string]interface{}
+	if err := yaml.Unmarshal(content, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse config file '%s': %w", filename, err)
+	}
+	return config, nil
+}
+
+// envName returns the name of the environment variable for a flag, eg. 'FW_LOG_LEVEL'
+// for '--log-level'.
+func envName(flagName string) string {
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(flagName, "-", "_"))
+}
+
+// configValueString returns a config file value as a flag value. YAML numbers decode as
+// float64, which are formatted without an exponent, so large integers remain valid.
+func configValueString(value interface{}) string {
+	if number, ok := value.(float64); ok {
+		return strconv.FormatFloat(number, 'f', -1, 64)
+	}
+	return fmt.Sprint(value)
+}
+
+// setFlagValue sets a flag from a config file value. Lists are only accepted for
+// flags taking multiple values.
+func setFlagValue(flags *pflag.FlagSet, flag *pflag.Flag, value interface{}) error {
+	list, isList := value.([]interface{})
+	if !isList {
+		return flags.Set(flag.Name, configValueString(value))
+	}
+
+	sliceValue, ok := flag.Value.(pflag.SliceValue)
+	if !ok {
+		return fmt.Errorf("expected a single value, got a list")
+	}
+	values := make([]string, len(list))
+	for i, item := range list {
+		values[i] = configValueString(item)
+	}
+	if err := sliceValue.Replace(values); err != nil {
+		return err
+	}
+	flag.Changed = true
+	return nil
+}
+
+// applyConfig sets the flags of the command that were not given on the command line,
+// from the environment, or the config file. The precedence is:
+//
+//  1. flags on the command line
+//  2. the profiles given by '--profile' on the command line, see applyProfiles
+//  3. environment variables, 'FW_' plus the flag name, eg. 'FW_LOG_LEVEL'
+//  4. the section for the command in the config file, eg. 'convert:'
+//  5. the top level of the config file, these apply to all commands having the flag
+//  6. the profiles given by '--profile' in the environment or the config file
+//  7. the flag defaults
+//
+// Top level keys that are not flags of the command are ignored, so the config file can
+// be shared by all commands, but unknown keys in the command section are an error. The
+// configKeys are an error anywhere in the config file, and in the environment.
+func applyConfig(cmd *cobra.Command) error {
+	config, err := readConfig(cmd)
+	if err != nil {
+		return err
+	}
+
+	values := make(map[string]interface{})
+	commandLineOnly := make([]string, 0)
+	for key, value := range config {
+		if _, isSection := value.(map[string]interface{}); !isSection {
+			if configKeys[key] {
+				commandLineOnly = append(commandLineOnly, key)
+			}
+			values[key] = value
+		}
+	}
+	if section, ok := config[cmd.Name()].(map[string]interface{}); ok {
+		unknown := make([]string, 0)
+		for key, value := range section {
+			if configKeys[key] {
+				commandLineOnly = append(commandLineOnly, key)
+			} else if cmd.Flags().Lookup(key) == nil {
+				unknown = append(unknown, key)
+			}
+			values[key] = value
+		}
+		if len(unknown) > 0 {
+			sort.Strings(unknown)
+			return fmt.Errorf("unknown option(s) in the '%s' section of the config file: %s",
+				cmd.Name(), strings.Join(unknown, ", "))
+		}
+	}
+	if len(commandLineOnly) > 0 {
+		sort.Strings(commandLineOnly)
+		return fmt.Errorf("option(s) in the config file that can only be given on the command line: %s",
+			strings.Join(commandLineOnly, ", "))
+	}
+
+	// profiles the user asked for explicitly take precedence over the environment and
+	// the config file
+	flags := cmd.Flags()
+	profilesOnCommandLine := flags.Changed("profile")
+	if profilesOnCommandLine {
+		if err := applyProfiles(cmd); err != nil {
+			return err
+		}
+	}
+
+	var errs []string
+	flags.VisitAll(func(flag *pflag.Flag) {
+		if flag.Changed {
+			return
+		}
+		if configKeys[flag.Name] {
+			if _, found := os.LookupEnv(envName(flag.Name)); found {
+				errs = append(errs, fmt.Sprintf("'%s' can only be given on the command line, not using '%s'",
+					flag.Name, envName(flag.Name)))
+			}
+			return
+		}
+		if env, found := os.LookupEnv(envName(flag.Name)); found {
+			if err := flags.Set(flag.Name, env); err != nil {
+				errs = append(errs, fmt.Sprintf("invalid value for '%s': %v", envName(flag.Name), err))
+			}
+			return
+		}
+		if value, found := values[flag.Name]; found {
+			if err := setFlagValue(flags, flag, value); err != nil {
+				errs = append(errs, fmt.Sprintf("invalid value for '%s' in the config file: %v", flag.Name, err))
+			}
+		}
+	})
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "\n"))
+	}
+	if profilesOnCommandLine {
+		return nil
+	}
+	return applyProfiles(cmd)
+}
+
+func init() {
+	rootCmd.PersistentFlags().String("config", "",
+		"config file with defaults for the flags (default: '"+defaultConfigFile+"' in the current directory, if present)")
+	_ = rootCmd.MarkPersistentFlagFilename("config", "yaml", "yml")
+}
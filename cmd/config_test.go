@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newConfigTestCmd returns a command with the flags used by the config tests, and the
+// config file, with the content, given by '--config'.
+func newConfigTestCmd(t *testing.T, config string) *cobra.Command {
+	cmd := &cobra.Command{Use: "convert"}
+	cmd.Flags().String("config", "", "")
+	cmd.Flags().StringSlice("profile", nil, "")
+	cmd.Flags().String("profiles-file", "", "")
+	cmd.Flags().StringArray("policy", nil, "")
+	cmd.Flags().Int64("input-max-size", 0, "")
+	cmd.Flags().StringSlice("tags", nil, "")
+	cmd.Flags().IntSlice("ports", nil, "")
+	cmd.Flags().Bool("plain-paths", false, "")
+	cmd.Flags().Bool("exact-match", true, "")
+
+	filename := filepath.Join(t.TempDir(), ".fw.yaml")
+	require.NoError(t, os.WriteFile(filename, []byte(config), 0o600))
+	require.NoError(t, cmd.Flags().Set("config", filename))
+	return cmd
+}
+
+func Test_applyConfig_CommandLineOnly(t *testing.T) {
+	for name, config := range map[string]string{
+		"top level":     "policy: [touch pwned]\n",
+		"section":       "convert:\n  policy: [touch pwned]\n",
+		"profiles-file": "profiles-file: profiles.yaml\n",
+	} {
+		t.Run(name, func(t *testing.T) {
+			err := applyConfig(newConfigTestCmd(t, config))
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "can only be given on the command line")
+		})
+	}
+
+	t.Run("environment", func(t *testing.T) {
+		t.Setenv("FW_POLICY", "touch pwned")
+		err := applyConfig(newConfigTestCmd(t, ""))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "'policy' can only be given on the command line, not using 'FW_POLICY'")
+	})
+
+	t.Run("profile", func(t *testing.T) {
+		profilesFile := filepath.Join(t.TempDir(), "profiles.yaml")
+		require.NoError(t, os.WriteFile(profilesFile, []byte("team:\n  policy: [touch pwned]\n"), 0o600))
+		cmd := newConfigTestCmd(t, "profile: [team]\n")
+		require.NoError(t, cmd.Flags().Set("profiles-file", profilesFile))
+		err := applyConfig(cmd)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "unknown option(s) in profile 'team': policy")
+	})
+
+	t.Run("command line", func(t *testing.T) {
+		cmd := newConfigTestCmd(t, "tags: [team]\n")
+		require.NoError(t, cmd.Flags().Set("policy", "true"))
+		require.NoError(t, applyConfig(cmd))
+		policies, _ := cmd.Flags().GetStringArray("policy")
+		assert.Equal(t, []string{"true"}, policies)
+	})
+}
+
+func Test_applyConfig_LargeNumbers(t *testing.T) {
+	cmd := newConfigTestCmd(t, "input-max-size: 10000000\nports: [8080, 12345678]\n")
+	require.NoError(t, applyConfig(cmd))
+
+	size, _ := cmd.Flags().GetInt64("input-max-size")
+	assert.Equal(t, int64(10000000), size)
+	ports, _ := cmd.Flags().GetIntSlice("ports")
+	assert.Equal(t, []int{8080, 12345678}, ports)
+}
+
+func Test_applyConfig_ProfilePrecedence(t *testing.T) {
+	t.Run("profile on the command line", func(t *testing.T) {
+		cmd := newConfigTestCmd(t, "exact-match: true\nconvert:\n  exact-match: true\n")
+		require.NoError(t, cmd.Flags().Set("profile", "minimal-routing"))
+		require.NoError(t, applyConfig(cmd))
+		plainPaths, _ := cmd.Flags().GetBool("plain-paths")
+		exactMatch, _ := cmd.Flags().GetBool("exact-match")
+		assert.True(t, plainPaths)
+		assert.False(t, exactMatch)
+	})
+
+	t.Run("profile in the config file", func(t *testing.T) {
+		cmd := newConfigTestCmd(t, "profile: [minimal-routing]\nexact-match: true\n")
+		require.NoError(t, applyConfig(cmd))
+		plainPaths, _ := cmd.Flags().GetBool("plain-paths")
+		exactMatch, _ := cmd.Flags().GetBool("exact-match")
+		assert.True(t, plainPaths)
+		assert.True(t, exactMatch)
+	})
+
+	t.Run("flag on the command line", func(t *testing.T) {
+		cmd := newConfigTestCmd(t, "")
+		require.NoError(t, cmd.Flags().Set("profile", "minimal-routing"))
+		require.NoError(t, cmd.Flags().Set("plain-paths", "false"))
+		require.NoError(t, applyConfig(cmd))
+		plainPaths, _ := cmd.Flags().GetBool("plain-paths")
+		assert.False(t, plainPaths)
+	})
+}
@@ -0,0 +1,630 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/Kong/fw/catalog"
+	"github.com/Kong/fw/convertoas3"
+	"github.com/Kong/fw/deckfile"
+	"github.com/Kong/fw/filebasics"
+	"github.com/Kong/fw/golden"
+	"github.com/Kong/fw/policy"
+	uuid "github.com/satori/go.uuid"
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/yaml"
+)
+
+// Executes the CLI command "convert"
+func executeConvert(cmd *cobra.Command, _ []string) error {
+	filenameIn, _ := cmd.Flags().GetString("input")
+	filenamesOut, _ := cmd.Flags().GetStringArray("output")
+	outputFormat, _ := cmd.Flags().GetString("format")
+	compress, _ := cmd.Flags().GetBool("compress")
+	backup, _ := cmd.Flags().GetBool("backup")
+	noClobber, _ := cmd.Flags().GetBool("no-clobber")
+	jsonIndent, _ := cmd.Flags().GetInt("json-indent")
+	jsonCompact, _ := cmd.Flags().GetBool("json-compact")
+	yamlIndent, _ := cmd.Flags().GetInt("yaml-indent")
+	keyOrder, _ := cmd.Flags().GetStringSlice("key-order")
+	docName, _ := cmd.Flags().GetString("doc-name")
+	idSeed, _ := cmd.Flags().GetString("id-seed")
+	uuidNamespaceString, _ := cmd.Flags().GetString("uuid-namespace")
+	pathPrefix, _ := cmd.Flags().GetString("path-prefix")
+	pathPrefixFromVersion, _ := cmd.Flags().GetBool("path-prefix-from-version")
+	plainPaths, _ := cmd.Flags().GetBool("plain-paths")
+	exactMatch, _ := cmd.Flags().GetBool("exact-match")
+	trailingSlash, _ := cmd.Flags().GetString("trailing-slash")
+	corsPreflight, _ := cmd.Flags().GetBool("cors-preflight")
+	headForGet, _ := cmd.Flags().GetBool("head-for-get")
+	serviceTimeouts, _ := cmd.Flags().GetIntSlice("service-timeouts")
+	upstreamAlgorithm, _ := cmd.Flags().GetString("upstream-algorithm")
+	upstreamHashOn, _ := cmd.Flags().GetString("upstream-hash-on")
+	upstreamHashFallback, _ := cmd.Flags().GetString("upstream-hash-fallback")
+	upstreamHostHeader, _ := cmd.Flags().GetBool("upstream-host-header")
+	targetOSS, _ := cmd.Flags().GetBool("oss")
+	targetEnterprise, _ := cmd.Flags().GetBool("enterprise")
+	kongVersion, _ := cmd.Flags().GetString("kong-version")
+	allowedPlugins, _ := cmd.Flags().GetStringSlice("allowed-plugins")
+	deniedPlugins, _ := cmd.Flags().GetStringSlice("denied-plugins")
+	secrets, _ := cmd.Flags().GetString("secrets")
+	workspace, _ := cmd.Flags().GetString("workspace")
+	aclSource, _ := cmd.Flags().GetString("acl-from")
+	pluginInheritance, _ := cmd.Flags().GetString("plugin-inheritance")
+	dedupPlugins, _ := cmd.Flags().GetBool("dedup-plugins")
+	environments, _ := cmd.Flags().GetString("environments-from")
+	provenanceTags, _ := cmd.Flags().GetBool("provenance-tags")
+	securityPlugins, _ := cmd.Flags().GetBool("security-plugins")
+	responseHeaders, _ := cmd.Flags().GetBool("response-headers")
+	callbacks, _ := cmd.Flags().GetBool("callbacks")
+	webhookServer, _ := cmd.Flags().GetString("webhook-server")
+	bestEffort, _ := cmd.Flags().GetBool("best-effort")
+	strict, _ := cmd.Flags().GetBool("strict")
+	validateSpec, _ := cmd.Flags().GetBool("validate")
+	validateOutput, _ := cmd.Flags().GetBool("validate-output")
+	outputSchema, _ := cmd.Flags().GetString("output-schema")
+	workers, _ := cmd.Flags().GetInt("workers")
+	failOnWarn, _ := cmd.Flags().GetBool("fail-on-warn")
+	embedVersion, _ := cmd.Flags().GetBool("embed-version")
+	catalogFile, _ := cmd.Flags().GetString("catalog")
+	portalDocFile, _ := cmd.Flags().GetString("portal-doc")
+	coverageFile, _ := cmd.Flags().GetString("coverage")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	expectFile, _ := cmd.Flags().GetString("expect")
+	updateGolden, _ := cmd.Flags().GetBool("update-golden")
+	policies, _ := cmd.Flags().GetStringArray("policy")
+
+	var asYaml bool
+	switch strings.ToLower(outputFormat) {
+	case "yaml":
+		asYaml = true
+	case "json":
+		asYaml = false
+	default:
+		return fmt.Errorf("expected '--format' to be either 'yaml' or 'json', got: '%s'", outputFormat)
+	}
+
+	uuidNamespace := uuid.NamespaceDNS
+	if uuidNamespaceString != "" {
+		var err error
+		if uuidNamespace, err = uuid.FromString(uuidNamespaceString); err != nil {
+			return fmt.Errorf("expected '--uuid-namespace' to be a valid UUID: %w", err)
+		}
+	}
+
+	options := []convertoas3.Option{
+		convertoas3.WithDocName(docName),
+		convertoas3.WithIDSeed(idSeed),
+		convertoas3.WithUUIDNamespace(uuidNamespace),
+		convertoas3.WithPathPrefix(pathPrefix),
+		convertoas3.WithTrailingSlash(trailingSlash),
+		convertoas3.WithUpstreamAlgorithm(upstreamAlgorithm),
+		convertoas3.WithUpstreamHash(upstreamHashOn, upstreamHashFallback),
+		convertoas3.WithWorkspace(workspace),
+		convertoas3.WithACLSource(aclSource),
+		convertoas3.WithPluginInheritance(pluginInheritance),
+		convertoas3.WithEnvironments(environments),
+		convertoas3.WithWorkers(workers),
+	}
+	baseLogger, err := newLogger(cmd)
+	if err != nil {
+		return err
+	}
+	recorder := &recordingLogger{Logger: baseLogger}
+	logger := &countingLogger{Logger: recorder}
+	options = append(options, convertoas3.WithLogger(logger))
+	if pathPrefixFromVersion {
+		options = append(options, convertoas3.WithPathPrefixFromVersion())
+	}
+	if plainPaths {
+		options = append(options, convertoas3.WithPlainPaths())
+	}
+	if !exactMatch {
+		options = append(options, convertoas3.WithPrefixMatch())
+	}
+	if !dedupPlugins {
+		options = append(options, convertoas3.WithKeepDuplicatePlugins())
+	}
+	if corsPreflight {
+		options = append(options, convertoas3.WithCORSPreflight())
+	}
+	if headForGet {
+		options = append(options, convertoas3.WithHeadForGet())
+	}
+	if upstreamHostHeader {
+		options = append(options, convertoas3.WithUpstreamHostHeader())
+	}
+	if bestEffort {
+		options = append(options, convertoas3.WithBestEffort())
+	}
+	if strict {
+		options = append(options, convertoas3.WithStrictExtensions())
+	}
+	if validateSpec {
+		options = append(options, convertoas3.WithValidateSpec())
+	}
+	if provenanceTags {
+		options = append(options, convertoas3.WithProvenanceTags())
+	}
+	if securityPlugins {
+		options = append(options, convertoas3.WithSecurityPlugins())
+	}
+	if responseHeaders {
+		options = append(options, convertoas3.WithResponseHeaders())
+	}
+	if callbacks {
+		options = append(options, convertoas3.WithCallbacks())
+	}
+	if webhookServer != "" {
+		options = append(options, convertoas3.WithWebhookServer(webhookServer))
+	}
+	if embedVersion {
+		options = append(options, convertoas3.WithGenerator(generatorName()))
+	}
+	if targetOSS {
+		options = append(options, convertoas3.WithPluginTier(convertoas3.PluginTierOSS))
+	}
+	if targetEnterprise {
+		options = append(options, convertoas3.WithPluginTier(convertoas3.PluginTierEnterprise))
+	}
+	if kongVersion != "" {
+		options = append(options, convertoas3.WithKongVersion(kongVersion))
+	}
+	if len(allowedPlugins) > 0 {
+		options = append(options, convertoas3.WithAllowedPlugins(allowedPlugins...))
+	}
+	if len(deniedPlugins) > 0 {
+		options = append(options, convertoas3.WithDeniedPlugins(deniedPlugins...))
+	}
+	if secrets != "" {
+		options = append(options, convertoas3.WithSecrets(secrets))
+	}
+	if len(serviceTimeouts) > 0 {
+		if len(serviceTimeouts) != 3 {
+			return fmt.Errorf("expected '--service-timeouts' to have 3 values; connect,read,write")
+		}
+		options = append(options,
+			convertoas3.WithServiceTimeouts(serviceTimeouts[0], serviceTimeouts[1], serviceTimeouts[2]))
+	}
+	if cmd.Flags().Changed("retries") {
+		retries, _ := cmd.Flags().GetInt("retries")
+		options = append(options, convertoas3.WithRetries(retries))
+	}
+	if cmd.Flags().Changed("tags") {
+		tags, _ := cmd.Flags().GetStringSlice("tags")
+		options = append(options, convertoas3.WithTags(tags))
+	}
+
+	if updateGolden && expectFile == "" {
+		return fmt.Errorf("'--update-golden' requires '--expect' with the golden file to write")
+	}
+	// in check mode only the '--expect' file is compared (or written), like a dry run
+	checkMode := dryRun || expectFile != ""
+
+	// report option errors before reading any input
+	o2kOptions := convertoas3.NewO2kOptions(options...)
+	if err := o2kOptions.Validate(); err != nil {
+		return err
+	}
+	if _, err := getConverter(cmd, nil); err != nil {
+		return err
+	}
+	outputOptions := make([]filebasics.OutputOptions, len(filenamesOut))
+	for i, filenameOut := range filenamesOut {
+		for _, other := range filenamesOut[:i] {
+			if other == filenameOut {
+				return fmt.Errorf("expected each '--output' to be unique, got '%s' more than once", filenameOut)
+			}
+		}
+		outputOptions[i] = filebasics.OutputOptions{
+			AsYaml:    asYaml,
+			Compress:  compress,
+			Backup:    backup,
+			NoClobber: noClobber,
+
+			JSONIndent:  jsonIndent,
+			JSONCompact: jsonCompact,
+			YAMLIndent:  yamlIndent,
+			KeyOrder:    keyOrder,
+		}
+		if !cmd.Flags().Changed("format") {
+			outputOptions[i].AsYaml = isYamlOutput(filenameOut, asYaml)
+		}
+		if checkMode {
+			continue // nothing is written
+		}
+		if err := filebasics.CheckOutput(filenameOut, outputOptions[i]); err != nil {
+			return ioError(err)
+		}
+	}
+	if catalogFile != "" && !checkMode {
+		if err := filebasics.CheckOutput(catalogFile, filebasics.OutputOptions{NoClobber: noClobber}); err != nil {
+			return ioError(err)
+		}
+	}
+	if coverageFile != "" && !checkMode {
+		if err := filebasics.CheckOutput(coverageFile, filebasics.OutputOptions{NoClobber: noClobber}); err != nil {
+			return ioError(err)
+		}
+	}
+	portalDocOptions := filebasics.OutputOptions{AsYaml: isYamlOutput(portalDocFile, asYaml), NoClobber: noClobber}
+	if portalDocFile != "" && !checkMode {
+		if err := filebasics.CheckOutput(portalDocFile, portalDocOptions); err != nil {
+			return ioError(err)
+		}
+	}
+
+	// do the work: read/convert/write
+	components, err := readComponents(cmd)
+	if err != nil {
+		return err
+	}
+	o2kOptions.Components = components
+	content, err := readInput(cmd, filenameIn)
+	if err != nil {
+		return err
+	}
+	format := inputFormat(cmd, content)
+	if portalDocFile != "" && format != "openapi" {
+		return fmt.Errorf("'--portal-doc' requires an OpenAPI spec as input, got: '%s'", format)
+	}
+	if coverageFile != "" && format != "openapi" {
+		return fmt.Errorf("'--coverage' requires an OpenAPI spec as input, got: '%s'", format)
+	}
+	convert, _ := getConverter(cmd, content)
+	deckData, err := convert(cmd.Context(), content, o2kOptions)
+	locateErrors(err, filenameIn, content)
+	sarifErr := writeSarif(cmd, filenameIn, content, recorder.warnings, err, ruleConversionError)
+	if err != nil {
+		return err
+	}
+	if sarifErr != nil {
+		return sarifErr
+	}
+	for _, command := range policies {
+		evaluated, err := policy.Evaluate(cmd.Context(), command, deckData)
+		if err != nil {
+			var violations *policy.Violations
+			if errors.As(err, &violations) {
+				return &exitError{code: ExitPolicy, err: err}
+			}
+			return err
+		}
+		if reflect.ValueOf(evaluated).Pointer() != reflect.ValueOf(deckData).Pointer() {
+			// the policy wrote a new declarative file, which must pass the same plugin checks
+			if err := convertoas3.CheckPlugins(evaluated, o2kOptions); err != nil {
+				return fmt.Errorf("output of policy '%s': %w", command, err)
+			}
+		}
+		deckData = evaluated
+	}
+	if validateOutput || outputSchema != "" {
+		if err := checkOutputSchema(cmd, filenameIn, outputSchema, deckData); err != nil {
+			return err
+		}
+	}
+	if failOnWarn && logger.Warnings() > 0 {
+		return &exitError{
+			code: ExitWarnings,
+			err:  fmt.Errorf("%d warning(s) reported, and '--fail-on-warn' is set", logger.Warnings()),
+		}
+	}
+	if dryRun {
+		fmt.Fprintf(cmd.OutOrStdout(), "converted '%s' (dry run, nothing written): %s, %d warning(s)\n",
+			filenameIn, entityCounts(deckData), logger.Warnings())
+		return nil
+	}
+	if expectFile != "" {
+		return checkExpected(cmd, filenameIn, expectFile, deckData, updateGolden)
+	}
+	for i, filenameOut := range filenamesOut {
+		if err := filebasics.WriteSerializedFile(filenameOut, deckData, outputOptions[i]); err != nil {
+			return ioError(err)
+		}
+	}
+	if portalDocFile != "" {
+		portalDoc, err := convertoas3.StripExtensions(content)
+		if err != nil {
+			return err
+		}
+		if err := filebasics.WriteSerializedFile(portalDocFile, portalDoc, portalDocOptions); err != nil {
+			return ioError(err)
+		}
+	}
+	if catalogFile != "" {
+		data, err := catalog.New(*content, deckData).Marshal()
+		if err != nil {
+			return err
+		}
+		if err := filebasics.WriteFile(catalogFile, &data); err != nil {
+			return ioError(err)
+		}
+	}
+	if coverageFile != "" {
+		report, err := convertoas3.Coverage(cmd.Context(), content, o2kOptions)
+		if err != nil {
+			return err
+		}
+		data, err := report.Marshal()
+		if err != nil {
+			return err
+		}
+		if err := filebasics.WriteFile(coverageFile, &data); err != nil {
+			return ioError(err)
+		}
+	}
+
+	fmt.Fprintf(cmd.ErrOrStderr(), "converted '%s': %s, %d warning(s)\n",
+		filenameIn, entityCounts(deckData), logger.Warnings())
+	return nil
+}
+
+// checkOutputSchema validates the generated file against the declarative config schema;
+// the one read from the schema file (or URL), or the vendored one if not given. Returns
+// an error listing the violations, if any.
+func checkOutputSchema(
+	cmd *cobra.Command, filenameIn string, schemaFile string, deckData map[string]interface{},
+) error {
+	var schema []byte
+	if schemaFile != "" {
+		content, err := readInput(cmd, schemaFile)
+		if err != nil {
+			return err
+		}
+		if schema, err = yaml.YAMLToJSON(*content); err != nil {
+			return fmt.Errorf("failed to parse schema file '%s': %w", schemaFile, err)
+		}
+	}
+	violations, err := deckfile.Validate(deckData, schema)
+	if err != nil {
+		return err
+	}
+	if len(violations) > 0 {
+		return fmt.Errorf("converted '%s' is not a valid declarative configuration:\n  %s",
+			filenameIn, strings.Join(violations, "\n  "))
+	}
+	return nil
+}
+
+// checkExpected compares the generated file to the '--expect' golden file, and returns
+// an error with the differences if they are not equal. With update set, the golden file
+// is written instead.
+func checkExpected(
+	cmd *cobra.Command, filenameIn string, expectFile string, deckData map[string]interface{}, update bool,
+) error {
+	actual, err := golden.Normalize(deckData)
+	if err != nil {
+		return err
+	}
+	if update {
+		if err := golden.Write(expectFile, actual); err != nil {
+			return ioError(err)
+		}
+		fmt.Fprintf(cmd.ErrOrStderr(), "converted '%s': updated '%s'\n", filenameIn, expectFile)
+		return nil
+	}
+
+	expected, err := golden.Read(expectFile)
+	if err != nil {
+		return ioError(err)
+	}
+	if diff := golden.Diff(expected, actual); diff != "" {
+		return &exitError{
+			code: ExitMismatch,
+			err: fmt.Errorf("converted '%s' differs from '%s' (-expected +actual):\n%s\n"+
+				"if the change is intended, use '--update-golden' to update it", filenameIn, expectFile, diff),
+		}
+	}
+	fmt.Fprintf(cmd.ErrOrStderr(), "converted '%s': matches '%s'\n", filenameIn, expectFile)
+	return nil
+}
+
+// pluginCount returns the number of plugins in a plugin list, as generated by the
+// converters; either a []interface{}, or a (pointer to a) []*map[string]interface{}.
+func pluginCount(plugins interface{}) int {
+	switch list := plugins.(type) {
+	case []interface{}:
+		return len(list)
+	case []*map[string]interface{}:
+		return len(list)
+	case *[]*map[string]interface{}:
+		if list != nil {
+			return len(*list)
+		}
+	}
+	return 0
+}
+
+// entityCounts returns the number of generated entities, for the summary.
+func entityCounts(deckData map[string]interface{}) string {
+	services, _ := deckData["services"].([]interface{})
+	upstreams, _ := deckData["upstreams"].([]interface{})
+	routes := 0
+	plugins := pluginCount(deckData["plugins"])
+	for _, service := range services {
+		if service, ok := service.(map[string]interface{}); ok {
+			plugins += pluginCount(service["plugins"])
+			serviceRoutes, _ := service["routes"].([]interface{})
+			routes += len(serviceRoutes)
+			for _, route := range serviceRoutes {
+				if route, ok := route.(map[string]interface{}); ok {
+					plugins += pluginCount(route["plugins"])
+				}
+			}
+		}
+	}
+	return fmt.Sprintf("%d service(s), %d route(s), %d upstream(s), %d plugin(s)",
+		len(services), routes, len(upstreams), plugins)
+}
+
+// isYamlOutput returns whether to write the output file as YAML, based on its
+// extension, ignoring a '.gz' suffix. Returns the default for other extensions.
+func isYamlOutput(filename string, defaultYaml bool) bool {
+	switch filepath.Ext(strings.TrimSuffix(strings.ToLower(filename), ".gz")) {
+	case ".yaml", ".yml":
+		return true
+	case ".json":
+		return false
+	default:
+		return defaultYaml
+	}
+}
+
+// convertCmd represents the convert command
+var convertCmd = &cobra.Command{
+	Use:   "convert",
+	Short: "Convert an OpenAPI spec to a Kong declarative file",
+	Long: `Convert an OpenAPI 3 spec to a Kong declarative file.
+
+The x-kong-... extensions in the spec are used to control the generated
+entities (services, routes, upstreams and plugins).`,
+	Args: cobra.NoArgs,
+	RunE: executeConvert,
+}
+
+func init() {
+	rootCmd.AddCommand(convertCmd)
+	convertCmd.Flags().StringP("input", "i", "-",
+		"OpenAPI spec (or other input format) file, or http(s) URL, to process. Use - to read from stdin. Can be gzipped")
+	convertCmd.Flags().String("input-format", autoInputFormat,
+		"format of the input: "+strings.Join(inputFormatNames(), ", ")+". For 'auto' it is detected from the content")
+	convertCmd.Flags().String("insomnia-environment", "",
+		"name of the Insomnia sub environment to resolve variables from, besides the base environment")
+	convertCmd.Flags().String("grpc-server", "",
+		"URL of the gRPC backend, for gRPC input, eg. 'grpc://greeter:50051' (default 'grpcs://localhost:443')")
+	convertCmd.Flags().Bool("grpc-web", false,
+		"for gRPC input, add the 'grpc-web' plugin and route gRPC-Web requests, instead of gRPC requests")
+	addInputURLFlags(convertCmd)
+	convertCmd.Flags().String("components", "",
+		"file, or http(s) URL, with shared 'x-kong' components, that '#/components/x-kong/...' references resolve to")
+	convertCmd.Flags().StringArrayP("output", "o", []string{"-"},
+		"output file to write, can be repeated to write multiple files. Use - to write to stdout")
+	convertCmd.Flags().StringP("format", "f", "yaml",
+		"output format: yaml or json. If omitted, the format is taken from the output file extension")
+	convertCmd.Flags().Bool("compress", false,
+		"gzip the output, this is implied if the output filename has a '.gz' suffix")
+	convertCmd.Flags().Bool("backup", false,
+		"keep a copy of an existing output file, with a '.bak' suffix")
+	convertCmd.Flags().Bool("no-clobber", false, "fail if the output file already exists")
+	convertCmd.Flags().String("sarif", "",
+		"SARIF file to write the conversion warnings and errors to, eg. for GitHub code scanning")
+	convertCmd.Flags().Bool("dry-run", false,
+		"convert, but only print a summary of the generated entities, without writing any output")
+	convertCmd.Flags().String("expect", "",
+		"check mode: compare the output to this golden file, and fail with a diff if it differs. Writes no output")
+	convertCmd.Flags().Bool("update-golden", false,
+		"with '--expect', write the output to the golden file instead of comparing it")
+	convertCmd.MarkFlagsMutuallyExclusive("dry-run", "expect")
+	convertCmd.MarkFlagsMutuallyExclusive("backup", "no-clobber")
+	convertCmd.Flags().Int("json-indent", 2, "number of spaces to indent JSON output with")
+	convertCmd.Flags().Bool("json-compact", false, "write JSON output without any whitespace")
+	convertCmd.MarkFlagsMutuallyExclusive("json-indent", "json-compact")
+	convertCmd.Flags().Int("yaml-indent", 0,
+		"number of spaces to indent YAML output with, also indents arrays in objects (default: 2, arrays not indented)")
+	convertCmd.Flags().StringSlice("key-order", nil,
+		"object keys to write first, in this order, eg. 'name,id'. Remaining keys are sorted")
+	convertCmd.Flags().StringSlice("tags", nil,
+		"tags to mark all generated entities with, takes precedence over 'x-kong-tags'")
+	convertCmd.Flags().String("doc-name", "",
+		"base name for the document, takes precedence over 'x-kong-name' and 'info.title'")
+	convertCmd.Flags().String("id-seed", "",
+		"seed for the generated IDs instead of the document name, takes precedence over 'x-kong-id-seed'")
+	convertCmd.Flags().String("uuid-namespace", uuid.NamespaceDNS.String(),
+		"namespace for UUID generation (UUIDv5)")
+	convertCmd.Flags().String("path-prefix", "",
+		"prefix to add to all route paths, stripped from the upstream path. Takes precedence over "+
+			"--path-prefix-from-version")
+	convertCmd.Flags().Bool("path-prefix-from-version", false,
+		"prefix all route paths with '/v{major}', taken from 'info.version'")
+	convertCmd.Flags().Bool("plain-paths", false,
+		"use plain paths for routes without path parameters, instead of regexes. Kong matches plain paths "+
+			"as a prefix, eg. '/users' also matches '/users/1' and '/usersX', so it requires --exact-match=false")
+	convertCmd.Flags().Bool("exact-match", true,
+		"anchor regex paths with a '$' to match the full path, set to false to match all paths as a prefix")
+	convertCmd.Flags().String("trailing-slash", convertoas3.TrailingSlashStrict,
+		"matching of paths with and without a trailing slash; 'strict' (as given only), 'optional' "+
+			"(both, in one route), or 'duplicate' (both, with an additional route)")
+	convertCmd.Flags().Bool("cors-preflight", false,
+		"add the OPTIONS method to routes with a 'cors' plugin, to match preflight requests")
+	convertCmd.Flags().Bool("head-for-get", false,
+		"add the HEAD method to routes of GET operations, unless the path defines a HEAD operation")
+	convertCmd.Flags().IntSlice("service-timeouts", nil,
+		"timeouts (in ms) to set on services; connect,read,write. Unless set in 'x-kong-service-defaults'")
+	convertCmd.Flags().Int("retries", 0,
+		"retries to set on services, unless set in 'x-kong-service-defaults'")
+	convertCmd.Flags().String("upstream-algorithm", "",
+		"load balancing algorithm for upstreams; round-robin, least-connections, or consistent-hashing")
+	convertCmd.Flags().String("upstream-hash-on", "",
+		"what to hash on for upstreams, as 'type[:input]', eg. 'header:X-User-Id', 'ip', or 'consumer'")
+	convertCmd.Flags().String("upstream-hash-fallback", "",
+		"what to hash on for upstreams if the primary 'hash-on' is not present, as 'type[:input]', "+
+			"a cookie is shared with 'hash-on'")
+	convertCmd.Flags().Bool("upstream-host-header", false,
+		"set the 'host_header' of upstreams to the hostname of the (first) server")
+	convertCmd.Flags().Bool("oss", false, "target Kong OSS, fails if Enterprise-only plugins are used")
+	convertCmd.Flags().Bool("enterprise", false, "target Kong Enterprise, all bundled plugins are allowed")
+	convertCmd.MarkFlagsMutuallyExclusive("oss", "enterprise")
+	convertCmd.Flags().StringSlice("allowed-plugins", nil,
+		"the only plugins allowed in the output, fails listing any others, whether given in the spec or generated")
+	convertCmd.Flags().StringSlice("denied-plugins", nil,
+		"plugins not allowed in the output, eg. 'pre-function', fails listing where they are used")
+	convertCmd.Flags().String("secrets", convertoas3.SecretsWarn,
+		"handling of plugin config fields like 'client_secret' or 'redis_password', and consumer keys, "+
+			"with literal values instead of vault references; 'warn', 'fail', or 'ignore'")
+	convertCmd.Flags().String("kong-version", "",
+		"Kong version to target, eg. '2.8' or '3.4', fails if the spec requires features it does not support")
+	convertCmd.Flags().String("workspace", "",
+		"Kong Enterprise workspace for the output, takes precedence over 'x-kong-workspace'")
+	convertCmd.Flags().String("plugin-inheritance", convertoas3.PluginInheritanceMixed,
+		"attachment of inherited plugins; 'mixed' (document plugins on the service, path plugins on "+
+			"the routes), 'service' (once, on the services), or 'route' (on each route)")
+	convertCmd.Flags().Bool("dedup-plugins", true,
+		"move plugins all routes of a service have with the same config to the service, "+
+			"set to false to keep them on each route")
+	convertCmd.Flags().String("acl-from", "",
+		"generate 'acl' plugins on routes, allowing groups from the operation 'tags' or oauth2 'scopes' "+
+			"(a single scope per requirement); they need an authentication plugin to have effect")
+	convertCmd.Flags().String("environments-from", "",
+		"server variable with an enum of environments, generates tagged entities per environment")
+	convertCmd.Flags().Bool("security-plugins", false,
+		"generate authentication plugins on routes from the 'security' requirements, eg. 'key-auth' for apiKey schemes")
+	convertCmd.Flags().Bool("response-headers", false,
+		"generate response-transformer plugins on routes setting the static response headers, eg. 'Cache-Control'")
+	convertCmd.Flags().Bool("callbacks", false,
+		"generate services and routes for the callback receivers of the operations, with a static server URL")
+	convertCmd.Flags().String("webhook-server", "",
+		"URL of the service receiving the OpenAPI 3.1 webhooks, each becomes a route '/webhooks/<name>' on it")
+	convertCmd.Flags().Bool("provenance-tags", false,
+		"tag each route with the spec document, path, and operation it was generated from")
+	convertCmd.Flags().Bool("best-effort", false,
+		"skip paths and operations that fail to convert, and report them as warnings")
+	convertCmd.Flags().Bool("strict", false,
+		"fail on unknown 'x-kong-...' extensions, instead of warning about them")
+	convertCmd.Flags().Bool("validate", false,
+		"validate the spec against the OpenAPI specification before converting")
+	convertCmd.Flags().StringArray("policy", nil,
+		"policy command to run on the generated file, can be repeated. It gets the file as JSON on stdin, "+
+			"and rejects it by failing or writing a JSON array of violations, or mutates it by writing a new file. "+
+			"Only accepted on the command line")
+	convertCmd.Flags().Bool("validate-output", false,
+		"validate the generated file against the declarative config schema, eg. port ranges and protocols")
+	convertCmd.Flags().String("output-schema", "",
+		"declarative config schema file, or http(s) URL, to validate the generated file against "+
+			"(implies '--validate-output'), instead of the vendored one")
+	convertCmd.Flags().Bool("fail-on-warn", false,
+		"fail, without writing the output, if any warnings are reported (eg. skipped paths or defaulted hosts)")
+	convertCmd.Flags().Bool("embed-version", false,
+		"add the version of fw to the output, as '_info.generator'")
+	convertCmd.Flags().String("portal-doc", "",
+		"also write the spec with all 'x-kong-...' extensions removed, for publishing on a developer portal")
+	convertCmd.Flags().String("catalog", "",
+		"also write a catalog of the generated services and routes, with the owners and tags from the spec, as JSON")
+	convertCmd.Flags().String("coverage", "",
+		"also write a report of the spec features ignored by the conversion, per operation, as JSON, "+
+			"eg. callbacks, links, and response schemas")
+	convertCmd.Flags().Int("workers", 0,
+		"number of paths to convert concurrently, defaults to the number of CPUs")
+}
@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/cobra/doc"
+)
+
+// Executes the CLI command "docs man"
+func executeDocsMan(cmd *cobra.Command, _ []string) error {
+	dir, _ := cmd.Flags().GetString("dir")
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return ioError(fmt.Errorf("failed to create directory '%s': %w", dir, err))
+	}
+	header := &doc.GenManHeader{
+		Title:   "FW",
+		Section: "1",
+		Source:  "fw",
+		Manual:  "fw manual",
+	}
+	if err := doc.GenManTree(rootCmd, header, dir); err != nil {
+		return ioError(fmt.Errorf("failed to write man pages: %w", err))
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "man pages written to '%s'\n", dir)
+	return nil
+}
+
+// docsCmd represents the docs command
+var docsCmd = &cobra.Command{
+	Use:   "docs",
+	Short: "Generate documentation for fw",
+	Args:  cobra.NoArgs,
+}
+
+// docsManCmd represents the docs man command
+var docsManCmd = &cobra.Command{
+	Use:   "man",
+	Short: "Generate man pages for fw",
+	Long: `Generate man pages for fw and all its commands, rendered from the command
+definitions. One page is written per command, eg. 'fw-convert.1'.`,
+	Args: cobra.NoArgs,
+	RunE: executeDocsMan,
+}
+
+func init() {
+	rootCmd.AddCommand(docsCmd)
+	docsCmd.AddCommand(docsManCmd)
+	docsManCmd.Flags().String("dir", ".", "directory to write the man pages to")
+	_ = docsManCmd.MarkFlagDirname("dir")
+}
@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sync/atomic"
+
+	"github.com/Kong/fw/convertoas3"
+)
+
+// Exit codes of the fw command.
+const (
+	ExitOK              = 0 // success
+	ExitConversionError = 1 // invalid options, or the input could not be converted
+	ExitWarnings        = 2 // converted, but with warnings, and '--fail-on-warn' was given
+	ExitIOError         = 3 // reading the input, or writing the output, failed
+	ExitMismatch        = 4 // converted, but the output differs from the '--expect' file
+	ExitPolicy          = 5 // converted, but a '--policy' rejected the output
+)
+
+// exitError is an error that determines the exit code of the command.
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string {
+	return e.err.Error()
+}
+
+func (e *exitError) Unwrap() error {
+	return e.err
+}
+
+// ioError marks the error as an I/O error, see ExitIOError. Returns nil if err is nil.
+func ioError(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &exitError{code: ExitIOError, err: err}
+}
+
+// exitCode returns the exit code for the error returned by a command. Errors not
+// marked otherwise are conversion errors.
+func exitCode(err error) int {
+	if err == nil {
+		return ExitOK
+	}
+	var exitErr *exitError
+	if errors.As(err, &exitErr) {
+		return exitErr.code
+	}
+	return ExitConversionError
+}
+
+// exitCodeDescription returns a short description of the exit code, for the summary.
+func exitCodeDescription(code int) string {
+	switch code {
+	case ExitOK:
+		return "success"
+	case ExitConversionError:
+		return "conversion error"
+	case ExitWarnings:
+		return "warnings"
+	case ExitIOError:
+		return "I/O error"
+	case ExitMismatch:
+		return "output differs from expected"
+	case ExitPolicy:
+		return "rejected by a policy"
+	default:
+		return "unknown error"
+	}
+}
+
+// printSummary writes the final error and summary line for a failed command.
+func printSummary(w io.Writer, err error) int {
+	code := exitCode(err)
+	fmt.Fprintln(w, "Error:", err)
+	fmt.Fprintf(w, "fw failed: %s (exit code %d)\n", exitCodeDescription(code), code)
+	return code
+}
+
+// countingLogger is a convertoas3.Logger that counts the warnings, before passing
+// them on. It is safe for concurrent use.
+type countingLogger struct {
+	convertoas3.Logger
+	warnings int64
+}
+
+func (l *countingLogger) Warn(msg string, keysAndValues ...interface{}) {
+	atomic.AddInt64(&l.warnings, 1)
+	l.Logger.Warn(msg, keysAndValues...)
+}
+
+// Warnings returns the number of warnings logged so far.
+func (l *countingLogger) Warnings() int {
+	return int(atomic.LoadInt64(&l.warnings))
+}
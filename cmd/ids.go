@@ -0,0 +1,123 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/Kong/fw/convertoas3"
+	uuid "github.com/satori/go.uuid"
+	"github.com/spf13/cobra"
+)
+
+// Executes the CLI command "ids"
+func executeIDs(cmd *cobra.Command, _ []string) error {
+	filenameIn, _ := cmd.Flags().GetString("input")
+	outputFormat, _ := cmd.Flags().GetString("format")
+	docName, _ := cmd.Flags().GetString("doc-name")
+	idSeed, _ := cmd.Flags().GetString("id-seed")
+	uuidNamespaceString, _ := cmd.Flags().GetString("uuid-namespace")
+	pluginInheritance, _ := cmd.Flags().GetString("plugin-inheritance")
+	dedupPlugins, _ := cmd.Flags().GetBool("dedup-plugins")
+
+	outputFormat = strings.ToLower(outputFormat)
+	if outputFormat != "text" && outputFormat != "json" {
+		return fmt.Errorf("expected '--format' to be either 'text' or 'json', got: '%s'", outputFormat)
+	}
+	uuidNamespace, err := uuid.FromString(uuidNamespaceString)
+	if err != nil {
+		return fmt.Errorf("expected '--uuid-namespace' to be a valid UUID: %w", err)
+	}
+	if _, err := getConverter(cmd, nil); err != nil {
+		return err
+	}
+
+	components, err := readComponents(cmd)
+	if err != nil {
+		return err
+	}
+	content, err := readInput(cmd, filenameIn)
+	if err != nil {
+		return err
+	}
+	convert, _ := getConverter(cmd, content)
+	options := []convertoas3.Option{
+		convertoas3.WithDocName(docName),
+		convertoas3.WithIDSeed(idSeed),
+		convertoas3.WithUUIDNamespace(uuidNamespace),
+		convertoas3.WithComponents(components),
+		convertoas3.WithPluginInheritance(pluginInheritance),
+	}
+	if !dedupPlugins {
+		options = append(options, convertoas3.WithKeepDuplicatePlugins())
+	}
+	deckData, err := convert(cmd.Context(), content, convertoas3.NewO2kOptions(options...))
+	if err != nil {
+		return err
+	}
+	ids := convertoas3.EntityIDs(deckData)
+
+	if outputFormat == "json" {
+		data, err := json.MarshalIndent(ids, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(cmd.OutOrStdout(), string(data))
+		return err
+	}
+	table := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "KIND\tNAME\tID")
+	for _, id := range ids {
+		name := id.Name
+		if id.Owner != "" {
+			name = strings.TrimSpace(name + " (on " + id.Owner + ")")
+		}
+		fmt.Fprintf(table, "%s\t%s\t%s\n", id.Kind, name, id.ID)
+	}
+	return table.Flush()
+}
+
+// idsCmd represents the ids command
+var idsCmd = &cobra.Command{
+	Use:   "ids",
+	Short: "Print the IDs generated for the entities of a spec",
+	Long: `Print the IDs generated for the entities of a spec; the services, routes, upstreams,
+plugins, consumers, and consumer groups. The IDs are UUIDv5, derived from the entity
+names and the document name (or the ID seed) in the UUID namespace, so they are the
+same for every conversion, and can be referenced up front, eg. in monitoring config.
+
+The IDs are those of a conversion with the default options, except for the options
+given here. '--plugin-inheritance' and '--dedup-plugins' decide whether a plugin is on
+a service or on each route, which changes its ID. Other options of 'convert' can change
+the IDs of plugins as well; options adding plugins, eg. '--security-plugins', or
+changing their config, can change which plugins all routes of a service share, and so
+are moved to the service. Options adding entities, eg. '--trailing-slash duplicate',
+add IDs. Use 'convert' with the same options for the exact IDs.`,
+	Args: cobra.NoArgs,
+	RunE: executeIDs,
+}
+
+func init() {
+	rootCmd.AddCommand(idsCmd)
+	idsCmd.Flags().StringP("input", "i", "-",
+		"OpenAPI spec (or other input format) file, or http(s) URL, to process. Use - to read from stdin")
+	idsCmd.Flags().String("input-format", autoInputFormat,
+		"format of the input: "+strings.Join(inputFormatNames(), ", ")+". For 'auto' it is detected from the content")
+	addInputURLFlags(idsCmd)
+	idsCmd.Flags().String("components", "",
+		"file, or http(s) URL, with shared 'x-kong' components, that '#/components/x-kong/...' references resolve to")
+	idsCmd.Flags().StringP("format", "f", "text", "output format: text or json")
+	idsCmd.Flags().String("doc-name", "",
+		"base name for the document, takes precedence over 'x-kong-name' and 'info.title'")
+	idsCmd.Flags().String("id-seed", "",
+		"seed for the generated IDs instead of the document name, takes precedence over 'x-kong-id-seed'")
+	idsCmd.Flags().String("uuid-namespace", uuid.NamespaceDNS.String(),
+		"namespace for UUID generation (UUIDv5)")
+	idsCmd.Flags().String("plugin-inheritance", convertoas3.PluginInheritanceMixed,
+		"attachment of inherited plugins; 'mixed' (document plugins on the service, path plugins on "+
+			"the routes), 'service' (once, on the services), or 'route' (on each route)")
+	idsCmd.Flags().Bool("dedup-plugins", true,
+		"move plugins all routes of a service have with the same config to the service, "+
+			"set to false to keep them on each route")
+}
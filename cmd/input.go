@@ -0,0 +1,131 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Kong/fw/convert"
+	"github.com/Kong/fw/convertoas3"
+	"github.com/Kong/fw/filebasics"
+	"github.com/spf13/cobra"
+)
+
+const autoInputFormat = "auto"
+
+// converter converts the input to a Kong declarative file.
+type converter func(ctx context.Context, content *[]byte, opts convertoas3.O2kOptions) (map[string]interface{}, error)
+
+// inputFormatNames returns the names of the input formats, including 'auto'.
+func inputFormatNames() []string {
+	names := []string{autoInputFormat}
+	for _, format := range convert.Formats {
+		names = append(names, string(format))
+	}
+	return names
+}
+
+// inputFormat returns the format of the input, from the '--input-format' flag. For
+// 'auto' the format is detected from the content, unless the content is nil.
+func inputFormat(cmd *cobra.Command, content *[]byte) string {
+	format, _ := cmd.Flags().GetString("input-format")
+	format = strings.ToLower(format)
+	if format == autoInputFormat && content != nil {
+		format = string(convert.DetectFormat(*content))
+	}
+	return format
+}
+
+// getConverter returns the converter for the '--input-format' flag. For 'auto' the
+// format is detected from the content, so it must be read first; if the content is
+// nil, only the flag is validated.
+func getConverter(cmd *cobra.Command, content *[]byte) (converter, error) {
+	format := inputFormat(cmd, content)
+	if format == autoInputFormat {
+		return nil, nil
+	}
+	valid := false
+	for _, allowed := range convert.Formats {
+		valid = valid || format == string(allowed)
+	}
+	if !valid {
+		return nil, fmt.Errorf("expected '--input-format' to be one of '%s', got: '%s'",
+			strings.Join(inputFormatNames(), "', '"), format)
+	}
+
+	convertOpts := convert.Options{Format: convert.Format(format)}
+	convertOpts.GRPC.Server, _ = cmd.Flags().GetString("grpc-server")
+	convertOpts.GRPC.GRPCWeb, _ = cmd.Flags().GetBool("grpc-web")
+	convertOpts.Insomnia.Environment, _ = cmd.Flags().GetString("insomnia-environment")
+	return func(ctx context.Context, content *[]byte, opts convertoas3.O2kOptions) (map[string]interface{}, error) {
+		convertOpts.Conversion = opts
+		result, err := convert.Convert(ctx, *content, convertOpts)
+		if err != nil {
+			return nil, err
+		}
+		return result.Content, nil
+	}, nil
+}
+
+// addInputURLFlags adds the flags to control reading the input from an http(s) URL.
+func addInputURLFlags(cmd *cobra.Command) {
+	cmd.Flags().StringArray("input-header", nil,
+		"header to add when reading the input from a URL, as 'Name: value', eg. for authentication")
+	cmd.Flags().Duration("input-timeout", filebasics.DefaultURLTimeout,
+		"timeout for reading the input from a URL")
+	cmd.Flags().Int64("input-max-size", filebasics.DefaultURLMaxSize,
+		"maximum size in bytes of the input read from a URL")
+	cmd.Flags().String("input-ca-cert", "",
+		"PEM file with CA certificates to trust when reading the input from a URL")
+	cmd.Flags().Bool("input-insecure", false,
+		"do not verify the server certificate when reading the input from a URL")
+}
+
+// readInput reads the input from the file, stdin, or an http(s) URL. Errors are
+// marked as I/O errors, see ExitIOError.
+func readInput(cmd *cobra.Command, filename string) (*[]byte, error) {
+	var opts filebasics.URLOptions
+	opts.Headers, _ = cmd.Flags().GetStringArray("input-header")
+	opts.Timeout, _ = cmd.Flags().GetDuration("input-timeout")
+	opts.MaxSize, _ = cmd.Flags().GetInt64("input-max-size")
+	opts.CACertFile, _ = cmd.Flags().GetString("input-ca-cert")
+	opts.InsecureSkipVerify, _ = cmd.Flags().GetBool("input-insecure")
+	content, err := filebasics.ReadFileOrURL(cmd.Context(), filename, opts)
+	return content, ioError(err)
+}
+
+// readComponents reads the shared 'x-kong' components from the '--components' file, or
+// http(s) URL. Returns nil if the flag is not set.
+func readComponents(cmd *cobra.Command) (map[string]interface{}, error) {
+	filename, _ := cmd.Flags().GetString("components")
+	if filename == "" {
+		return nil, nil
+	}
+	if filename == "-" {
+		return nil, fmt.Errorf("expected '--components' to be a file or URL, stdin is not supported")
+	}
+	content, err := readInput(cmd, filename)
+	if err != nil {
+		return nil, err
+	}
+	components, err := convertoas3.ParseComponents(*content)
+	if err != nil {
+		return nil, fmt.Errorf("'--components' file '%s': %w", filename, err)
+	}
+	return components, nil
+}
+
+// locateErrors sets the input filename and the position in the input of the errors in
+// the spec, if err is a convertoas3.ConversionErrors, so their messages point to the
+// line and column, eg. "api.yaml:12:7: ...".
+func locateErrors(err error, filenameIn string, content *[]byte) {
+	var errs convertoas3.ConversionErrors
+	if !errors.As(err, &errs) {
+		return
+	}
+	if filenameIn == "-" {
+		filenameIn = "stdin"
+	}
+	errs.Locate(*content, filenameIn)
+}
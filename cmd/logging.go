@@ -0,0 +1,37 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/Kong/fw/convertoas3"
+	"github.com/spf13/cobra"
+)
+
+// newLogger returns the logger for the conversion diagnostics, writing to stderr,
+// based on the '--log-level' and '--log-format' flags.
+func newLogger(cmd *cobra.Command) (convertoas3.Logger, error) {
+	levelName, _ := cmd.Flags().GetString("log-level")
+	format, _ := cmd.Flags().GetString("log-format")
+
+	level, err := convertoas3.ParseLogLevel(levelName)
+	if err != nil {
+		return nil, fmt.Errorf("expected '--log-level' to be one of 'debug', 'info', or 'warn', got: '%s'", levelName)
+	}
+	switch strings.ToLower(format) {
+	case "text":
+		return convertoas3.NewStdLogger(log.New(cmd.ErrOrStderr(), "", 0), level), nil
+	case "json":
+		return convertoas3.NewJSONLogger(cmd.ErrOrStderr(), level), nil
+	default:
+		return nil, fmt.Errorf("expected '--log-format' to be either 'text' or 'json', got: '%s'", format)
+	}
+}
+
+func init() {
+	rootCmd.PersistentFlags().StringP("log-level", "v", "warn",
+		"minimum level of the diagnostics to write to stderr: debug, info, or warn")
+	rootCmd.PersistentFlags().String("log-format", "text",
+		"format of the diagnostics written to stderr: text or json (one object per line)")
+}
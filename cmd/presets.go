@@ -0,0 +1,166 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"sigs.k8s.io/yaml"
+)
+
+const defaultProfilesFile = ".fw-profiles.yaml"
+
+// builtinProfiles are the conversion profiles available without a profiles file; named
+// sets of flag values. A profiles file can redefine them.
+var builtinProfiles = map[string]map[string]interface{}{
+	"strict-validation": {
+		"validate":        true,
+		"strict":          true,
+		"validate-output": true,
+		"fail-on-warn":    true,
+	},
+	"minimal-routing": {
+		"plain-paths": true,
+		"exact-match": false,
+	},
+}
+
+// profileKeys are the flags that cannot be set by a profile, besides the configKeys.
+var profileKeys = map[string]bool{
+	"profile":       true,
+	"profiles-file": true,
+	"policy":        true,
+}
+
+// readProfiles returns the built-in profiles, and the profiles from the file given by
+// '--profiles-file', or the default profiles file in the current directory, if present.
+func readProfiles(cmd *cobra.Command) (map[string]map[string]interface{}, error) {
+	profiles := make(map[string]map[string]interface{}, len(builtinProfiles))
+	for name, profile := range builtinProfiles {
+		profiles[name] = profile
+	}
+
+	filename, _ := cmd.Flags().GetString("profiles-file")
+	if filename == "" {
+		filename = defaultProfilesFile
+		if _, err := os.Stat(filename); errors.Is(err, fs.ErrNotExist) {
+			return profiles, nil
+		}
+	}
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, ioError(fmt.Errorf("failed to read profiles file: %w", err))
+	}
+	var fileProfiles map[string]interface{}
+	if err := yaml.Unmarshal(content, &fileProfiles); err != nil {
+		return nil, fmt.Errorf("failed to parse profiles file '%s': %w", filename, err)
+	}
+	for name, value := range fileProfiles {
+		profile, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("expected profile '%s' in profiles file '%s' to be an object of options",
+				name, filename)
+		}
+		profiles[name] = profile
+	}
+	return profiles, nil
+}
+
+// profileNames returns the sorted names of the profiles.
+func profileNames(profiles map[string]map[string]interface{}) []string {
+	names := make([]string, 0, len(profiles))
+	for name := range profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// isFlag returns true if the name is a flag of any command.
+func isFlag(command *cobra.Command, name string) bool {
+	if command.Flags().Lookup(name) != nil || command.PersistentFlags().Lookup(name) != nil {
+		return true
+	}
+	for _, sub := range command.Commands() {
+		if isFlag(sub, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// applyProfiles sets the flags of the command that were not set otherwise, from the
+// profiles given by '--profile'. With multiple profiles, later ones take precedence.
+// Options in a profile that are not flags of the command are ignored, so profiles can
+// be used with all commands, but options that are not flags of any command are an error.
+func applyProfiles(cmd *cobra.Command) error {
+	names, _ := cmd.Flags().GetStringSlice("profile")
+	if len(names) == 0 {
+		return nil
+	}
+	profiles, err := readProfiles(cmd)
+	if err != nil {
+		return err
+	}
+
+	values := make(map[string]interface{})
+	for _, name := range names {
+		profile, found := profiles[name]
+		if !found {
+			return fmt.Errorf("unknown profile '%s', available: %s", name,
+				strings.Join(profileNames(profiles), ", "))
+		}
+		unknown := make([]string, 0)
+		for key, value := range profile {
+			if configKeys[key] || profileKeys[key] || !isFlag(cmd.Root(), key) {
+				unknown = append(unknown, key)
+			}
+			values[key] = value
+		}
+		if len(unknown) > 0 {
+			sort.Strings(unknown)
+			return fmt.Errorf("unknown option(s) in profile '%s': %s", name, strings.Join(unknown, ", "))
+		}
+	}
+
+	var errs []string
+	flags := cmd.Flags()
+	flags.VisitAll(func(flag *pflag.Flag) {
+		if flag.Changed {
+			return
+		}
+		if value, found := values[flag.Name]; found {
+			if err := setFlagValue(flags, flag, value); err != nil {
+				errs = append(errs, fmt.Sprintf("invalid value for '%s' in profile(s) '%s': %v",
+					flag.Name, strings.Join(names, "', '"), err))
+			}
+		}
+	})
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "\n"))
+	}
+	return nil
+}
+
+// completeProfiles offers the names of the available profiles.
+func completeProfiles(cmd *cobra.Command, _ []string, _ string) ([]string, cobra.ShellCompDirective) {
+	profiles, err := readProfiles(cmd)
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveError
+	}
+	return profileNames(profiles), cobra.ShellCompDirectiveNoFileComp
+}
+
+func init() {
+	rootCmd.PersistentFlags().StringSlice("profile", nil,
+		"conversion profile(s) to apply, named sets of options; built-in are '"+
+			strings.Join(profileNames(builtinProfiles), "', '")+"', more can be defined in the profiles file")
+	rootCmd.PersistentFlags().String("profiles-file", "",
+		"file with conversion profiles (default: '"+defaultProfilesFile+"' in the current directory, if present)")
+	_ = rootCmd.MarkPersistentFlagFilename("profiles-file", "yaml", "yml")
+}
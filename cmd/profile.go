@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"runtime/pprof"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	cpuProfile *os.File // the file the CPU profile is being written to, if any
+	memProfile string   // the filename to write the memory profile to, if any
+)
+
+// startProfiling starts the CPU profile if '--cpuprofile' was given, and records the
+// filename to write the memory profile to when done.
+func startProfiling(cmd *cobra.Command, _ []string) error {
+	cpuFilename, _ := cmd.Flags().GetString("cpuprofile")
+	memProfile, _ = cmd.Flags().GetString("memprofile")
+
+	if cpuFilename != "" {
+		file, err := os.Create(cpuFilename)
+		if err != nil {
+			return fmt.Errorf("failed to create CPU profile: %w", err)
+		}
+		if err = pprof.StartCPUProfile(file); err != nil {
+			file.Close()
+			return fmt.Errorf("failed to start CPU profile: %w", err)
+		}
+		cpuProfile = file
+	}
+	return nil
+}
+
+// stopProfiling stops the CPU profile, and writes the memory profile, if requested.
+func stopProfiling() error {
+	if cpuProfile != nil {
+		pprof.StopCPUProfile()
+		if err := cpuProfile.Close(); err != nil {
+			return fmt.Errorf("failed to write CPU profile: %w", err)
+		}
+		cpuProfile = nil
+	}
+
+	if memProfile != "" {
+		file, err := os.Create(memProfile)
+		if err != nil {
+			return fmt.Errorf("failed to create memory profile: %w", err)
+		}
+		defer file.Close()
+		runtime.GC() // get up-to-date statistics
+		// the 'allocs' profile has all allocations made, not only those still in use
+		if err = pprof.Lookup("allocs").WriteTo(file, 0); err != nil {
+			return fmt.Errorf("failed to write memory profile: %w", err)
+		}
+		memProfile = ""
+	}
+	return nil
+}
+
+func init() {
+	rootCmd.PersistentFlags().String("cpuprofile", "", "write a CPU profile to this file")
+	rootCmd.PersistentFlags().String("memprofile", "", "write a memory profile to this file, when done")
+}
@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"os/signal"
+
+	"github.com/spf13/cobra"
+)
+
+// rootCmd represents the base command when called without any subcommands
+var rootCmd = &cobra.Command{
+	Use:   "fw",
+	Short: "Convert OpenAPI specs to Kong declarative configuration",
+	Long: `fw converts OpenAPI 3 specifications into Kong declarative configuration files,
+as used by decK.`,
+	SilenceUsage:  true, // errors are not about usage, so don't print it
+	SilenceErrors: true, // errors are printed by Execute, with the exit code
+}
+
+// preRun applies the config file and environment to the flags of the command, and
+// starts profiling if requested. It runs before any command.
+func preRun(cmd *cobra.Command, args []string) error {
+	if err := applyConfig(cmd); err != nil {
+		return err
+	}
+	return ioError(startProfiling(cmd, args))
+}
+
+// Execute adds all child commands to the root command and sets flags appropriately.
+// This is called by main.main(). It only needs to happen once to the rootCmd.
+func Execute() {
+	rootCmd.PersistentPreRunE = preRun
+	registerCompletions()
+
+	// cancel long running operations on Ctrl-C
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	err := rootCmd.ExecuteContext(ctx)
+	stop()
+	if profileErr := stopProfiling(); profileErr != nil && err == nil {
+		err = ioError(profileErr)
+	}
+	if err != nil {
+		os.Exit(printSummary(os.Stderr, err))
+	}
+}
@@ -0,0 +1,145 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/Kong/fw/convertoas3"
+	"github.com/Kong/fw/filebasics"
+	"github.com/Kong/fw/sarif"
+	"github.com/spf13/cobra"
+)
+
+// The rules of the findings in the SARIF output.
+const (
+	ruleConversionError   = "conversion-error"
+	ruleConversionWarning = "conversion-warning"
+	ruleInvalidExtension  = "invalid-extension"
+)
+
+var ruleDescriptions = map[string]string{
+	ruleConversionError:   "The spec could not be converted to a Kong declarative file",
+	ruleConversionWarning: "The spec was converted, but part of it was ignored or adapted",
+	ruleInvalidExtension:  "An x-kong extension does not match the extensions schema",
+}
+
+// warning is a warning logged during the conversion.
+type warning struct {
+	msg           string
+	keysAndValues []interface{}
+}
+
+// recordingLogger is a convertoas3.Logger that records the warnings, before passing
+// them on, for the SARIF output. It is safe for concurrent use.
+type recordingLogger struct {
+	convertoas3.Logger
+	mu       sync.Mutex
+	warnings []warning
+}
+
+func (l *recordingLogger) Warn(msg string, keysAndValues ...interface{}) {
+	l.mu.Lock()
+	l.warnings = append(l.warnings, warning{msg, keysAndValues})
+	l.mu.Unlock()
+	l.Logger.Warn(msg, keysAndValues...)
+}
+
+// sarifURI returns the URI of the input file for the SARIF output; a relative path with
+// forward slashes, or the URL. Returns "" for stdin.
+func sarifURI(filename string) string {
+	switch {
+	case filename == "-":
+		return ""
+	case strings.HasPrefix(filename, "http://") || strings.HasPrefix(filename, "https://"):
+		return filename
+	default:
+		return filepath.ToSlash(filepath.Clean(filename))
+	}
+}
+
+// sarifFinding returns a finding in the input file, at the JSON pointer.
+func sarifFinding(
+	ruleID string, level string, msg string, uri string, positions *convertoas3.Positions, pointer string,
+) sarif.Finding {
+	finding := sarif.Finding{RuleID: ruleID, Level: level, Message: msg, URI: uri, Pointer: pointer}
+	finding.Line, finding.Column = positions.Position(pointer)
+	return finding
+}
+
+// warningFinding returns the finding for a conversion warning. The 'location' is used
+// if it is a JSON pointer, the other keys and values are added to the message.
+func warningFinding(w warning, uri string, positions *convertoas3.Positions) sarif.Finding {
+	var pointer string
+	details := make([]string, 0)
+	for i := 0; i+1 < len(w.keysAndValues); i += 2 {
+		key, value := fmt.Sprint(w.keysAndValues[i]), fmt.Sprint(w.keysAndValues[i+1])
+		if key == "location" && strings.HasPrefix(value, "/") && pointer == "" {
+			pointer = value
+			continue
+		}
+		details = append(details, key+"="+value)
+	}
+	msg := w.msg
+	if len(details) > 0 {
+		msg += " (" + strings.Join(details, ", ") + ")"
+	}
+	return sarifFinding(ruleConversionWarning, sarif.LevelWarning, msg, uri, positions, pointer)
+}
+
+// errorFindings returns the findings for an error; one for each error in the spec if it
+// is a convertoas3.ConversionErrors.
+func errorFindings(err error, ruleID string, uri string, positions *convertoas3.Positions) []sarif.Finding {
+	var conversionErrs convertoas3.ConversionErrors
+	if !errors.As(err, &conversionErrs) {
+		var conversionErr *convertoas3.ConversionError
+		if !errors.As(err, &conversionErr) {
+			return []sarif.Finding{sarifFinding(ruleID, sarif.LevelError, err.Error(), uri, positions, "")}
+		}
+		conversionErrs = convertoas3.ConversionErrors{conversionErr}
+	}
+	findings := make([]sarif.Finding, len(conversionErrs))
+	for i, conversionErr := range conversionErrs {
+		findings[i] = sarifFinding(ruleID, sarif.LevelError, conversionErr.Err.Error(), uri, positions,
+			conversionErr.Pointer)
+	}
+	return findings
+}
+
+// writeSarif writes the '--sarif' file, if given, with the warnings, and the error, for
+// the input file. The input content is used to locate the findings, if available.
+func writeSarif(
+	cmd *cobra.Command, filenameIn string, content *[]byte, warnings []warning, err error, errRuleID string,
+) error {
+	filename, _ := cmd.Flags().GetString("sarif")
+	if filename == "" {
+		return nil
+	}
+
+	log := sarif.New("fw", version, "https://github.com/Kong/fw")
+	uri := sarifURI(filenameIn)
+	var positions *convertoas3.Positions
+	if content != nil {
+		positions = convertoas3.NewPositions(*content)
+	}
+	log.AddRule(errRuleID, ruleDescriptions[errRuleID])
+	if len(warnings) > 0 {
+		log.AddRule(ruleConversionWarning, ruleDescriptions[ruleConversionWarning])
+	}
+	for _, w := range warnings {
+		log.Add(warningFinding(w, uri, positions))
+	}
+	if err != nil {
+		for _, finding := range errorFindings(err, errRuleID, uri, positions) {
+			log.Add(finding)
+		}
+	}
+
+	data, marshalErr := log.Marshal()
+	if marshalErr != nil {
+		return marshalErr
+	}
+	return ioError(filebasics.WriteFile(filename, &data))
+}
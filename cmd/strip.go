@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Kong/fw/convertoas3"
+	"github.com/Kong/fw/filebasics"
+	"github.com/spf13/cobra"
+)
+
+// specOutputOptions returns the options to write a spec, from the '--format' flag. If
+// the format is not given, it is taken from the extension of the output file.
+func specOutputOptions(cmd *cobra.Command, filenameOut string) (filebasics.OutputOptions, error) {
+	outputFormat, _ := cmd.Flags().GetString("format")
+	var opts filebasics.OutputOptions
+	switch strings.ToLower(outputFormat) {
+	case "yaml":
+		opts.AsYaml = true
+	case "json":
+		opts.AsYaml = false
+	default:
+		return opts, fmt.Errorf("expected '--format' to be either 'yaml' or 'json', got: '%s'", outputFormat)
+	}
+	if !cmd.Flags().Changed("format") {
+		opts.AsYaml = isYamlOutput(filenameOut, opts.AsYaml)
+	}
+	return opts, nil
+}
+
+// Executes the CLI command "strip"
+func executeStrip(cmd *cobra.Command, _ []string) error {
+	filenameIn, _ := cmd.Flags().GetString("input")
+	filenameOut, _ := cmd.Flags().GetString("output")
+	keep, _ := cmd.Flags().GetStringSlice("keep")
+
+	opts, err := specOutputOptions(cmd, filenameOut)
+	if err != nil {
+		return err
+	}
+
+	content, err := readInput(cmd, filenameIn)
+	if err != nil {
+		return err
+	}
+	doc, err := convertoas3.StripExtensions(content, keep...)
+	if err != nil {
+		return err
+	}
+	return ioError(filebasics.WriteSerializedFile(filenameOut, doc, opts))
+}
+
+// stripCmd represents the strip command
+var stripCmd = &cobra.Command{
+	Use:   "strip",
+	Short: "Remove the x-kong extensions from an OpenAPI spec",
+	Long: `Remove the x-kong-... extensions, and '/components/x-kong', from an OpenAPI
+spec. The result can be shared externally, without the gateway configuration.
+
+Extensions can be kept using '--keep', eg. '--keep x-kong-name,x-kong-plugin-*'. If any
+extension is kept, '/components/x-kong' is kept as well, since it may be referenced.`,
+	Args: cobra.NoArgs,
+	RunE: executeStrip,
+}
+
+func init() {
+	rootCmd.AddCommand(stripCmd)
+	stripCmd.Flags().StringP("input", "i", "-",
+		"OpenAPI spec file, or http(s) URL, to process. Use - to read from stdin")
+	addInputURLFlags(stripCmd)
+	stripCmd.Flags().StringP("output", "o", "-", "output file to write. Use - to write to stdout")
+	stripCmd.Flags().StringP("format", "f", "yaml", "output format: yaml or json")
+	stripCmd.Flags().StringSlice("keep", nil,
+		"extensions to keep, a trailing '*' matches a prefix, eg. 'x-kong-plugin-*'")
+}
@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/Kong/fw/convertoas3"
+	"github.com/spf13/cobra"
+)
+
+// Executes the CLI command "validate-extensions"
+func executeValidateExtensions(cmd *cobra.Command, _ []string) error {
+	filenameIn, _ := cmd.Flags().GetString("input")
+	printSchema, _ := cmd.Flags().GetBool("print-schema")
+
+	if printSchema {
+		_, err := cmd.OutOrStdout().Write(convertoas3.ExtensionsSchema)
+		return err
+	}
+
+	content, err := readInput(cmd, filenameIn)
+	if err != nil {
+		return err
+	}
+	err = convertoas3.ValidateExtensions(content)
+	locateErrors(err, filenameIn, content)
+	sarifErr := writeSarif(cmd, filenameIn, content, nil, err, ruleInvalidExtension)
+	if err != nil {
+		return fmt.Errorf("invalid extensions in '%s': %w", filenameIn, err)
+	}
+	if sarifErr != nil {
+		return sarifErr
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "extensions in '%s' are valid\n", filenameIn)
+	return nil
+}
+
+// validateExtensionsCmd represents the validate-extensions command
+var validateExtensionsCmd = &cobra.Command{
+	Use:   "validate-extensions",
+	Short: "Validate the x-kong extensions of an OpenAPI spec",
+	Long: `Validate the x-kong-... extensions of an OpenAPI spec against the JSON Schema
+describing them. References to '#/components/x-kong/...' are resolved, and the
+referenced objects validated as well.
+
+The schema itself can be printed using '--print-schema', for use in editors.`,
+	Args: cobra.NoArgs,
+	RunE: executeValidateExtensions,
+}
+
+func init() {
+	rootCmd.AddCommand(validateExtensionsCmd)
+	validateExtensionsCmd.Flags().StringP("input", "i", "-",
+		"OpenAPI spec file, or http(s) URL, to validate. Use - to read from stdin")
+	addInputURLFlags(validateExtensionsCmd)
+	validateExtensionsCmd.Flags().String("sarif", "",
+		"SARIF file to write the findings to, eg. for GitHub code scanning")
+	validateExtensionsCmd.Flags().Bool("print-schema", false, "print the JSON Schema for the extensions, and exit")
+}
@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"fmt"
+	"runtime/debug"
+	"strings"
+
+	"github.com/Kong/fw/convertoas3"
+	"github.com/spf13/cobra"
+)
+
+// The build version and commit, set at build time using:
+//
+//	go build -ldflags "-X github.com/Kong/fw/cmd.version=v1.2.3 -X github.com/Kong/fw/cmd.commit=abc1234"
+var (
+	version = "dev"
+	commit  = ""
+)
+
+// buildCommit returns the commit the binary was built from. If not set at build
+// time, it is taken from the VCS information embedded by the Go toolchain.
+func buildCommit() string {
+	if commit != "" {
+		return commit
+	}
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range info.Settings {
+			if setting.Key == "vcs.revision" {
+				return setting.Value
+			}
+		}
+	}
+	return "unknown"
+}
+
+// generatorName returns the name and version of the converter, as embedded in the
+// output by '--embed-version'.
+func generatorName() string {
+	return fmt.Sprintf("fw %s (%s)", version, buildCommit())
+}
+
+// Executes the CLI command "version"
+func executeVersion(cmd *cobra.Command, _ []string) error {
+	out := cmd.OutOrStdout()
+	fmt.Fprintf(out, "fw %s\n", version)
+	fmt.Fprintf(out, "commit: %s\n", buildCommit())
+	openAPIVersions := append([]string{}, convertoas3.SupportedOpenAPIVersions...)
+	for _, partial := range convertoas3.PartiallySupportedOpenAPIVersions {
+		openAPIVersions = append(openAPIVersions, partial+" (partial)")
+	}
+	fmt.Fprintf(out, "OpenAPI versions: %s\n", strings.Join(openAPIVersions, ", "))
+	fmt.Fprintf(out, "Kong format versions: %s\n", strings.Join(convertoas3.SupportedFormatVersions, ", "))
+	return nil
+}
+
+// versionCmd represents the version command
+var versionCmd = &cobra.Command{
+	Use:   "version",
+	Short: "Print the version of fw, and the supported spec versions",
+	Long: `Print the build version and commit of fw, and the OpenAPI versions accepted
+as input and the Kong declarative format versions generated as output.`,
+	Args: cobra.NoArgs,
+	RunE: executeVersion,
+}
+
+func init() {
+	rootCmd.AddCommand(versionCmd)
+}
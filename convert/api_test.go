@@ -0,0 +1,91 @@
+package convert
+
+import (
+	"bytes"
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// exportedAPI returns the declarations of the exported API of the package, without
+// comments and function bodies, one per entry, sorted.
+func exportedAPI(t *testing.T) []string {
+	files, err := filepath.Glob("*.go")
+	require.NoError(t, err)
+
+	fset := token.NewFileSet()
+	var api []string
+	add := func(node ast.Node) {
+		var buf bytes.Buffer
+		require.NoError(t, printer.Fprint(&buf, fset, node))
+		api = append(api, buf.String())
+	}
+	for _, filename := range files {
+		if strings.HasSuffix(filename, "_test.go") {
+			continue
+		}
+		file, err := parser.ParseFile(fset, filename, nil, 0)
+		require.NoError(t, err)
+		for _, decl := range file.Decls {
+			switch decl := decl.(type) {
+			case *ast.FuncDecl:
+				if !decl.Name.IsExported() {
+					continue
+				}
+				if decl.Recv != nil {
+					recv := decl.Recv.List[0].Type
+					if star, ok := recv.(*ast.StarExpr); ok {
+						recv = star.X
+					}
+					if !recv.(*ast.Ident).IsExported() {
+						continue
+					}
+				}
+				decl.Body = nil
+				add(decl)
+			case *ast.GenDecl:
+				for _, spec := range decl.Specs {
+					switch spec := spec.(type) {
+					case *ast.TypeSpec:
+						if spec.Name.IsExported() {
+							add(&ast.GenDecl{Tok: decl.Tok, Specs: []ast.Spec{spec}})
+						}
+					case *ast.ValueSpec:
+						for i, name := range spec.Names {
+							if !name.IsExported() {
+								continue
+							}
+							value := &ast.ValueSpec{Names: []*ast.Ident{name}, Type: spec.Type}
+							if i < len(spec.Values) {
+								value.Values = []ast.Expr{spec.Values[i]}
+							}
+							add(&ast.GenDecl{Tok: decl.Tok, Specs: []ast.Spec{value}})
+						}
+					}
+				}
+			}
+		}
+	}
+	sort.Strings(api)
+	return api
+}
+
+// Test_APIStability fails if the exported API of the package changes, compared to
+// testdata/api.golden. Additions are fine, but must be added to the golden file, so
+// they are a deliberate part of the stable API.
+func Test_APIStability(t *testing.T) {
+	golden, err := os.ReadFile("testdata/api.golden")
+	require.NoError(t, err)
+	actual := strings.Join(exportedAPI(t), "\n\n") + "\n"
+	assert.Equal(t, string(golden), actual,
+		"the exported API changed; existing declarations must not change, additions go in testdata/api.golden")
+}
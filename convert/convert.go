@@ -0,0 +1,223 @@
+// Package convert is the stable API for embedding the converter in other tools. It
+// converts any of the supported input formats to a Kong declarative file, and returns
+// the warnings along with the result, instead of only logging them. It has no
+// panicking helpers; all failures are returned as errors.
+//
+// The exported surface of this package (Convert, Options, Result and its accessors,
+// Warning, Format and DetectFormat) follows semantic versioning; fields may be added, but existing ones are
+// not removed or changed. The underlying converter packages may change more freely.
+package convert
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/Kong/fw/convertasyncapi"
+	"github.com/Kong/fw/convertgraphql"
+	"github.com/Kong/fw/convertgrpc"
+	"github.com/Kong/fw/convertinsomnia"
+	"github.com/Kong/fw/convertoas3"
+	"github.com/Kong/fw/convertpostman"
+	"github.com/Kong/fw/convertraml"
+)
+
+// Format is an input format.
+type Format string
+
+// The supported input formats.
+const (
+	FormatAsyncAPI Format = "asyncapi"
+	FormatGraphQL  Format = "graphql"
+	FormatGRPC     Format = "grpc"
+	FormatInsomnia Format = "insomnia"
+	FormatOpenAPI  Format = "openapi"
+	FormatPostman  Format = "postman"
+	FormatRAML     Format = "raml"
+)
+
+// Formats are the supported input formats, sorted by name.
+var Formats = []Format{
+	FormatAsyncAPI, FormatGraphQL, FormatGRPC, FormatInsomnia, FormatOpenAPI, FormatPostman, FormatRAML,
+}
+
+// DetectFormat returns the format of the input, based on its content. Defaults to
+// FormatOpenAPI.
+func DetectFormat(content []byte) Format {
+	switch {
+	case convertpostman.IsCollection(content):
+		return FormatPostman
+	case convertinsomnia.IsExport(content):
+		return FormatInsomnia
+	case convertasyncapi.IsSpec(content):
+		return FormatAsyncAPI
+	case convertraml.IsSpec(content):
+		return FormatRAML
+	case convertgraphql.IsSchema(content):
+		return FormatGraphQL
+	case convertgrpc.IsProto(content) || convertgrpc.IsDescriptorSet(content):
+		return FormatGRPC
+	default:
+		return FormatOpenAPI
+	}
+}
+
+// Options are the options for a conversion.
+type Options struct {
+	// Format is the input format, detected from the content if empty.
+	Format Format
+	// Conversion are the options controlling the generated entities, see
+	// convertoas3.O2kOptions. They apply to all input formats. A Logger set in there
+	// still receives all messages, including the warnings.
+	Conversion convertoas3.O2kOptions
+	// GRPC are the options specific to gRPC input.
+	GRPC convertgrpc.Options
+	// Insomnia are the options specific to Insomnia input.
+	Insomnia convertinsomnia.Options
+}
+
+// Warning is a warning reported during the conversion, eg. a skipped operation or a
+// defaulted hostname. Fields has the structured context of the message.
+type Warning struct {
+	Message string
+	Fields  map[string]interface{}
+}
+
+// sortedKeys returns the keys of the map, sorted.
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// String returns the warning as 'message key=value ...', with the keys sorted.
+func (w Warning) String() string {
+	var line strings.Builder
+	line.WriteString(w.Message)
+	for _, key := range sortedKeys(w.Fields) {
+		line.WriteString(fmt.Sprintf(" %s=%v", key, w.Fields[key]))
+	}
+	return line.String()
+}
+
+// Result is the result of a conversion.
+type Result struct {
+	// Format is the input format converted, as given or detected.
+	Format Format
+	// Content is the generated Kong declarative file.
+	Content map[string]interface{}
+	// Warnings are the warnings reported during the conversion, in order.
+	Warnings []Warning
+
+	operationRoutes map[string][]string // route IDs by operation, see operationKey
+}
+
+// warningCollector is a Logger collecting the warnings, and passing all messages on to
+// the next Logger. It is safe for concurrent use.
+type warningCollector struct {
+	next            convertoas3.Logger
+	lock            sync.Mutex
+	warnings        []Warning
+	operationRoutes map[string][]string
+}
+
+// fields returns the key-value pairs of a log message as a map.
+func fields(keysAndValues []interface{}) map[string]interface{} {
+	result := make(map[string]interface{}, len(keysAndValues)/2)
+	for i := 0; i+1 < len(keysAndValues); i += 2 {
+		result[fmt.Sprint(keysAndValues[i])] = keysAndValues[i+1]
+	}
+	return result
+}
+
+// Debug passes the message on, and records the operation of the routes created, as
+// logged by convertoas3.Convert.
+func (c *warningCollector) Debug(msg string, keysAndValues ...interface{}) {
+	if msg == "created route" {
+		routeFields := fields(keysAndValues)
+		key := operationKey(fmt.Sprint(routeFields["path"]), fmt.Sprint(routeFields["method"]))
+		c.lock.Lock()
+		if c.operationRoutes == nil {
+			c.operationRoutes = make(map[string][]string)
+		}
+		c.operationRoutes[key] = append(c.operationRoutes[key], fmt.Sprint(routeFields["id"]))
+		c.lock.Unlock()
+	}
+	c.next.Debug(msg, keysAndValues...)
+}
+
+func (c *warningCollector) Info(msg string, keysAndValues ...interface{}) {
+	c.next.Info(msg, keysAndValues...)
+}
+
+func (c *warningCollector) Warn(msg string, keysAndValues ...interface{}) {
+	c.lock.Lock()
+	c.warnings = append(c.warnings, Warning{Message: msg, Fields: fields(keysAndValues)})
+	c.lock.Unlock()
+	c.next.Warn(msg, keysAndValues...)
+}
+
+// converter returns the conversion function for the format.
+func converter(format Format, opts Options) (
+	func(context.Context, *[]byte, convertoas3.O2kOptions) (map[string]interface{}, error), error,
+) {
+	switch format {
+	case FormatOpenAPI:
+		return convertoas3.Convert, nil
+	case FormatPostman:
+		return convertpostman.Convert, nil
+	case FormatAsyncAPI:
+		return convertasyncapi.Convert, nil
+	case FormatGraphQL:
+		return convertgraphql.Convert, nil
+	case FormatRAML:
+		return convertraml.Convert, nil
+	case FormatGRPC:
+		return func(ctx context.Context, content *[]byte, o2kOpts convertoas3.O2kOptions) (map[string]interface{}, error) {
+			return convertgrpc.ConvertWithOptions(ctx, content, opts.GRPC, o2kOpts)
+		}, nil
+	case FormatInsomnia:
+		return func(ctx context.Context, content *[]byte, o2kOpts convertoas3.O2kOptions) (map[string]interface{}, error) {
+			return convertinsomnia.ConvertEnvironment(ctx, content, opts.Insomnia, o2kOpts)
+		}, nil
+	default:
+		return nil, fmt.Errorf("expected the input format to be one of %v, got: '%s'", Formats, format)
+	}
+}
+
+// Convert converts the input to a Kong declarative file. The input format is taken from
+// the options, or detected from the content. Errors in the input are returned as
+// convertoas3.ConversionErrors where the format supports it.
+func Convert(ctx context.Context, content []byte, opts Options) (*Result, error) {
+	format := opts.Format
+	if format == "" {
+		format = DetectFormat(content)
+	}
+	convert, err := converter(format, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	collector := &warningCollector{next: opts.Conversion.Logger}
+	if collector.next == nil {
+		collector.next = convertoas3.NopLogger()
+	}
+	o2kOpts := opts.Conversion
+	o2kOpts.Logger = collector
+
+	deckData, err := convert(ctx, &content, o2kOpts)
+	if err != nil {
+		return nil, err
+	}
+	return &Result{
+		Format:          format,
+		Content:         deckData,
+		Warnings:        collector.warnings,
+		operationRoutes: collector.operationRoutes,
+	}, nil
+}
@@ -0,0 +1,131 @@
+package convert
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"os"
+	"testing"
+
+	"github.com/Kong/fw/convertoas3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_DetectFormat(t *testing.T) {
+	tests := []struct {
+		name     string
+		file     string
+		expected Format
+	}{
+		{"openapi", "../learnservice_oas.yaml", FormatOpenAPI},
+		{"raml", "../convertraml/testdata/users.raml", FormatRAML},
+		{"graphql", "../convertgraphql/testdata/schema.graphql", FormatGraphQL},
+	}
+
+	for _, tst := range tests {
+		content, err := os.ReadFile(tst.file)
+		require.NoError(t, err, tst.name)
+		assert.Equal(t, tst.expected, DetectFormat(content), tst.name)
+	}
+}
+
+func Test_Convert(t *testing.T) {
+	content := []byte(`
+openapi: 3.0.3
+info:
+  title: Learn Service
+  version: 1.0.0
+servers:
+  - url: /api
+paths:
+  /tracks:
+    get:
+      x-kong-unknown: true
+      responses:
+        "200":
+          description: OK
+`)
+	var buf bytes.Buffer
+	result, err := Convert(context.Background(), content, Options{
+		Conversion: convertoas3.O2kOptions{
+			Logger: convertoas3.NewStdLogger(log.New(&buf, "", 0), convertoas3.LogLevelWarn),
+		},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, FormatOpenAPI, result.Format)
+	assert.Len(t, result.Content["services"], 1)
+	require.Len(t, result.Warnings, 2)
+	assert.Equal(t, "server without a hostname, defaulting to 'localhost' location=document",
+		result.Warnings[0].String())
+	assert.Equal(t, "unknown extension, ignored location=/paths/~1tracks/get/x-kong-unknown",
+		result.Warnings[1].String())
+	assert.Contains(t, buf.String(), "unknown extension, ignored", "the logger should still get the warnings")
+}
+
+func Test_ConvertErrors(t *testing.T) {
+	_, err := Convert(context.Background(), []byte("{}"), Options{Format: "wsdl"})
+	assert.EqualError(t, err,
+		"expected the input format to be one of [asyncapi graphql grpc insomnia openapi postman raml], got: 'wsdl'")
+
+	_, err = Convert(context.Background(), []byte("openapi: 3.0.3\npaths: {}\nx-kong-tags: true\n"), Options{})
+	var conversionErrors convertoas3.ConversionErrors
+	assert.ErrorAs(t, err, &conversionErrors)
+}
+
+func Test_ResultAccessors(t *testing.T) {
+	content := []byte(`
+openapi: 3.0.3
+info:
+  title: Learn Service
+  version: 1.0.0
+servers:
+  - url: https://learn.example.com
+x-kong-plugin-cors:
+  config:
+    origins: ["*"]
+x-kong-plugin-rate-limiting:
+  config:
+    minute: 10
+paths:
+  /tracks:
+    get:
+      x-kong-plugin-rate-limiting:
+        config:
+          minute: 100
+    post:
+      x-kong-service-defaults:
+        retries: 1
+  /tracks/{id}:
+    get:
+      operationId: getTrack
+`)
+	result, err := Convert(context.Background(), content, Options{
+		Conversion: convertoas3.O2kOptions{TrailingSlash: convertoas3.TrailingSlashDuplicate},
+	})
+	require.NoError(t, err)
+
+	require.Len(t, result.Services(), 2)
+	assert.Equal(t, "learn-service", result.Services()[0]["name"])
+	assert.Len(t, result.Routes(), 6)
+
+	routes := result.LookupByOASPath("/tracks", "get")
+	require.Len(t, routes, 2)
+	assert.Equal(t, "learn-service_tracks_get", routes[0]["name"])
+	assert.Equal(t, "learn-service_tracks_get~", routes[1]["name"])
+	plugins := result.PluginsFor(routes[0])
+	require.Len(t, plugins, 2)
+	assert.Equal(t, "rate-limiting", plugins[0]["name"])
+	assert.EqualValues(t, 100, plugins[0]["config"].(map[string]interface{})["minute"])
+	assert.Equal(t, "cors", plugins[1]["name"])
+
+	routes = result.LookupByOASPath("/tracks/{id}", "GET")
+	require.Len(t, routes, 2)
+	assert.Equal(t, "learn-service_gettrack", routes[0]["name"])
+	assert.Nil(t, result.LookupByOASPath("/tracks/{id}", "DELETE"))
+
+	// the entities are the ones in the content
+	routes[0]["strip_path"] = true
+	assert.Equal(t, true, result.Routes()[2]["strip_path"])
+}
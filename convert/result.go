@@ -0,0 +1,110 @@
+package convert
+
+import "strings"
+
+// operationKey returns the key of an operation of the spec, eg. "GET /users/{id}".
+func operationKey(path string, method string) string {
+	return strings.ToUpper(method) + " " + path
+}
+
+// entityList returns the entities in a list of the generated file. The converters
+// generate the lists with different types, eg. plugin lists as pointers.
+func entityList(value interface{}) []map[string]interface{} {
+	result := make([]map[string]interface{}, 0)
+	switch list := value.(type) {
+	case []interface{}:
+		for _, entry := range list {
+			if entity, ok := entry.(map[string]interface{}); ok {
+				result = append(result, entity)
+			}
+		}
+	case []map[string]interface{}:
+		result = append(result, list...)
+	case *[]*map[string]interface{}:
+		if list != nil {
+			for _, entity := range *list {
+				if entity != nil {
+					result = append(result, *entity)
+				}
+			}
+		}
+	case []*map[string]interface{}:
+		for _, entity := range list {
+			if entity != nil {
+				result = append(result, *entity)
+			}
+		}
+	}
+	return result
+}
+
+// Services returns the services of the generated file, in order. The entities are the
+// ones in Content, so changes to them are changes to the result.
+func (r *Result) Services() []map[string]interface{} {
+	return entityList(r.Content["services"])
+}
+
+// Routes returns the routes of all services of the generated file, in order. The
+// entities are the ones in Content, so changes to them are changes to the result.
+func (r *Result) Routes() []map[string]interface{} {
+	routes := make([]map[string]interface{}, 0)
+	for _, service := range r.Services() {
+		routes = append(routes, entityList(service["routes"])...)
+	}
+	return routes
+}
+
+// serviceOf returns the service with the route, or nil if the route is not in the result.
+func (r *Result) serviceOf(route map[string]interface{}) map[string]interface{} {
+	for _, service := range r.Services() {
+		for _, serviceRoute := range entityList(service["routes"]) {
+			if serviceRoute["id"] == route["id"] && serviceRoute["name"] == route["name"] {
+				return service
+			}
+		}
+	}
+	return nil
+}
+
+// PluginsFor returns the plugins applying to the route; the plugins configured on the
+// route, followed by the plugins of its service that the route does not override (by
+// name). Plugins bound to consumers, in the top-level 'plugins', are not included.
+func (r *Result) PluginsFor(route map[string]interface{}) []map[string]interface{} {
+	plugins := entityList(route["plugins"])
+	overridden := make(map[interface{}]bool, len(plugins))
+	for _, plugin := range plugins {
+		overridden[plugin["name"]] = true
+	}
+	if service := r.serviceOf(route); service != nil {
+		for _, plugin := range entityList(service["plugins"]) {
+			if !overridden[plugin["name"]] {
+				plugins = append(plugins, plugin)
+			}
+		}
+	}
+	return plugins
+}
+
+// LookupByOASPath returns the routes generated for the operation of the OpenAPI spec,
+// by its path (as in the spec, eg. '/users/{id}') and method. Usually a single route,
+// or 2 when generating a route for each trailing slash variant. Other input formats are
+// converted through an OpenAPI document, its paths apply. Returns nil if the operation
+// was not converted, eg. it was skipped.
+func (r *Result) LookupByOASPath(path string, method string) []map[string]interface{} {
+	ids := r.operationRoutes[operationKey(path, method)]
+	if len(ids) == 0 {
+		return nil
+	}
+	wanted := make(map[interface{}]bool, len(ids))
+	for _, id := range ids {
+		wanted[id] = true
+	}
+
+	var routes []map[string]interface{}
+	for _, route := range r.Routes() {
+		if wanted[route["id"]] {
+			routes = append(routes, route)
+		}
+	}
+	return routes
+}
@@ -0,0 +1,227 @@
+// Package convertasyncapi converts AsyncAPI 2.x specs to Kong declarative configuration.
+// Only channels on HTTP servers (http, https, ws and wss) are converted. The spec is
+// converted to an OpenAPI document first, which is then converted by convertoas3.
+package convertasyncapi
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Kong/fw/convertoas3"
+	"github.com/Kong/fw/importer"
+	"github.com/getkin/kin-openapi/openapi3"
+	"sigs.k8s.io/yaml"
+)
+
+const versionPrefix = "2."
+
+// httpProtocols maps the AsyncAPI server protocols that can be proxied by Kong to the
+// URL scheme to use. WebSockets are proxied over http(s).
+var httpProtocols = map[string]string{
+	"http":  "http",
+	"https": "https",
+	"ws":    "http",
+	"wss":   "https",
+}
+
+// spec is the part of an AsyncAPI spec used for the conversion.
+type spec struct {
+	AsyncAPI string `json:"asyncapi"`
+	Info     struct {
+		Title       string `json:"title"`
+		Version     string `json:"version"`
+		Description string `json:"description"`
+	} `json:"info"`
+	Servers  map[string]server  `json:"servers"`
+	Channels map[string]channel `json:"channels"`
+}
+
+type server struct {
+	URL       string `json:"url"`
+	Protocol  string `json:"protocol"`
+	Variables map[string]struct {
+		Default string `json:"default"`
+	} `json:"variables"`
+}
+
+type channel struct {
+	Description string     `json:"description"`
+	Servers     []string   `json:"servers"`
+	Subscribe   *operation `json:"subscribe"`
+	Publish     *operation `json:"publish"`
+}
+
+type operation struct {
+	OperationID string `json:"operationId"`
+	Summary     string `json:"summary"`
+	Description string `json:"description"`
+	Tags        []struct {
+		Name string `json:"name"`
+	} `json:"tags"`
+	Bindings struct {
+		HTTP *struct {
+			Method string `json:"method"`
+		} `json:"http"`
+	} `json:"bindings"`
+}
+
+// IsSpec returns true if the content is an AsyncAPI 2.x spec, in JSON or YAML.
+func IsSpec(content []byte) bool {
+	var s struct {
+		AsyncAPI string `json:"asyncapi"`
+	}
+	if err := yaml.Unmarshal(content, &s); err != nil {
+		return false
+	}
+	return strings.HasPrefix(s.AsyncAPI, versionPrefix)
+}
+
+// serverURL returns the URL of an HTTP server, with the variables replaced by their
+// defaults. AsyncAPI server URLs need not have a scheme, it is taken from the protocol.
+func serverURL(s server) string {
+	serverURL := s.URL
+	for name, variable := range s.Variables {
+		serverURL = strings.ReplaceAll(serverURL, "{"+name+"}", variable.Default)
+	}
+	scheme := httpProtocols[strings.ToLower(s.Protocol)]
+	if before, after, found := strings.Cut(serverURL, "://"); found {
+		if _, isHTTP := httpProtocols[strings.ToLower(before)]; isHTTP {
+			return scheme + "://" + after
+		}
+	}
+	return scheme + "://" + serverURL
+}
+
+// channelMethod returns the HTTP method for an operation, taken from the http binding.
+// Otherwise a 'publish' (the client sends) is a POST, and a 'subscribe' (the client
+// receives) a GET. On WebSocket servers both are a GET; the upgrade request.
+func channelMethod(op *operation, action string, webSocket bool) string {
+	switch {
+	case op.Bindings.HTTP != nil && op.Bindings.HTTP.Method != "":
+		return strings.ToUpper(op.Bindings.HTTP.Method)
+	case webSocket || action == "subscribe":
+		return "GET"
+	default:
+		return "POST"
+	}
+}
+
+// ToOpenAPI converts an AsyncAPI 2.x spec to an OpenAPI document. Servers with an HTTP
+// protocol (http, https, ws, wss) become OpenAPI servers, and the channels on them paths,
+// with an operation for 'publish' and 'subscribe'. Servers with other protocols, and the
+// channels only on those, are skipped and logged as a warning.
+func ToOpenAPI(content *[]byte, logger convertoas3.Logger) (*openapi3.T, error) {
+	if logger == nil {
+		logger = convertoas3.NopLogger()
+	}
+
+	var s spec
+	if err := yaml.Unmarshal(*content, &s); err != nil {
+		return nil, fmt.Errorf("failed to parse AsyncAPI spec: %w", err)
+	}
+	if !strings.HasPrefix(s.AsyncAPI, versionPrefix) {
+		return nil, fmt.Errorf("expected an AsyncAPI 2.x spec, got version '%s'", s.AsyncAPI)
+	}
+
+	// the HTTP servers, by name, and all server names sorted for deterministic output
+	serverNames := make([]string, 0, len(s.Servers))
+	httpServers := make(map[string]string)
+	webSocketServers := make(map[string]bool)
+	for name := range s.Servers {
+		serverNames = append(serverNames, name)
+	}
+	sort.Strings(serverNames)
+	for _, name := range serverNames {
+		protocol := strings.ToLower(s.Servers[name].Protocol)
+		if _, isHTTP := httpProtocols[protocol]; !isHTTP {
+			logger.Warn("unsupported server protocol, server ignored",
+				"location", "/servers/"+name, "protocol", protocol)
+			continue
+		}
+		httpServers[name] = serverURL(s.Servers[name])
+		webSocketServers[name] = protocol == "ws" || protocol == "wss"
+	}
+
+	channelNames := make([]string, 0, len(s.Channels))
+	for name := range s.Channels {
+		channelNames = append(channelNames, name)
+	}
+	sort.Strings(channelNames)
+
+	doc := importer.NewDocument(s.Info.Title, s.Info.Description, s.Info.Version)
+	var errs convertoas3.ConversionErrors
+	for _, name := range channelNames {
+		ch := s.Channels[name]
+		location := "/channels/" + strings.ReplaceAll(strings.ReplaceAll(name, "~", "~0"), "/", "~1")
+
+		// a channel is on all servers, unless it lists its servers
+		names := ch.Servers
+		if len(names) == 0 {
+			names = serverNames
+		}
+		var servers []string
+		webSocket := false
+		for _, serverName := range names {
+			if _, found := s.Servers[serverName]; !found {
+				errs = append(errs, &convertoas3.ConversionError{
+					Pointer: location + "/servers",
+					Err:     fmt.Errorf("server '%s' is not declared in '#/servers'", serverName),
+				})
+				continue
+			}
+			if serverURL, isHTTP := httpServers[serverName]; isHTTP {
+				servers = append(servers, serverURL)
+				webSocket = webSocket || webSocketServers[serverName]
+			}
+		}
+		if len(servers) == 0 {
+			logger.Warn("channel is not on an HTTP server, skipped", "location", location)
+			continue
+		}
+
+		path := "/" + strings.TrimPrefix(name, "/")
+		for _, action := range []string{"publish", "subscribe"} {
+			op := ch.Publish
+			if action == "subscribe" {
+				op = ch.Subscribe
+			}
+			if op == nil {
+				continue
+			}
+
+			operation := openapi3.NewOperation()
+			operation.OperationID = op.OperationID
+			if operation.OperationID == "" {
+				operation.OperationID = importer.OperationID(name, action)
+			}
+			operation.Summary = op.Summary
+			operation.Description = op.Description
+			if operation.Description == "" {
+				operation.Description = ch.Description
+			}
+			for _, tag := range op.Tags {
+				operation.Tags = append(operation.Tags, tag.Name)
+			}
+			method := channelMethod(op, action, webSocket)
+			if err := doc.AddOperation(servers, path, method, operation); err != nil {
+				errs = append(errs, &convertoas3.ConversionError{Pointer: location + "/" + action, Err: err})
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return doc.OpenAPI(), nil
+}
+
+// Convert converts an AsyncAPI 2.x spec to a Kong declarative file, see ToOpenAPI, and
+// convertoas3.Convert for the options.
+func Convert(ctx context.Context, content *[]byte, opts convertoas3.O2kOptions) (map[string]interface{}, error) {
+	doc, err := ToOpenAPI(content, opts.Logger)
+	if err != nil {
+		return nil, err
+	}
+	return convertoas3.ConvertDocument(ctx, doc, opts)
+}
@@ -0,0 +1,93 @@
+package convertasyncapi
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"os"
+	"testing"
+
+	"github.com/Kong/fw/convertoas3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_IsSpec(t *testing.T) {
+	content, err := os.ReadFile("testdata/asyncapi.yaml")
+	require.NoError(t, err)
+
+	assert.True(t, IsSpec(content))
+	assert.True(t, IsSpec([]byte(`{"asyncapi": "2.0.0"}`)))
+	assert.False(t, IsSpec([]byte(`{"asyncapi": "3.0.0"}`)))
+	assert.False(t, IsSpec([]byte("openapi: 3.0.3")))
+}
+
+func Test_ToOpenAPI(t *testing.T) {
+	content, err := os.ReadFile("testdata/asyncapi.yaml")
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	doc, err := ToOpenAPI(&content, convertoas3.NewStdLogger(log.New(&buf, "", 0), convertoas3.LogLevelWarn))
+	require.NoError(t, err)
+
+	assert.Equal(t, "Notifications", doc.Info.Title)
+	assert.Equal(t, "1.2.0", doc.Info.Version)
+	assert.Len(t, doc.Paths, 2)
+
+	notifications := doc.Paths["/notifications/{userId}"]
+	assert.Equal(t, "https://notify.example.com/api", doc.Servers[0].URL)
+	assert.Equal(t, "sendNotification", notifications.Post.OperationID)
+	assert.Equal(t, []string{"notifications"}, notifications.Post.Tags)
+	assert.Equal(t, "notifications-userid-subscribe", notifications.Get.OperationID)
+	assert.Equal(t, "userId", notifications.Parameters[0].Value.Name)
+
+	// a WebSocket channel is a GET on an http(s) server
+	stream := doc.Paths["/stream"]
+	assert.Equal(t, "https://ws.example.com", stream.Servers[0].URL)
+	assert.NotNil(t, stream.Get)
+
+	assert.Equal(t, "WARN unsupported server protocol, server ignored location=/servers/broker protocol=kafka\n"+
+		"WARN channel is not on an HTTP server, skipped location=/channels/user.signedup\n", buf.String())
+}
+
+func Test_ToOpenAPIErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		spec     string
+		expected string
+	}{
+		{
+			name:     "not AsyncAPI 2",
+			spec:     "asyncapi: 3.0.0\n",
+			expected: "expected an AsyncAPI 2.x spec, got version '3.0.0'",
+		},
+		{
+			name:     "undeclared server",
+			spec:     "asyncapi: 2.6.0\nchannels:\n  a:\n    servers: [missing]\n",
+			expected: "/channels/a/servers: server 'missing' is not declared in '#/servers'",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content := []byte(tt.spec)
+			_, err := ToOpenAPI(&content, nil)
+			assert.EqualError(t, err, tt.expected)
+		})
+	}
+}
+
+func Test_Convert(t *testing.T) {
+	content, err := os.ReadFile("testdata/asyncapi.yaml")
+	require.NoError(t, err)
+
+	result, err := Convert(context.Background(), &content, convertoas3.O2kOptions{})
+	require.NoError(t, err)
+
+	services := result["services"].([]interface{})
+	assert.Len(t, services, 2)
+	service := services[0].(map[string]interface{})
+	assert.Equal(t, "notifications", service["name"])
+	assert.Equal(t, "notify.example.com", service["host"])
+	assert.Equal(t, "/api", service["path"])
+	assert.Len(t, service["routes"], 2)
+}
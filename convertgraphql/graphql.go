@@ -0,0 +1,356 @@
+// Package convertgraphql converts a GraphQL schema (SDL) to Kong declarative
+// configuration; a single service and route for the GraphQL endpoint. Directives in the
+// schema add the plugins:
+//
+//	schema
+//	  @kong(server: "https://api.example.com", path: "/graphql")
+//	  @kongRateLimiting(limit: 100, window: 60) {
+//	  query: Query
+//	}
+//
+//	type Query {
+//	  user(id: ID!): User @kongDegraphql(uri: "/users/:id")
+//	}
+//
+// '@kongRateLimiting' adds the 'graphql-rate-limiting-advanced' plugin, and each
+// '@kongDegraphql' a REST route, served by the 'degraphql' plugin.
+//
+// The schema is converted to an OpenAPI document first, which is then converted by
+// convertoas3.
+package convertgraphql
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/Kong/fw/convertoas3"
+	"github.com/Kong/fw/importer"
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+const (
+	defaultPath = "/graphql"
+
+	kongDirective          = "kong"
+	rateLimitingDirective  = "kongRateLimiting"
+	degraphqlDirective     = "kongDegraphql"
+	rateLimitingPluginName = "graphql-rate-limiting-advanced"
+	degraphqlPluginName    = "degraphql"
+	degraphqlRoutesKey     = "degraphql_routes"
+)
+
+// schemaRegex matches the definitions that identify a GraphQL schema.
+var schemaRegex = regexp.MustCompile(`(?m)^\s*(schema\s*[@{]|(extend\s+)?type\s+Query\b)`)
+
+// IsSchema returns true if the content is a GraphQL schema, in SDL notation.
+func IsSchema(content []byte) bool {
+	return utf8.Valid(content) && schemaRegex.Match(content)
+}
+
+// degraphqlRoute is a 'degraphql_routes' entity, mapping a REST URI to a query.
+type degraphqlRoute struct {
+	URI   string
+	Query string
+}
+
+// findDirective returns the first directive with the name, or nil.
+func findDirective(directives []directive, name string) *directive {
+	for i := range directives {
+		if directives[i].Name == name {
+			return &directives[i]
+		}
+	}
+	return nil
+}
+
+// stringArgument returns a string argument of a directive, or the default if omitted.
+func stringArgument(d *directive, name string, defaultValue string) (string, error) {
+	value, found := d.Arguments[name]
+	if !found || value == nil {
+		return defaultValue, nil
+	}
+	s, ok := value.(string)
+	if !ok {
+		return "", fmt.Errorf("expected '@%s(%s:)' to be a string, got: '%v'", d.Name, name, value)
+	}
+	return s, nil
+}
+
+// numberArgument returns a numeric argument of a directive, or the default if omitted.
+func numberArgument(d *directive, name string, defaultValue *float64) (*float64, error) {
+	value, found := d.Arguments[name]
+	if !found || value == nil {
+		return defaultValue, nil
+	}
+	n, ok := value.(float64)
+	if !ok {
+		return nil, fmt.Errorf("expected '@%s(%s:)' to be a number, got: '%v'", d.Name, name, value)
+	}
+	return &n, nil
+}
+
+// rateLimitingConfig returns the 'graphql-rate-limiting-advanced' plugin from the
+// '@kongRateLimiting' directive.
+func rateLimitingConfig(d *directive) (map[string]interface{}, error) {
+	config := map[string]interface{}{"sync_rate": 0}
+	for _, name := range []string{"limit", "window"} {
+		n, err := numberArgument(d, name, nil)
+		if err != nil {
+			return nil, err
+		}
+		if n == nil {
+			return nil, fmt.Errorf("expected '@%s' to have a '%s' argument", d.Name, name)
+		}
+		key := name
+		if name == "window" {
+			key = "window_size"
+		}
+		config[key] = []float64{*n}
+	}
+
+	strategy, err := stringArgument(d, "strategy", "cluster")
+	if err != nil {
+		return nil, err
+	}
+	config["strategy"] = strategy
+	syncRate, err := numberArgument(d, "syncRate", nil)
+	if err != nil {
+		return nil, err
+	}
+	if syncRate != nil {
+		config["sync_rate"] = *syncRate
+	}
+	maxCost, err := numberArgument(d, "maxCost", nil)
+	if err != nil {
+		return nil, err
+	}
+	if maxCost != nil {
+		config["max_cost"] = *maxCost
+	}
+	costStrategy, err := stringArgument(d, "costStrategy", "")
+	if err != nil {
+		return nil, err
+	}
+	if costStrategy != "" {
+		config["cost_strategy"] = costStrategy
+	}
+	return map[string]interface{}{"config": config}, nil
+}
+
+// isRequired returns true if an argument must be provided.
+func isRequired(arg argument) bool {
+	return arg.Type.NonNull && !arg.Default
+}
+
+// generateQuery returns the query for a field of the query type, selecting the scalar
+// fields of the type returned. The arguments of the field become variables.
+func generateQuery(doc *document, field fieldDef) (string, error) {
+	var variables, arguments []string
+	for _, arg := range field.Arguments {
+		variables = append(variables, "$"+arg.Name+": "+arg.Type.String())
+		arguments = append(arguments, arg.Name+": $"+arg.Name)
+	}
+
+	query := "query"
+	if len(variables) > 0 {
+		query += "(" + strings.Join(variables, ", ") + ")"
+	}
+	selection := field.Name
+	if len(arguments) > 0 {
+		selection += "(" + strings.Join(arguments, ", ") + ")"
+	}
+
+	typeName := field.Type.namedType()
+	if doc.abstractTypes[typeName] {
+		return "", fmt.Errorf("cannot generate a query for interface or union '%s', add a 'query' argument",
+			typeName)
+	}
+	if obj := doc.Types[typeName]; obj != nil {
+		var fields []string
+		for _, f := range obj.Fields {
+			fieldType := f.Type.namedType()
+			if doc.Types[fieldType] != nil || doc.abstractTypes[fieldType] {
+				continue // not a scalar or enum
+			}
+			required := false
+			for _, arg := range f.Arguments {
+				required = required || isRequired(arg)
+			}
+			if !required {
+				fields = append(fields, f.Name)
+			}
+		}
+		if len(fields) == 0 {
+			return "", fmt.Errorf("cannot generate a query, type '%s' has no scalar fields, add a 'query' argument",
+				typeName)
+		}
+		selection += " { " + strings.Join(fields, " ") + " }"
+	}
+	return query + " { " + selection + " }", nil
+}
+
+// degraphqlRoutes returns the REST routes declared by the '@kongDegraphql' directives on
+// the fields of the query type.
+func degraphqlRoutes(doc *document) ([]degraphqlRoute, error) {
+	queryType := doc.Types[doc.QueryType]
+	if queryType == nil {
+		return nil, nil
+	}
+	var routes []degraphqlRoute
+	for _, field := range queryType.Fields {
+		d := findDirective(field.Directives, degraphqlDirective)
+		if d == nil {
+			continue
+		}
+		uri, err := stringArgument(d, "uri", "")
+		if err == nil && !strings.HasPrefix(uri, "/") {
+			err = fmt.Errorf("expected '@%s(uri:)' to be a path starting with '/', got: '%s'", d.Name, uri)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("field '%s.%s': %w", queryType.Name, field.Name, err)
+		}
+		query, err := stringArgument(d, "query", "")
+		if err == nil && query == "" {
+			query, err = generateQuery(doc, field)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("field '%s.%s': %w", queryType.Name, field.Name, err)
+		}
+		routes = append(routes, degraphqlRoute{URI: uri, Query: query})
+	}
+	return routes, nil
+}
+
+// openAPIPath converts the ':name' segments of a degraphql URI to path parameters.
+func openAPIPath(uri string) string {
+	segments := strings.Split(uri, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") && len(segment) > 1 {
+			segments[i] = "{" + segment[1:] + "}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// toOpenAPI converts a GraphQL schema to an OpenAPI document, and returns the degraphql
+// routes to add to the service.
+func toOpenAPI(content *[]byte, logger convertoas3.Logger) (*openapi3.T, []degraphqlRoute, error) {
+	if logger == nil {
+		logger = convertoas3.NopLogger()
+	}
+
+	doc, err := parseSDL(string(*content))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse GraphQL schema: %w", err)
+	}
+	if doc.Types[doc.QueryType] == nil {
+		return nil, nil, fmt.Errorf("expected the GraphQL schema to define the query type '%s'", doc.QueryType)
+	}
+
+	kong := findDirective(doc.SchemaDirectives, kongDirective)
+	if kong == nil {
+		kong = &directive{Name: kongDirective}
+	}
+	var server, path, name string
+	if server, err = stringArgument(kong, "server", ""); err != nil {
+		return nil, nil, err
+	}
+	if path, err = stringArgument(kong, "path", defaultPath); err != nil {
+		return nil, nil, err
+	}
+	if name, err = stringArgument(kong, "name", "graphql"); err != nil {
+		return nil, nil, err
+	}
+	if !strings.HasPrefix(path, "/") {
+		return nil, nil, fmt.Errorf("expected '@%s(path:)' to be a path starting with '/', got: '%s'",
+			kongDirective, path)
+	}
+	var servers []string
+	if server != "" {
+		servers = []string{server}
+	} else {
+		logger.Warn("no server in the '@kong' directive, using the default", "location", "schema")
+	}
+
+	result := importer.NewDocument(name, "", "")
+	openapi := result.OpenAPI()
+	if d := findDirective(doc.SchemaDirectives, rateLimitingDirective); d != nil {
+		plugin, err := rateLimitingConfig(d)
+		if err != nil {
+			return nil, nil, err
+		}
+		openapi.Extensions["x-kong-plugin-"+rateLimitingPluginName] = plugin
+	}
+
+	operation := openapi3.NewOperation()
+	operation.OperationID = importer.OperationID(name)
+	operation.Summary = "GraphQL endpoint"
+	if err := result.AddOperation(servers, path, "POST", operation); err != nil {
+		return nil, nil, err
+	}
+
+	routes, err := degraphqlRoutes(doc)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, route := range routes {
+		operation := openapi3.NewOperation()
+		operation.OperationID = importer.OperationID(route.URI)
+		operation.Summary = route.Query
+		operation.Extensions = map[string]interface{}{
+			"x-kong-plugin-" + degraphqlPluginName: map[string]interface{}{
+				"config": map[string]interface{}{"graphql_server_path": path},
+			},
+		}
+		if err := result.AddOperation(servers, openAPIPath(route.URI), "GET", operation); err != nil {
+			return nil, nil, fmt.Errorf("degraphql uri '%s': %w", route.URI, err)
+		}
+	}
+	return openapi, routes, nil
+}
+
+// ToOpenAPI converts a GraphQL schema to an OpenAPI document; a POST operation for the
+// GraphQL endpoint, and a GET operation for each '@kongDegraphql' directive. The
+// directives on the schema are described in the package documentation.
+func ToOpenAPI(content *[]byte, logger convertoas3.Logger) (*openapi3.T, error) {
+	doc, _, err := toOpenAPI(content, logger)
+	return doc, err
+}
+
+// Convert converts a GraphQL schema to a Kong declarative file, see ToOpenAPI, and
+// convertoas3.Convert for the options. The 'degraphql_routes' are added to the service.
+func Convert(ctx context.Context, content *[]byte, opts convertoas3.O2kOptions) (map[string]interface{}, error) {
+	doc, routes, err := toOpenAPI(content, opts.Logger)
+	if err != nil {
+		return nil, err
+	}
+	result, err := convertoas3.ConvertDocument(ctx, doc, opts)
+	if err != nil {
+		return nil, err
+	}
+	if len(routes) == 0 {
+		return result, nil
+	}
+
+	services, _ := result["services"].([]interface{})
+	if len(services) == 0 {
+		return result, nil
+	}
+	service, ok := services[0].(map[string]interface{})
+	if !ok {
+		return result, nil
+	}
+	entities := make([]interface{}, 0, len(routes))
+	for _, route := range routes {
+		entities = append(entities, map[string]interface{}{
+			"uri":     route.URI,
+			"query":   route.Query,
+			"methods": []string{"GET"},
+		})
+	}
+	service[degraphqlRoutesKey] = entities
+	return result, nil
+}
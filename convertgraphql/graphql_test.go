@@ -0,0 +1,144 @@
+package convertgraphql
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/Kong/fw/convertoas3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_parseSDL(t *testing.T) {
+	content, err := os.ReadFile("testdata/schema.graphql")
+	require.NoError(t, err)
+
+	doc, err := parseSDL(string(content))
+	require.NoError(t, err)
+	assert.Equal(t, "Query", doc.QueryType)
+	assert.Len(t, doc.SchemaDirectives, 2)
+	assert.Equal(t, map[string]interface{}{"limit": 100.0, "window": 60.0, "maxCost": 50.0},
+		findDirective(doc.SchemaDirectives, rateLimitingDirective).Arguments)
+	assert.Equal(t, map[string]bool{"Node": true, "Account": true}, doc.abstractTypes)
+	assert.ElementsMatch(t, []string{"User", "Query", "Mutation"}, func() []string {
+		var names []string
+		for name := range doc.Types {
+			names = append(names, name)
+		}
+		return names
+	}())
+	assert.Equal(t, "[User!]!", doc.Types["User"].Fields[3].Type.String())
+
+	_, err = parseSDL("type Query { user(id: ID!) User }")
+	assert.EqualError(t, err, "field 'user': expected ':', got 'User'")
+	_, err = parseSDL(`type Query { "unterminated }`)
+	assert.EqualError(t, err, "unterminated string")
+}
+
+func Test_generateQuery(t *testing.T) {
+	content, err := os.ReadFile("testdata/schema.graphql")
+	require.NoError(t, err)
+	doc, err := parseSDL(string(content))
+	require.NoError(t, err)
+
+	fields := doc.Types["Query"].Fields
+	query, err := generateQuery(doc, fields[0])
+	require.NoError(t, err)
+	assert.Equal(t, "query($id: ID!) { user(id: $id) { id name role } }", query)
+
+	_, err = generateQuery(doc, fields[2])
+	assert.EqualError(t, err, "cannot generate a query for interface or union 'Node', add a 'query' argument")
+}
+
+func Test_Detection(t *testing.T) {
+	content, err := os.ReadFile("testdata/schema.graphql")
+	require.NoError(t, err)
+
+	assert.True(t, IsSchema(content))
+	assert.True(t, IsSchema([]byte("type Query {\n  hello: String\n}")))
+	assert.False(t, IsSchema([]byte("openapi: 3.0.3")))
+}
+
+func Test_ToOpenAPI(t *testing.T) {
+	content, err := os.ReadFile("testdata/schema.graphql")
+	require.NoError(t, err)
+
+	doc, err := ToOpenAPI(&content, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "https://users.example.com", doc.Servers[0].URL)
+	assert.Len(t, doc.Paths, 3)
+	assert.NotNil(t, doc.Paths["/graphql"].Post)
+	assert.NotNil(t, doc.Paths["/users/{id}"].Get)
+	assert.Equal(t, map[string]interface{}{
+		"config": map[string]interface{}{
+			"limit":       []float64{100},
+			"window_size": []float64{60},
+			"strategy":    "cluster",
+			"sync_rate":   0,
+			"max_cost":    50.0,
+		},
+	}, doc.Extensions["x-kong-plugin-graphql-rate-limiting-advanced"])
+
+	tests := []struct {
+		name   string
+		schema string
+		err    string
+	}{
+		{
+			name:   "no query type",
+			schema: "schema { query: Root }",
+			err:    "expected the GraphQL schema to define the query type 'Root'",
+		},
+		{
+			name:   "missing limit",
+			schema: "schema @kongRateLimiting(window: 60) { query: Query } type Query { a: Int }",
+			err:    "expected '@kongRateLimiting' to have a 'limit' argument",
+		},
+		{
+			name:   "relative uri",
+			schema: `type Query { a: Int @kongDegraphql(uri: "a") }`,
+			err:    "field 'Query.a': expected '@kongDegraphql(uri:)' to be a path starting with '/', got: 'a'",
+		},
+		{
+			name:   "duplicate uri",
+			schema: `type Query { a: Int @kongDegraphql(uri: "/a") b: Int @kongDegraphql(uri: "/a") }`,
+			err:    "degraphql uri '/a': duplicate operation 'GET /a'",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schema := []byte(tt.schema)
+			_, err := ToOpenAPI(&schema, nil)
+			assert.EqualError(t, err, tt.err)
+		})
+	}
+}
+
+func Test_Convert(t *testing.T) {
+	content, err := os.ReadFile("testdata/schema.graphql")
+	require.NoError(t, err)
+
+	result, err := Convert(context.Background(), &content, convertoas3.O2kOptions{})
+	require.NoError(t, err)
+
+	services := result["services"].([]interface{})
+	require.Len(t, services, 1)
+	service := services[0].(map[string]interface{})
+	assert.Equal(t, "users.example.com", service["host"])
+	assert.Len(t, service["routes"], 3)
+	plugins := service["plugins"].(*[]*map[string]interface{})
+	assert.Equal(t, "graphql-rate-limiting-advanced", (*(*plugins)[0])["name"])
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{
+			"uri":     "/users/:id",
+			"query":   "query($id: ID!) { user(id: $id) { id name role } }",
+			"methods": []string{"GET"},
+		},
+		map[string]interface{}{
+			"uri":     "/users",
+			"query":   "query { users { id } }",
+			"methods": []string{"GET"},
+		},
+	}, service["degraphql_routes"])
+}
@@ -0,0 +1,431 @@
+package convertgraphql
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// typeRef is a GraphQL type reference, eg. '[User!]!'.
+type typeRef struct {
+	Name    string   // the named type, eg. 'User', unset for lists
+	OfType  *typeRef // the type of the list elements, for lists
+	NonNull bool
+}
+
+// String returns the type in SDL notation.
+func (t *typeRef) String() string {
+	s := t.Name
+	if t.OfType != nil {
+		s = "[" + t.OfType.String() + "]"
+	}
+	if t.NonNull {
+		s += "!"
+	}
+	return s
+}
+
+// namedType returns the name of the type, with the lists and non-null removed.
+func (t *typeRef) namedType() string {
+	if t.OfType != nil {
+		return t.OfType.namedType()
+	}
+	return t.Name
+}
+
+// directive is an applied directive, eg. '@kong(path: "/graphql")'. The arguments
+// are strings, numbers (as float64), or booleans.
+type directive struct {
+	Name      string
+	Arguments map[string]interface{}
+}
+
+type argument struct {
+	Name    string
+	Type    *typeRef
+	Default bool // has a default value
+}
+
+type fieldDef struct {
+	Name       string
+	Arguments  []argument
+	Type       *typeRef
+	Directives []directive
+}
+
+type objectType struct {
+	Name   string
+	Fields []fieldDef
+}
+
+// document is the part of a GraphQL schema used for the conversion.
+type document struct {
+	SchemaDirectives []directive
+	QueryType        string // the name of the root query type
+	Types            map[string]*objectType
+	abstractTypes    map[string]bool // the interfaces and unions, by name
+}
+
+// definitionKeywords are the keywords starting a definition.
+var definitionKeywords = map[string]bool{
+	"schema": true, "type": true, "extend": true, "scalar": true, "enum": true,
+	"input": true, "interface": true, "union": true, "directive": true,
+}
+
+// tokenize splits a GraphQL SDL document into tokens; names, numbers, strings (quoted),
+// and punctuators. Comments and commas are dropped.
+func tokenize(content string) ([]string, error) {
+	var tokens []string
+	for i := 0; i < len(content); {
+		c := content[i]
+		switch {
+		case c == '#':
+			end := strings.IndexByte(content[i:], '\n')
+			if end < 0 {
+				return tokens, nil
+			}
+			i += end + 1
+		case c == ',' || c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == 0xef || c == 0xbb || c == 0xbf:
+			i++ // commas are insignificant, as is the byte order mark
+		case strings.HasPrefix(content[i:], `"""`):
+			end := strings.Index(content[i+3:], `"""`)
+			if end < 0 {
+				return nil, errors.New("unterminated block string")
+			}
+			tokens = append(tokens, content[i:i+end+6])
+			i += end + 6
+		case c == '"':
+			end := i + 1
+			for end < len(content) && content[end] != '"' && content[end] != '\n' {
+				if content[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(content) || content[end] != '"' {
+				return nil, errors.New("unterminated string")
+			}
+			tokens = append(tokens, content[i:end+1])
+			i = end + 1
+		case strings.HasPrefix(content[i:], "..."):
+			tokens = append(tokens, "...")
+			i += 3
+		case c == '_' || c == '-' || isAlphaNumeric(c):
+			end := i + 1
+			for end < len(content) && (content[end] == '_' || content[end] == '.' || isAlphaNumeric(content[end])) {
+				end++
+			}
+			tokens = append(tokens, content[i:end])
+			i = end
+		case strings.IndexByte("!$&()/:=@[]{|}", c) >= 0:
+			tokens = append(tokens, string(c))
+			i++
+		default:
+			return nil, fmt.Errorf("unexpected character '%c'", c)
+		}
+	}
+	return tokens, nil
+}
+
+func isAlphaNumeric(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+func isString(token string) bool {
+	return strings.HasPrefix(token, `"`)
+}
+
+// sdlParser parses the schema definition, directives, and object types of a GraphQL
+// schema. Other definitions are skipped.
+type sdlParser struct {
+	tokens []string
+	pos    int
+}
+
+func (p *sdlParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+func (p *sdlParser) next() string {
+	token := p.peek()
+	p.pos++
+	return token
+}
+
+func (p *sdlParser) expect(expected string) error {
+	if token := p.next(); token != expected {
+		return fmt.Errorf("expected '%s', got '%s'", expected, token)
+	}
+	return nil
+}
+
+// skipDescription skips a description string, if present.
+func (p *sdlParser) skipDescription() {
+	if isString(p.peek()) {
+		p.next()
+	}
+}
+
+// skipDefinition skips a definition that is not parsed, up to the next definition.
+func (p *sdlParser) skipDefinition() error {
+	depth := 0
+	for p.pos < len(p.tokens) {
+		token := p.peek()
+		if depth == 0 && (definitionKeywords[token] || isString(token)) {
+			return nil
+		}
+		p.next()
+		switch token {
+		case "{", "(", "[":
+			depth++
+		case "}", ")", "]":
+			if depth--; depth < 0 {
+				return fmt.Errorf("unexpected '%s'", token)
+			}
+		}
+	}
+	return nil
+}
+
+// parseValue parses a constant value; a string, number, boolean, null, or enum value.
+// Lists and objects are skipped, and returned as nil.
+func (p *sdlParser) parseValue() (interface{}, error) {
+	token := p.next()
+	switch {
+	case token == "[" || token == "{":
+		closing := map[string]string{"[": "]", "{": "}"}[token]
+		depth := 1
+		for depth > 0 {
+			switch p.next() {
+			case token:
+				depth++
+			case closing:
+				depth--
+			case "":
+				return nil, errors.New("unexpected end of schema")
+			}
+		}
+		return nil, nil
+	case strings.HasPrefix(token, `"""`):
+		return strings.TrimSpace(token[3 : len(token)-3]), nil
+	case isString(token):
+		return strconv.Unquote(token)
+	case token == "true" || token == "false":
+		return token == "true", nil
+	case token == "null":
+		return nil, nil
+	case token == "":
+		return nil, errors.New("unexpected end of schema")
+	}
+	if number, err := strconv.ParseFloat(token, 64); err == nil {
+		return number, nil
+	}
+	return token, nil // an enum value
+}
+
+// parseDirectives parses zero or more applied directives.
+func (p *sdlParser) parseDirectives() ([]directive, error) {
+	var directives []directive
+	for p.peek() == "@" {
+		p.next()
+		d := directive{Name: p.next(), Arguments: make(map[string]interface{})}
+		if p.peek() == "(" {
+			p.next()
+			for p.peek() != ")" {
+				name := p.next()
+				if err := p.expect(":"); err != nil {
+					return nil, fmt.Errorf("directive '@%s': %w", d.Name, err)
+				}
+				value, err := p.parseValue()
+				if err != nil {
+					return nil, fmt.Errorf("directive '@%s': %w", d.Name, err)
+				}
+				d.Arguments[name] = value
+			}
+			p.next()
+		}
+		directives = append(directives, d)
+	}
+	return directives, nil
+}
+
+// parseType parses a type reference.
+func (p *sdlParser) parseType() (*typeRef, error) {
+	t := &typeRef{}
+	token := p.next()
+	switch {
+	case token == "[":
+		ofType, err := p.parseType()
+		if err != nil {
+			return nil, err
+		}
+		t.OfType = ofType
+		if err := p.expect("]"); err != nil {
+			return nil, err
+		}
+	case token == "" || !(token[0] == '_' || isAlphaNumeric(token[0])):
+		return nil, fmt.Errorf("expected a type, got '%s'", token)
+	default:
+		t.Name = token
+	}
+	if p.peek() == "!" {
+		p.next()
+		t.NonNull = true
+	}
+	return t, nil
+}
+
+// parseArguments parses the argument definitions of a field, if present.
+func (p *sdlParser) parseArguments() ([]argument, error) {
+	if p.peek() != "(" {
+		return nil, nil
+	}
+	p.next()
+	var arguments []argument
+	for p.peek() != ")" {
+		p.skipDescription()
+		arg := argument{Name: p.next()}
+		if err := p.expect(":"); err != nil {
+			return nil, fmt.Errorf("argument '%s': %w", arg.Name, err)
+		}
+		var err error
+		if arg.Type, err = p.parseType(); err != nil {
+			return nil, fmt.Errorf("argument '%s': %w", arg.Name, err)
+		}
+		if p.peek() == "=" {
+			p.next()
+			arg.Default = true
+			if _, err = p.parseValue(); err != nil {
+				return nil, fmt.Errorf("argument '%s': %w", arg.Name, err)
+			}
+		}
+		if _, err = p.parseDirectives(); err != nil {
+			return nil, err
+		}
+		arguments = append(arguments, arg)
+	}
+	p.next()
+	return arguments, nil
+}
+
+// parseObjectType parses an object type, after the 'type' keyword.
+func (p *sdlParser) parseObjectType(doc *document) error {
+	name := p.next()
+	if p.peek() == "implements" {
+		p.next()
+		for p.peek() == "&" || (p.peek() != "{" && p.peek() != "@" && p.peek() != "" &&
+			!definitionKeywords[p.peek()] && !isString(p.peek())) {
+			p.next()
+		}
+	}
+	if _, err := p.parseDirectives(); err != nil {
+		return err
+	}
+
+	obj := doc.Types[name]
+	if obj == nil {
+		obj = &objectType{Name: name}
+		doc.Types[name] = obj
+	}
+	if p.peek() != "{" {
+		return nil // no fields, eg. an extension adding an interface
+	}
+	p.next()
+	for p.peek() != "}" {
+		if p.peek() == "" {
+			return errors.New("unexpected end of schema")
+		}
+		p.skipDescription()
+		field := fieldDef{Name: p.next()}
+		var err error
+		if field.Arguments, err = p.parseArguments(); err != nil {
+			return fmt.Errorf("field '%s': %w", field.Name, err)
+		}
+		if err = p.expect(":"); err != nil {
+			return fmt.Errorf("field '%s': %w", field.Name, err)
+		}
+		if field.Type, err = p.parseType(); err != nil {
+			return fmt.Errorf("field '%s': %w", field.Name, err)
+		}
+		if field.Directives, err = p.parseDirectives(); err != nil {
+			return fmt.Errorf("field '%s': %w", field.Name, err)
+		}
+		obj.Fields = append(obj.Fields, field)
+	}
+	p.next()
+	return nil
+}
+
+// parseSchemaDefinition parses the schema definition, after the 'schema' keyword.
+func (p *sdlParser) parseSchemaDefinition(doc *document) error {
+	directives, err := p.parseDirectives()
+	if err != nil {
+		return err
+	}
+	doc.SchemaDirectives = append(doc.SchemaDirectives, directives...)
+	if p.peek() != "{" {
+		return nil
+	}
+	p.next()
+	for p.peek() != "}" {
+		operation := p.next()
+		if err := p.expect(":"); err != nil {
+			return fmt.Errorf("schema: %w", err)
+		}
+		typeName := p.next()
+		if operation == "query" {
+			doc.QueryType = typeName
+		}
+		if operation == "" {
+			return errors.New("unexpected end of schema")
+		}
+	}
+	p.next()
+	return nil
+}
+
+// parseSDL parses a GraphQL schema in SDL notation.
+func parseSDL(content string) (*document, error) {
+	tokens, err := tokenize(content)
+	if err != nil {
+		return nil, err
+	}
+	p := &sdlParser{tokens: tokens}
+	doc := &document{
+		QueryType:     "Query",
+		Types:         make(map[string]*objectType),
+		abstractTypes: make(map[string]bool),
+	}
+
+	for p.pos < len(p.tokens) {
+		p.skipDescription()
+		keyword := p.next()
+		if keyword == "extend" {
+			keyword = p.next()
+		}
+		switch keyword {
+		case "schema":
+			err = p.parseSchemaDefinition(doc)
+		case "type":
+			err = p.parseObjectType(doc)
+		case "":
+			return doc, nil
+		default:
+			if !definitionKeywords[keyword] {
+				return nil, fmt.Errorf("unexpected '%s'", keyword)
+			}
+			if keyword == "interface" || keyword == "union" {
+				doc.abstractTypes[p.peek()] = true
+			}
+			err = p.skipDefinition()
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return doc, nil
+}
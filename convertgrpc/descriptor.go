@@ -0,0 +1,157 @@
+package convertgrpc
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Field numbers in the descriptor messages, see google/protobuf/descriptor.proto.
+const (
+	fileSetFileField        = 1 // FileDescriptorSet.file
+	filePackageField        = 2 // FileDescriptorProto.package
+	fileServiceField        = 6 // FileDescriptorProto.service
+	serviceNameField        = 1 // ServiceDescriptorProto.name
+	serviceMethodField      = 2 // ServiceDescriptorProto.method
+	methodNameField         = 1 // MethodDescriptorProto.name
+	methodInputTypeField    = 2 // MethodDescriptorProto.input_type
+	methodOutputTypeField   = 3 // MethodDescriptorProto.output_type
+	methodClientStreamField = 5 // MethodDescriptorProto.client_streaming
+	methodServerStreamField = 6 // MethodDescriptorProto.server_streaming
+)
+
+// Protobuf wire types.
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+// field is a decoded protobuf field; the value is set for varints, the data for
+// length delimited fields.
+type field struct {
+	number int
+	value  uint64
+	data   []byte
+}
+
+// decodeMessage decodes the fields of a protobuf message. Only the wire format is
+// decoded, the meaning of the fields is up to the caller.
+func decodeMessage(data []byte) ([]field, error) {
+	var fields []field
+	for len(data) > 0 {
+		key, n := binary.Uvarint(data)
+		if n <= 0 {
+			return nil, errors.New("invalid field key")
+		}
+		data = data[n:]
+		f := field{number: int(key >> 3)}
+		switch key & 7 {
+		case wireVarint:
+			if f.value, n = binary.Uvarint(data); n <= 0 {
+				return nil, errors.New("invalid varint")
+			}
+			data = data[n:]
+		case wireFixed64:
+			if len(data) < 8 {
+				return nil, errors.New("truncated fixed64")
+			}
+			data = data[8:]
+		case wireFixed32:
+			if len(data) < 4 {
+				return nil, errors.New("truncated fixed32")
+			}
+			data = data[4:]
+		case wireBytes:
+			length, n := binary.Uvarint(data)
+			if n <= 0 || length > uint64(len(data)-n) {
+				return nil, errors.New("invalid length")
+			}
+			f.data = data[n : n+int(length)]
+			data = data[n+int(length):]
+		default:
+			return nil, fmt.Errorf("unsupported wire type %d", key&7)
+		}
+		fields = append(fields, f)
+	}
+	return fields, nil
+}
+
+// parseDescriptorSet returns the services defined in a FileDescriptorSet, as generated
+// by 'protoc --descriptor_set_out'.
+func parseDescriptorSet(data []byte) ([]service, error) {
+	files, err := decodeMessage(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var services []service
+	for _, file := range files {
+		if file.number != fileSetFileField {
+			continue
+		}
+		fileFields, err := decodeMessage(file.data)
+		if err != nil {
+			return nil, fmt.Errorf("invalid file descriptor: %w", err)
+		}
+		pkg := ""
+		for _, f := range fileFields {
+			if f.number == filePackageField {
+				pkg = string(f.data)
+			}
+		}
+		for _, f := range fileFields {
+			if f.number != fileServiceField {
+				continue
+			}
+			svc, err := parseServiceDescriptor(f.data, pkg)
+			if err != nil {
+				return nil, err
+			}
+			services = append(services, svc)
+		}
+	}
+	return services, nil
+}
+
+// parseServiceDescriptor decodes a ServiceDescriptorProto.
+func parseServiceDescriptor(data []byte, pkg string) (service, error) {
+	var svc service
+	fields, err := decodeMessage(data)
+	if err != nil {
+		return svc, fmt.Errorf("invalid service descriptor: %w", err)
+	}
+	for _, f := range fields {
+		switch f.number {
+		case serviceNameField:
+			svc.FullName = string(f.data)
+			if pkg != "" {
+				svc.FullName = pkg + "." + svc.FullName
+			}
+		case serviceMethodField:
+			methodFields, err := decodeMessage(f.data)
+			if err != nil {
+				return svc, fmt.Errorf("invalid method descriptor: %w", err)
+			}
+			var m method
+			for _, mf := range methodFields {
+				switch mf.number {
+				case methodNameField:
+					m.Name = string(mf.data)
+				case methodInputTypeField:
+					m.InputType = strings.TrimPrefix(string(mf.data), ".")
+				case methodOutputTypeField:
+					m.OutputType = strings.TrimPrefix(string(mf.data), ".")
+				case methodClientStreamField:
+					m.ClientStreaming = mf.value != 0
+				case methodServerStreamField:
+					m.ServerStreaming = mf.value != 0
+				}
+			}
+			svc.Methods = append(svc.Methods, m)
+		}
+	}
+	return svc, nil
+}
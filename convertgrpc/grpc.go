@@ -0,0 +1,195 @@
+// Package convertgrpc converts gRPC service definitions, either a .proto file or a
+// FileDescriptorSet, to Kong declarative configuration. Each rpc becomes a route with
+// the path '/package.Service/Method', on a grpc(s) service.
+//
+// The services are converted to an OpenAPI document first, which is then converted by
+// convertoas3, and the generated entities adapted to gRPC.
+package convertgrpc
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/Kong/fw/convertoas3"
+	"github.com/Kong/fw/importer"
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+const (
+	grpcScheme  = "grpc"
+	grpcsScheme = "grpcs"
+)
+
+// protoRegex matches the statements that identify a .proto file.
+var protoRegex = regexp.MustCompile(`(?m)^\s*(syntax\s*=\s*["']proto[23]["']|service\s+\w+\s*\{)`)
+
+// Options defines the options for converting gRPC services.
+type Options struct {
+	// Server is the URL of the gRPC backend, with a 'grpc' or 'grpcs' scheme, eg.
+	// 'grpc://greeter:50051'. If omitted, the backend is 'grpcs://localhost:443'.
+	Server string
+	// GRPCWeb, if set, adds the 'grpc-web' plugin, and routes the HTTP requests from
+	// gRPC-Web clients (eg. browsers), instead of gRPC requests.
+	GRPCWeb bool
+}
+
+// IsProto returns true if the content is a .proto file.
+func IsProto(content []byte) bool {
+	return utf8.Valid(content) && protoRegex.Match(content)
+}
+
+// IsDescriptorSet returns true if the content is a FileDescriptorSet, with at least one
+// file.
+func IsDescriptorSet(content []byte) bool {
+	fields, err := decodeMessage(content)
+	if err != nil || len(fields) == 0 {
+		return false
+	}
+	for _, f := range fields {
+		if f.number != fileSetFileField {
+			return false
+		}
+		fileFields, err := decodeMessage(f.data)
+		if err != nil || len(fileFields) == 0 || fileFields[0].number != 1 ||
+			!strings.HasSuffix(string(fileFields[0].data), ".proto") {
+			return false
+		}
+	}
+	return true
+}
+
+// parseServices returns the services in a .proto file or a FileDescriptorSet.
+func parseServices(content []byte) ([]service, error) {
+	if IsDescriptorSet(content) {
+		services, err := parseDescriptorSet(content)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse FileDescriptorSet: %w", err)
+		}
+		return services, nil
+	}
+	services, err := parseProto(string(content))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse .proto file: %w", err)
+	}
+	return services, nil
+}
+
+// streamType returns the type for the description of an rpc.
+func streamType(typeName string, streaming bool) string {
+	if streaming {
+		return "stream " + typeName
+	}
+	return typeName
+}
+
+// ToOpenAPI converts the gRPC services in a .proto file or FileDescriptorSet to an
+// OpenAPI document. Each rpc becomes a POST operation on '/package.Service/Method',
+// tagged with the service name.
+func ToOpenAPI(content *[]byte, opts Options) (*openapi3.T, error) {
+	services, err := parseServices(*content)
+	if err != nil {
+		return nil, err
+	}
+	if len(services) == 0 {
+		return nil, fmt.Errorf("expected at least one gRPC service, found none")
+	}
+
+	var servers []string
+	if opts.Server != "" {
+		serverURL, err := url.Parse(opts.Server)
+		if err != nil || (serverURL.Scheme != grpcScheme && serverURL.Scheme != grpcsScheme) || serverURL.Host == "" {
+			return nil, fmt.Errorf("expected the server to be a '%s://' or '%s://' URL, got: '%s'",
+				grpcScheme, grpcsScheme, opts.Server)
+		}
+		servers = []string{opts.Server}
+	}
+
+	// the title is the package, of the first service
+	title := services[0].FullName
+	if i := strings.LastIndex(title, "."); i > 0 {
+		title = title[:i]
+	}
+	doc := importer.NewDocument(title, "", "")
+	openapi := doc.OpenAPI()
+	if opts.Server == "" {
+		openapi.Extensions["x-kong-service-defaults"] = map[string]interface{}{"protocol": grpcsScheme}
+	}
+	if opts.GRPCWeb {
+		openapi.Extensions["x-kong-plugin-grpc-web"] = map[string]interface{}{}
+	}
+
+	for _, svc := range services {
+		for _, m := range svc.Methods {
+			operation := openapi3.NewOperation()
+			operation.OperationID = importer.OperationID(svc.FullName, m.Name)
+			operation.Summary = fmt.Sprintf("rpc %s (%s) returns (%s)", m.Name,
+				streamType(m.InputType, m.ClientStreaming), streamType(m.OutputType, m.ServerStreaming))
+			operation.Tags = []string{svc.FullName}
+			if err := doc.AddOperation(servers, "/"+svc.FullName+"/"+m.Name, "POST", operation); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return openapi, nil
+}
+
+// adaptToGRPC adapts the entities generated from the OpenAPI document to gRPC. Services
+// cannot have a path, and routes match the gRPC protocols without methods. For gRPC-Web
+// the routes match the HTTP protocols instead, since these requests are converted by
+// the 'grpc-web' plugin.
+func adaptToGRPC(result map[string]interface{}, grpcWeb bool) {
+	services, _ := result["services"].([]interface{})
+	for _, s := range services {
+		service, ok := s.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		delete(service, "path")
+		routes, _ := service["routes"].([]interface{})
+		for _, r := range routes {
+			route, ok := r.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if grpcWeb {
+				route["protocols"] = []string{"http", "https"}
+			} else {
+				delete(route, "methods")
+				route["protocols"] = []string{grpcScheme, grpcsScheme}
+			}
+		}
+	}
+}
+
+// Convert converts the gRPC services in a .proto file or FileDescriptorSet to a Kong
+// declarative file, with the default Options, see ConvertWithOptions.
+func Convert(ctx context.Context, content *[]byte, opts convertoas3.O2kOptions) (map[string]interface{}, error) {
+	return ConvertWithOptions(ctx, content, Options{}, opts)
+}
+
+// ConvertWithOptions converts the gRPC services in a .proto file or FileDescriptorSet
+// to a Kong declarative file, see ToOpenAPI, and convertoas3.Convert for the options.
+// The routes use plain paths, matching as a prefix, since gRPC paths have no parameters.
+func ConvertWithOptions(
+	ctx context.Context,
+	content *[]byte,
+	grpcOpts Options,
+	opts convertoas3.O2kOptions,
+) (map[string]interface{}, error) {
+	doc, err := ToOpenAPI(content, grpcOpts)
+	if err != nil {
+		return nil, err
+	}
+	opts.PlainPaths = true
+	opts.PrefixMatch = true
+	result, err := convertoas3.ConvertDocument(ctx, doc, opts)
+	if err != nil {
+		return nil, err
+	}
+	adaptToGRPC(result, grpcOpts.GRPCWeb)
+	return result, nil
+}
@@ -0,0 +1,160 @@
+package convertgrpc
+
+import (
+	"context"
+	"encoding/binary"
+	"os"
+	"testing"
+
+	"github.com/Kong/fw/convertoas3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// appendField appends a length delimited protobuf field.
+func appendField(data []byte, number int, value []byte) []byte {
+	data = binary.AppendUvarint(data, uint64(number<<3|wireBytes))
+	data = binary.AppendUvarint(data, uint64(len(value)))
+	return append(data, value...)
+}
+
+// appendVarint appends a varint protobuf field.
+func appendVarint(data []byte, number int, value uint64) []byte {
+	data = binary.AppendUvarint(data, uint64(number<<3|wireVarint))
+	return binary.AppendUvarint(data, value)
+}
+
+// greeterDescriptorSet returns a FileDescriptorSet for a subset of testdata/greeter.proto.
+func greeterDescriptorSet() []byte {
+	var sayHello, stream, svc, file []byte
+	sayHello = appendField(sayHello, methodNameField, []byte("SayHello"))
+	sayHello = appendField(sayHello, methodInputTypeField, []byte(".helloworld.HelloRequest"))
+	sayHello = appendField(sayHello, methodOutputTypeField, []byte(".helloworld.HelloReply"))
+	stream = appendField(stream, methodNameField, []byte("SayHelloStream"))
+	stream = appendField(stream, methodInputTypeField, []byte(".helloworld.HelloRequest"))
+	stream = appendField(stream, methodOutputTypeField, []byte(".helloworld.HelloReply"))
+	stream = appendVarint(stream, methodClientStreamField, 1)
+	stream = appendVarint(stream, methodServerStreamField, 1)
+	svc = appendField(svc, serviceNameField, []byte("Greeter"))
+	svc = appendField(svc, serviceMethodField, sayHello)
+	svc = appendField(svc, serviceMethodField, stream)
+	file = appendField(file, 1, []byte("greeter.proto"))
+	file = appendField(file, filePackageField, []byte("helloworld"))
+	file = appendField(file, fileServiceField, svc)
+	return appendField(nil, fileSetFileField, file)
+}
+
+func Test_parseProto(t *testing.T) {
+	content, err := os.ReadFile("testdata/greeter.proto")
+	require.NoError(t, err)
+
+	services, err := parseProto(string(content))
+	require.NoError(t, err)
+	assert.Equal(t, []service{{
+		FullName: "helloworld.Greeter",
+		Methods: []method{
+			{Name: "SayHello", InputType: "HelloRequest", OutputType: "HelloReply"},
+			{
+				Name: "SayHelloStream", InputType: "HelloRequest", OutputType: "HelloReply",
+				ClientStreaming: true, ServerStreaming: true,
+			},
+			{Name: "Ping", InputType: "google.protobuf.Empty", OutputType: "google.protobuf.Empty"},
+		},
+	}}, services)
+
+	_, err = parseProto("service Greeter { rpc SayHello (HelloRequest) HelloReply; }")
+	assert.EqualError(t, err, "service 'Greeter': rpc 'SayHello': expected 'returns', got 'HelloReply'")
+	_, err = parseProto("/* unterminated")
+	assert.EqualError(t, err, "unterminated comment")
+}
+
+func Test_parseDescriptorSet(t *testing.T) {
+	services, err := parseDescriptorSet(greeterDescriptorSet())
+	require.NoError(t, err)
+	assert.Equal(t, []service{{
+		FullName: "helloworld.Greeter",
+		Methods: []method{
+			{Name: "SayHello", InputType: "helloworld.HelloRequest", OutputType: "helloworld.HelloReply"},
+			{
+				Name: "SayHelloStream", InputType: "helloworld.HelloRequest", OutputType: "helloworld.HelloReply",
+				ClientStreaming: true, ServerStreaming: true,
+			},
+		},
+	}}, services)
+
+	_, err = parseDescriptorSet([]byte{0x0a, 0x05, 0x01})
+	assert.EqualError(t, err, "invalid length")
+}
+
+func Test_Detection(t *testing.T) {
+	content, err := os.ReadFile("testdata/greeter.proto")
+	require.NoError(t, err)
+
+	assert.True(t, IsProto(content))
+	assert.False(t, IsProto([]byte("openapi: 3.0.3")))
+	assert.True(t, IsDescriptorSet(greeterDescriptorSet()))
+	assert.False(t, IsDescriptorSet(content))
+	assert.False(t, IsDescriptorSet([]byte("openapi: 3.0.3")))
+}
+
+func Test_ToOpenAPI(t *testing.T) {
+	content, err := os.ReadFile("testdata/greeter.proto")
+	require.NoError(t, err)
+
+	doc, err := ToOpenAPI(&content, Options{})
+	require.NoError(t, err)
+	assert.Equal(t, "helloworld", doc.Info.Title)
+	assert.Len(t, doc.Paths, 3)
+	sayHello := doc.Paths["/helloworld.Greeter/SayHello"].Post
+	assert.Equal(t, "rpc SayHello (HelloRequest) returns (HelloReply)", sayHello.Summary)
+	assert.Equal(t, []string{"helloworld.Greeter"}, sayHello.Tags)
+	assert.Equal(t, "rpc SayHelloStream (stream HelloRequest) returns (stream HelloReply)",
+		doc.Paths["/helloworld.Greeter/SayHelloStream"].Post.Summary)
+
+	_, err = ToOpenAPI(&content, Options{Server: "http://greeter:50051"})
+	assert.EqualError(t, err, "expected the server to be a 'grpc://' or 'grpcs://' URL, got: 'http://greeter:50051'")
+
+	empty := []byte(`syntax = "proto3";`)
+	_, err = ToOpenAPI(&empty, Options{})
+	assert.EqualError(t, err, "expected at least one gRPC service, found none")
+}
+
+func Test_Convert(t *testing.T) {
+	content, err := os.ReadFile("testdata/greeter.proto")
+	require.NoError(t, err)
+
+	result, err := ConvertWithOptions(context.Background(), &content,
+		Options{Server: "grpc://greeter:9000"}, convertoas3.O2kOptions{})
+	require.NoError(t, err)
+
+	services := result["services"].([]interface{})
+	require.Len(t, services, 1)
+	service := services[0].(map[string]interface{})
+	assert.Equal(t, "grpc", service["protocol"])
+	assert.Equal(t, "greeter", service["host"])
+	assert.EqualValues(t, 9000, service["port"])
+	assert.NotContains(t, service, "path")
+
+	routes := service["routes"].([]interface{})
+	require.Len(t, routes, 3)
+	route := routes[0].(map[string]interface{})
+	assert.Equal(t, []string{"/helloworld.Greeter/Ping"}, route["paths"])
+	assert.Equal(t, []string{"grpc", "grpcs"}, route["protocols"])
+	assert.NotContains(t, route, "methods")
+}
+
+func Test_ConvertGRPCWeb(t *testing.T) {
+	content := greeterDescriptorSet()
+	result, err := ConvertWithOptions(context.Background(), &content, Options{GRPCWeb: true}, convertoas3.O2kOptions{})
+	require.NoError(t, err)
+
+	service := result["services"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, "grpcs", service["protocol"])
+	assert.EqualValues(t, 443, service["port"])
+	plugins := service["plugins"].(*[]*map[string]interface{})
+	assert.Equal(t, "grpc-web", (*(*plugins)[0])["name"])
+
+	route := service["routes"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, []string{"http", "https"}, route["protocols"])
+	assert.Equal(t, []string{"POST"}, route["methods"])
+}
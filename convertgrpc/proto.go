@@ -0,0 +1,220 @@
+package convertgrpc
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// method is an rpc of a gRPC service.
+type method struct {
+	Name            string
+	InputType       string
+	OutputType      string
+	ClientStreaming bool
+	ServerStreaming bool
+}
+
+// service is a gRPC service, with its fully qualified name, eg. 'helloworld.Greeter'.
+type service struct {
+	FullName string
+	Methods  []method
+}
+
+// tokenize splits a .proto file into tokens; identifiers (including dots), quoted
+// strings, and single character symbols. Comments are dropped.
+func tokenize(content string) ([]string, error) {
+	var tokens []string
+	for i := 0; i < len(content); {
+		c := content[i]
+		switch {
+		case strings.HasPrefix(content[i:], "//"):
+			end := strings.IndexByte(content[i:], '\n')
+			if end < 0 {
+				return tokens, nil
+			}
+			i += end + 1
+		case strings.HasPrefix(content[i:], "/*"):
+			end := strings.Index(content[i+2:], "*/")
+			if end < 0 {
+				return nil, errors.New("unterminated comment")
+			}
+			i += end + 4
+		case c == '"' || c == '\'':
+			end := i + 1
+			for end < len(content) && content[end] != c {
+				if content[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(content) {
+				return nil, errors.New("unterminated string")
+			}
+			tokens = append(tokens, content[i:end+1])
+			i = end + 1
+		case c == '_' || c == '.' || c < utf8.RuneSelf && (unicode.IsLetter(rune(c)) || unicode.IsDigit(rune(c))):
+			end := i
+			for end < len(content) && (content[end] == '_' || content[end] == '.' ||
+				unicode.IsLetter(rune(content[end])) || unicode.IsDigit(rune(content[end]))) {
+				end++
+			}
+			tokens = append(tokens, content[i:end])
+			i = end
+		case unicode.IsSpace(rune(c)):
+			i++
+		default:
+			tokens = append(tokens, string(c))
+			i++
+		}
+	}
+	return tokens, nil
+}
+
+// protoParser parses the package and services of a .proto file. Everything else, eg.
+// messages and options, is skipped.
+type protoParser struct {
+	tokens []string
+	pos    int
+}
+
+func (p *protoParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+func (p *protoParser) next() string {
+	token := p.peek()
+	p.pos++
+	return token
+}
+
+func (p *protoParser) expect(expected string) error {
+	if token := p.next(); token != expected {
+		return fmt.Errorf("expected '%s', got '%s'", expected, token)
+	}
+	return nil
+}
+
+// skipStatement skips a statement up to and including the ';', or a block up to and
+// including its closing '}'.
+func (p *protoParser) skipStatement() error {
+	depth := 0
+	for p.pos < len(p.tokens) {
+		switch p.next() {
+		case ";":
+			if depth == 0 {
+				return nil
+			}
+		case "{":
+			depth++
+		case "}":
+			depth--
+			if depth == 0 {
+				return nil
+			}
+		}
+	}
+	return errors.New("unexpected end of file")
+}
+
+// parseType parses '(' ['stream'] type ')', and returns the type, and whether it
+// is streamed.
+func (p *protoParser) parseType() (string, bool, error) {
+	if err := p.expect("("); err != nil {
+		return "", false, err
+	}
+	streaming := false
+	if p.peek() == "stream" && p.pos+1 < len(p.tokens) && p.tokens[p.pos+1] != ")" {
+		p.next()
+		streaming = true
+	}
+	typeName := p.next()
+	return typeName, streaming, p.expect(")")
+}
+
+// parseService parses the body of a service, after its name.
+func (p *protoParser) parseService(fullName string) (service, error) {
+	svc := service{FullName: fullName}
+	if err := p.expect("{"); err != nil {
+		return svc, err
+	}
+	for {
+		switch token := p.peek(); token {
+		case "}":
+			p.next()
+			return svc, nil
+		case "":
+			return svc, errors.New("unexpected end of file")
+		case "rpc":
+			p.next()
+			m := method{Name: p.next()}
+			var err error
+			if m.InputType, m.ClientStreaming, err = p.parseType(); err != nil {
+				return svc, fmt.Errorf("rpc '%s': %w", m.Name, err)
+			}
+			if err = p.expect("returns"); err != nil {
+				return svc, fmt.Errorf("rpc '%s': %w", m.Name, err)
+			}
+			if m.OutputType, m.ServerStreaming, err = p.parseType(); err != nil {
+				return svc, fmt.Errorf("rpc '%s': %w", m.Name, err)
+			}
+			if p.peek() == ";" {
+				p.next()
+			} else if err = p.skipStatement(); err != nil { // the options block
+				return svc, err
+			}
+			svc.Methods = append(svc.Methods, m)
+		default:
+			if err := p.skipStatement(); err != nil { // eg. an option
+				return svc, err
+			}
+		}
+	}
+}
+
+// parseProto returns the services defined in a .proto file.
+func parseProto(content string) ([]service, error) {
+	tokens, err := tokenize(content)
+	if err != nil {
+		return nil, err
+	}
+	p := &protoParser{tokens: tokens}
+
+	var (
+		pkg      string
+		services []service
+	)
+	for p.pos < len(p.tokens) {
+		switch p.peek() {
+		case "package":
+			p.next()
+			pkg = p.next()
+			if err := p.expect(";"); err != nil {
+				return nil, err
+			}
+		case "service":
+			p.next()
+			name := p.next()
+			if pkg != "" {
+				name = pkg + "." + name
+			}
+			svc, err := p.parseService(name)
+			if err != nil {
+				return nil, fmt.Errorf("service '%s': %w", name, err)
+			}
+			services = append(services, svc)
+		case ";":
+			p.next() // empty statement
+		default:
+			if err := p.skipStatement(); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return services, nil
+}
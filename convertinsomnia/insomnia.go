@@ -0,0 +1,302 @@
+// Package convertinsomnia converts Insomnia exports (v4) to Kong declarative
+// configuration. The export is converted to an OpenAPI document first, which is then
+// converted by convertoas3.
+package convertinsomnia
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/Kong/fw/convertoas3"
+	"github.com/Kong/fw/importer"
+	"github.com/getkin/kin-openapi/openapi3"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	exportType   = "export"
+	exportFormat = 4
+
+	workspaceType    = "workspace"
+	environmentType  = "environment"
+	requestGroupType = "request_group"
+	requestType      = "request"
+
+	// maxResolvePasses limits resolving variables that refer to other variables
+	maxResolvePasses = 10
+)
+
+var (
+	// variableRegex matches Insomnia variables, eg. '{{ _.base_url }}', or the legacy
+	// '{{ base_url }}'.
+	variableRegex = regexp.MustCompile(`{{\s*(?:_\.)?([^}\s]+)\s*}}`)
+	// tagRegex matches template tags, eg. '{% response ... %}', which cannot be resolved.
+	tagRegex = regexp.MustCompile(`{%.*?%}`)
+)
+
+// Options defines the options for converting an Insomnia export.
+type Options struct {
+	// Environment is the name of the sub environment whose variables are used, on top of
+	// the base environment. If empty, only the base environment is used.
+	Environment string
+}
+
+// export is the part of an Insomnia export used for the conversion.
+type export struct {
+	Type      string     `json:"_type"`
+	Format    int        `json:"__export_format"`
+	Resources []resource `json:"resources"`
+}
+
+// resource is any of the resources in an export, they are linked by their parentId.
+type resource struct {
+	ID          string                 `json:"_id"`
+	Type        string                 `json:"_type"`
+	ParentID    string                 `json:"parentId"`
+	Name        string                 `json:"name"`
+	Description string                 `json:"description"`
+	Data        map[string]interface{} `json:"data"`        // environments
+	Environment map[string]interface{} `json:"environment"` // request groups
+	Method      string                 `json:"method"`
+	URL         string                 `json:"url"`
+	Parameters  []struct {
+		Name     string `json:"name"`
+		Disabled bool   `json:"disabled"`
+	} `json:"parameters"`
+	Authentication map[string]interface{} `json:"authentication"`
+}
+
+// IsExport returns true if the content is an Insomnia export (v4), in JSON or YAML.
+func IsExport(content []byte) bool {
+	var e export
+	if err := yaml.Unmarshal(content, &e); err != nil {
+		return false
+	}
+	return e.Type == exportType && e.Format == exportFormat
+}
+
+// flatten adds the values in the data to the variables, nested objects are added with
+// dotted names, eg. 'api.host'.
+func flatten(variables map[string]string, prefix string, data map[string]interface{}) {
+	for key, value := range data {
+		if nested, ok := value.(map[string]interface{}); ok {
+			flatten(variables, prefix+key+".", nested)
+		} else if value != nil {
+			variables[prefix+key] = fmt.Sprint(value)
+		}
+	}
+}
+
+// resolveVariables replaces the variables that are defined, also in the values of
+// other variables. Undefined variables are left in place.
+func resolveVariables(s string, variables map[string]string) string {
+	for i := 0; i < maxResolvePasses && variableRegex.MatchString(s); i++ {
+		resolved := variableRegex.ReplaceAllStringFunc(s, func(match string) string {
+			if value, found := variables[variableRegex.FindStringSubmatch(match)[1]]; found {
+				return value
+			}
+			return match
+		})
+		if resolved == s {
+			break
+		}
+		s = resolved
+	}
+	return s
+}
+
+// splitURL splits a URL, with the variables resolved, into the server and the OpenAPI
+// path, see importer.SplitURL. Path segments that are an unresolved variable are
+// converted to path parameters as well.
+func splitURL(rawURL string) (string, string, error) {
+	if tag := tagRegex.FindString(rawURL); tag != "" {
+		return "", "", fmt.Errorf("template tag '%s' in the URL cannot be resolved", tag)
+	}
+	scheme, rest, found := strings.Cut(rawURL, "://")
+	if !found {
+		scheme, rest = "", rawURL
+	}
+	host, path, _ := strings.Cut(rest, "/")
+	if variableRegex.MatchString(host) {
+		return "", "", fmt.Errorf("undefined variable in the host '%s'", host)
+	}
+
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if match := variableRegex.FindStringSubmatch(segment); match != nil && match[0] == segment {
+			segments[i] = ":" + match[1]
+		}
+	}
+	rawURL = host + "/" + strings.Join(segments, "/")
+	if scheme != "" {
+		rawURL = scheme + "://" + rawURL
+	}
+	return importer.SplitURL(rawURL)
+}
+
+// converter holds the state while converting an export.
+type converter struct {
+	doc       *importer.Document
+	logger    convertoas3.Logger
+	children  map[string][]int // indices of the resources, by parentId
+	resources []resource
+}
+
+// addChildren adds the requests below the parent, recursing into request groups. The
+// groups are the names of the enclosing request groups, used as tags. The variables
+// are those of the environment, and the enclosing request groups.
+func (c *converter) addChildren(parentID string, groups []string, variables map[string]string,
+) convertoas3.ConversionErrors {
+	var errs convertoas3.ConversionErrors
+	for _, i := range c.children[parentID] {
+		res := c.resources[i]
+		location := fmt.Sprintf("/resources/%d", i)
+		switch res.Type {
+		case requestGroupType:
+			groupVariables := make(map[string]string, len(variables))
+			for name, value := range variables {
+				groupVariables[name] = value
+			}
+			flatten(groupVariables, "", res.Environment)
+			// copy, so the groups of sibling resources do not share the same array
+			subGroups := append(append([]string{}, groups...), res.Name)
+			errs = append(errs, c.addChildren(res.ID, subGroups, groupVariables)...)
+		case requestType:
+			if err := c.addRequest(res, groups, variables, location); err != nil {
+				errs = append(errs, &convertoas3.ConversionError{Pointer: location, Err: err})
+			}
+		}
+	}
+	return errs
+}
+
+// addRequest adds the request as an operation to the document.
+func (c *converter) addRequest(res resource, groups []string, variables map[string]string, location string) error {
+	server, path, err := splitURL(resolveVariables(res.URL, variables))
+	if err != nil {
+		return err
+	}
+	if authType, _ := res.Authentication["type"].(string); authType != "" && authType != "none" {
+		if disabled, _ := res.Authentication["disabled"].(bool); !disabled {
+			c.logger.Warn("authentication of a request is not converted, ignored", "location", location)
+		}
+	}
+
+	operation := openapi3.NewOperation()
+	operation.Summary = res.Name
+	operation.Description = res.Description
+	operation.Tags = groups
+	operation.OperationID = importer.OperationID(append(append([]string{}, groups...), res.Name)...)
+	query := importer.QueryNames(res.URL)
+	for _, param := range res.Parameters {
+		if !param.Disabled {
+			query = append(query, param.Name)
+		}
+	}
+	importer.AddQueryParameters(operation, query)
+
+	method := res.Method
+	if method == "" {
+		method = "GET"
+	}
+	return c.doc.AddOperation([]string{server}, path, method, operation)
+}
+
+// ToOpenAPI converts an Insomnia export (v4) to an OpenAPI document. The first
+// workspace is converted; request groups become tags, requests become operations, and
+// the variables of the environment (and request groups) are resolved in the request
+// URLs, determining the servers. Unresolved variables in a path become path parameters.
+// What cannot be converted (eg. authentication) is logged as a warning.
+func ToOpenAPI(content *[]byte, opts Options, logger convertoas3.Logger) (*openapi3.T, error) {
+	if logger == nil {
+		logger = convertoas3.NopLogger()
+	}
+
+	var e export
+	if err := yaml.Unmarshal(*content, &e); err != nil {
+		return nil, fmt.Errorf("failed to parse Insomnia export: %w", err)
+	}
+	if e.Type != exportType || e.Format != exportFormat {
+		return nil, fmt.Errorf("expected an Insomnia export format %d, got type '%s' format %d",
+			exportFormat, e.Type, e.Format)
+	}
+
+	conv := converter{
+		logger:    logger,
+		children:  make(map[string][]int),
+		resources: e.Resources,
+	}
+	var workspace *resource
+	for i, res := range e.Resources {
+		conv.children[res.ParentID] = append(conv.children[res.ParentID], i)
+		if res.Type == workspaceType && workspace == nil {
+			workspace = &e.Resources[i]
+		}
+	}
+	if workspace == nil {
+		return nil, fmt.Errorf("expected the Insomnia export to have a workspace")
+	}
+	conv.doc = importer.NewDocument(workspace.Name, workspace.Description, "")
+
+	// the base environment is a child of the workspace, the sub environments of the base
+	variables := make(map[string]string)
+	environments := make([]string, 0)
+	for _, i := range conv.children[workspace.ID] {
+		base := e.Resources[i]
+		if base.Type != environmentType {
+			continue
+		}
+		flatten(variables, "", base.Data)
+		for _, j := range conv.children[base.ID] {
+			sub := e.Resources[j]
+			if sub.Type != environmentType {
+				continue
+			}
+			environments = append(environments, sub.Name)
+			if sub.Name == opts.Environment {
+				flatten(variables, "", sub.Data)
+			}
+		}
+		break
+	}
+	if opts.Environment != "" {
+		found := false
+		for _, name := range environments {
+			found = found || name == opts.Environment
+		}
+		if !found {
+			sort.Strings(environments)
+			return nil, fmt.Errorf("environment '%s' not found, available: '%s'",
+				opts.Environment, strings.Join(environments, "', '"))
+		}
+	}
+
+	if errs := conv.addChildren(workspace.ID, []string{}, variables); len(errs) > 0 {
+		return nil, errs
+	}
+	return conv.doc.OpenAPI(), nil
+}
+
+// Convert converts an Insomnia export (v4) to a Kong declarative file, using only the
+// base environment, see ToOpenAPI, and convertoas3.Convert for the options.
+func Convert(ctx context.Context, content *[]byte, opts convertoas3.O2kOptions) (map[string]interface{}, error) {
+	return ConvertEnvironment(ctx, content, Options{}, opts)
+}
+
+// ConvertEnvironment converts an Insomnia export (v4) to a Kong declarative file, see
+// ToOpenAPI, and convertoas3.Convert for the options.
+func ConvertEnvironment(
+	ctx context.Context,
+	content *[]byte,
+	insomniaOpts Options,
+	opts convertoas3.O2kOptions,
+) (map[string]interface{}, error) {
+	doc, err := ToOpenAPI(content, insomniaOpts, opts.Logger)
+	if err != nil {
+		return nil, err
+	}
+	return convertoas3.ConvertDocument(ctx, doc, opts)
+}
@@ -0,0 +1,120 @@
+package convertinsomnia
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"os"
+	"testing"
+
+	"github.com/Kong/fw/convertoas3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_IsExport(t *testing.T) {
+	content, err := os.ReadFile("testdata/export.yaml")
+	require.NoError(t, err)
+
+	assert.True(t, IsExport(content))
+	assert.True(t, IsExport([]byte(`{"_type": "export", "__export_format": 4, "resources": []}`)))
+	assert.False(t, IsExport([]byte(`{"_type": "export", "__export_format": 3}`)))
+	assert.False(t, IsExport([]byte("openapi: 3.0.3")))
+}
+
+func Test_ToOpenAPI(t *testing.T) {
+	content, err := os.ReadFile("testdata/export.yaml")
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	logger := convertoas3.NewStdLogger(log.New(&buf, "", 0), convertoas3.LogLevelWarn)
+	doc, err := ToOpenAPI(&content, Options{}, logger)
+	require.NoError(t, err)
+
+	assert.Equal(t, "Orders API", doc.Info.Title)
+	assert.Equal(t, "Order management", doc.Info.Description)
+	assert.Equal(t, "https://orders.example.com", doc.Servers[0].URL)
+	assert.Len(t, doc.Paths, 3)
+
+	list := doc.Paths["/orders"].Get
+	assert.Equal(t, "orders-list-orders", list.OperationID)
+	assert.Equal(t, []string{"Orders"}, list.Tags)
+	assert.Len(t, list.Parameters, 1)
+	assert.Equal(t, "status", list.Parameters[0].Value.Name)
+
+	// an undefined variable in the path becomes a path parameter
+	get := doc.Paths["/orders/{orderId}"]
+	assert.Equal(t, "orderId", get.Parameters[0].Value.Name)
+
+	// the request group environment overrides the server
+	assert.Equal(t, "https://admin.example.com", doc.Paths["/stats"].Servers[0].URL)
+
+	assert.Equal(t, "WARN authentication of a request is not converted, ignored location=/resources/5\n", buf.String())
+}
+
+func Test_ToOpenAPIEnvironment(t *testing.T) {
+	content, err := os.ReadFile("testdata/export.yaml")
+	require.NoError(t, err)
+
+	doc, err := ToOpenAPI(&content, Options{Environment: "Production"}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "https://orders.example.org", doc.Servers[0].URL)
+
+	_, err = ToOpenAPI(&content, Options{Environment: "Staging"}, nil)
+	assert.EqualError(t, err, "environment 'Staging' not found, available: 'Production'")
+}
+
+func Test_ToOpenAPIErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		export   string
+		expected string
+	}{
+		{
+			name:     "not an export",
+			export:   `{"_type": "other"}`,
+			expected: "expected an Insomnia export format 4, got type 'other' format 0",
+		},
+		{
+			name:     "no workspace",
+			export:   `{"_type": "export", "__export_format": 4, "resources": []}`,
+			expected: "expected the Insomnia export to have a workspace",
+		},
+		{
+			name: "template tag",
+			export: `{"_type": "export", "__export_format": 4, "resources": [
+				{"_id": "w", "_type": "workspace", "name": "w"},
+				{"_id": "r", "_type": "request", "parentId": "w", "url": "{% uuid 'v4' %}/a"}]}`,
+			expected: "/resources/1: template tag '{% uuid 'v4' %}' in the URL cannot be resolved",
+		},
+		{
+			name: "undefined host variable",
+			export: `{"_type": "export", "__export_format": 4, "resources": [
+				{"_id": "w", "_type": "workspace", "name": "w"},
+				{"_id": "r", "_type": "request", "parentId": "w", "url": "{{ _.host }}/a"}]}`,
+			expected: "/resources/1: undefined variable in the host '{{ _.host }}'",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content := []byte(tt.export)
+			_, err := ToOpenAPI(&content, Options{}, nil)
+			assert.EqualError(t, err, tt.expected)
+		})
+	}
+}
+
+func Test_Convert(t *testing.T) {
+	content, err := os.ReadFile("testdata/export.yaml")
+	require.NoError(t, err)
+
+	result, err := Convert(context.Background(), &content, convertoas3.O2kOptions{})
+	require.NoError(t, err)
+
+	services := result["services"].([]interface{})
+	assert.Len(t, services, 2)
+	service := services[0].(map[string]interface{})
+	assert.Equal(t, "orders-api", service["name"])
+	assert.Equal(t, "orders.example.com", service["host"])
+	assert.Len(t, service["routes"], 2)
+}
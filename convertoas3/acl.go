@@ -0,0 +1,103 @@
+package convertoas3
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	uuid "github.com/satori/go.uuid"
+)
+
+const (
+	// ACLFromTags generates ACL groups from the OAS tags of an operation.
+	ACLFromTags = "tags"
+	// ACLFromScopes generates ACL groups from the oauth2/openIdConnect scopes required
+	// by an operation. Only supported if each security requirement needs a single scope,
+	// as a consumer in any of the allowed groups passes the 'acl' plugin.
+	ACLFromScopes = "scopes"
+)
+
+// getACLGroups returns the sorted list of ACL groups for an operation, based on the
+// source; ACLFromTags or ACLFromScopes. Returns an empty list if there are none. With
+// ACLFromScopes, the scopes of a requirement are all needed, while the 'acl' plugin
+// allows any of its groups, so a requirement with more than one scope is an error.
+func getACLGroups(doc *openapi3.T, operation *openapi3.Operation, source string) ([]string, error) {
+	groups := make(map[string]bool)
+
+	switch source {
+	case ACLFromTags:
+		for _, tag := range operation.Tags {
+			groups[tag] = true
+		}
+
+	case ACLFromScopes:
+		requirements := getSecurityRequirements(doc, operation)
+		scoped := 0
+		for _, requirement := range requirements {
+			required := make([]string, 0)
+			for schemeName, scopes := range requirement {
+				scheme, err := getSecurityScheme(doc, schemeName)
+				if err != nil {
+					return nil, err
+				}
+				if scheme.Type != "oauth2" && scheme.Type != "openIdConnect" {
+					continue
+				}
+				required = append(required, scopes...)
+			}
+			if len(required) > 1 {
+				sort.Strings(required)
+				return nil, fmt.Errorf("expected each security requirement to need a single scope, as the "+
+					"'acl' plugin allows any of its groups, got: '%s'", strings.Join(required, ", "))
+			}
+			for _, scope := range required {
+				groups[scope] = true
+				scoped++
+			}
+		}
+		if scoped > 0 && scoped < len(requirements) {
+			// an 'acl' plugin would deny the requirements without a scope
+			return nil, fmt.Errorf("expected each security requirement to need a single scope, as the " +
+				"'acl' plugin allows any of its groups, got: a requirement without scopes")
+		}
+
+	default:
+		return nil, fmt.Errorf("invalid ACL source '%s', expected '%s' or '%s'", source, ACLFromTags, ACLFromScopes)
+	}
+
+	result := make([]string, 0, len(groups))
+	for group := range groups {
+		result = append(result, group)
+	}
+	sort.Strings(result)
+	return result, nil
+}
+
+// addACLPlugin adds an 'acl' plugin allowing the groups to the list, unless the list
+// already has an 'acl' plugin, or there are no groups. The list remains sorted by
+// plugin name.
+func addACLPlugin(
+	list *[]*map[string]interface{},
+	groups []string,
+	uuidNamespace uuid.UUID,
+	baseName string,
+	tags []string,
+) *[]*map[string]interface{} {
+	if len(groups) == 0 || hasPlugin(list, "acl") {
+		return list
+	}
+
+	plugin := map[string]interface{}{
+		"name": "acl",
+		"config": map[string]interface{}{
+			"allow": groups,
+		},
+		"tags": tags,
+	}
+	plugin["id"] = createPluginID(uuidNamespace, baseName, plugin)
+
+	plugins := newPluginSet(list)
+	plugins.insert(&plugin)
+	return plugins.list()
+}
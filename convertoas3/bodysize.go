@@ -0,0 +1,219 @@
+package convertoas3
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	uuid "github.com/satori/go.uuid"
+)
+
+const (
+	maxBodySizeExtension = "x-kong-max-body-size"
+
+	// jsonNumberSize is the maximum size of a JSON number, or boolean/null, in bytes.
+	jsonNumberSize = 32
+	// jsonCharSize is the maximum size of a character in a JSON string, escaped as '\uXXXX'.
+	jsonCharSize = 6
+)
+
+// bodySizeUnits are the units of the 'x-kong-max-body-size' extension, and the
+// request-size-limiting 'size_unit' they map to.
+var bodySizeUnits = map[string]string{
+	"":   "bytes",
+	"b":  "bytes",
+	"kb": "kilobytes",
+	"mb": "megabytes",
+}
+
+var bodySizePattern = regexp.MustCompile(`^(?i)\s*([0-9]+)\s*(b|kb|mb)?\s*$`)
+
+// bodySizeLimit is a maximum request body size, in the request-size-limiting units.
+type bodySizeLimit struct {
+	size int
+	unit string // 'bytes', 'kilobytes', or 'megabytes'
+}
+
+// getMaxBodySize returns the 'x-kong-max-body-size' extension; either a number of bytes,
+// or a string with a unit, eg. '512KB' or '10MB'. Returns nil if there is none.
+func getMaxBodySize(props openapi3.ExtensionProps) (*bodySizeLimit, error) {
+	value := props.Extensions[maxBodySizeExtension]
+	if value == nil {
+		return nil, nil
+	}
+	var decoded interface{}
+	if err := decodeExtension(value, &decoded); err != nil {
+		return nil, fmt.Errorf("expected '%s' to be a number or a string: %w", maxBodySizeExtension, err)
+	}
+
+	var text string
+	switch v := decoded.(type) {
+	case float64:
+		text = strconv.FormatFloat(v, 'f', -1, 64)
+	case string:
+		text = v
+	}
+	match := bodySizePattern.FindStringSubmatch(text)
+	if match == nil {
+		return nil, fmt.Errorf("expected '%s' to be a number of bytes, or a size like '512KB' or '10MB', got: '%v'",
+			maxBodySizeExtension, decoded)
+	}
+	size, err := strconv.Atoi(match[1])
+	if err != nil || size <= 0 {
+		return nil, fmt.Errorf("expected '%s' to be a positive size, got: '%v'", maxBodySizeExtension, decoded)
+	}
+	return &bodySizeLimit{size: size, unit: bodySizeUnits[strings.ToLower(match[2])]}, nil
+}
+
+// schemaSizeBound returns the maximum size in bytes of a compact JSON value valid for
+// the schema. Returns false if the size is unbounded, eg. a string without 'maxLength',
+// or an object allowing additional properties.
+func schemaSizeBound(schemaRef *openapi3.SchemaRef, visiting map[*openapi3.Schema]bool) (int, bool) {
+	if schemaRef == nil || schemaRef.Value == nil {
+		return 0, false
+	}
+	schema := schemaRef.Value
+	if visiting[schema] {
+		return 0, false // recursive schemas are unbounded
+	}
+	visiting[schema] = true
+	defer delete(visiting, schema)
+
+	if len(schema.OneOf) > 0 || len(schema.AnyOf) > 0 {
+		// any of the alternatives, so the largest one
+		largest := 0
+		for _, alternative := range append(append([]*openapi3.SchemaRef{}, schema.OneOf...), schema.AnyOf...) {
+			size, bounded := schemaSizeBound(alternative, visiting)
+			if !bounded {
+				return 0, false
+			}
+			if size > largest {
+				largest = size
+			}
+		}
+		return largest, true
+	}
+	if len(schema.AllOf) > 0 {
+		// all of them, so the smallest bounded one
+		smallest, found := 0, false
+		for _, part := range schema.AllOf {
+			if size, bounded := schemaSizeBound(part, visiting); bounded && (!found || size < smallest) {
+				smallest, found = size, true
+			}
+		}
+		return smallest, found
+	}
+
+	switch schema.Type {
+	case "string":
+		if len(schema.Enum) > 0 {
+			longest := 0
+			for _, value := range schema.Enum {
+				if s, ok := value.(string); ok && len(s) > longest {
+					longest = len(s)
+				}
+			}
+			return longest*jsonCharSize + 2, true
+		}
+		if schema.MaxLength == nil {
+			return 0, false
+		}
+		if schema.Format == "binary" {
+			return int(*schema.MaxLength), true
+		}
+		return int(*schema.MaxLength)*jsonCharSize + 2, true
+	case "integer", "number", "boolean":
+		return jsonNumberSize, true
+	case "array":
+		if schema.MaxItems == nil {
+			return 0, false
+		}
+		itemSize, bounded := schemaSizeBound(schema.Items, visiting)
+		if !bounded {
+			return 0, false
+		}
+		return 2 + int(*schema.MaxItems)*(itemSize+1), true
+	case "object":
+		if schema.AdditionalPropertiesAllowed == nil || *schema.AdditionalPropertiesAllowed {
+			return 0, false
+		}
+		size := 2
+		for name, property := range schema.Properties {
+			propertySize, bounded := schemaSizeBound(property, visiting)
+			if !bounded {
+				return 0, false
+			}
+			size += len(name)*jsonCharSize + 2 + 2 + propertySize // name, quotes, ':' and ','
+		}
+		return size, true
+	default:
+		return 0, false
+	}
+}
+
+// getSchemaBodySize returns the maximum request body size derived from the schemas of
+// the request body, for all its content types. Returns nil if there is no request body,
+// or the size of any of them is unbounded. To allow for whitespace in the body, the
+// limit is twice the compact size, in kilobytes (rounded up).
+func getSchemaBodySize(operation *openapi3.Operation) *bodySizeLimit {
+	if operation.RequestBody == nil || operation.RequestBody.Value == nil ||
+		len(operation.RequestBody.Value.Content) == 0 {
+		return nil
+	}
+
+	// sorted, to be deterministic
+	contentTypes := make([]string, 0, len(operation.RequestBody.Value.Content))
+	for contentType := range operation.RequestBody.Value.Content {
+		contentTypes = append(contentTypes, contentType)
+	}
+	sort.Strings(contentTypes)
+
+	largest := 0
+	for _, contentType := range contentTypes {
+		mediaType := operation.RequestBody.Value.Content[contentType]
+		if mediaType == nil {
+			return nil
+		}
+		size, bounded := schemaSizeBound(mediaType.Schema, make(map[*openapi3.Schema]bool))
+		if !bounded {
+			return nil
+		}
+		if size > largest {
+			largest = size
+		}
+	}
+	return &bodySizeLimit{size: (2*largest + 1023) / 1024, unit: "kilobytes"}
+}
+
+// addBodySizePlugin adds a 'request-size-limiting' plugin for the limit to the list,
+// unless the limit is nil, or the list or the service already has one. The list
+// remains sorted by plugin name.
+func addBodySizePlugin(
+	list *[]*map[string]interface{},
+	servicePlugins interface{},
+	limit *bodySizeLimit,
+	uuidNamespace uuid.UUID,
+	baseName string,
+	tags []string,
+) *[]*map[string]interface{} {
+	if limit == nil || hasPlugin(list, "request-size-limiting") || hasPlugin(servicePlugins, "request-size-limiting") {
+		return list
+	}
+
+	plugin := map[string]interface{}{
+		"name": "request-size-limiting",
+		"config": map[string]interface{}{
+			"allowed_payload_size": limit.size,
+			"size_unit":            limit.unit,
+		},
+		"tags": tags,
+	}
+	plugin["id"] = createPluginID(uuidNamespace, baseName, plugin)
+
+	plugins := newPluginSet(list)
+	plugins.insert(&plugin)
+	return plugins.list()
+}
@@ -0,0 +1,95 @@
+package convertoas3
+
+import (
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_getMaxBodySize(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    interface{}
+		expected *bodySizeLimit
+		err      string
+	}{
+		{"none", nil, nil, ""},
+		{"bytes", 1024, &bodySizeLimit{size: 1024, unit: "bytes"}, ""},
+		{"bytes string", "1024", &bodySizeLimit{size: 1024, unit: "bytes"}, ""},
+		{"bytes unit", "100 B", &bodySizeLimit{size: 100, unit: "bytes"}, ""},
+		{"kilobytes", "512KB", &bodySizeLimit{size: 512, unit: "kilobytes"}, ""},
+		{"megabytes", "10mb", &bodySizeLimit{size: 10, unit: "megabytes"}, ""},
+		{"zero", 0, nil, "to be a positive size"},
+		{"fraction", 1.5, nil, "to be a number of bytes"},
+		{"unknown unit", "1GB", nil, "to be a number of bytes"},
+		{"wrong type", true, nil, "to be a number of bytes"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			props := openapi3.ExtensionProps{Extensions: map[string]interface{}{}}
+			if tt.value != nil {
+				props.Extensions[maxBodySizeExtension] = tt.value
+			}
+			limit, err := getMaxBodySize(props)
+			if tt.err != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, limit)
+		})
+	}
+}
+
+func Test_schemaSizeBound(t *testing.T) {
+	maxLength := uint64(10)
+	maxItems := uint64(3)
+	closed := false
+	str := &openapi3.Schema{Type: "string", MaxLength: &maxLength}
+	recursive := &openapi3.Schema{Type: "array", MaxItems: &maxItems}
+	recursive.Items = openapi3.NewSchemaRef("", recursive)
+
+	tests := []struct {
+		name     string
+		schema   *openapi3.Schema
+		expected int
+		bounded  bool
+	}{
+		{"string", str, 62, true},
+		{"binary", &openapi3.Schema{Type: "string", Format: "binary", MaxLength: &maxLength}, 10, true},
+		{"unbounded string", &openapi3.Schema{Type: "string"}, 0, false},
+		{"enum", &openapi3.Schema{Type: "string", Enum: []interface{}{"a", "abc"}}, 20, true},
+		{"number", &openapi3.Schema{Type: "number"}, jsonNumberSize, true},
+		{"array", &openapi3.Schema{Type: "array", MaxItems: &maxItems, Items: openapi3.NewSchemaRef("", str)}, 191, true},
+		{"unbounded array", &openapi3.Schema{Type: "array", Items: openapi3.NewSchemaRef("", str)}, 0, false},
+		{"recursive", recursive, 0, false},
+		{"object", &openapi3.Schema{
+			Type:                        "object",
+			AdditionalPropertiesAllowed: &closed,
+			Properties:                  openapi3.Schemas{"a": openapi3.NewSchemaRef("", str)},
+		}, 2 + 6 + 4 + 62, true},
+		{"open object", &openapi3.Schema{
+			Type:       "object",
+			Properties: openapi3.Schemas{"a": openapi3.NewSchemaRef("", str)},
+		}, 0, false},
+		{"oneOf", &openapi3.Schema{OneOf: openapi3.SchemaRefs{
+			openapi3.NewSchemaRef("", str),
+			openapi3.NewSchemaRef("", &openapi3.Schema{Type: "boolean"}),
+		}}, 62, true},
+		{"allOf", &openapi3.Schema{AllOf: openapi3.SchemaRefs{
+			openapi3.NewSchemaRef("", &openapi3.Schema{Type: "string"}),
+			openapi3.NewSchemaRef("", str),
+		}}, 62, true},
+		{"untyped", &openapi3.Schema{}, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			size, bounded := schemaSizeBound(openapi3.NewSchemaRef("", tt.schema), make(map[*openapi3.Schema]bool))
+			assert.Equal(t, tt.bounded, bounded)
+			assert.Equal(t, tt.expected, size)
+		})
+	}
+}
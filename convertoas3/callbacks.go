@@ -0,0 +1,148 @@
+package convertoas3
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	uuid "github.com/satori/go.uuid"
+)
+
+// callbackConversion holds the entities generated for the callbacks of an operation.
+type callbackConversion struct {
+	services   []interface{}   // a service per callback URL, with the routes
+	upstreams  []interface{}   // upstreams for the services, if any
+	routeCount int             // number of routes generated
+	dynamic    []string        // JSON pointers of callback URLs that are runtime expressions, skipped
+	locations  entityLocations // the spec elements the entities were generated from
+}
+
+// splitCallbackURL splits a callback URL expression into the server, eg.
+// 'https://partner.example.com', and the path, eg. '/hooks/{$request.query.id}'. The
+// query string, if any, is dropped. Returns false if the server is not static; the URL
+// is a runtime expression itself, eg. '{$request.body#/callbackUrl}', or the host has
+// runtime expressions.
+func splitCallbackURL(expression string) (string, string, bool) {
+	schemeEnd := strings.Index(expression, "://")
+	if schemeEnd < 0 || strings.ContainsAny(expression[:schemeEnd], "{}") {
+		return "", "", false
+	}
+	server, path := expression, "/"
+	if pathStart := strings.Index(expression[schemeEnd+3:], "/"); pathStart >= 0 {
+		server = expression[:schemeEnd+3+pathStart]
+		path = expression[schemeEnd+3+pathStart:]
+	}
+	if strings.ContainsAny(server, "{}") {
+		return "", "", false
+	}
+	if queryStart := strings.Index(path, "?"); queryStart >= 0 {
+		path = path[:queryStart]
+	}
+	return server, path, true
+}
+
+// createCallbackServices creates a service for each callback URL of the operation, with a
+// route for each of its operations, so the calls to the callback receivers can be sent
+// through Kong. The route paths are the paths of the callback URLs, runtime expressions in
+// them match a single segment. Callback URLs without a static server cannot be routed,
+// those are returned as 'dynamic'.
+func createCallbackServices(
+	callbacks openapi3.Callbacks,
+	pointer string, // JSON pointer to the callbacks of the operation
+	uuidNamespace uuid.UUID,
+	baseName string, // the operation base name
+	components *map[string]interface{},
+	tags []string,
+) (*callbackConversion, error) {
+	conversion := &callbackConversion{
+		services:  make([]interface{}, 0),
+		upstreams: make([]interface{}, 0),
+	}
+
+	callbackNames := make([]string, 0, len(callbacks))
+	for name := range callbacks {
+		callbackNames = append(callbackNames, name)
+	}
+	sort.Strings(callbackNames)
+
+	for _, callbackName := range callbackNames {
+		callback := callbacks[callbackName]
+		if callback == nil || callback.Value == nil {
+			continue
+		}
+		expressions := make([]string, 0, len(*callback.Value))
+		for expression := range *callback.Value {
+			expressions = append(expressions, expression)
+		}
+		sort.Strings(expressions)
+
+		for i, expression := range expressions {
+			pathitem := (*callback.Value)[expression]
+			expressionPointer := pointer + jsonPointer(callbackName, expression)
+			server, path, static := splitCallbackURL(expression)
+			if !static {
+				conversion.dynamic = append(conversion.dynamic, expressionPointer)
+				continue
+			}
+			if pathitem == nil {
+				continue
+			}
+
+			callbackSlug, _ := slugOrHash(callbackName)
+			serviceBaseName := baseName + "_" + callbackSlug
+			if len(expressions) > 1 {
+				serviceBaseName = serviceBaseName + "_" + strconv.Itoa(i+1)
+			}
+			service, upstream, err := CreateKongService(serviceBaseName, &openapi3.Servers{{URL: server}},
+				nil, nil, tags, uuidNamespace)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create service for callback '%s': %w", expression, err)
+			}
+			if upstream != nil {
+				conversion.upstreams = append(conversion.upstreams, upstream)
+				conversion.locations.add("upstream", upstream, expressionPointer)
+			}
+
+			routeRegex, captures := createRouteRegex("", path)
+			regexPriority := 200 // non-regexed (no params) paths have higher precedence in OAS
+			if len(captures) > 0 {
+				regexPriority = 100
+			}
+
+			operations := pathitem.Operations()
+			methods := make([]string, 0, len(operations))
+			for method := range operations {
+				methods = append(methods, method)
+			}
+			sort.Strings(methods)
+
+			for _, method := range methods {
+				routeBaseName := serviceBaseName + "_" + strings.ToLower(method)
+				plugins, err := getPluginsList(operations[method].ExtensionProps, nil, uuidNamespace,
+					routeBaseName, components, tags)
+				if err != nil {
+					return nil, fmt.Errorf("failed to create plugins list for callback '%s %s': %w",
+						method, expression, err)
+				}
+				route := map[string]interface{}{
+					"id":             uuid.NewV5(uuidNamespace, routeBaseName+".route").String(),
+					"name":           routeBaseName,
+					"methods":        []string{method},
+					"paths":          []string{"~" + routeRegex + "$"},
+					"plugins":        plugins,
+					"regex_priority": regexPriority,
+					"strip_path":     false,
+					"tags":           tags,
+				}
+				service["routes"] = append(service["routes"].([]interface{}), route)
+				conversion.routeCount++
+			}
+			conversion.services = append(conversion.services, service)
+			conversion.locations.add("service", service, expressionPointer)
+			conversion.locations.addRoutes(service, expressionPointer)
+		}
+	}
+	return conversion, nil
+}
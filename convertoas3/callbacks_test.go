@@ -0,0 +1,109 @@
+package convertoas3
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_splitCallbackURL(t *testing.T) {
+	tests := []struct {
+		expression string
+		server     string
+		path       string
+		static     bool
+	}{
+		{"https://partner.example.com/hooks", "https://partner.example.com", "/hooks", true},
+		{"https://partner.example.com", "https://partner.example.com", "/", true},
+		{"http://partner.example.com:8080/hooks/{$request.query.id}?token={$request.header.token}",
+			"http://partner.example.com:8080", "/hooks/{$request.query.id}", true},
+		{"{$request.body#/callbackUrl}", "", "", false},
+		{"{$request.body#/scheme}://example.com/hooks", "", "", false},
+		{"https://{$request.query.host}/hooks", "", "", false},
+		{"/hooks", "", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			server, path, static := splitCallbackURL(tt.expression)
+			assert.Equal(t, tt.static, static)
+			assert.Equal(t, tt.server, server)
+			assert.Equal(t, tt.path, path)
+		})
+	}
+}
+
+func Test_ConvertCallbacks(t *testing.T) {
+	spec := []byte(`
+openapi: 3.0.3
+info:
+  title: callbacks
+  version: v1
+servers:
+  - url: https://api.example.com
+paths:
+  /subscriptions:
+    post:
+      operationId: subscribe
+      responses:
+        "201":
+          description: Created
+      callbacks:
+        event:
+          "https://partner.example.com/hooks/{$request.body#/id}":
+            post:
+              x-kong-plugin-key-auth:
+                config:
+                  key_names: [x-api-key]
+              responses:
+                "200":
+                  description: OK
+            delete:
+              responses:
+                "200":
+                  description: OK
+        dynamic:
+          "{$request.body#/callbackUrl}":
+            post:
+              responses:
+                "200":
+                  description: OK
+`)
+
+	result, err := Convert(context.Background(), &spec, O2kOptions{})
+	require.NoError(t, err)
+	assert.Len(t, result["services"], 1)
+
+	var buf bytes.Buffer
+	result, err = Convert(context.Background(), &spec, O2kOptions{
+		Callbacks: true,
+		Logger:    NewStdLogger(log.New(&buf, "", 0), LogLevelWarn),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "WARN callback URL is a runtime expression, skipped "+
+		"location=/paths/~1subscriptions/post/callbacks/dynamic/{$request.body#~1callbackUrl}\n", buf.String())
+
+	services := result["services"].([]interface{})
+	require.Len(t, services, 2)
+	service := services[1].(map[string]interface{})
+	assert.Equal(t, "callbacks_subscribe_event", service["name"])
+	assert.Equal(t, "partner.example.com", service["host"])
+	assert.Equal(t, "https", service["protocol"])
+
+	routes := service["routes"].([]interface{})
+	require.Len(t, routes, 2)
+	names := make([]string, 0)
+	for _, route := range routes {
+		r := route.(map[string]interface{})
+		names = append(names, r["name"].(string))
+		assert.Equal(t, []string{`~/hooks/(?<request_body_id>[^#?/]+)$`}, r["paths"])
+	}
+	assert.Equal(t, []string{"callbacks_subscribe_event_delete", "callbacks_subscribe_event_post"}, names)
+	post := routes[1].(map[string]interface{})
+	plugins := *post["plugins"].(*[]*map[string]interface{})
+	require.Len(t, plugins, 1)
+	assert.Equal(t, "key-auth", (*plugins[0])["name"])
+}
@@ -0,0 +1,69 @@
+package convertoas3
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"sigs.k8s.io/yaml"
+)
+
+// ParseComponents parses a components file (JSON or YAML), holding shared 'x-kong'
+// components, eg. plugin configs maintained by a platform team, to be referenced from
+// many specs as '#/components/x-kong/...'. The file is the components object itself, or
+// an OpenAPI spec, of which its '/components/x-kong' object is used.
+func ParseComponents(content []byte) (map[string]interface{}, error) {
+	jsonContent, err := yaml.YAMLToJSON(content)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing components file: [%w]", err)
+	}
+	var components interface{}
+	if err = json.Unmarshal(jsonContent, &components); err != nil {
+		return nil, fmt.Errorf("error parsing components file: [%w]", err)
+	}
+	object, ok := components.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("expected the components file to be a JSON object")
+	}
+
+	if docComponents, ok := object["components"].(map[string]interface{}); ok && object["openapi"] != nil {
+		xKong, ok := docComponents["x-kong"].(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("expected '/components/x-kong' in the components file to be a JSON object")
+		}
+		return xKong, nil
+	}
+	return object, nil
+}
+
+// mergeComponents returns the shared components, with the document components merged
+// in. Objects are merged by key, so the document can add entries to a shared object,
+// eg. 'plugins', other values of the document replace the shared ones. Returns the
+// document components if there are no shared ones.
+func mergeComponents(
+	shared map[string]interface{},
+	components *map[string]interface{},
+) *map[string]interface{} {
+	if len(shared) == 0 {
+		return components
+	}
+	merged := mergeObjects(shared, *components)
+	return &merged
+}
+
+// mergeObjects returns a new object with the entries of base and overrides, merging
+// the objects both have, and otherwise taking the value from overrides.
+func mergeObjects(base map[string]interface{}, overrides map[string]interface{}) map[string]interface{} {
+	merged := make(map[string]interface{}, len(base)+len(overrides))
+	for key, value := range base {
+		merged[key] = value
+	}
+	for key, value := range overrides {
+		baseObject, baseIsObject := merged[key].(map[string]interface{})
+		object, isObject := value.(map[string]interface{})
+		if baseIsObject && isObject {
+			value = mergeObjects(baseObject, object)
+		}
+		merged[key] = value
+	}
+	return merged
+}
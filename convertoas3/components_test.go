@@ -0,0 +1,110 @@
+package convertoas3
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ParseComponents(t *testing.T) {
+	expected := map[string]interface{}{
+		"plugins": map[string]interface{}{
+			"rate-limiting": map[string]interface{}{"config": map[string]interface{}{"minute": float64(10)}},
+		},
+	}
+
+	components, err := ParseComponents([]byte(`
+plugins:
+  rate-limiting:
+    config:
+      minute: 10
+`))
+	require.NoError(t, err)
+	assert.Equal(t, expected, components)
+
+	// from an OpenAPI spec
+	components, err = ParseComponents([]byte(`
+openapi: 3.0.3
+info:
+  title: shared
+  version: 1.0.0
+paths: {}
+components:
+  x-kong:
+    plugins:
+      rate-limiting:
+        config:
+          minute: 10
+`))
+	require.NoError(t, err)
+	assert.Equal(t, expected, components)
+
+	_, err = ParseComponents([]byte(`[]`))
+	assert.EqualError(t, err, "expected the components file to be a JSON object")
+	_, err = ParseComponents([]byte(`{"openapi": "3.0.3", "components": {"x-kong": []}}`))
+	assert.EqualError(t, err, "expected '/components/x-kong' in the components file to be a JSON object")
+	_, err = ParseComponents([]byte(`plugins: [`))
+	assert.Error(t, err)
+}
+
+func Test_ConvertComponents(t *testing.T) {
+	shared, err := ParseComponents([]byte(`
+tags: [platform]
+plugins:
+  rate-limiting:
+    config:
+      minute: 10
+  cors:
+    config:
+      origins: [https://example.com]
+`))
+	require.NoError(t, err)
+
+	spec := []byte(`
+openapi: 3.0.3
+info:
+  title: components
+  version: 1.0.0
+servers:
+  - url: https://api.example.com
+x-kong-tags:
+  $ref: '#/components/x-kong/tags'
+components:
+  x-kong:
+    plugins:
+      cors:
+        config:
+          origins: [https://api.example.com]
+paths:
+  /users:
+    get:
+      operationId: getUsers
+      x-kong-plugin-rate-limiting:
+        $ref: '#/components/x-kong/plugins/rate-limiting'
+      x-kong-plugin-cors:
+        $ref: '#/components/x-kong/plugins/cors'
+`)
+
+	_, err = Convert(context.Background(), &spec, O2kOptions{})
+	assert.EqualError(t, err, "/x-kong-tags/$ref: reference '#/components/x-kong/tags' not found")
+
+	result, err := Convert(context.Background(), &spec, NewO2kOptions(WithComponents(shared)))
+	require.NoError(t, err)
+	service := result["services"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, []string{"platform"}, service["tags"])
+	var plugins []map[string]interface{}
+	data, _ := json.Marshal(service["routes"].([]interface{})[0].(map[string]interface{})["plugins"])
+	require.NoError(t, json.Unmarshal(data, &plugins))
+	require.Len(t, plugins, 2)
+	// the spec's own components take precedence
+	assert.Equal(t, map[string]interface{}{"origins": []interface{}{"https://api.example.com"}}, plugins[0]["config"])
+	assert.Equal(t, map[string]interface{}{"minute": float64(10)}, plugins[1]["config"])
+
+	// the shared components are not changed
+	assert.Len(t, shared["plugins"], 2)
+	cors := shared["plugins"].(map[string]interface{})["cors"].(map[string]interface{})
+	assert.Equal(t, []interface{}{"https://example.com"}, cors["config"].(map[string]interface{})["origins"])
+}
@@ -0,0 +1,300 @@
+package convertoas3
+
+import (
+	"fmt"
+	"sort"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+// getConsumerGroups returns the consumer_groups entities, generated from the
+// '#/components/x-kong/consumer-groups' object. That object is keyed by the group
+// name, with each entry an object with an optional 'plugins' array of plugin configs
+// scoped to the group (eg. rate-limiting-advanced overrides). Returns an empty
+// slice if there are no consumer groups. The result is sorted by name.
+func getConsumerGroups(
+	components *map[string]interface{},
+	uuidNamespace uuid.UUID,
+	tags []string,
+) ([]interface{}, error) {
+	result := make([]interface{}, 0)
+
+	if (*components)["consumer-groups"] == nil {
+		return result, nil
+	}
+	groups, err := toJSONObject((*components)["consumer-groups"])
+	if err != nil {
+		return nil, fmt.Errorf("expected '#/components/x-kong/consumer-groups' to be a JSON object")
+	}
+
+	sortedNames := make([]string, 0, len(groups))
+	for name := range groups {
+		sortedNames = append(sortedNames, name)
+	}
+	sort.Strings(sortedNames)
+
+	for _, name := range sortedNames {
+		path := "#/components/x-kong/consumer-groups/" + name
+		group, err := toJSONObject(groups[name])
+		if err != nil {
+			return nil, fmt.Errorf("expected '%s' to be a JSON object", path)
+		}
+
+		baseName := name + ".consumer-group"
+		plugins, err := getConsumerGroupPlugins(group["plugins"], path, uuidNamespace, baseName, tags)
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, map[string]interface{}{
+			"id":      uuid.NewV5(uuidNamespace, baseName).String(),
+			"name":    name,
+			"tags":    tags,
+			"plugins": plugins,
+		})
+	}
+
+	return result, nil
+}
+
+// getConsumerGroupPlugins validates the plugins of a consumer group and sets their
+// id and tags. Returns the plugins sorted by name.
+func getConsumerGroupPlugins(
+	pluginList interface{},
+	path string,
+	uuidNamespace uuid.UUID,
+	baseName string,
+	tags []string,
+) ([]interface{}, error) {
+	result := make([]interface{}, 0)
+	if pluginList == nil {
+		return result, nil
+	}
+
+	plugins, ok := pluginList.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("expected '%s/plugins' to be an array", path)
+	}
+
+	seen := make(map[string]bool)
+	for i, p := range plugins {
+		plugin, err := toJSONObject(p)
+		if err != nil {
+			return nil, fmt.Errorf("expected '%s/plugins/%d' to be a JSON object", path, i)
+		}
+		name, ok := plugin["name"].(string)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("expected '%s/plugins/%d/name' to be a string", path, i)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("duplicate plugin '%s' in '%s/plugins'", name, path)
+		}
+		seen[name] = true
+		if err = validatePluginFields(plugin, fmt.Sprintf("%s/plugins/%d", path, i), "consumer_group"); err != nil {
+			return nil, err
+		}
+
+		plugin["id"] = createPluginID(uuidNamespace, baseName, plugin)
+		plugin["tags"] = tags
+		result = append(result, plugin)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].(map[string]interface{})["name"].(string) <
+			result[j].(map[string]interface{})["name"].(string)
+	})
+	return result, nil
+}
+
+// getConsumers returns the consumers entities, generated from the
+// '#/components/x-kong/consumers' object. That object is keyed by the consumer
+// username, with each entry an object with an optional 'custom_id', an optional
+// 'groups' array with consumer group names, an optional 'acls' array with ACL
+// group names, and an optional 'keys' array with key-auth credentials. The groups
+// must be declared in '#/components/x-kong/consumer-groups'. Returns an empty slice
+// if there are no consumers. The result is sorted by username.
+func getConsumers(
+	components *map[string]interface{},
+	consumerGroups []interface{},
+	uuidNamespace uuid.UUID,
+	tags []string,
+) ([]interface{}, error) {
+	result := make([]interface{}, 0)
+
+	if (*components)["consumers"] == nil {
+		return result, nil
+	}
+	consumers, err := toJSONObject((*components)["consumers"])
+	if err != nil {
+		return nil, fmt.Errorf("expected '#/components/x-kong/consumers' to be a JSON object")
+	}
+
+	groupNames := getEntityNames(consumerGroups, "name")
+
+	sortedNames := make([]string, 0, len(consumers))
+	for name := range consumers {
+		sortedNames = append(sortedNames, name)
+	}
+	sort.Strings(sortedNames)
+
+	for _, username := range sortedNames {
+		path := "#/components/x-kong/consumers/" + username
+		consumerDef, err := toJSONObject(consumers[username])
+		if err != nil {
+			return nil, fmt.Errorf("expected '%s' to be a JSON object", path)
+		}
+
+		consumer := map[string]interface{}{
+			"id":       uuid.NewV5(uuidNamespace, username+".consumer").String(),
+			"username": username,
+			"tags":     tags,
+		}
+
+		if consumerDef["custom_id"] != nil {
+			customID, ok := consumerDef["custom_id"].(string)
+			if !ok {
+				return nil, fmt.Errorf("expected '%s/custom_id' to be a string", path)
+			}
+			consumer["custom_id"] = customID
+		}
+
+		if consumerDef["groups"] != nil {
+			groupList, ok := consumerDef["groups"].([]interface{})
+			if !ok {
+				return nil, fmt.Errorf("expected '%s/groups' to be an array of strings", path)
+			}
+			groups := make([]interface{}, len(groupList))
+			for i, g := range groupList {
+				groupName, ok := g.(string)
+				if !ok {
+					return nil, fmt.Errorf("expected '%s/groups' to be an array of strings", path)
+				}
+				if !groupNames[groupName] {
+					return nil, fmt.Errorf("consumer group '%s' in '%s/groups' is not declared in "+
+						"'#/components/x-kong/consumer-groups'", groupName, path)
+				}
+				groups[i] = map[string]interface{}{"name": groupName}
+			}
+			consumer["groups"] = groups
+		}
+
+		if consumerDef["acls"] != nil {
+			aclList, ok := consumerDef["acls"].([]interface{})
+			if !ok {
+				return nil, fmt.Errorf("expected '%s/acls' to be an array of strings", path)
+			}
+			acls := make([]interface{}, len(aclList))
+			for i, a := range aclList {
+				aclGroup, ok := a.(string)
+				if !ok {
+					return nil, fmt.Errorf("expected '%s/acls' to be an array of strings", path)
+				}
+				acls[i] = map[string]interface{}{
+					"id":    uuid.NewV5(uuidNamespace, username+".consumer.acl."+aclGroup).String(),
+					"group": aclGroup,
+					"tags":  tags,
+				}
+			}
+			consumer["acls"] = acls
+		}
+
+		if consumerDef["keys"] != nil {
+			credentials, err := getKeyAuthCredentials(consumerDef["keys"], path, username, uuidNamespace, tags)
+			if err != nil {
+				return nil, err
+			}
+			consumer["keyauth_credentials"] = credentials
+		}
+
+		result = append(result, consumer)
+	}
+
+	return result, nil
+}
+
+// getKeyAuthCredentials returns the key-auth credentials of a consumer, from its 'keys'
+// array. Each entry is either the key, or an object with the 'key' and an optional
+// 'id'. The IDs are derived from the key, not the position, so adding and removing
+// keys (key rotation) leaves the other credentials untouched.
+func getKeyAuthCredentials(
+	keyList interface{},
+	path string,
+	username string,
+	uuidNamespace uuid.UUID,
+	tags []string,
+) ([]interface{}, error) {
+	keys, ok := keyList.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("expected '%s/keys' to be an array", path)
+	}
+
+	result := make([]interface{}, len(keys))
+	seen := make(map[string]bool)
+	for i, k := range keys {
+		var key, id string
+		switch value := k.(type) {
+		case string:
+			key = value
+		case map[string]interface{}:
+			key, _ = value["key"].(string)
+			if value["id"] != nil {
+				if id, ok = value["id"].(string); !ok || id == "" {
+					return nil, fmt.Errorf("expected '%s/keys/%d/id' to be a string", path, i)
+				}
+			}
+		}
+		if key == "" {
+			return nil, fmt.Errorf("expected '%s/keys/%d' to be a key, or an object with a 'key'", path, i)
+		}
+		if seen[key] {
+			return nil, fmt.Errorf("duplicate key in '%s/keys', at index %d", path, i)
+		}
+		seen[key] = true
+
+		if id == "" {
+			id = uuid.NewV5(uuidNamespace, username+".consumer.key-auth."+key).String()
+		}
+		result[i] = map[string]interface{}{
+			"id":   id,
+			"key":  key,
+			"tags": tags,
+		}
+	}
+	return result, nil
+}
+
+// getEntityNames returns a set of the names (from field 'key') of the entities.
+func getEntityNames(entities []interface{}, key string) map[string]bool {
+	names := make(map[string]bool)
+	for _, entity := range entities {
+		if name, ok := entity.(map[string]interface{})[key].(string); ok {
+			names[name] = true
+		}
+	}
+	return names
+}
+
+// checkConsumerReferences validates the 'consumer' and 'consumer_group' references of
+// plugins against the consumers and consumer groups declared in the spec. References
+// are only validated if the spec declares consumers or consumer groups respectively,
+// otherwise they are assumed to refer to entities managed elsewhere.
+func checkConsumerReferences(
+	result map[string]interface{},
+	consumers []interface{},
+	consumerGroups []interface{},
+) error {
+	usernames := getEntityNames(consumers, "username")
+	groupNames := getEntityNames(consumerGroups, "name")
+
+	for _, ref := range collectPlugins(result) {
+		if name, ok := ref.plugin["consumer"].(string); ok && len(consumers) > 0 && !usernames[name] {
+			return fmt.Errorf("plugin '%s' (on %s) references consumer '%s', which is not declared in "+
+				"'#/components/x-kong/consumers'", ref.plugin["name"], ref.owner, name)
+		}
+		if name, ok := ref.plugin["consumer_group"].(string); ok && len(consumerGroups) > 0 && !groupNames[name] {
+			return fmt.Errorf("plugin '%s' (on %s) references consumer group '%s', which is not declared in "+
+				"'#/components/x-kong/consumer-groups'", ref.plugin["name"], ref.owner, name)
+		}
+	}
+	return nil
+}
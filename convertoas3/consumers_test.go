@@ -0,0 +1,105 @@
+package convertoas3
+
+import (
+	"context"
+	"testing"
+
+	uuid "github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ConvertConsumerReferences(t *testing.T) {
+	tests := []struct {
+		name        string
+		spec        string
+		expectError string
+	}{
+		{
+			"undeclared consumer",
+			`
+x-kong-plugin-rate-limiting:
+  consumer: unknown
+components:
+  x-kong:
+    consumers:
+      known: {}`,
+			"plugin 'rate-limiting' (on document) references consumer 'unknown', which is not declared " +
+				"in '#/components/x-kong/consumers'",
+		},
+		{
+			"undeclared consumer group",
+			`
+x-kong-plugin-rate-limiting:
+  consumer_group: unknown
+components:
+  x-kong:
+    consumer-groups:
+      known: {}`,
+			"plugin 'rate-limiting' (on document) references consumer group 'unknown', which is not " +
+				"declared in '#/components/x-kong/consumer-groups'",
+		},
+		{
+			"undeclared group on consumer",
+			`
+components:
+  x-kong:
+    consumers:
+      known:
+        groups: [unknown]`,
+			"/components/x-kong/consumers: consumer group 'unknown' in " +
+				"'#/components/x-kong/consumers/known/groups' is not declared " +
+				"in '#/components/x-kong/consumer-groups'",
+		},
+		{
+			"external consumer",
+			`
+x-kong-plugin-rate-limiting:
+  consumer: external`,
+			"",
+		},
+	}
+
+	for _, tst := range tests {
+		spec := []byte(`
+openapi: 3.0.3
+info:
+  title: consumers
+  version: v1
+paths: {}` + tst.spec)
+
+		_, err := Convert(context.Background(), &spec, O2kOptions{})
+		if tst.expectError == "" {
+			assert.NoError(t, err, tst.name)
+		} else {
+			assert.EqualError(t, err, tst.expectError, tst.name)
+		}
+	}
+}
+
+func Test_getKeyAuthCredentials(t *testing.T) {
+	namespace := uuid.NamespaceDNS
+	before, err := getKeyAuthCredentials([]interface{}{"old-key", "new-key"}, "#/p", "alice", namespace, nil)
+	assert.NoError(t, err)
+	after, err := getKeyAuthCredentials([]interface{}{"new-key"}, "#/p", "alice", namespace, nil)
+	assert.NoError(t, err)
+	// rotating out the old key keeps the credential of the new key
+	assert.Equal(t, before[1], after[0])
+
+	tests := []struct {
+		name        string
+		keys        interface{}
+		expectError string
+	}{
+		{"not an array", "key", "expected '#/p/keys' to be an array"},
+		{"empty key", []interface{}{""}, "expected '#/p/keys/0' to be a key, or an object with a 'key'"},
+		{"object without key", []interface{}{map[string]interface{}{"id": "x"}},
+			"expected '#/p/keys/0' to be a key, or an object with a 'key'"},
+		{"invalid id", []interface{}{map[string]interface{}{"key": "k", "id": 1.0}},
+			"expected '#/p/keys/0/id' to be a string"},
+		{"duplicate", []interface{}{"k", map[string]interface{}{"key": "k"}}, "duplicate key in '#/p/keys', at index 1"},
+	}
+	for _, tst := range tests {
+		_, err := getKeyAuthCredentials(tst.keys, "#/p", "alice", namespace, nil)
+		assert.EqualError(t, err, tst.expectError, tst.name)
+	}
+}
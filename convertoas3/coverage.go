@@ -0,0 +1,295 @@
+package convertoas3
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// The spec features reported by Coverage, when present but not enforced by the gateway.
+const (
+	// CoverageCallbacks are callbacks not converted; all of them unless the Callbacks
+	// option is set, otherwise those with a runtime expression as server.
+	CoverageCallbacks = "callbacks"
+	// CoverageLinks are response links, they have no gateway equivalent.
+	CoverageLinks = "links"
+	// CoverageResponseSchemas are response body schemas, responses are not validated.
+	CoverageResponseSchemas = "response-schemas"
+	// CoverageSecurity are security requirements without an authentication plugin; all of
+	// them unless the SecurityPlugins option is set, otherwise those with a security
+	// scheme type that has no plugin mapping.
+	CoverageSecurity = "security"
+	// CoverageWebhooks are the OpenAPI 3.1 webhooks, not converted without a WebhookServer.
+	CoverageWebhooks = "webhooks"
+)
+
+// IgnoredFeature is a feature in the spec that the generated file does not enforce.
+type IgnoredFeature struct {
+	Feature string `json:"feature"` // eg. CoverageCallbacks
+	Pointer string `json:"pointer"` // JSON pointer to the feature in the spec
+	Reason  string `json:"reason"`
+}
+
+// OperationCoverage lists the ignored features of an operation.
+type OperationCoverage struct {
+	Path        string           `json:"path"`
+	Method      string           `json:"method"`
+	OperationID string           `json:"operation_id,omitempty"`
+	Ignored     []IgnoredFeature `json:"ignored"`
+}
+
+// CoverageReport lists the features of a spec that are present, but ignored by the
+// conversion, so the gateway does not enforce them.
+type CoverageReport struct {
+	Title   string `json:"title,omitempty"`
+	Version string `json:"version,omitempty"`
+	// Document are the ignored features not specific to an operation, eg. webhooks, or
+	// security schemes without a plugin mapping.
+	Document []IgnoredFeature `json:"document"`
+	// Operations are all operations of the spec, sorted by path, with their ignored
+	// features.
+	Operations []OperationCoverage `json:"operations"`
+}
+
+// Ignored returns the number of ignored features in the report.
+func (r *CoverageReport) Ignored() int {
+	count := len(r.Document)
+	for _, operation := range r.Operations {
+		count += len(operation.Ignored)
+	}
+	return count
+}
+
+// Marshal returns the report as indented JSON.
+func (r *CoverageReport) Marshal() ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(r); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// securityPluginDeclared returns true if an authentication plugin is given in an
+// 'x-kong-plugin-...' extension of any of the objects, see securityPluginNames.
+func securityPluginDeclared(objects ...openapi3.ExtensionProps) bool {
+	for _, object := range objects {
+		for _, name := range securityPluginNames {
+			if _, found := object.Extensions[pluginPrefix+name]; found {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// hasSecurityPluginMapping returns true if Convert generates an authentication plugin
+// for the security scheme, see createSecurityPlugin. Schemes with invalid hints, eg. a
+// literal client secret, are mapped, they fail the conversion instead.
+func hasSecurityPluginMapping(schemeName string, scheme *openapi3.SecurityScheme) bool {
+	plugin, err := createSecurityPlugin(schemeName, scheme, nil)
+	return err != nil || plugin != nil
+}
+
+// schemeDescription returns the type of the security scheme, for the reasons.
+func schemeDescription(scheme *openapi3.SecurityScheme) string {
+	if scheme.Type == "http" {
+		return fmt.Sprintf("'http' (%s)", strings.ToLower(scheme.Scheme))
+	}
+	return fmt.Sprintf("'%s'", scheme.Type)
+}
+
+// getCallbacksCoverage returns the ignored callbacks of the operation.
+func getCallbacksCoverage(operation *openapi3.Operation, pointer string, opts O2kOptions) []IgnoredFeature {
+	ignored := make([]IgnoredFeature, 0)
+	names := make([]string, 0, len(operation.Callbacks))
+	for name := range operation.Callbacks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		callback := operation.Callbacks[name]
+		if callback == nil || callback.Value == nil {
+			continue
+		}
+		expressions := make([]string, 0, len(*callback.Value))
+		for expression := range *callback.Value {
+			expressions = append(expressions, expression)
+		}
+		sort.Strings(expressions)
+		for _, expression := range expressions {
+			callbackPointer := pointer + jsonPointer("callbacks", name, expression)
+			if !opts.Callbacks {
+				ignored = append(ignored, IgnoredFeature{CoverageCallbacks, callbackPointer,
+					"callbacks are not converted, unless enabled"})
+			} else if _, _, static := splitCallbackURL(expression); !static {
+				ignored = append(ignored, IgnoredFeature{CoverageCallbacks, callbackPointer,
+					"callback URL is a runtime expression, it cannot be routed"})
+			}
+		}
+	}
+	return ignored
+}
+
+// getResponsesCoverage returns the ignored links and response schemas of the operation.
+func getResponsesCoverage(operation *openapi3.Operation, pointer string) []IgnoredFeature {
+	ignored := make([]IgnoredFeature, 0)
+	codes := make([]string, 0, len(operation.Responses))
+	for code := range operation.Responses {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	for _, code := range codes {
+		response := operation.Responses[code]
+		if response == nil || response.Value == nil {
+			continue
+		}
+		responsePointer := pointer + jsonPointer("responses", code)
+
+		mediaTypes := make([]string, 0, len(response.Value.Content))
+		for mediaType := range response.Value.Content {
+			mediaTypes = append(mediaTypes, mediaType)
+		}
+		sort.Strings(mediaTypes)
+		for _, mediaType := range mediaTypes {
+			if content := response.Value.Content[mediaType]; content != nil && content.Schema != nil {
+				ignored = append(ignored, IgnoredFeature{CoverageResponseSchemas,
+					responsePointer + jsonPointer("content", mediaType, "schema"),
+					"responses are not validated by the gateway"})
+			}
+		}
+
+		links := make([]string, 0, len(response.Value.Links))
+		for name := range response.Value.Links {
+			links = append(links, name)
+		}
+		sort.Strings(links)
+		for _, name := range links {
+			ignored = append(ignored, IgnoredFeature{CoverageLinks, responsePointer + jsonPointer("links", name),
+				"links have no gateway equivalent"})
+		}
+	}
+	return ignored
+}
+
+// getSecurityCoverage returns the security requirements of the operation that have no
+// authentication plugin. Security plugins given in 'x-kong-plugin-...' extensions
+// enforce all requirements.
+func getSecurityCoverage(
+	doc *openapi3.T, pathItem *openapi3.PathItem, operation *openapi3.Operation, pointer string, opts O2kOptions,
+) []IgnoredFeature {
+	ignored := make([]IgnoredFeature, 0)
+	if securityPluginDeclared(doc.ExtensionProps, pathItem.ExtensionProps, operation.ExtensionProps) {
+		return ignored
+	}
+	securityPointer := "/security"
+	if operation.Security != nil {
+		securityPointer = pointer + "/security"
+	}
+
+	reported := make(map[string]bool)
+	for _, requirement := range getSecurityRequirements(doc, operation) {
+		schemeNames := make([]string, 0, len(requirement))
+		for schemeName := range requirement {
+			schemeNames = append(schemeNames, schemeName)
+		}
+		sort.Strings(schemeNames)
+		for _, schemeName := range schemeNames {
+			if reported[schemeName] {
+				continue
+			}
+			reported[schemeName] = true
+			scheme, err := getSecurityScheme(doc, schemeName)
+			switch {
+			case err != nil:
+				ignored = append(ignored, IgnoredFeature{CoverageSecurity, securityPointer, err.Error()})
+			case !opts.SecurityPlugins:
+				ignored = append(ignored, IgnoredFeature{CoverageSecurity, securityPointer,
+					fmt.Sprintf("security scheme '%s' is not enforced, unless security plugins are enabled",
+						schemeName)})
+			case !hasSecurityPluginMapping(schemeName, scheme):
+				ignored = append(ignored, IgnoredFeature{CoverageSecurity, securityPointer,
+					fmt.Sprintf("security scheme '%s' of type %s has no plugin mapping",
+						schemeName, schemeDescription(scheme))})
+			}
+		}
+	}
+	return ignored
+}
+
+// Coverage returns the report of the features in an OpenAPI spec that the conversion,
+// with the given options, ignores; eg. callbacks, links, response schemas, and security
+// schemes without a plugin mapping. So users know what the gateway does not enforce.
+// The context is checked between paths.
+func Coverage(ctx context.Context, content *[]byte, opts O2kOptions) (*CoverageReport, error) {
+	loader := openapi3.NewLoader()
+	doc, err := loader.LoadFromData(*content)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing OAS3 file: [%w]", err)
+	}
+
+	report := &CoverageReport{
+		Document:   make([]IgnoredFeature, 0),
+		Operations: make([]OperationCoverage, 0),
+	}
+	if doc.Info != nil {
+		report.Title = doc.Info.Title
+		report.Version = doc.Info.Version
+	}
+
+	if doc.Extensions[webhooksKey] != nil && opts.WebhookServer == "" {
+		report.Document = append(report.Document, IgnoredFeature{CoverageWebhooks, "/" + webhooksKey,
+			"webhooks are not converted without a webhook server"})
+	}
+	if doc.Components.SecuritySchemes != nil {
+		schemeNames := make([]string, 0, len(doc.Components.SecuritySchemes))
+		for schemeName := range doc.Components.SecuritySchemes {
+			schemeNames = append(schemeNames, schemeName)
+		}
+		sort.Strings(schemeNames)
+		for _, schemeName := range schemeNames {
+			scheme := doc.Components.SecuritySchemes[schemeName]
+			if scheme != nil && scheme.Value != nil && !hasSecurityPluginMapping(schemeName, scheme.Value) {
+				report.Document = append(report.Document, IgnoredFeature{CoverageSecurity,
+					jsonPointer("components", "securitySchemes", schemeName),
+					fmt.Sprintf("security scheme type %s has no plugin mapping", schemeDescription(scheme.Value))})
+			}
+		}
+	}
+
+	paths := make([]string, 0, len(doc.Paths))
+	for path := range doc.Paths {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		if err := ctx.Err(); err != nil {
+			return nil, fmt.Errorf("coverage aborted: %w", err)
+		}
+		pathItem := doc.Paths[path]
+		for _, method := range operationKeys {
+			operation := pathItem.GetOperation(strings.ToUpper(method))
+			if operation == nil {
+				continue
+			}
+			pointer := jsonPointer("paths", path, method)
+			ignored := getCallbacksCoverage(operation, pointer, opts)
+			ignored = append(ignored, getResponsesCoverage(operation, pointer)...)
+			ignored = append(ignored, getSecurityCoverage(doc, pathItem, operation, pointer, opts)...)
+			report.Operations = append(report.Operations, OperationCoverage{
+				Path:        path,
+				Method:      strings.ToUpper(method),
+				OperationID: operation.OperationID,
+				Ignored:     ignored,
+			})
+		}
+	}
+	return report, nil
+}
@@ -0,0 +1,113 @@
+package convertoas3
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Coverage(t *testing.T) {
+	spec := []byte(`
+openapi: 3.0.3
+info:
+  title: coverage
+  version: v1
+security:
+  - apiKey: []
+components:
+  securitySchemes:
+    apiKey:
+      type: apiKey
+      in: header
+      name: X-Api-Key
+    oauth:
+      type: oauth2
+      flows:
+        clientCredentials:
+          tokenUrl: https://idp.example.com/token
+          scopes: {}
+paths:
+  /users:
+    get:
+      operationId: getUsers
+      responses:
+        "200":
+          description: OK
+          content:
+            application/json:
+              schema:
+                type: array
+          links:
+            next:
+              operationId: getUsers
+    post:
+      security:
+        - oauth: []
+      callbacks:
+        created:
+          "{$request.body#/callbackUrl}":
+            post:
+              responses:
+                "200":
+                  description: OK
+          "https://partner.example.com/hooks":
+            post:
+              responses:
+                "200":
+                  description: OK
+      responses:
+        "201":
+          description: Created
+  /health:
+    x-kong-plugin-key-auth: {}
+    get:
+      responses:
+        "204":
+          description: No Content
+`)
+	unmappedScheme := IgnoredFeature{CoverageSecurity, "/components/securitySchemes/oauth",
+		"security scheme type 'oauth2' has no plugin mapping"}
+	responseSchema := IgnoredFeature{CoverageResponseSchemas,
+		"/paths/~1users/get/responses/200/content/application~1json/schema", "responses are not validated by the gateway"}
+	link := IgnoredFeature{CoverageLinks, "/paths/~1users/get/responses/200/links/next",
+		"links have no gateway equivalent"}
+	dynamicCallback := "/paths/~1users/post/callbacks/created/{$request.body#~1callbackUrl}"
+
+	report, err := Coverage(context.Background(), &spec, O2kOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, &CoverageReport{
+		Title:    "coverage",
+		Version:  "v1",
+		Document: []IgnoredFeature{unmappedScheme},
+		Operations: []OperationCoverage{
+			{Path: "/health", Method: "GET", Ignored: []IgnoredFeature{}},
+			{Path: "/users", Method: "GET", OperationID: "getUsers", Ignored: []IgnoredFeature{
+				responseSchema,
+				link,
+				{CoverageSecurity, "/security",
+					"security scheme 'apiKey' is not enforced, unless security plugins are enabled"},
+			}},
+			{Path: "/users", Method: "POST", Ignored: []IgnoredFeature{
+				{CoverageCallbacks, "/paths/~1users/post/callbacks/created/https:~1~1partner.example.com~1hooks",
+					"callbacks are not converted, unless enabled"},
+				{CoverageCallbacks, dynamicCallback, "callbacks are not converted, unless enabled"},
+				{CoverageSecurity, "/paths/~1users/post/security",
+					"security scheme 'oauth' is not enforced, unless security plugins are enabled"},
+			}},
+		},
+	}, report)
+	assert.Equal(t, 7, report.Ignored())
+
+	report, err = Coverage(context.Background(), &spec, NewO2kOptions(WithCallbacks(), WithSecurityPlugins()))
+	require.NoError(t, err)
+	assert.Equal(t, []IgnoredFeature{responseSchema, link}, report.Operations[1].Ignored)
+	assert.Equal(t, []IgnoredFeature{
+		{CoverageCallbacks, dynamicCallback, "callback URL is a runtime expression, it cannot be routed"},
+		{CoverageSecurity, "/paths/~1users/post/security", "security scheme 'oauth' of type 'oauth2' has no plugin mapping"},
+	}, report.Operations[2].Ignored)
+
+	_, err = Coverage(context.Background(), &[]byte{'['}, O2kOptions{})
+	assert.Error(t, err)
+}
@@ -0,0 +1,47 @@
+package convertoas3
+
+// deepCopy returns a deep copy of a value as produced by decoding JSON; objects,
+// arrays, and scalars. String slices are copied as well. Any other type is returned
+// as is, without copying.
+func deepCopy(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return deepCopyObject(v)
+	case *map[string]interface{}:
+		if v == nil {
+			return v
+		}
+		object := deepCopyObject(*v)
+		return &object
+	case []interface{}:
+		if v == nil {
+			return v
+		}
+		list := make([]interface{}, len(v))
+		for i, entry := range v {
+			list[i] = deepCopy(entry)
+		}
+		return list
+	case []string:
+		if v == nil {
+			return v
+		}
+		list := make([]string, len(v))
+		copy(list, v)
+		return list
+	default:
+		return value
+	}
+}
+
+// deepCopyObject returns a deep copy of a JSON object, see deepCopy.
+func deepCopyObject(object map[string]interface{}) map[string]interface{} {
+	if object == nil {
+		return nil
+	}
+	result := make(map[string]interface{}, len(object))
+	for key, value := range object {
+		result[key] = deepCopy(value)
+	}
+	return result
+}
@@ -0,0 +1,42 @@
+package convertoas3
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_deepCopy(t *testing.T) {
+	nested := map[string]interface{}{"key": "value"}
+	original := map[string]interface{}{
+		"string":  "hello",
+		"number":  float64(1),
+		"bool":    true,
+		"null":    nil,
+		"object":  map[string]interface{}{"nested": nested},
+		"array":   []interface{}{"a", nested, []interface{}{float64(2)}},
+		"strings": []string{"x", "y"},
+		"pointer": &nested,
+	}
+
+	copied := deepCopyObject(original)
+	assert.Equal(t, original, copied)
+
+	// changing the copy must not change the original
+	copied["string"] = "changed"
+	copied["object"].(map[string]interface{})["nested"].(map[string]interface{})["key"] = "changed"
+	copied["array"].([]interface{})[1].(map[string]interface{})["key"] = "changed"
+	copied["array"].([]interface{})[2].([]interface{})[0] = "changed"
+	copied["strings"].([]string)[0] = "changed"
+	(*copied["pointer"].(*map[string]interface{}))["key"] = "changed"
+
+	assert.Equal(t, "hello", original["string"])
+	assert.Equal(t, map[string]interface{}{"key": "value"}, nested)
+	assert.Equal(t, []interface{}{float64(2)}, original["array"].([]interface{})[2])
+	assert.Equal(t, []string{"x", "y"}, original["strings"])
+
+	// nil values are retained
+	assert.Nil(t, deepCopyObject(nil))
+	assert.Equal(t, []interface{}(nil), deepCopy([]interface{}(nil)))
+	assert.Equal(t, []string(nil), deepCopy([]string(nil)))
+}
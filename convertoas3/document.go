@@ -0,0 +1,21 @@
+package convertoas3
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// ConvertDocument converts an already parsed OpenAPI document to a Kong declarative
+// file, see Convert. This is used by the converters for other input formats, which
+// generate an OpenAPI document (including 'x-kong-...' extensions) as the intermediate
+// model, and then convert that.
+func ConvertDocument(ctx context.Context, doc *openapi3.T, opts O2kOptions) (map[string]interface{}, error) {
+	content, err := json.Marshal(doc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize the OpenAPI document: %w", err)
+	}
+	return Convert(ctx, &content, opts)
+}
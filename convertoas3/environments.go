@@ -0,0 +1,204 @@
+package convertoas3
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"sigs.k8s.io/yaml"
+)
+
+// serverLists returns the 'servers' lists in the document; on the document level, and
+// on the path items and operations.
+func serverLists(doc map[string]interface{}) [][]interface{} {
+	var lists [][]interface{}
+	if servers, ok := doc["servers"].([]interface{}); ok {
+		lists = append(lists, servers)
+	}
+	paths, _ := doc["paths"].(map[string]interface{})
+	for _, p := range paths {
+		pathItem, ok := p.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if servers, ok := pathItem["servers"].([]interface{}); ok {
+			lists = append(lists, servers)
+		}
+		for _, method := range operationKeys {
+			if operation, ok := pathItem[method].(map[string]interface{}); ok {
+				if servers, ok := operation["servers"].([]interface{}); ok {
+					lists = append(lists, servers)
+				}
+			}
+		}
+	}
+	return lists
+}
+
+// serverVariables returns the server variable objects with the name, in all servers in
+// the document.
+func serverVariables(doc map[string]interface{}, name string) []map[string]interface{} {
+	var result []map[string]interface{}
+	for _, servers := range serverLists(doc) {
+		for _, s := range servers {
+			server, _ := s.(map[string]interface{})
+			variables, _ := server["variables"].(map[string]interface{})
+			if variable, ok := variables[name].(map[string]interface{}); ok {
+				result = append(result, variable)
+			}
+		}
+	}
+	return result
+}
+
+// environmentValues returns the enum values of the server variable, in order of first
+// appearance over all servers.
+func environmentValues(doc map[string]interface{}, name string) ([]string, error) {
+	var values []string
+	seen := make(map[string]bool)
+	for _, variable := range serverVariables(doc, name) {
+		enum, _ := variable["enum"].([]interface{})
+		for _, v := range enum {
+			value, ok := v.(string)
+			if !ok {
+				return nil, fmt.Errorf("expected the enum values of server variable '%s' to be strings", name)
+			}
+			if !seen[value] {
+				seen[value] = true
+				values = append(values, value)
+			}
+		}
+	}
+	if len(values) == 0 {
+		return nil, fmt.Errorf("expected a server variable '%s' with an enum of environments, found none", name)
+	}
+	return values, nil
+}
+
+// environmentBaseName returns the document name, as Convert determines it; the
+// DocName option, 'x-kong-name', or 'info.title'. Falls back to a hash of the content.
+func environmentBaseName(doc map[string]interface{}, content []byte, opts O2kOptions) string {
+	if opts.DocName != "" {
+		return opts.DocName
+	}
+	if name, ok := doc["x-kong-name"].(string); ok && name != "" {
+		return name
+	}
+	if info, ok := doc["info"].(map[string]interface{}); ok {
+		if title, ok := info["title"].(string); ok && Slugify(title) != "" {
+			return title
+		}
+	}
+	return hashName(content)
+}
+
+// environmentTags returns the tags for the entities of all environments; the Tags option,
+// or 'x-kong-tags'.
+func environmentTags(doc map[string]interface{}, opts O2kOptions) []string {
+	if opts.Tags != nil {
+		return append([]string{}, *opts.Tags...)
+	}
+	var tags []string
+	docTags, _ := doc["x-kong-tags"].([]interface{})
+	for _, tag := range docTags {
+		if s, ok := tag.(string); ok {
+			tags = append(tags, s)
+		}
+	}
+	return tags
+}
+
+// mergeEnvironment adds the entities of an environment to the result. Services,
+// upstreams and plugins are added, consumers and consumer groups only once, since they
+// are shared by all environments (they keep the tags of the first environment).
+func mergeEnvironment(result map[string]interface{}, environment map[string]interface{}) {
+	for key, value := range environment {
+		existing, found := result[key]
+		if !found {
+			result[key] = value
+			continue
+		}
+		switch key {
+		case "services", "upstreams":
+			result[key] = append(existing.([]interface{}), value.([]interface{})...)
+		case "plugins":
+			plugins := existing.(*[]*map[string]interface{})
+			*plugins = append(*plugins, *value.(*[]*map[string]interface{})...)
+		case "consumers", "consumer_groups":
+			ids := make(map[interface{}]bool)
+			list := existing.([]interface{})
+			for _, entity := range list {
+				ids[entity.(map[string]interface{})["id"]] = true
+			}
+			for _, entity := range value.([]interface{}) {
+				if !ids[entity.(map[string]interface{})["id"]] {
+					list = append(list, entity)
+				}
+			}
+			result[key] = list
+		default:
+			if !reflect.DeepEqual(existing, value) {
+				result[key] = value
+			}
+		}
+	}
+}
+
+// convertEnvironments converts the spec once for every enum value of the server
+// variable EnvironmentVariable, and merges the results. In each conversion the variable
+// defaults to the environment, the entity names get the environment as a suffix, and
+// the entities are tagged '<variable>:<environment>'.
+func convertEnvironments(ctx context.Context, content *[]byte, opts O2kOptions) (map[string]interface{}, error) {
+	jsonContent, err := yaml.YAMLToJSON(*content)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing OAS3 file: [%w]", err)
+	}
+	var doc map[string]interface{}
+	if err = json.Unmarshal(jsonContent, &doc); err != nil {
+		return nil, fmt.Errorf("error parsing OAS3 file: [%w]", err)
+	}
+
+	variable := opts.EnvironmentVariable
+	environments, err := environmentValues(doc, variable)
+	if err != nil {
+		return nil, &ConversionError{Pointer: "/servers", Err: err}
+	}
+	baseName := environmentBaseName(doc, *content, opts)
+	idSeed := opts.IDSeed
+	if idSeed == "" {
+		idSeed, _ = doc["x-kong-id-seed"].(string)
+	}
+	tags := environmentTags(doc, opts)
+
+	var result map[string]interface{}
+	for _, environment := range environments {
+		for _, v := range serverVariables(doc, variable) {
+			v["default"] = environment
+		}
+		envContent, err := json.Marshal(doc)
+		if err != nil {
+			return nil, fmt.Errorf("failed to serialize the OpenAPI document: %w", err)
+		}
+
+		envOpts := opts
+		envOpts.EnvironmentVariable = ""
+		envOpts.DocName = baseName + " " + environment
+		if idSeed != "" {
+			envOpts.IDSeed = idSeed + " " + environment
+		}
+		envTags := append(append([]string{}, tags...), variable+":"+environment)
+		envOpts.Tags = &envTags
+		opts.Logger.Info("converting environment", variable, environment)
+		envResult, err := Convert(ctx, &envContent, envOpts)
+		if err != nil {
+			return nil, fmt.Errorf("environment '%s': %w", environment, err)
+		}
+		if result == nil {
+			result = envResult
+		} else {
+			mergeEnvironment(result, envResult)
+		}
+	}
+	return result, nil
+}
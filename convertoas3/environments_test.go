@@ -0,0 +1,68 @@
+package convertoas3
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Convert_Environments(t *testing.T) {
+	content := []byte(`
+openapi: 3.0.3
+info:
+  title: Learn Service
+  version: 1.0.0
+x-kong-tags: [team-a]
+servers:
+  - url: https://{env}.api.example.com
+    variables:
+      env:
+        default: dev
+        enum: [dev, prod]
+paths:
+  /tracks:
+    get:
+      operationId: listTracks
+      responses:
+        "200":
+          description: OK
+components:
+  x-kong:
+    consumers:
+      alice:
+        username: alice
+`)
+	result, err := Convert(context.Background(), &content, NewO2kOptions(WithEnvironments("env")))
+	require.NoError(t, err)
+
+	services := result["services"].([]interface{})
+	require.Len(t, services, 2)
+	for i, env := range []string{"dev", "prod"} {
+		service := services[i].(map[string]interface{})
+		assert.Equal(t, "learn-service-"+env, service["name"])
+		assert.Equal(t, env+".api.example.com", service["host"])
+		assert.Equal(t, []string{"team-a", "env:" + env}, service["tags"])
+	}
+	assert.NotEqual(t, services[0].(map[string]interface{})["id"], services[1].(map[string]interface{})["id"])
+	assert.Len(t, result["consumers"], 1)
+}
+
+func Test_Convert_EnvironmentsWithoutEnum(t *testing.T) {
+	content := []byte(`
+openapi: 3.0.3
+info:
+  title: Learn Service
+  version: 1.0.0
+servers:
+  - url: https://{env}.api.example.com
+    variables:
+      env:
+        default: dev
+paths: {}
+`)
+	_, err := Convert(context.Background(), &content, NewO2kOptions(WithEnvironments("env")))
+	assert.EqualError(t, err,
+		"/servers: expected a server variable 'env' with an enum of environments, found none")
+}
@@ -0,0 +1,127 @@
+package convertoas3
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ConversionError is an error in the OpenAPI spec, with the location where it was found.
+type ConversionError struct {
+	// Pointer is the JSON pointer (RFC 6901) to the offending element, eg.
+	// "/paths/~1users/get/x-kong-plugin-foo". An empty pointer refers to the whole document.
+	Pointer string
+	Err     error
+	// Line and Column (1-based) are the position of the offending element in the spec, see
+	// Locate. Zero if unknown. File is the name of the spec file; if set, the position is
+	// included in the message, eg. "api.yaml:12:7: /paths/~1users/get/x-kong-plugin-foo: ...".
+	File   string
+	Line   int
+	Column int
+}
+
+func (e *ConversionError) Error() string {
+	var position string
+	if e.File != "" && e.Line > 0 {
+		position = fmt.Sprintf("%s:%d:%d: ", e.File, e.Line, e.Column)
+	}
+	if e.Pointer == "" {
+		return position + e.Err.Error()
+	}
+	return position + e.Pointer + ": " + e.Err.Error()
+}
+
+func (e *ConversionError) Unwrap() error {
+	return e.Err
+}
+
+// ConversionErrors is a list of errors found in an OpenAPI spec. Convert collects all
+// errors it can find, instead of stopping at the first one, and returns them as a
+// ConversionErrors. Use errors.As to get them, and range over them for the individual errors.
+type ConversionErrors []*ConversionError
+
+func (errs ConversionErrors) Error() string {
+	if len(errs) == 1 {
+		return errs[0].Error()
+	}
+	messages := make([]string, len(errs))
+	for i, err := range errs {
+		messages[i] = "  - " + err.Error()
+	}
+	return fmt.Sprintf("found %d errors:\n%s", len(errs), strings.Join(messages, "\n"))
+}
+
+// Unwrap returns the individual errors. Only Go 1.20 and later use it, to have errors.Is
+// and errors.As check all of them. This module targets Go 1.19, so range over the
+// ConversionErrors to check the individual errors.
+func (errs ConversionErrors) Unwrap() []error {
+	result := make([]error, len(errs))
+	for i, err := range errs {
+		result[i] = err
+	}
+	return result
+}
+
+// Locate sets the position of the errors in the spec content, and the spec filename,
+// which may be empty. Errors about the whole document are not located. Errors pointing
+// to an element that does not exist in the content (eg. a default) get the position of
+// its closest parent.
+func (errs ConversionErrors) Locate(content []byte, filename string) {
+	if len(errs) == 0 {
+		return
+	}
+	positions := NewPositions(content)
+	for _, err := range errs {
+		if err.Pointer == "" {
+			continue
+		}
+		err.File = filename
+		err.Line, err.Column = positions.Position(err.Pointer)
+	}
+}
+
+// add appends err as a ConversionError at the given location. If err wraps a
+// pointerError, its relative pointer is appended to the location.
+func (errs *ConversionErrors) add(pointer string, err error) {
+	var relative *pointerError
+	if errors.As(err, &relative) {
+		pointer += relative.pointer
+	}
+	*errs = append(*errs, &ConversionError{Pointer: pointer, Err: err})
+}
+
+// pointerError records the location of an error, relative to the object being
+// processed (eg. "/x-kong-plugin-foo"). The error message itself is not altered.
+type pointerError struct {
+	pointer string
+	err     error
+}
+
+func (e *pointerError) Error() string {
+	return e.err.Error()
+}
+
+func (e *pointerError) Unwrap() error {
+	return e.err
+}
+
+// jsonPointer returns a JSON pointer from the given (unescaped) reference tokens.
+func jsonPointer(tokens ...string) string {
+	var pointer string
+	for _, token := range tokens {
+		token = strings.ReplaceAll(token, "~", "~0")
+		token = strings.ReplaceAll(token, "/", "~1")
+		pointer = pointer + "/" + token
+	}
+	return pointer
+}
+
+// prefixPointer returns err located at the pointer, relative to the object being
+// processed. If err is a pointerError, its location is appended to the pointer.
+func prefixPointer(pointer string, err error) error {
+	var relative *pointerError
+	if errors.As(err, &relative) {
+		return &pointerError{pointer + relative.pointer, relative.err}
+	}
+	return &pointerError{pointer, err}
+}
@@ -0,0 +1,150 @@
+package convertoas3
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_jsonPointer(t *testing.T) {
+	assert.Equal(t, "", jsonPointer())
+	assert.Equal(t, "/paths/~1users~1{id}/get", jsonPointer("paths", "/users/{id}", "get"))
+	assert.Equal(t, "/a~0b", jsonPointer("a~b"))
+}
+
+func Test_ConversionErrors(t *testing.T) {
+	var errs ConversionErrors
+	errs.add("", errors.New("root error"))
+	errs.add("/paths/~1users", &pointerError{"/x-kong-name", errors.New("bad name")})
+
+	assert.Equal(t, "", errs[0].Pointer)
+	assert.Equal(t, "/paths/~1users/x-kong-name", errs[1].Pointer)
+	assert.Equal(t, "found 2 errors:\n  - root error\n  - /paths/~1users/x-kong-name: bad name", errs.Error())
+	assert.Equal(t, "root error", errs[:1].Error())
+}
+
+func Test_ConvertCollectsErrors(t *testing.T) {
+	spec := []byte(`
+openapi: 3.0.3
+info:
+  title: errors
+  version: v1
+paths:
+  /users:
+    x-kong-plugin-key-auth: not an object
+    get:
+      responses:
+        "200":
+          description: OK
+  /orders:
+    get:
+      x-kong-name: 123
+      responses:
+        "200":
+          description: OK
+    post:
+      x-kong-route-defaults:
+        $ref: "#/components/x-kong/missing"
+      responses:
+        "200":
+          description: OK
+`)
+
+	_, err := Convert(context.Background(), &spec, O2kOptions{})
+
+	var errs ConversionErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("expected ConversionErrors, got: %v", err)
+	}
+	pointers := make([]string, len(errs))
+	positions := make([][]int, len(errs))
+	for i, e := range errs {
+		pointers[i] = e.Pointer
+		positions[i] = []int{e.Line, e.Column}
+	}
+	assert.Equal(t, []string{
+		"/paths/~1orders/get/x-kong-name",
+		"/paths/~1orders/post/x-kong-route-defaults/$ref",
+		"/paths/~1users/x-kong-plugin-key-auth",
+	}, pointers)
+	assert.Equal(t, [][]int{{15, 7}, {21, 9}, {8, 5}}, positions)
+}
+
+func Test_ConversionErrorsLocate(t *testing.T) {
+	content := []byte("openapi: 3.0.3\npaths:\n  /users:\n    x-kong-name: 123\n")
+	var errs ConversionErrors
+	errs.add("", errors.New("root error"))
+	errs.add("/paths/~1users/x-kong-name", errors.New("bad name"))
+
+	errs.Locate(content, "")
+	assert.Equal(t, "found 2 errors:\n  - root error\n  - /paths/~1users/x-kong-name: bad name", errs.Error())
+	assert.Equal(t, []int{4, 5}, []int{errs[1].Line, errs[1].Column})
+
+	errs.Locate(content, "api.yaml")
+	assert.Equal(t, "found 2 errors:\n  - root error\n  - api.yaml:4:5: /paths/~1users/x-kong-name: bad name",
+		errs.Error())
+	assert.Equal(t, 0, errs[0].Line)
+}
+
+func Test_ConvertBestEffort(t *testing.T) {
+	spec := []byte(`
+openapi: 3.0.3
+info:
+  title: best-effort
+  version: v1
+servers:
+  - url: https://api.example.com
+paths:
+  /users:
+    get:
+      x-kong-name: 123
+      responses:
+        "200":
+          description: OK
+    post:
+      responses:
+        "200":
+          description: OK
+  /orders:
+    get:
+      servers:
+        - url: https://orders.example.com
+      x-kong-upstream-defaults:
+        algorithm: random
+      responses:
+        "200":
+          description: OK
+`)
+
+	var buf bytes.Buffer
+	result, err := Convert(context.Background(), &spec, O2kOptions{
+		BestEffort: true,
+		Logger:     NewStdLogger(log.New(&buf, "", 0), LogLevelWarn),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t,
+		"WARN skipped invalid path or operation error=/paths/~1orders/get/x-kong-upstream-defaults: "+
+			"failed to create upstream from operation '/orders GET': invalid 'algorithm' value 'random', "+
+			"expected one of: round-robin, least-connections, consistent-hashing\n"+
+			"WARN skipped invalid path or operation error=/paths/~1users/get/x-kong-name: "+
+			"expected 'x-kong-name' to be a string: json: cannot unmarshal number into Go value of type string\n",
+		buf.String())
+
+	// only the valid operation remains, without the service/upstream of the skipped one
+	services := result["services"].([]interface{})
+	assert.Len(t, services, 1)
+	routes := services[0].(map[string]interface{})["routes"].([]interface{})
+	assert.Len(t, routes, 1)
+	assert.Equal(t, "best-effort_users_post", routes[0].(map[string]interface{})["name"])
+	assert.Empty(t, result["upstreams"])
+
+	// without best-effort both errors are returned
+	_, err = Convert(context.Background(), &spec, O2kOptions{})
+	var errs ConversionErrors
+	assert.ErrorAs(t, err, &errs)
+	assert.Len(t, errs, 2)
+}
@@ -0,0 +1,114 @@
+package convertoas3
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+const (
+	kongExtensionPrefix = "x-kong-"
+	pluginPrefix        = "x-kong-plugin-"
+)
+
+// docExtensions are the 'x-kong-...' extensions recognized on the document level,
+// besides the 'x-kong-plugin-...' ones.
+var docExtensions = map[string]bool{
+	"x-kong-name":              true,
+	"x-kong-id-seed":           true,
+	"x-kong-tags":              true,
+	"x-kong-workspace":         true,
+	"x-kong-version-header":    true,
+	"x-kong-service-defaults":  true,
+	"x-kong-upstream-defaults": true,
+	"x-kong-route-defaults":    true,
+	"x-kong-max-body-size":     true,
+	"x-kong-filter-chains":     true,
+}
+
+// pathExtensions are the 'x-kong-...' extensions recognized on path items and
+// operations, besides the 'x-kong-plugin-...' ones.
+var pathExtensions = map[string]bool{
+	"x-kong-name":              true,
+	"x-kong-service-defaults":  true,
+	"x-kong-upstream-defaults": true,
+	"x-kong-route-defaults":    true,
+	"x-kong-max-body-size":     true,
+	"x-kong-upstream-path":     true,
+	"x-kong-filter-chains":     true,
+}
+
+// getUnknownExtensions returns the sorted names of the 'x-kong-...' extensions that are
+// not recognized; not in the 'known' set, nor a 'x-kong-plugin-...' extension.
+func getUnknownExtensions(props openapi3.ExtensionProps, known map[string]bool) []string {
+	unknown := make([]string, 0)
+	for name := range props.Extensions {
+		if !strings.HasPrefix(name, kongExtensionPrefix) || known[name] {
+			continue
+		}
+		if strings.HasPrefix(name, pluginPrefix) && len(name) > len(pluginPrefix) {
+			continue
+		}
+		unknown = append(unknown, name)
+	}
+	sort.Strings(unknown)
+	return unknown
+}
+
+// getKnownExtensions returns the sorted names of the recognized 'x-kong-...' extensions
+// in the props, including the plugin extensions.
+func getKnownExtensions(props openapi3.ExtensionProps, known map[string]bool) []string {
+	found := make([]string, 0)
+	for name := range props.Extensions {
+		if known[name] || (strings.HasPrefix(name, pluginPrefix) && len(name) > len(pluginPrefix)) {
+			found = append(found, name)
+		}
+	}
+	sort.Strings(found)
+	return found
+}
+
+// checkExtensions checks for unrecognized 'x-kong-...' extensions, which are most likely
+// typos. In strict mode they are returned as errors, otherwise they are logged as
+// warnings. The pointer is the location of the props, used for reporting.
+func checkExtensions(
+	props openapi3.ExtensionProps,
+	known map[string]bool,
+	pointer string,
+	opts O2kOptions,
+) ConversionErrors {
+	var errs ConversionErrors
+	for _, name := range getKnownExtensions(props, known) {
+		opts.Logger.Debug("found extension", "location", pointer+jsonPointer(name))
+	}
+	for _, name := range getUnknownExtensions(props, known) {
+		if opts.StrictExtensions {
+			errs.add(pointer+jsonPointer(name), fmt.Errorf("unknown extension '%s'", name))
+		} else {
+			opts.Logger.Warn("unknown extension, ignored", "location", pointer+jsonPointer(name))
+		}
+	}
+	return errs
+}
+
+// decodeExtension decodes the value of an extension into target. The loader stores the
+// values as json.RawMessage, but any other (already decoded) value is accepted as well,
+// it is re-encoded first. Returns an error if the value cannot be decoded into target.
+func decodeExtension(value interface{}, target interface{}) error {
+	var raw []byte
+	switch v := value.(type) {
+	case json.RawMessage:
+		raw = v
+	case []byte:
+		raw = v
+	default:
+		var err error
+		if raw, err = json.Marshal(v); err != nil {
+			return err
+		}
+	}
+	return json.Unmarshal(raw, target)
+}
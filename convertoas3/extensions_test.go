@@ -0,0 +1,194 @@
+package convertoas3
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log"
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	uuid "github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ConvertUnknownExtensions(t *testing.T) {
+	spec := []byte(`
+openapi: 3.0.3
+info:
+  title: extensions
+  version: v1
+servers:
+  - url: https://api.example.com
+x-kong-plugin_rate-limiting:
+  config:
+    minute: 10
+x-kong-other: for another tool
+paths:
+  /users:
+    x-kong-tags: [not, here]
+    get:
+      x-kong-route-default:
+        strip_path: true
+      x-kong-plugin-key-auth: {}
+      responses:
+        "200":
+          description: OK
+`)
+
+	var buf bytes.Buffer
+	_, err := Convert(context.Background(), &spec, O2kOptions{
+		Logger: NewStdLogger(log.New(&buf, "", 0), LogLevelWarn),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t,
+		"WARN unknown extension, ignored location=/x-kong-other\n"+
+			"WARN unknown extension, ignored location=/x-kong-plugin_rate-limiting\n"+
+			"WARN unknown extension, ignored location=/paths/~1users/x-kong-tags\n"+
+			"WARN unknown extension, ignored location=/paths/~1users/get/x-kong-route-default\n",
+		buf.String())
+
+	_, err = Convert(context.Background(), &spec, O2kOptions{StrictExtensions: true})
+	assert.EqualError(t, err, "found 2 errors:\n"+
+		"  - /x-kong-other: unknown extension 'x-kong-other'\n"+
+		"  - /x-kong-plugin_rate-limiting: unknown extension 'x-kong-plugin_rate-limiting'")
+}
+
+func Test_ConvertYAMLAnchors(t *testing.T) {
+	content := []byte(`
+openapi: 3.0.3
+info:
+  title: Learn Service
+  version: 1.0.0
+servers:
+  - url: https://api.example.com
+x-kong-plugin-rate-limiting: &rate-limiting
+  config:
+    minute: 10
+paths:
+  /tracks:
+    get:
+      operationId: listTracks
+      x-kong-plugin-rate-limiting:
+        <<: *rate-limiting
+        enabled: false
+      responses:
+        "200":
+          description: OK
+`)
+	result, err := Convert(context.Background(), &content, NewO2kOptions())
+	require.NoError(t, err)
+
+	services := result["services"].([]interface{})
+	require.Len(t, services, 1)
+	routes := services[0].(map[string]interface{})["routes"].([]interface{})
+	require.Len(t, routes, 1)
+	plugins := *routes[0].(map[string]interface{})["plugins"].(*[]*map[string]interface{})
+	require.Len(t, plugins, 1)
+	assert.Equal(t, map[string]interface{}{"minute": float64(10)}, (*plugins[0])["config"])
+	assert.Equal(t, false, (*plugins[0])["enabled"])
+}
+
+func Test_decodeExtension(t *testing.T) {
+	tests := []struct {
+		name        string
+		value       interface{}
+		expected    interface{}
+		expectError bool
+	}{
+		{"raw message", json.RawMessage(`"hello"`), "hello", false},
+		{"bytes", []byte(`"hello"`), "hello", false},
+		{"decoded string", "hello", "hello", false},
+		{"decoded object", map[string]interface{}{"a": 1}, map[string]interface{}{"a": float64(1)}, false},
+		{"invalid raw message", json.RawMessage(`{`), nil, true},
+		{"unencodable value", func() {}, nil, true},
+	}
+
+	for _, tst := range tests {
+		var result interface{}
+		err := decodeExtension(tst.value, &result)
+		if tst.expectError {
+			assert.Error(t, err, tst.name)
+		} else {
+			assert.NoError(t, err, tst.name)
+			assert.Equal(t, tst.expected, result, tst.name)
+		}
+	}
+}
+
+func Test_ExtensionReadersDecodedValues(t *testing.T) {
+	// values that were already decoded, instead of the json.RawMessage set by the loader
+	doc := &openapi3.T{
+		ExtensionProps: openapi3.ExtensionProps{Extensions: map[string]interface{}{
+			"x-kong-name":           "decoded",
+			"x-kong-tags":           []string{"a", "b"},
+			"x-kong-workspace":      123,
+			"x-kong-version-header": nil,
+		}},
+		Components: openapi3.Components{
+			ExtensionProps: openapi3.ExtensionProps{Extensions: map[string]interface{}{
+				"x-kong": "not an object",
+			}},
+		},
+	}
+
+	name, err := getKongName(doc.ExtensionProps)
+	assert.NoError(t, err)
+	assert.Equal(t, "decoded", name)
+
+	tags, err := getKongTags(doc, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, tags)
+
+	_, err = getKongWorkspace(doc, "")
+	assert.Error(t, err)
+
+	_, err = getXKongComponents(doc)
+	assert.Error(t, err)
+}
+
+// extensionFuzzSeeds are the extension values to start fuzzing the readers with.
+var extensionFuzzSeeds = []string{
+	`"name"`, `123`, `null`, `true`, `[]`, `["a", 1]`, `{}`, `{`,
+	`{"$ref": "#/components/x-kong/a"}`, `{"$ref": 1}`, `{"$ref": "#/components/x-kong/"}`,
+	`{"config": {"minute": 10}, "consumer": "john"}`,
+}
+
+func FuzzExtensionReaders(f *testing.F) {
+	for _, seed := range extensionFuzzSeeds {
+		f.Add([]byte(seed))
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		props := openapi3.ExtensionProps{Extensions: map[string]interface{}{}}
+		for _, key := range []string{
+			"x-kong-name", "x-kong-tags", "x-kong-workspace", "x-kong-version-header",
+			"x-kong-service-defaults", "x-kong-upstream-defaults", "x-kong-route-defaults",
+			"x-kong-plugin-key-auth",
+		} {
+			props.Extensions[key] = json.RawMessage(data)
+		}
+		doc := &openapi3.T{
+			ExtensionProps: props,
+			Components: openapi3.Components{ExtensionProps: openapi3.ExtensionProps{
+				Extensions: map[string]interface{}{"x-kong": json.RawMessage(data)},
+			}},
+		}
+
+		// errors are fine, panics are not
+		components, err := getXKongComponents(doc)
+		if err != nil {
+			empty := make(map[string]interface{})
+			components = &empty
+		}
+		_, _ = getKongName(props)
+		_, _ = getKongTags(doc, nil)
+		_, _ = getKongWorkspace(doc, "")
+		_, _ = getVersionHeader(doc)
+		_, _ = getServiceDefaults(props, components)
+		_, _ = getUpstreamDefaults(props, components)
+		_, _ = getRouteDefaults(props, components)
+		_, _ = getPluginsList(props, nil, uuid.NamespaceDNS, "fuzz", components, nil)
+		_, _ = getConsumerGroups(components, uuid.NamespaceDNS, nil)
+	})
+}
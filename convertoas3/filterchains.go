@@ -0,0 +1,90 @@
+package convertoas3
+
+import (
+	"fmt"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	uuid "github.com/satori/go.uuid"
+)
+
+// filterChainsExtension holds the WASM filter chain of a service or route, as a list
+// with a single filter chain, eg. '[{"filters": [{"name": "my-filter"}]}]'. Filter
+// chains require Kong 3.4 or later.
+const filterChainsExtension = "x-kong-filter-chains"
+
+// getFilterChain returns the filter chain in the 'x-kong-filter-chains' extension, or nil
+// if there is none. Kong allows a single filter chain per service or route, each filter
+// requires a name.
+func getFilterChain(props openapi3.ExtensionProps) (map[string]interface{}, error) {
+	value := props.Extensions[filterChainsExtension]
+	if value == nil {
+		return nil, nil
+	}
+	var chains []map[string]interface{}
+	if err := decodeExtension(value, &chains); err != nil {
+		return nil, fmt.Errorf("expected '%s' to be an array of filter chains: %w", filterChainsExtension, err)
+	}
+	if len(chains) == 0 {
+		return nil, nil
+	}
+	if len(chains) > 1 {
+		return nil, fmt.Errorf("expected '%s' to have a single filter chain, Kong allows one per "+
+			"service or route, got: %d", filterChainsExtension, len(chains))
+	}
+
+	chain := chains[0]
+	if enabled, found := chain["enabled"]; found {
+		if _, ok := enabled.(bool); !ok {
+			return nil, fmt.Errorf("expected '%s/0/enabled' to be a boolean, got: '%v'", filterChainsExtension, enabled)
+		}
+	}
+	filters, ok := chain["filters"].([]interface{})
+	if !ok || len(filters) == 0 {
+		return nil, fmt.Errorf("expected '%s/0/filters' to be a non-empty array", filterChainsExtension)
+	}
+	for i, f := range filters {
+		filter, ok := f.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("expected '%s/0/filters/%d' to be an object", filterChainsExtension, i)
+		}
+		if name, ok := filter["name"].(string); !ok || name == "" {
+			return nil, fmt.Errorf("expected '%s/0/filters/%d/name' to be a non-empty string", filterChainsExtension, i)
+		}
+		if enabled, found := filter["enabled"]; found {
+			if _, ok := enabled.(bool); !ok {
+				return nil, fmt.Errorf("expected '%s/0/filters/%d/enabled' to be a boolean, got: '%v'",
+					filterChainsExtension, i, enabled)
+			}
+		}
+	}
+	return chain, nil
+}
+
+// setFilterChain sets the filter chain on the service or route entity, as its
+// 'filter_chains', with an ID derived from the baseName. Does nothing if the chain is nil.
+func setFilterChain(
+	entity map[string]interface{},
+	chain map[string]interface{},
+	uuidNamespace uuid.UUID,
+	baseName string,
+	tags []string,
+) {
+	if chain == nil {
+		return
+	}
+	chainCopy := deepCopyObject(chain)
+	chainCopy["id"] = uuid.NewV5(uuidNamespace, baseName+".filter-chain").String()
+	chainCopy["tags"] = tags
+	entity["filter_chains"] = []interface{}{chainCopy}
+}
+
+// getEntityFilterChain returns the filter chain set on the service or route entity, or
+// nil if it has none.
+func getEntityFilterChain(entity map[string]interface{}) map[string]interface{} {
+	chains, _ := entity["filter_chains"].([]interface{})
+	if len(chains) == 0 {
+		return nil
+	}
+	chain, _ := chains[0].(map[string]interface{})
+	return chain
+}
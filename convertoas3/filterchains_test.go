@@ -0,0 +1,53 @@
+package convertoas3
+
+import (
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	uuid "github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_getFilterChain(t *testing.T) {
+	chain, err := getFilterChain(openapi3.ExtensionProps{})
+	require.NoError(t, err)
+	assert.Nil(t, chain)
+
+	tests := []struct {
+		name  string
+		value interface{}
+		err   string
+	}{
+		{"not an array", map[string]interface{}{"filters": []interface{}{}}, "to be an array of filter chains"},
+		{"multiple chains", []interface{}{
+			map[string]interface{}{"filters": []interface{}{map[string]interface{}{"name": "a"}}},
+			map[string]interface{}{"filters": []interface{}{map[string]interface{}{"name": "b"}}},
+		}, "to have a single filter chain, Kong allows one per service or route, got: 2"},
+		{"no filters", []interface{}{map[string]interface{}{}}, "'x-kong-filter-chains/0/filters' to be a non-empty array"},
+		{"no filter name", []interface{}{map[string]interface{}{"filters": []interface{}{
+			map[string]interface{}{"config": "{}"},
+		}}}, "'x-kong-filter-chains/0/filters/0/name' to be a non-empty string"},
+		{"enabled", []interface{}{map[string]interface{}{"enabled": "yes", "filters": []interface{}{
+			map[string]interface{}{"name": "a"},
+		}}}, "'x-kong-filter-chains/0/enabled' to be a boolean, got: 'yes'"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := getFilterChain(openapi3.ExtensionProps{Extensions: map[string]interface{}{
+				filterChainsExtension: tt.value,
+			}})
+			assert.ErrorContains(t, err, tt.err)
+		})
+	}
+}
+
+func Test_copyRouteForPathFilterChain(t *testing.T) {
+	route := map[string]interface{}{"name": "route"}
+	setFilterChain(route, map[string]interface{}{"filters": []interface{}{}}, uuid.NamespaceDNS, "route", nil)
+	routeCopy := copyRouteForPath(route, "~/route/$", uuid.NamespaceDNS, "route~")
+	assert.Equal(t, uuid.NewV5(uuid.NamespaceDNS, "route~.filter-chain").String(),
+		getEntityFilterChain(routeCopy)["id"])
+	assert.Equal(t, uuid.NewV5(uuid.NamespaceDNS, "route.filter-chain").String(),
+		getEntityFilterChain(route)["id"])
+}
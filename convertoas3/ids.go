@@ -0,0 +1,58 @@
+package convertoas3
+
+import "fmt"
+
+// EntityID is the ID generated for an entity, see EntityIDs.
+type EntityID struct {
+	Kind  string `json:"kind"`            // the entity type, eg. "service", "route", or "plugin"
+	Name  string `json:"name,omitempty"`  // the name of the entity, the plugin name for plugins
+	Owner string `json:"owner,omitempty"` // the entity a plugin or filter chain is attached to
+	ID    string `json:"id"`
+}
+
+// EntityIDs returns the IDs of the entities in a generated declarative file; the services
+// with their filter chains and routes, the upstreams, the plugins, the consumers, and the
+// consumer groups, in output order.
+func EntityIDs(result map[string]interface{}) []EntityID {
+	ids := make([]EntityID, 0)
+	add := func(kind string, entity map[string]interface{}, nameField string, owner string) {
+		if id, ok := entity["id"].(string); ok {
+			name, _ := entity[nameField].(string)
+			ids = append(ids, EntityID{Kind: kind, Name: name, Owner: owner, ID: id})
+		}
+	}
+	addFilterChain := func(entity map[string]interface{}, owner string) {
+		if chain := getEntityFilterChain(entity); chain != nil {
+			add("filter_chain", chain, "name", owner)
+		}
+	}
+
+	services, _ := result["services"].([]interface{})
+	for _, s := range services {
+		service := s.(map[string]interface{})
+		add("service", service, "name", "")
+		addFilterChain(service, fmt.Sprintf("service '%s'", service["name"]))
+		routes, _ := service["routes"].([]interface{})
+		for _, r := range routes {
+			route := r.(map[string]interface{})
+			add("route", route, "name", "")
+			addFilterChain(route, fmt.Sprintf("route '%s'", route["name"]))
+		}
+	}
+	upstreams, _ := result["upstreams"].([]interface{})
+	for _, u := range upstreams {
+		add("upstream", u.(map[string]interface{}), "name", "")
+	}
+	for _, ref := range collectPlugins(result) {
+		add("plugin", ref.plugin, "name", ref.owner)
+	}
+	consumers, _ := result["consumers"].([]interface{})
+	for _, c := range consumers {
+		add("consumer", c.(map[string]interface{}), "username", "")
+	}
+	groups, _ := result["consumer_groups"].([]interface{})
+	for _, g := range groups {
+		add("consumer_group", g.(map[string]interface{}), "name", "")
+	}
+	return ids
+}
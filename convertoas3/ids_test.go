@@ -0,0 +1,58 @@
+package convertoas3
+
+import (
+	"context"
+	"testing"
+
+	uuid "github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_EntityIDs(t *testing.T) {
+	spec := []byte(`
+openapi: 3.0.3
+info:
+  title: ids
+  version: 1.0.0
+servers:
+  - url: https://ids.example.com
+x-kong-plugin-cors: {}
+components:
+  x-kong:
+    consumers:
+      alice: {}
+paths:
+  /users:
+    get:
+      operationId: getUsers
+      x-kong-filter-chains:
+        - filters:
+            - name: my-filter
+`)
+	result, err := Convert(context.Background(), &spec, O2kOptions{})
+	require.NoError(t, err)
+
+	ids := EntityIDs(result)
+	kinds := make([]string, len(ids))
+	for i, id := range ids {
+		kinds[i] = id.Kind
+	}
+	assert.Equal(t, []string{"service", "route", "filter_chain", "plugin", "consumer"}, kinds)
+
+	service := result["services"].([]interface{})[0].(map[string]interface{})
+	route := service["routes"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, EntityID{Kind: "service", Name: "ids", ID: service["id"].(string)}, ids[0])
+	assert.Equal(t, EntityID{Kind: "route", Name: "ids_getusers", ID: route["id"].(string)}, ids[1])
+	assert.Equal(t, "route 'ids_getusers'", ids[2].Owner)
+	assert.Equal(t, EntityID{
+		Kind: "plugin", Name: "cors", Owner: "service 'ids'",
+		ID: createPluginID(uuid.NamespaceDNS, "ids", map[string]interface{}{"name": "cors"}),
+	}, ids[3])
+	assert.Equal(t, "alice", ids[4].Name)
+
+	// the IDs depend on the namespace
+	result, err = Convert(context.Background(), &spec, O2kOptions{UUIDNamespace: uuid.NamespaceURL})
+	require.NoError(t, err)
+	assert.NotEqual(t, ids[0].ID, EntityIDs(result)[0].ID)
+}
@@ -0,0 +1,81 @@
+package convertoas3
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// getIDSeed returns the provided seed or if empty, then the `x-kong-id-seed` property,
+// validated to be a string. Returns "" if there is none.
+func getIDSeed(doc *openapi3.T, seedProvided string) (string, error) {
+	if seedProvided != "" {
+		return seedProvided, nil
+	}
+
+	if doc.ExtensionProps.Extensions == nil || doc.ExtensionProps.Extensions["x-kong-id-seed"] == nil {
+		return "", nil
+	}
+
+	var seed string
+	err := decodeExtension(doc.ExtensionProps.Extensions["x-kong-id-seed"], &seed)
+	if err != nil {
+		return "", fmt.Errorf("expected 'x-kong-id-seed' to be a string: %w", err)
+	}
+	if Slugify(seed) == "" {
+		return "", fmt.Errorf("expected 'x-kong-id-seed' to contain valid name characters, got: '%s'", seed)
+	}
+	return seed, nil
+}
+
+// renameEntity replaces the prefix 'from' of the name in entity[key] by 'to'.
+func renameEntity(entity map[string]interface{}, key string, from string, to string) {
+	if name, ok := entity[key].(string); ok && strings.HasPrefix(name, from) {
+		entity[key] = to + strings.TrimPrefix(name, from)
+	}
+}
+
+// renameEntities renames the generated entities from the ID seed to the document name.
+// The conversion derives both the names and the IDs from the seed, so renaming
+// afterwards keeps the IDs stable when the document name changes. The names of the
+// services, upstreams, routes, the anonymous consumer, and the references to them,
+// start with 'from', and are replaced by 'to'.
+func renameEntities(result map[string]interface{}, from string, to string) {
+	anonymousName := from + anonymousSuffix
+	upstreamNames := make(map[interface{}]bool)
+	upstreams, _ := result["upstreams"].([]interface{})
+	for _, u := range upstreams {
+		upstream := u.(map[string]interface{})
+		upstreamNames[upstream["name"]] = true
+		renameEntity(upstream, "name", from, to)
+	}
+	services, _ := result["services"].([]interface{})
+	for _, s := range services {
+		service := s.(map[string]interface{})
+		renameEntity(service, "name", from, to)
+		if upstreamNames[service["host"]] {
+			renameEntity(service, "host", from, to)
+		}
+		routes, _ := service["routes"].([]interface{})
+		for _, r := range routes {
+			renameEntity(r.(map[string]interface{}), "name", from, to)
+		}
+	}
+	if plugins, ok := result["plugins"].(*[]*map[string]interface{}); ok {
+		for _, plugin := range *plugins {
+			renameEntity(*plugin, "service", from, to)
+			renameEntity(*plugin, "route", from, to)
+			if (*plugin)["consumer"] == anonymousName {
+				(*plugin)["consumer"] = to + anonymousSuffix
+			}
+		}
+	}
+	consumers, _ := result["consumers"].([]interface{})
+	for _, c := range consumers {
+		consumer := c.(map[string]interface{})
+		if consumer["username"] == anonymousName {
+			consumer["username"] = to + anonymousSuffix
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package convertoas3
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// collectEntities returns the names and IDs of the generated services, upstreams,
+// routes, and plugins (with their foreign keys).
+func collectEntities(result map[string]interface{}) (names []string, ids []string) {
+	for _, s := range result["services"].([]interface{}) {
+		service := s.(map[string]interface{})
+		names = append(names, service["name"].(string), service["host"].(string))
+		ids = append(ids, service["id"].(string))
+		for _, r := range service["routes"].([]interface{}) {
+			route := r.(map[string]interface{})
+			names = append(names, route["name"].(string))
+			ids = append(ids, route["id"].(string))
+		}
+	}
+	for _, u := range result["upstreams"].([]interface{}) {
+		upstream := u.(map[string]interface{})
+		names = append(names, upstream["name"].(string))
+		ids = append(ids, upstream["id"].(string))
+	}
+	for _, plugin := range *result["plugins"].(*[]*map[string]interface{}) {
+		names = append(names, (*plugin)["route"].(string))
+		ids = append(ids, (*plugin)["id"].(string))
+	}
+	return names, ids
+}
+
+func Test_ConvertIDSeed(t *testing.T) {
+	spec := func(title string) []byte {
+		return []byte(`
+openapi: 3.0.3
+info:
+  title: ` + title + `
+  version: 1.0.0
+x-kong-id-seed: learn-service
+servers:
+  - url: https://server1.com
+  - url: https://server2.com
+paths:
+  /tracks:
+    get:
+      x-kong-plugin-rate-limiting:
+        consumer: alice
+        config:
+          minute: 10
+      responses:
+        "200":
+          description: OK
+components:
+  x-kong:
+    consumers:
+      alice:
+        username: alice
+`)
+	}
+
+	content := spec("Learn Service")
+	result1, err := Convert(context.Background(), &content, O2kOptions{})
+	require.NoError(t, err)
+	content = spec("Renamed Service")
+	result2, err := Convert(context.Background(), &content, O2kOptions{})
+	require.NoError(t, err)
+
+	names1, ids1 := collectEntities(result1)
+	names2, ids2 := collectEntities(result2)
+	assert.Equal(t, ids1, ids2, "the IDs should not depend on the title")
+	assert.Equal(t, []string{
+		"renamed-service", "renamed-service.upstream", "renamed-service_tracks_get",
+		"renamed-service.upstream", "renamed-service_tracks_get",
+	}, names2)
+	for _, name := range names1 {
+		assert.True(t, strings.HasPrefix(name, "learn-service"), name)
+	}
+
+	// the option takes precedence over x-kong-id-seed
+	result3, err := Convert(context.Background(), &content, O2kOptions{IDSeed: "other"})
+	require.NoError(t, err)
+	names3, ids3 := collectEntities(result3)
+	assert.Equal(t, names2, names3)
+	assert.NotEqual(t, ids2, ids3)
+}
+
+func Test_ConvertInvalidIDSeed(t *testing.T) {
+	content := []byte("openapi: 3.0.3\ninfo:\n  title: t\n  version: v1\npaths: {}\nx-kong-id-seed: '!!!'\n")
+	_, err := Convert(context.Background(), &content, O2kOptions{})
+	assert.EqualError(t, err,
+		"/x-kong-id-seed: expected 'x-kong-id-seed' to contain valid name characters, got: '!!!'")
+}
@@ -0,0 +1,141 @@
+package convertoas3
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	uuid "github.com/satori/go.uuid"
+)
+
+// The strategies for attaching the plugins inherited from the document and path levels.
+const (
+	// PluginInheritanceMixed attaches the document plugins to the document service, and
+	// the path plugins to the path service, if there is one, or to each route otherwise.
+	PluginInheritanceMixed = "mixed"
+	// PluginInheritanceService attaches the inherited plugins once, to the services. Paths
+	// declaring plugins get a service of their own, and the plugins of an operation with a
+	// service of its own are attached to that service.
+	PluginInheritanceService = "service"
+	// PluginInheritanceRoute attaches all plugins to each route, so every route lists the
+	// plugins applying to it. Services get no plugins.
+	PluginInheritanceRoute = "route"
+)
+
+// declaresPlugins returns true if the extension properties declare plugins, other than
+// the request-validator, which is only generated on the operations.
+func declaresPlugins(props openapi3.ExtensionProps) bool {
+	for name := range props.Extensions {
+		if strings.HasPrefix(name, pluginPrefix) && len(name) > len(pluginPrefix) &&
+			name != pluginPrefix+"request-validator" {
+			return true
+		}
+	}
+	return false
+}
+
+// explodeServicePlugins moves the plugins of the service to each of its routes. A route
+// plugin takes precedence over a service plugin with the same name, as it does in Kong.
+// The copies get IDs derived from the route names. Services without routes keep their
+// plugins, as do the plugins bound to a consumer, which are in the top-level list.
+func explodeServicePlugins(service map[string]interface{}, uuidNamespace uuid.UUID) {
+	servicePlugins, ok := service["plugins"].(*[]*map[string]interface{})
+	if !ok || servicePlugins == nil || len(*servicePlugins) == 0 {
+		return
+	}
+	routes, _ := service["routes"].([]interface{})
+	if len(routes) == 0 {
+		return
+	}
+
+	for _, r := range routes {
+		route := r.(map[string]interface{})
+		routeName := route["name"].(string)
+		routePlugins, _ := route["plugins"].(*[]*map[string]interface{})
+
+		plugins := newPluginSet(nil)
+		for _, plugin := range *servicePlugins {
+			pluginCopy := deepCopy(plugin).(*map[string]interface{})
+			(*pluginCopy)["id"] = createPluginID(uuidNamespace, routeName, *pluginCopy)
+			plugins.insert(pluginCopy)
+		}
+		if routePlugins != nil {
+			for _, plugin := range *routePlugins {
+				plugins.insert(plugin)
+			}
+		}
+		route["plugins"] = plugins.list()
+	}
+	service["plugins"] = &[]*map[string]interface{}{}
+}
+
+// pluginConfigKey returns the plugin without its ID as JSON, to compare plugin configs.
+// Returns "" if it cannot be marshalled.
+func pluginConfigKey(plugin *map[string]interface{}) string {
+	config := make(map[string]interface{}, len(*plugin))
+	for key, value := range *plugin {
+		if key != "id" {
+			config[key] = value
+		}
+	}
+	key, err := json.Marshal(config)
+	if err != nil {
+		return ""
+	}
+	return string(key)
+}
+
+// hoistRoutePlugins moves the plugins that all routes of the service have, with the same
+// config, to the service, so they are given once. The hoisted plugins get IDs derived
+// from the service name. Plugins the service already has, with the same name, stay on
+// the routes, since they override the service one. Services with fewer than 2 routes
+// are left as is, there is nothing to gain.
+func hoistRoutePlugins(service map[string]interface{}, uuidNamespace uuid.UUID) {
+	routes, _ := service["routes"].([]interface{})
+	if len(routes) < 2 {
+		return
+	}
+	servicePlugins, _ := service["plugins"].(*[]*map[string]interface{})
+	serviceSet := newPluginSet(servicePlugins)
+	routeSets := make([]*pluginSet, len(routes))
+	for i, route := range routes {
+		routePlugins, _ := route.(map[string]interface{})["plugins"].(*[]*map[string]interface{})
+		routeSets[i] = newPluginSet(routePlugins)
+	}
+
+	hoisted := false
+	for _, plugin := range *routeSets[0].list() {
+		name := pluginName(plugin)
+		config := pluginConfigKey(plugin)
+		if config == "" || serviceSet.get(name) != nil {
+			continue
+		}
+		shared := true
+		for _, routeSet := range routeSets[1:] {
+			other := routeSet.get(name)
+			if other == nil || pluginConfigKey(other) != config {
+				shared = false
+				break
+			}
+		}
+		if !shared {
+			continue
+		}
+
+		servicePlugin := deepCopy(plugin).(*map[string]interface{})
+		(*servicePlugin)["id"] = createPluginID(uuidNamespace, service["name"].(string), *servicePlugin)
+		serviceSet.insert(servicePlugin)
+		for _, routeSet := range routeSets {
+			routeSet.remove(name)
+		}
+		hoisted = true
+	}
+	if !hoisted {
+		return
+	}
+
+	service["plugins"] = serviceSet.list()
+	for i, route := range routes {
+		route.(map[string]interface{})["plugins"] = routeSets[i].list()
+	}
+}
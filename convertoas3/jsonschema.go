@@ -3,6 +3,7 @@ package convertoas3
 import (
 	"encoding/json"
 	"strings"
+	"sync"
 
 	"github.com/getkin/kin-openapi/openapi3"
 )
@@ -54,15 +55,10 @@ func extractSchema(s *openapi3.SchemaRef) string {
 
 	// inject subschema's referenced
 	if len(seenBefore) > 0 {
-		definitions := make(map[string]interface{})
+		definitions := make(map[string]json.RawMessage)
 		for key, schema := range seenBefore {
-			// copy the subschema
-			var copySchema map[string]interface{}
-			jConf, _ := schema.MarshalJSON()
-			_ = json.Unmarshal(jConf, &copySchema)
-
-			// store under new key
-			definitions[strings.Replace(key, "#/components/schemas/", "", 1)] = copySchema
+			// store under new key, as serialized, no need to decode it again
+			definitions[strings.Replace(key, "#/components/schemas/", "", 1)], _ = schema.MarshalJSON()
 		}
 		finalSchema["definitions"] = definitions
 	}
@@ -71,3 +67,45 @@ func extractSchema(s *openapi3.SchemaRef) string {
 	// update the $ref values; this is safe because plain " (double-quotes) would be escaped if in actual values
 	return strings.ReplaceAll(string(result), "\"$ref\":\"#/components/schemas/", "\"$ref\":\"#/definitions/")
 }
+
+// schemaCacheKey identifies a schema; a reference and the schema it resolves to.
+type schemaCacheKey struct {
+	ref   string
+	value *openapi3.Schema
+}
+
+// schemaCache caches the results of extractSchema. Referenced schemas are typically
+// shared by many operations, so they only need to be extracted once per document. It
+// is safe for concurrent use.
+type schemaCache struct {
+	lock    sync.Mutex
+	schemas map[schemaCacheKey]string
+}
+
+// newSchemaCache returns a new, empty, schemaCache.
+func newSchemaCache() *schemaCache {
+	return &schemaCache{schemas: make(map[schemaCacheKey]string)}
+}
+
+// extractSchema returns the cached result of extractSchema for the given schema. A nil
+// cache does not cache anything.
+func (cache *schemaCache) extractSchema(s *openapi3.SchemaRef) string {
+	if s == nil || cache == nil {
+		return extractSchema(s)
+	}
+
+	key := schemaCacheKey{s.Ref, s.Value}
+	cache.lock.Lock()
+	result, found := cache.schemas[key]
+	cache.lock.Unlock()
+	if found {
+		return result
+	}
+
+	// extract without holding the lock, worst case a schema is extracted more than once
+	result = extractSchema(s)
+	cache.lock.Lock()
+	cache.schemas[key] = result
+	cache.lock.Unlock()
+	return result
+}
@@ -0,0 +1,51 @@
+package convertoas3
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/Kong/fw/kongcompat"
+)
+
+// legacyFormatVersion is the decK '_format_version' for Kong versions before 3.0.
+const legacyFormatVersion = "1.1"
+
+// applyKongVersion adapts the output document to the targeted Kong version, eg. "2.8".
+// Before Kong 3.0 the format version is '1.1', and regex paths have no '~' prefix, since
+// Kong detects them itself. Returns an error listing the features used that the version
+// does not support, see kongcompat.CheckVersion.
+func applyKongVersion(result map[string]interface{}, kongVersion string) error {
+	version, err := kongcompat.ParseVersion(kongVersion)
+	if err != nil {
+		return err
+	}
+
+	if !version.Supports(kongcompat.FeatureRegexPrefix) {
+		result[formatVersionKey] = legacyFormatVersion
+		services, _ := result["services"].([]interface{})
+		for _, service := range services {
+			routes, _ := service.(map[string]interface{})["routes"].([]interface{})
+			for _, route := range routes {
+				paths, _ := route.(map[string]interface{})["paths"].([]string)
+				for i, path := range paths {
+					paths[i] = strings.TrimPrefix(path, "~")
+				}
+			}
+		}
+	}
+
+	// check a generic copy, the entities hold typed lists
+	data, err := json.Marshal(result)
+	if err != nil {
+		return fmt.Errorf("failed to serialize the output document: %w", err)
+	}
+	var content map[string]interface{}
+	if err = json.Unmarshal(data, &content); err != nil {
+		return fmt.Errorf("failed to serialize the output document: %w", err)
+	}
+	if problems := kongcompat.CheckVersion(content, version); len(problems) > 0 {
+		return fmt.Errorf("features not supported by Kong %s: %v", version, problems)
+	}
+	return nil
+}
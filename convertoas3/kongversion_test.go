@@ -0,0 +1,66 @@
+package convertoas3
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ConvertKongVersion(t *testing.T) {
+	spec := []byte(`
+openapi: 3.0.3
+info:
+  title: versions
+  version: 1.0.0
+servers:
+  - url: https://api.example.com
+paths:
+  /users/{id}:
+    get:
+      operationId: getUser
+`)
+
+	result, err := Convert(context.Background(), &spec, O2kOptions{KongVersion: "3.4"})
+	require.NoError(t, err)
+	assert.Equal(t, "3.0", result["_format_version"])
+	route := result["services"].([]interface{})[0].(map[string]interface{})["routes"].([]interface{})[0]
+	assert.Equal(t, []string{"~/users/(?<id>[^#?/]+)$"}, route.(map[string]interface{})["paths"])
+
+	// Kong 2.x detects regex paths itself
+	result, err = Convert(context.Background(), &spec, O2kOptions{KongVersion: "2.8"})
+	require.NoError(t, err)
+	assert.Equal(t, "1.1", result["_format_version"])
+	route = result["services"].([]interface{})[0].(map[string]interface{})["routes"].([]interface{})[0]
+	assert.Equal(t, []string{"/users/(?<id>[^#?/]+)$"}, route.(map[string]interface{})["paths"])
+
+	// unsupported features fail the conversion
+	spec = []byte(`
+openapi: 3.0.3
+info:
+  title: versions
+  version: 1.0.0
+servers:
+  - url: https://api.example.com
+x-kong-filter-chains:
+  - filters:
+      - name: my-filter
+x-kong-plugin-cors:
+  ordering:
+    before:
+      access:
+        - key-auth
+paths:
+  /users:
+    get:
+      operationId: getUsers
+`)
+	_, err = Convert(context.Background(), &spec, O2kOptions{KongVersion: "3.4"})
+	require.NoError(t, err)
+	_, err = Convert(context.Background(), &spec, O2kOptions{KongVersion: "3.3"})
+	assert.ErrorContains(t, err, "features not supported by Kong 3.3: "+
+		"[filter chains (on service 'versions') require Kong 3.4 or later]")
+	_, err = Convert(context.Background(), &spec, O2kOptions{KongVersion: "2.8"})
+	assert.ErrorContains(t, err, "ordering of plugin 'cors' (on service 'versions') requires Kong 3.0 or later")
+}
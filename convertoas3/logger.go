@@ -0,0 +1,159 @@
+package convertoas3
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"sync"
+)
+
+// Logger is the interface used to report conversion diagnostics. The keysAndValues
+// are alternating keys and values, providing structured context for the message.
+// Library consumers can implement it to route diagnostics into their own logging.
+type Logger interface {
+	Debug(msg string, keysAndValues ...interface{})
+	Info(msg string, keysAndValues ...interface{})
+	Warn(msg string, keysAndValues ...interface{})
+}
+
+// LogLevel is the minimum level of messages to write for a StdLogger.
+type LogLevel int
+
+const (
+	LogLevelDebug LogLevel = iota
+	LogLevelInfo
+	LogLevelWarn
+)
+
+// ParseLogLevel returns the LogLevel for a name; 'debug', 'info', or 'warn'.
+func ParseLogLevel(name string) (LogLevel, error) {
+	switch strings.ToLower(name) {
+	case "debug":
+		return LogLevelDebug, nil
+	case "info":
+		return LogLevelInfo, nil
+	case "warn", "warning":
+		return LogLevelWarn, nil
+	default:
+		return LogLevelWarn, fmt.Errorf("expected log level to be one of 'debug', 'info', or 'warn', got: '%s'", name)
+	}
+}
+
+// nopLogger discards all messages, it is the default Logger.
+type nopLogger struct{}
+
+func (nopLogger) Debug(string, ...interface{}) {}
+func (nopLogger) Info(string, ...interface{})  {}
+func (nopLogger) Warn(string, ...interface{})  {}
+
+// NopLogger returns a Logger discarding all messages.
+func NopLogger() Logger {
+	return nopLogger{}
+}
+
+// StdLogger is a Logger writing to a standard library logger.
+type StdLogger struct {
+	logger *log.Logger
+	level  LogLevel
+}
+
+// NewStdLogger returns a Logger writing messages of at least 'level' to 'logger'.
+func NewStdLogger(logger *log.Logger, level LogLevel) *StdLogger {
+	return &StdLogger{logger: logger, level: level}
+}
+
+func (l *StdLogger) write(level LogLevel, prefix string, msg string, keysAndValues []interface{}) {
+	if level < l.level {
+		return
+	}
+	var line strings.Builder
+	line.WriteString(prefix + " " + msg)
+	for i := 0; i < len(keysAndValues); i += 2 {
+		if i+1 < len(keysAndValues) {
+			line.WriteString(fmt.Sprintf(" %v=%v", keysAndValues[i], keysAndValues[i+1]))
+		} else {
+			line.WriteString(fmt.Sprintf(" %v", keysAndValues[i]))
+		}
+	}
+	l.logger.Print(line.String())
+}
+
+// Debug writes a debug message.
+func (l *StdLogger) Debug(msg string, keysAndValues ...interface{}) {
+	l.write(LogLevelDebug, "DEBUG", msg, keysAndValues)
+}
+
+// Info writes an informational message.
+func (l *StdLogger) Info(msg string, keysAndValues ...interface{}) {
+	l.write(LogLevelInfo, "INFO", msg, keysAndValues)
+}
+
+// Warn writes a warning message.
+func (l *StdLogger) Warn(msg string, keysAndValues ...interface{}) {
+	l.write(LogLevelWarn, "WARN", msg, keysAndValues)
+}
+
+// JSONLogger is a Logger writing each message as a single line JSON object, eg. for
+// log aggregators. The keysAndValues are added as fields of the object. It is safe for
+// concurrent use.
+type JSONLogger struct {
+	lock  sync.Mutex
+	w     io.Writer
+	level LogLevel
+}
+
+// NewJSONLogger returns a Logger writing messages of at least 'level' to 'w'.
+func NewJSONLogger(w io.Writer, level LogLevel) *JSONLogger {
+	return &JSONLogger{w: w, level: level}
+}
+
+func (l *JSONLogger) write(level LogLevel, name string, msg string, keysAndValues []interface{}) {
+	if level < l.level {
+		return
+	}
+	entry := map[string]interface{}{
+		"level": name,
+		"msg":   msg,
+	}
+	for i := 0; i < len(keysAndValues); i += 2 {
+		key := fmt.Sprint(keysAndValues[i])
+		if i+1 >= len(keysAndValues) {
+			entry[key] = nil
+			continue
+		}
+		switch value := keysAndValues[i+1].(type) {
+		case error:
+			entry[key] = value.Error()
+		case fmt.Stringer:
+			entry[key] = value.String()
+		default:
+			entry[key] = value
+		}
+	}
+	line, err := json.Marshal(entry)
+	if err != nil {
+		// a value that cannot be encoded, fall back to the message only
+		line, _ = json.Marshal(map[string]interface{}{"level": name, "msg": msg})
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	_, _ = l.w.Write(append(line, '\n'))
+}
+
+// Debug writes a debug message.
+func (l *JSONLogger) Debug(msg string, keysAndValues ...interface{}) {
+	l.write(LogLevelDebug, "debug", msg, keysAndValues)
+}
+
+// Info writes an informational message.
+func (l *JSONLogger) Info(msg string, keysAndValues ...interface{}) {
+	l.write(LogLevelInfo, "info", msg, keysAndValues)
+}
+
+// Warn writes a warning message.
+func (l *JSONLogger) Warn(msg string, keysAndValues ...interface{}) {
+	l.write(LogLevelWarn, "warn", msg, keysAndValues)
+}
@@ -0,0 +1,59 @@
+package convertoas3
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_StdLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewStdLogger(log.New(&buf, "", 0), LogLevelInfo)
+
+	logger.Debug("not written")
+	logger.Info("written", "key", "value", "number", 1)
+	logger.Warn("odd", "key")
+
+	assert.Equal(t, "INFO written key=value number=1\nWARN odd key\n", buf.String())
+}
+
+func Test_JSONLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewJSONLogger(&buf, LogLevelInfo)
+
+	logger.Debug("not written")
+	logger.Info("written", "key", "value", "number", 1)
+	logger.Warn("failed", "error", errors.New("oops"), "odd")
+
+	assert.Equal(t, `{"key":"value","level":"info","msg":"written","number":1}`+"\n"+
+		`{"error":"oops","level":"warn","msg":"failed","odd":null}`+"\n", buf.String())
+}
+
+func Test_ParseLogLevel(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected LogLevel
+		wantErr  bool
+	}{
+		{name: "debug", input: "debug", expected: LogLevelDebug},
+		{name: "info", input: "INFO", expected: LogLevelInfo},
+		{name: "warn", input: "warn", expected: LogLevelWarn},
+		{name: "warning", input: "warning", expected: LogLevelWarn},
+		{name: "invalid", input: "verbose", expected: LogLevelWarn, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			level, err := ParseLogLevel(tt.input)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.expected, level)
+		})
+	}
+}
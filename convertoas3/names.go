@@ -0,0 +1,82 @@
+package convertoas3
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// entityLocation records the spec element an entity was generated from.
+type entityLocation struct {
+	kind    string // the entity type; 'service', 'route', or 'upstream'
+	name    string
+	pointer string // JSON pointer to the spec element, "" for the document
+}
+
+// entityLocations is a list of generated entities, to detect name collisions.
+type entityLocations []entityLocation
+
+// add records the entity, a map with a 'name', generated from the spec element at the
+// pointer. Entities without a name are ignored.
+func (locations *entityLocations) add(kind string, entity map[string]interface{}, pointer string) {
+	if name, ok := entity["name"].(string); ok {
+		*locations = append(*locations, entityLocation{kind: kind, name: name, pointer: pointer})
+	}
+}
+
+// addRoutes records the routes of the service, generated from the spec element at the
+// pointer.
+func (locations *entityLocations) addRoutes(service map[string]interface{}, pointer string) {
+	routes, _ := service["routes"].([]interface{})
+	for _, route := range routes {
+		if r, ok := route.(map[string]interface{}); ok {
+			locations.add("route", r, pointer)
+		}
+	}
+}
+
+// formatLocation returns the JSON pointer quoted, or 'the document' for the root.
+func formatLocation(pointer string) string {
+	if pointer == "" {
+		return "the document"
+	}
+	return "'" + pointer + "'"
+}
+
+// checkNameCollisions returns an error for each entity name that was generated from more
+// than one spec element, for the same entity type. Kong requires the names to be unique,
+// eg. a path '/accounts' with 'x-kong-name: users' generates the same names as the path
+// '/users'. The errors are located at the second element, and list all of them.
+func checkNameCollisions(locations entityLocations) ConversionErrors {
+	pointers := make(map[string][]string) // by kind and name
+	keys := make([]string, 0)
+	for _, location := range locations {
+		key := location.kind + " name '" + location.name + "'"
+		found := false
+		for _, pointer := range pointers[key] {
+			found = found || pointer == location.pointer
+		}
+		if found {
+			continue
+		}
+		if pointers[key] == nil {
+			keys = append(keys, key)
+		}
+		pointers[key] = append(pointers[key], location.pointer)
+	}
+	sort.Strings(keys)
+
+	var errs ConversionErrors
+	for _, key := range keys {
+		if len(pointers[key]) < 2 {
+			continue
+		}
+		formatted := make([]string, len(pointers[key]))
+		for i, pointer := range pointers[key] {
+			formatted[i] = formatLocation(pointer)
+		}
+		errs.add(pointers[key][1], fmt.Errorf("duplicate %s, generated from %s; "+
+			"set a unique 'x-kong-name' or 'operationId' on them", key, strings.Join(formatted, " and ")))
+	}
+	return errs
+}
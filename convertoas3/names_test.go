@@ -0,0 +1,61 @@
+package convertoas3
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_checkNameCollisions(t *testing.T) {
+	var locations entityLocations
+	locations.add("service", map[string]interface{}{"name": "doc"}, "")
+	locations.add("route", map[string]interface{}{"name": "doc"}, "/paths/~1a/get")
+	locations.add("route", map[string]interface{}{"name": "doc_a"}, "/paths/~1a/get")
+	locations.add("route", map[string]interface{}{"name": "doc_a"}, "/paths/~1a/get")
+	locations.add("upstream", map[string]interface{}{}, "/paths/~1a")
+	assert.Empty(t, checkNameCollisions(locations))
+
+	locations.add("service", map[string]interface{}{"name": "doc"}, "/paths/~1b")
+	locations.add("service", map[string]interface{}{"name": "doc"}, "/paths/~1c")
+	errs := checkNameCollisions(locations)
+	require.Len(t, errs, 1)
+	assert.Equal(t, "/paths/~1b", errs[0].Pointer)
+	assert.Equal(t, "duplicate service name 'doc', generated from the document and '/paths/~1b' and "+
+		"'/paths/~1c'; set a unique 'x-kong-name' or 'operationId' on them", errs[0].Err.Error())
+}
+
+func Test_ConvertNameCollisions(t *testing.T) {
+	spec := []byte(`
+openapi: 3.0.3
+info:
+  title: doc
+  version: v1
+paths:
+  /users:
+    get:
+      responses:
+        "200":
+          description: OK
+  /accounts:
+    x-kong-name: users
+    get:
+      responses:
+        "200":
+          description: OK
+`)
+
+	_, err := Convert(context.Background(), &spec, O2kOptions{})
+	var errs ConversionErrors
+	require.True(t, errors.As(err, &errs))
+	require.Len(t, errs, 1)
+	assert.Equal(t, "/paths/~1users/get: duplicate route name 'doc_users_get', generated from "+
+		"'/paths/~1accounts/get' and '/paths/~1users/get'; set a unique 'x-kong-name' or 'operationId' on them",
+		errs[0].Error())
+
+	// best effort does not skip them, the output would be invalid
+	_, err = Convert(context.Background(), &spec, O2kOptions{BestEffort: true})
+	assert.Error(t, err)
+}
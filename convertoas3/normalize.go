@@ -0,0 +1,181 @@
+package convertoas3
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+// parameterStyles are the default styles of the parameters, by location.
+var parameterStyles = map[string]string{
+	"query":  "form",
+	"path":   "simple",
+	"header": "simple",
+	"cookie": "form",
+}
+
+// lookupPointer returns the value at a local JSON pointer ('#/...') in the document.
+func lookupPointer(doc map[string]interface{}, ref string) (interface{}, bool) {
+	var value interface{} = doc
+	for _, token := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+		token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+		switch v := value.(type) {
+		case map[string]interface{}:
+			var found bool
+			if value, found = v[token]; !found {
+				return nil, false
+			}
+		default:
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+// resolveRefs returns the value with all local references ('$ref': '#/...') replaced by
+// a copy of the value referenced, recursively. A reference to an object that is being
+// resolved (a recursive schema) is retained, as are references to other documents.
+// Errors are added to errs, pointer is the location of the value.
+func resolveRefs(
+	value interface{},
+	pointer string,
+	doc map[string]interface{},
+	resolving map[string]bool,
+	errs *ConversionErrors,
+) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if ref, ok := v["$ref"].(string); ok && strings.HasPrefix(ref, "#/") {
+			if resolving[ref] {
+				return v
+			}
+			target, found := lookupPointer(doc, ref)
+			if !found {
+				errs.add(pointer+jsonPointer("$ref"), fmt.Errorf("reference '%s' not found", ref))
+				return v
+			}
+			resolving[ref] = true
+			resolved := resolveRefs(deepCopy(target), pointer, doc, resolving, errs)
+			delete(resolving, ref)
+			return resolved
+		}
+		for key, child := range v {
+			if !literalKeys[key] {
+				v[key] = resolveRefs(child, pointer+jsonPointer(key), doc, resolving, errs)
+			}
+		}
+	case []interface{}:
+		for i, child := range v {
+			v[i] = resolveRefs(child, fmt.Sprintf("%s/%d", pointer, i), doc, resolving, errs)
+		}
+	}
+	return value
+}
+
+// expandParameterDefaults sets the defaults of the parameters that are not given;
+// 'required' and 'style'. 'explode' is left unset, since the generated request-validator
+// plugin defaults it to false, unlike OpenAPI, so setting it would alter the conversion.
+func expandParameterDefaults(parameters []interface{}) {
+	for _, p := range parameters {
+		param, ok := p.(map[string]interface{})
+		if !ok || param["$ref"] != nil {
+			continue
+		}
+		in, _ := param["in"].(string)
+		if _, found := param["required"]; !found {
+			param["required"] = in == "path"
+		}
+		if _, found := param["content"]; found {
+			continue // style only applies to parameters with a schema
+		}
+		if _, found := param["style"]; !found && parameterStyles[in] != "" {
+			param["style"] = parameterStyles[in]
+		}
+	}
+}
+
+// parameterKey returns the key identifying a parameter; its location and name.
+func parameterKey(p interface{}) string {
+	param, _ := p.(map[string]interface{})
+	return fmt.Sprintf("%v:%v", param["in"], param["name"])
+}
+
+// expandDefaults makes the defaults in the document explicit. The servers default to
+// '/', the parameters of a path item are moved to its operations (unless overridden
+// there), and the parameter defaults are set, see expandParameterDefaults.
+func expandDefaults(doc map[string]interface{}) {
+	if servers, _ := doc["servers"].([]interface{}); len(servers) == 0 {
+		doc["servers"] = []interface{}{map[string]interface{}{"url": "/"}}
+	}
+
+	paths, _ := doc["paths"].(map[string]interface{})
+	for _, p := range paths {
+		pathItem, ok := p.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		pathParameters, _ := pathItem["parameters"].([]interface{})
+		delete(pathItem, "parameters")
+		for _, method := range operationKeys {
+			operation, ok := pathItem[method].(map[string]interface{})
+			if !ok {
+				continue
+			}
+			parameters, _ := operation["parameters"].([]interface{})
+			overridden := make(map[string]bool, len(parameters))
+			for _, param := range parameters {
+				overridden[parameterKey(param)] = true
+			}
+			for _, param := range pathParameters {
+				if !overridden[parameterKey(param)] {
+					parameters = append(parameters, deepCopy(param))
+				}
+			}
+			sort.SliceStable(parameters, func(i, j int) bool {
+				return parameterKey(parameters[i]) < parameterKey(parameters[j])
+			})
+			expandParameterDefaults(parameters)
+			if len(parameters) > 0 {
+				operation["parameters"] = parameters
+			}
+			if _, found := operation["deprecated"]; !found {
+				operation["deprecated"] = false
+			}
+			if body, ok := operation["requestBody"].(map[string]interface{}); ok && body["required"] == nil {
+				body["required"] = false
+			}
+		}
+	}
+}
+
+// Normalize returns the canonical form of an OpenAPI spec (JSON or YAML); with the local
+// references resolved, the defaults made explicit, and the parameters sorted. Serialized
+// with sorted keys, specs with the same meaning result in the same output. Any errors
+// are returned as ConversionErrors.
+func Normalize(content *[]byte) (map[string]interface{}, error) {
+	jsonContent, err := yaml.YAMLToJSON(*content)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing OAS3 file: [%w]", err)
+	}
+	var doc map[string]interface{}
+	if err = json.Unmarshal(jsonContent, &doc); err != nil {
+		return nil, fmt.Errorf("error parsing OAS3 file: [%w]", err)
+	}
+
+	// resolve against the original document, so references are resolved the same way,
+	// regardless of the order in which the objects are visited
+	original := deepCopyObject(doc)
+	var errs ConversionErrors
+	resolveRefs(doc, "", original, make(map[string]bool), &errs)
+	if len(errs) > 0 {
+		sort.SliceStable(errs, func(i, j int) bool {
+			return errs[i].Pointer < errs[j].Pointer
+		})
+		return nil, errs
+	}
+	expandDefaults(doc)
+	return doc, nil
+}
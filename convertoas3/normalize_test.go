@@ -0,0 +1,100 @@
+package convertoas3
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Normalize(t *testing.T) {
+	content := []byte(`
+openapi: 3.0.3
+info:
+  title: Learn Service
+  version: 1.0.0
+paths:
+  /tracks/{id}:
+    parameters:
+      - name: id
+        in: path
+        schema:
+          type: string
+      - $ref: '#/components/parameters/Limit'
+    get:
+      parameters:
+        - name: limit
+          in: query
+          required: true
+      x-kong-plugin-key-auth:
+        $ref: '#/components/x-kong/auth'
+      responses:
+        "200":
+          description: OK
+          content:
+            application/json:
+              schema:
+                $ref: '#/components/schemas/Track'
+components:
+  parameters:
+    Limit:
+      name: limit
+      in: query
+  schemas:
+    Track:
+      type: object
+      properties:
+        next:
+          $ref: '#/components/schemas/Track'
+  x-kong:
+    auth:
+      config:
+        key_names: [apikey]
+`)
+	doc, err := Normalize(&content)
+	require.NoError(t, err)
+
+	assert.Equal(t, []interface{}{map[string]interface{}{"url": "/"}}, doc["servers"])
+	pathItem := doc["paths"].(map[string]interface{})["/tracks/{id}"].(map[string]interface{})
+	assert.NotContains(t, pathItem, "parameters")
+
+	get := pathItem["get"].(map[string]interface{})
+	assert.Equal(t, false, get["deprecated"])
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{
+			"name": "id", "in": "path", "required": true, "style": "simple",
+			"schema": map[string]interface{}{"type": "string"},
+		},
+		map[string]interface{}{
+			"name": "limit", "in": "query", "required": true, "style": "form",
+		},
+	}, get["parameters"])
+	assert.Equal(t, map[string]interface{}{
+		"config": map[string]interface{}{"key_names": []interface{}{"apikey"}},
+	}, get["x-kong-plugin-key-auth"])
+
+	// the recursive reference is retained
+	response := get["responses"].(map[string]interface{})["200"].(map[string]interface{})
+	mediaType := response["content"].(map[string]interface{})["application/json"].(map[string]interface{})
+	schema := mediaType["schema"].(map[string]interface{})
+	assert.Equal(t, map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"next": map[string]interface{}{"$ref": "#/components/schemas/Track"},
+		},
+	}, schema)
+}
+
+func Test_NormalizeErrors(t *testing.T) {
+	content := []byte(`
+openapi: 3.0.3
+paths:
+  /tracks:
+    get:
+      parameters:
+        - $ref: '#/components/parameters/Missing'
+`)
+	_, err := Normalize(&content)
+	assert.EqualError(t, err,
+		"/paths/~1tracks/get/parameters/0/$ref: reference '#/components/parameters/Missing' not found")
+}
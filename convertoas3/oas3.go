@@ -1,12 +1,17 @@
 package convertoas3
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
-	"regexp"
+	"runtime"
 	"sort"
 	"strings"
+	"sync"
 
 	"github.com/getkin/kin-openapi/openapi3"
 	"github.com/mozillazg/go-slugify"
@@ -16,13 +21,147 @@ import (
 const (
 	formatVersionKey   = "_format_version"
 	formatVersionValue = "3.0"
+	workspaceKey       = "_workspace"
+	infoKey            = "_info"
+
+	maxRegexCaptureLength = 32 // PCRE limit for the length of named captures
 )
 
+// SupportedOpenAPIVersions are the (major.minor) OpenAPI versions accepted as input.
+var SupportedOpenAPIVersions = []string{"3.0"}
+
+// PartiallySupportedOpenAPIVersions are the (major.minor) OpenAPI versions accepted as
+// input, but parsed as OpenAPI 3.0. Their webhooks are converted, but schema constructs
+// only they have are not; type arrays fail the conversion, and keywords like 'const'
+// are passed on to the request-validator, which ignores them.
+var PartiallySupportedOpenAPIVersions = []string{"3.1"}
+
+// SupportedFormatVersions are the decK '_format_version' values generated as output.
+var SupportedFormatVersions = []string{formatVersionValue, legacyFormatVersion}
+
 // O2KOptions defines the options for an O2K conversion operation
 type O2kOptions struct {
 	Tags          *[]string // Array of tags to mark all generated entities with, taken from 'x-kong-tags' if omitted.
-	DocName       string    // Base document name, will be taken from x-kong-name, or info.title (for UUID generation!)
+	DocName       string    // Base document name, from x-kong-name, or info.title (for UUIDs, unless IDSeed is set!)
 	UUIDNamespace uuid.UUID // Namespace for UUID generation, defaults to DNS namespace for UUID v5
+	PathPrefix    string    // Prefix to add to all route paths, takes precedence over PathPrefixFromVersion
+	// PathPrefixFromVersion, if set, prefixes all route paths with '/v{major}', where
+	// the major version is taken from info.version. Ignored if PathPrefix is given.
+	PathPrefixFromVersion bool
+	PlainPaths            bool // Use plain (prefix) paths instead of regexes, requires PrefixMatch
+	PrefixMatch           bool // Do not anchor regex paths with a '$', so they match as a prefix
+	// TrailingSlash controls matching paths with and without a trailing slash, eg. '/users'
+	// and '/users/'; TrailingSlashStrict (default) matches the path as given only,
+	// TrailingSlashOptional matches both using a single route, and TrailingSlashDuplicate
+	// generates an additional route (named with a '~' suffix) for the other variant.
+	TrailingSlash string
+	// HeadForGet, if set, adds the HEAD method to the routes of GET operations, unless the
+	// path defines a HEAD operation, or the GET operation has a request body.
+	HeadForGet bool
+	// CORSPreflight, if set, adds the OPTIONS method to a route for each path that has
+	// a 'cors' plugin configured (on the route or its service), so preflight requests
+	// will match. Unless the path already has an OPTIONS operation.
+	CORSPreflight bool
+	// Timeouts (in ms) and retries to set on every generated service, unless overridden
+	// by x-kong-service-defaults. Timeouts of 0, and a nil Retries, are not set.
+	ConnectTimeout int
+	ReadTimeout    int
+	WriteTimeout   int
+	Retries        *int
+	// Load balancing settings for every generated upstream, unless overridden by
+	// x-kong-upstream-defaults. The hash options are specified as "type[:input]",
+	// eg. "header:X-User-Id" or "ip".
+	UpstreamAlgorithm    string
+	UpstreamHashOn       string
+	UpstreamHashFallback string
+	// UpstreamHostHeader, if set, sets the 'host_header' of generated upstreams to the
+	// hostname of the (first) server, unless set in x-kong-upstream-defaults. Otherwise
+	// the backend receives the upstream name as the hostname.
+	UpstreamHostHeader bool
+	// PluginInheritance is the strategy for attaching the plugins inherited from the
+	// document and path levels; PluginInheritanceMixed (default), PluginInheritanceService
+	// to attach them once to the services, or PluginInheritanceRoute to attach them to
+	// each route.
+	PluginInheritance string
+	// KeepDuplicatePlugins, if set, keeps plugins that all routes of a service have with the
+	// same config on each route. Otherwise they are moved to the service, unless the service
+	// has a plugin with the same name. Ignored with PluginInheritanceRoute.
+	KeepDuplicatePlugins bool
+	// PluginTier is the Kong edition targeted; PluginTierOSS or PluginTierEnterprise. When
+	// targeting OSS, the conversion fails if Enterprise-only plugins are used.
+	PluginTier string
+	// AllowedPlugins, if set, are the only plugins the output may have, and DeniedPlugins the
+	// plugins it may not have, eg. 'pre-function'. Plugins given in the spec, and those
+	// generated, are checked; the conversion fails listing the plugins not allowed.
+	AllowedPlugins []string
+	DeniedPlugins  []string
+	Workspace      string // Kong Enterprise workspace for the output, taken from 'x-kong-workspace' if omitted
+	// Secrets is the handling of plugin config fields named like secrets, eg. 'client_secret'
+	// or 'redis_password', and consumer keys, with literal values instead of vault
+	// references; SecretsWarn (default), SecretsFail, or SecretsIgnore.
+	Secrets string
+	// KongVersion, if set, is the Kong version targeted, eg. "2.8" or "3.4". Output features
+	// are adapted to it, eg. regex paths for Kong 2.x, and the conversion fails if the spec
+	// requires features it does not support, eg. filter chains before Kong 3.4.
+	KongVersion string
+	// ACLSource, if set, generates an 'acl' plugin on each route, allowing the groups
+	// derived from the operation; ACLFromTags or ACLFromScopes. An 'acl' plugin does
+	// nothing without an authentication plugin to identify the consumer.
+	ACLSource string
+	Logger    Logger // Logger for conversion diagnostics, defaults to discarding all messages
+	// BestEffort, if set, skips paths and operations that fail to convert, and logs them as
+	// warnings, instead of failing the conversion. Errors on the document level still fail.
+	BestEffort bool
+	// StrictExtensions, if set, fails on unrecognized 'x-kong-...' extensions (most likely
+	// typos), instead of logging a warning and ignoring them.
+	StrictExtensions bool
+	// ValidateSpec, if set, validates the spec against the OpenAPI specification before
+	// converting, so structurally invalid specs fail instead of generating a wrong config.
+	ValidateSpec bool
+	// Workers is the number of paths converted concurrently, defaults to the number of
+	// CPUs. The output does not depend on it. A custom Logger must be safe for concurrent
+	// use when converting with more than 1 worker.
+	Workers int
+	// Generator, if set, is added to the output as '_info.generator', to identify the
+	// converter (and its version) that produced it.
+	Generator string
+	// EnvironmentVariable, if set, is the name of a server variable with an enum of
+	// environments, eg. 'env' in 'https://{env}.api.example.com'. A set of entities is
+	// generated per enum value, named after and tagged '<variable>:<value>'.
+	EnvironmentVariable string
+	// ProvenanceTags, if set, tags each route with the spec element it was generated from;
+	// 'oas-doc:<name>@<version>', 'oas-path:<path>', and 'oas-operation:<operationId>'.
+	ProvenanceTags bool
+	// SecurityPlugins, if set, generates authentication plugins on each route from the
+	// 'security' requirements of the operation; 'key-auth' for apiKey schemes, 'basic-auth'
+	// for http basic schemes, 'jwt' or 'openid-connect' for http bearer JWT schemes, and
+	// 'openid-connect' for openIdConnect schemes. Alternative requirements are chained using an
+	// anonymous consumer, and a 'request-termination' plugin rejecting it. Plugins
+	// given in 'x-kong-plugin-...' take precedence. Operations opting out of security,
+	// using 'security: []', get no inherited authentication (and 'acl') plugins.
+	SecurityPlugins bool
+	// ResponseHeaders, if set, generates a 'response-transformer' plugin on each route
+	// setting the static headers of the success responses; headers with a schema
+	// enumerating a single value, eg. a 'Cache-Control' header. Plugins given in
+	// 'x-kong-plugin-...' take precedence.
+	ResponseHeaders bool
+	// Callbacks, if set, generates a service for each callback URL of the operations, with
+	// a route for each callback operation, so the calls to the callback receivers can be
+	// sent through Kong. Callback URLs without a static server, eg.
+	// '{$request.body#/callbackUrl}', are skipped with a warning.
+	Callbacks bool
+	// WebhookServer, if set, is the URL of the service receiving the webhooks of OpenAPI 3.1
+	// documents. Each webhook operation becomes a route '/webhooks/<name>' on it, with a
+	// request-validator plugin built from the request body. Otherwise the webhooks are
+	// ignored, with a warning.
+	WebhookServer string
+	// IDSeed, if set, is used instead of the document name to generate the IDs, taken from
+	// 'x-kong-id-seed' if omitted. So the IDs remain the same when the document is renamed.
+	IDSeed string
+	// Components, if set, are shared 'x-kong' components, eg. maintained centrally in a
+	// components file, see ParseComponents. References '#/components/x-kong/...' resolve
+	// to them as well, merged with the spec's own '/components/x-kong', which takes precedence.
+	Components map[string]interface{}
 }
 
 // setDefaults sets the defaults for ConvertOas3 operation.
@@ -31,6 +170,12 @@ func (opts *O2kOptions) setDefaults() {
 	if uuid.Equal(emptyUUID, opts.UUIDNamespace) {
 		opts.UUIDNamespace = uuid.NamespaceDNS
 	}
+	if opts.Logger == nil {
+		opts.Logger = nopLogger{}
+	}
+	if opts.Workers == 0 {
+		opts.Workers = runtime.GOMAXPROCS(0)
+	}
 }
 
 // Slugify converts a name to a valid Kong name by removing and replacing unallowed characters
@@ -43,15 +188,41 @@ func Slugify(name ...string) string {
 	return strings.Join(name, "_")
 }
 
+// hashName returns a name derived from the content, for when no name is available.
+// The name is a valid Kong name, and stable for the same content.
+func hashName(content []byte) string {
+	hash := sha256.Sum256(content)
+	return "oas-" + hex.EncodeToString(hash[:])[:12]
+}
+
+// slugOrHash returns the slug of the name. If nothing is left of a non-empty name after
+// slugifying, eg. an emoji-only name, it returns a name derived from a hash of the name
+// instead, and true.
+func slugOrHash(name string) (string, bool) {
+	if slug := Slugify(name); slug != "" || name == "" {
+		return slug, false
+	}
+	return hashName([]byte(name)), true
+}
+
+// unusableNameWarning is logged when a name has no characters usable in a Kong name.
+const unusableNameWarning = "name has no characters usable in a Kong name, using a hash of it"
+
 // sanitizeRegexCapture will remove illegal characters from the path-variable name.
 // The returned name will be valid for PCRE regex captures; Alphanumeric + '_', starting
-// with [a-zA-Z].
+// with [a-zA-Z], and at most 32 characters long.
 func sanitizeRegexCapture(varName string) string {
 	varName = slugify.Slugify(varName)
 	varName = strings.ReplaceAll(varName, "-", "_")
-	if strings.HasPrefix(varName, "_") {
+	if varName == "" {
+		varName = "param"
+	}
+	if c := varName[0]; !(c >= 'a' && c <= 'z') && !(c >= 'A' && c <= 'Z') {
 		varName = "a" + varName
 	}
+	if len(varName) > maxRegexCaptureLength {
+		varName = varName[:maxRegexCaptureLength]
+	}
 	return varName
 }
 
@@ -70,7 +241,7 @@ func getKongTags(doc *openapi3.T, tagsProvided *[]string) ([]string, error) {
 	}
 
 	var tagsValue interface{}
-	err := json.Unmarshal(doc.ExtensionProps.Extensions["x-kong-tags"].(json.RawMessage), &tagsValue)
+	err := decodeExtension(doc.ExtensionProps.Extensions["x-kong-tags"], &tagsValue)
 	if err != nil {
 		return nil, fmt.Errorf("expected 'x-kong-tags' to be an array of strings: %w", err)
 	}
@@ -99,7 +270,7 @@ func getKongTags(doc *openapi3.T, tagsProvided *[]string) ([]string, error) {
 func getKongName(props openapi3.ExtensionProps) (string, error) {
 	if props.Extensions != nil && props.Extensions["x-kong-name"] != nil {
 		var name string
-		err := json.Unmarshal(props.Extensions["x-kong-name"].(json.RawMessage), &name)
+		err := decodeExtension(props.Extensions["x-kong-name"], &name)
 		if err != nil {
 			return "", fmt.Errorf("expected 'x-kong-name' to be a string: %w", err)
 		}
@@ -108,47 +279,101 @@ func getKongName(props openapi3.ExtensionProps) (string, error) {
 	return "", nil
 }
 
-func dereferenceJSONObject(
-	value map[string]interface{},
-	components *map[string]interface{},
-) (map[string]interface{}, error) {
-	var pointer string
-
-	switch value["$ref"].(type) {
-	case nil: // it is not a reference, so return the object
-		return value, nil
+// getKongWorkspace returns the provided workspace or if empty, then the `x-kong-workspace`
+// property, validated to be a string. Returns "" if there is none.
+func getKongWorkspace(doc *openapi3.T, workspaceProvided string) (string, error) {
+	if workspaceProvided != "" {
+		return workspaceProvided, nil
+	}
 
-	case string: // it is a json pointer
-		pointer = value["$ref"].(string)
-		if !strings.HasPrefix(pointer, "#/components/x-kong/") {
-			return nil, fmt.Errorf("all 'x-kong-...' references must be at '#/components/x-kong/...'")
-		}
+	if doc.ExtensionProps.Extensions == nil || doc.ExtensionProps.Extensions["x-kong-workspace"] == nil {
+		return "", nil
+	}
 
-	default: // bad pointer
-		return nil, fmt.Errorf("expected '$ref' pointer to be a string")
+	var workspace string
+	err := decodeExtension(doc.ExtensionProps.Extensions["x-kong-workspace"], &workspace)
+	if err != nil {
+		return "", fmt.Errorf("expected 'x-kong-workspace' to be a string: %w", err)
 	}
+	return workspace, nil
+}
 
-	// walk the tree to find the reference
-	segments := strings.Split(pointer, "/")
-	path := "#/components/x-kong"
-	result := components
+// getVersionHeader returns the header name from the `x-kong-version-header` property,
+// validated to be a string. If set, the document must also have an info.version, since
+// that is used as the value to match on. Returns "" if not set.
+func getVersionHeader(doc *openapi3.T) (string, error) {
+	if doc.ExtensionProps.Extensions == nil || doc.ExtensionProps.Extensions["x-kong-version-header"] == nil {
+		return "", nil
+	}
 
-	for i := 3; i < len(segments); i++ {
-		segment := segments[i]
-		path = path + "/" + segment
+	var name string
+	err := decodeExtension(doc.ExtensionProps.Extensions["x-kong-version-header"], &name)
+	if err != nil {
+		return "", fmt.Errorf("expected 'x-kong-version-header' to be a string: %w", err)
+	}
+	if name == "" {
+		return "", fmt.Errorf("expected 'x-kong-version-header' to be a non-empty string")
+	}
+	if doc.Info == nil || doc.Info.Version == "" {
+		return "", fmt.Errorf("'x-kong-version-header' requires 'info.version' to be set")
+	}
+	return name, nil
+}
 
-		switch (*result)[segment].(type) {
-		case nil:
-			return nil, fmt.Errorf("reference '%s' not found", pointer)
-		case map[string]interface{}:
-			target := (*result)[segment].(map[string]interface{})
-			result = &target
-		default:
-			return nil, fmt.Errorf("expected '%s' to be a JSON object", path)
+// getPathPrefix returns the prefix to add to all route paths. Either the one
+// provided, or generated from 'info.version' if requested. The returned prefix
+// will start with a '/', and will not have a trailing '/'. Returns "" if there
+// is no prefix.
+func getPathPrefix(doc *openapi3.T, opts O2kOptions) (string, error) {
+	prefix := opts.PathPrefix
+	if prefix == "" && opts.PathPrefixFromVersion {
+		if doc.Info == nil || doc.Info.Version == "" {
+			return "", fmt.Errorf("cannot create path prefix from version; 'info.version' is not set")
+		}
+		major := strings.TrimLeft(doc.Info.Version, "vV")
+		if i := strings.IndexFunc(major, func(r rune) bool { return r < '0' || r > '9' }); i != -1 {
+			major = major[:i]
 		}
+		if major == "" {
+			return "", fmt.Errorf("cannot create path prefix from version; no major version found in '%s'",
+				doc.Info.Version)
+		}
+		prefix = "v" + major
+	}
+
+	prefix = strings.Trim(prefix, "/")
+	if prefix == "" {
+		return "", nil
 	}
+	return "/" + prefix, nil
+}
+
+// setRouteHeader adds a header matcher to the route, keeping any other headers
+// that were already set (eg. from route-defaults).
+func setRouteHeader(route map[string]interface{}, name string, value string) {
+	headers, _ := toJSONObject(route["headers"])
+	if headers == nil {
+		headers = make(map[string]interface{})
+	}
+	headers[name] = []string{value}
+	route["headers"] = headers
+}
 
-	return *result, nil
+// dereferenceJSONObject returns the object with its references resolved, see
+// dereferenceJSONValue. If the object is a '$ref' itself, it must refer to an object.
+func dereferenceJSONObject(
+	value map[string]interface{},
+	components *map[string]interface{},
+) (map[string]interface{}, error) {
+	resolved, err := dereferenceJSONValue(value, components)
+	if err != nil {
+		return nil, err
+	}
+	object, ok := resolved.(map[string]interface{})
+	if !ok {
+		return nil, &pointerError{"/$ref", fmt.Errorf("expected '%s' to be a JSON object", value["$ref"])}
+	}
+	return object, nil
 }
 
 func toJSONObject(object interface{}) (map[string]interface{}, error) {
@@ -166,15 +391,18 @@ func toJSONObject(object interface{}) (map[string]interface{}, error) {
 func getXKongObject(props openapi3.ExtensionProps, key string, components *map[string]interface{}) ([]byte, error) {
 	if props.Extensions != nil && props.Extensions[key] != nil {
 		var jsonBlob interface{}
-		_ = json.Unmarshal(props.Extensions[key].(json.RawMessage), &jsonBlob)
+		err := decodeExtension(props.Extensions[key], &jsonBlob)
+		if err != nil {
+			return nil, &pointerError{jsonPointer(key), fmt.Errorf("expected '%s' to be a JSON object: %w", key, err)}
+		}
 		jsonObject, err := toJSONObject(jsonBlob)
 		if err != nil {
-			return nil, fmt.Errorf("expected '%s' to be a JSON object", key)
+			return nil, &pointerError{jsonPointer(key), fmt.Errorf("expected '%s' to be a JSON object", key)}
 		}
 
 		object, err := dereferenceJSONObject(jsonObject, components)
 		if err != nil {
-			return nil, err
+			return nil, prefixPointer(jsonPointer(key), err)
 		}
 		return json.Marshal(object)
 	}
@@ -194,7 +422,9 @@ func getXKongComponents(doc *openapi3.T) (*map[string]interface{}, error) {
 	default:
 		// we got some json blob
 		var xKong interface{}
-		_ = json.Unmarshal(prop.(json.RawMessage), &xKong)
+		if err := decodeExtension(prop, &xKong); err != nil {
+			return nil, fmt.Errorf("expected '/components/x-kong' to be a JSON object: %w", err)
+		}
 
 		switch val := xKong.(type) {
 		case map[string]interface{}:
@@ -241,32 +471,27 @@ func getPluginsList(
 	components *map[string]interface{},
 	tags []string,
 ) (*[]*map[string]interface{}, error) {
-	plugins := make(map[string]*map[string]interface{})
+	plugins := newPluginSet(nil)
 
 	// copy inherited list of plugins
 	if pluginsToInclude != nil {
 		for _, config := range *pluginsToInclude {
-			pluginName := (*config)["name"].(string) // safe because it was previously parsed
-
-			// serialize/deserialize to create a deep-copy
-			var configCopy map[string]interface{}
-			jConf, _ := json.Marshal(config)
-			_ = json.Unmarshal(jConf, &configCopy)
+			configCopy := deepCopyObject(*config)
 
 			// generate a new ID, for a new plugin, based on new basename
 			configCopy["id"] = createPluginID(uuidNamespace, baseName, configCopy)
 
 			configCopy["tags"] = tags
 
-			plugins[pluginName] = &configCopy
+			plugins.insert(&configCopy)
 		}
 	}
 
 	if props.Extensions != nil {
 		// there are extensions, go check if there are plugins
 		for extensionName := range props.Extensions {
-			if strings.HasPrefix(extensionName, "x-kong-plugin-") {
-				pluginName := strings.TrimPrefix(extensionName, "x-kong-plugin-")
+			if strings.HasPrefix(extensionName, pluginPrefix) {
+				pluginName := strings.TrimPrefix(extensionName, pluginPrefix)
 
 				jsonstr, err := getXKongObject(props, extensionName, components)
 				if err != nil {
@@ -276,82 +501,60 @@ func getPluginsList(
 				var pluginConfig map[string]interface{}
 				err = json.Unmarshal(jsonstr, &pluginConfig)
 				if err != nil {
-					return nil, fmt.Errorf(fmt.Sprintf("failed to parse JSON object for '%s': %%w", extensionName), err)
+					return nil, &pointerError{jsonPointer(extensionName),
+						fmt.Errorf(fmt.Sprintf("failed to parse JSON object for '%s': %%w", extensionName), err)}
+				}
+
+				// the name is given by the extension name
+				if name, found := pluginConfig["name"]; found && name != pluginName {
+					return nil, &pointerError{jsonPointer(extensionName, "name"),
+						fmt.Errorf("expected '%s/name' to be '%s', got: '%v'", extensionName, pluginName, name)}
+				}
+				// foreign keys to service+route are not allowed (consumer is allowed)
+				if err = validatePluginFields(pluginConfig, extensionName, "service", "route"); err != nil {
+					return nil, &pointerError{jsonPointer(extensionName), err}
 				}
 
 				pluginConfig["name"] = pluginName
 				pluginConfig["id"] = createPluginID(uuidNamespace, baseName, pluginConfig)
 				pluginConfig["tags"] = tags
 
-				// foreign keys to service+route are not allowed (consumer is allowed)
-				delete(pluginConfig, "service")
-				delete(pluginConfig, "route")
-
-				plugins[pluginName] = &pluginConfig
+				plugins.insert(&pluginConfig)
 			}
 		}
 	}
 
-	// the list is complete, sort to be deterministic in the output
-	sortedNames := make([]string, len(plugins))
-	i := 0
-	for pluginName := range plugins {
-		sortedNames[i] = pluginName
-		i++
-	}
-	sort.Strings(sortedNames)
-
-	sorted := make([]*map[string]interface{}, len(plugins))
-	for i, pluginName := range sortedNames {
-		sorted[i] = plugins[pluginName]
-	}
-	return &sorted, nil
+	return plugins.list(), nil
 }
 
 // getValidatorPlugin will remove the request validator config from the plugin list
 // and return it as a JSON string, along with the updated plugin list. If there
 // is none, the returned config will be the currentConfig.
 func getValidatorPlugin(list *[]*map[string]interface{}, currentConfig []byte) ([]byte, *[]*map[string]interface{}) {
-	for i, plugin := range *list {
-		pluginName := (*plugin)["name"].(string) // safe because it was previously parsed
-		if pluginName == "request-validator" {
-			// found it. Serialize to JSON and remove from list
-			jsonConfig, _ := json.Marshal(plugin)
-			l := append((*list)[:i], (*list)[i+1:]...)
-			return jsonConfig, &l
-		}
-	}
-
-	// no validator config found, so current config remains valid
-	return currentConfig, list
-}
-
-// insertPlugin will insert a plugin in the list array, in a sorted manner.
-// List must already be sorted by plugin-name.
-func insertPlugin(list *[]*map[string]interface{}, plugin *map[string]interface{}) *[]*map[string]interface{} {
+	plugins := newPluginSet(list)
+	plugin := plugins.remove("request-validator")
 	if plugin == nil {
-		return list
+		// no validator config found, so current config remains valid
+		return currentConfig, list
 	}
 
-	newPluginName := (*plugin)["name"].(string) // safe because it was previously parsed
+	// found it. Serialize to JSON and return the list without it
+	jsonConfig, _ := json.Marshal(plugin)
+	return jsonConfig, plugins.list()
+}
 
-	for i, config := range *list {
-		pluginName := (*config)["name"].(string) // safe because it was previously parsed
-		if pluginName > newPluginName {
-			l := (*list)[:i-1]
-			l = append(l, config)
-			l = append(l, (*list)[:i]...)
-			return &l
-		}
+// hasPlugin returns true if the plugin list contains a plugin with the given name.
+func hasPlugin(list interface{}, name string) bool {
+	plugins, ok := list.(*[]*map[string]interface{})
+	if !ok || plugins == nil {
+		return false
 	}
-
-	// it's the last one, append it
-	l := append(*list, plugin)
-	return &l
+	return newPluginSet(plugins).get(name) != nil
 }
 
 // getForeignKeyPlugins checks the pluginList for plugins that also have a foreign key
-// for a consumer, and moves them to the docPlugins array. Returns update docPlugins and pluginList.
+// for a consumer or consumer group, and moves them to the docPlugins array. Returns
+// update docPlugins and pluginList.
 func getForeignKeyPlugins(
 	docPlugins *[]*map[string]interface{},
 	pluginList *[]*map[string]interface{},
@@ -373,7 +576,7 @@ func getForeignKeyPlugins(
 
 	newPluginList := make([]*map[string]interface{}, 0)
 	for _, plugin := range entityPlugins {
-		if (*plugin)["consumer"] == nil {
+		if (*plugin)["consumer"] == nil && (*plugin)["consumer_group"] == nil {
 			// single key, so leave it, just append to outgoing slice
 			newPluginList = append(newPluginList, plugin)
 		} else {
@@ -385,32 +588,77 @@ func getForeignKeyPlugins(
 	return &genericPlugins, &newPluginList
 }
 
+// pathConversion holds the entities generated for a single path. Paths are converted
+// concurrently, and the results merged in order of the paths.
+type pathConversion struct {
+	services          []interface{}              // new path and operation level services
+	upstreams         []interface{}              // new path and operation level upstreams
+	docRoutes         []interface{}              // routes to add to the doc-level service
+	foreignKeyPlugins *[]*map[string]interface{} // plugins with multiple foreign keys
+	skipped           ConversionErrors           // errors in the path or its operations
+	routeCount        int                        // number of routes generated
+	docValidatorUsed  bool                       // an operation inherited the doc-level request-validator
+	anonymous         bool                       // the anonymous consumer is used by security plugins
+	locations         entityLocations            // the spec elements the entities were generated from
+}
+
 // MustConvert is the same as Convert, but will panic if an error is returned.
-func MustConvert(content *[]byte, opts O2kOptions) map[string]interface{} {
-	result, err := Convert(content, opts)
+//
+// Deprecated: exits the program on errors, use Convert, or the convert package.
+func MustConvert(ctx context.Context, content *[]byte, opts O2kOptions) map[string]interface{} {
+	result, err := Convert(ctx, content, opts)
 	if err != nil {
 		log.Fatal(err)
 	}
 	return result
 }
 
-// Convert converts an OpenAPI spec to a Kong declarative file.
-func Convert(content *[]byte, opts O2kOptions) (map[string]interface{}, error) {
+// Convert converts an OpenAPI spec to a Kong declarative file. The context is checked
+// between paths and operations, so long conversions can be cancelled or timed out.
+// Loading the spec does no I/O, as external references are not allowed.
+// Errors in the spec do not stop the conversion at the first one; all errors found are
+// returned as ConversionErrors, each with a JSON pointer to its location in the spec,
+// and its line and column.
+func Convert(ctx context.Context, content *[]byte, opts O2kOptions) (map[string]interface{}, error) {
+	result, err := convert(ctx, content, opts)
+	var errs ConversionErrors
+	if errors.As(err, &errs) {
+		errs.Locate(*content, "")
+	}
+	return result, err
+}
+
+// convert is Convert, without locating the errors.
+func convert(ctx context.Context, content *[]byte, opts O2kOptions) (map[string]interface{}, error) {
 	opts.setDefaults()
+	if err := opts.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid options: %w", err)
+	}
+	if opts.EnvironmentVariable != "" {
+		return convertEnvironments(ctx, content, opts)
+	}
 
 	// set up output document
 	result := make(map[string]interface{})
 	result[formatVersionKey] = formatVersionValue
+	if opts.Generator != "" {
+		result[infoKey] = map[string]interface{}{"generator": opts.Generator}
+	}
 	services := make([]interface{}, 0)
 	upstreams := make([]interface{}, 0)
 
 	var (
 		err            error
+		errs           ConversionErrors        // all errors found in the spec
+		skipped        ConversionErrors        // errors in paths/operations, skipped if BestEffort
 		doc            *openapi3.T             // the OAS3 document we're operating on
 		kongComponents *map[string]interface{} // contents of OAS key `/components/x-kong/`
 		kongTags       []string                // tags to attach to Kong entities
+		versionHeader  string                  // header name to match the API version on, if any
+		pathPrefix     string                  // prefix to add to all route paths, if any
 
 		docBaseName         string                     // the slugified basename for the document
+		docDisplayName      string                     // the slugified document name, if docBaseName is the ID seed
 		docServers          *openapi3.Servers          // servers block on document level
 		docServiceDefaults  []byte                     // JSON string representation of service-defaults on document level
 		docService          map[string]interface{}     // service entity in use on document level
@@ -420,34 +668,22 @@ func Convert(content *[]byte, opts O2kOptions) (map[string]interface{}, error) {
 		docPluginList       *[]*map[string]interface{} // array of plugin configs, sorted by plugin name
 		docValidatorConfig  []byte                     // JSON string representation of validator config to generate
 		foreignKeyPlugins   *[]*map[string]interface{} // top-level array of plugin configs, sorted by plugin name+id
-
-		pathBaseName         string                     // the slugified basename for the path
-		pathServers          *openapi3.Servers          // servers block on current path level
-		pathServiceDefaults  []byte                     // JSON string representation of service-defaults on path level
-		pathService          map[string]interface{}     // service entity in use on path level
-		pathUpstreamDefaults []byte                     // JSON string representation of upstream-defaults on path level
-		pathUpstream         map[string]interface{}     // upstream entity in use on path level
-		pathRouteDefaults    []byte                     // JSON string representation of route-defaults on path level
-		pathPluginList       *[]*map[string]interface{} // array of plugin configs, sorted by plugin name
-		pathValidatorConfig  []byte                     // JSON string representation of validator config to generate
-
-		operationBaseName         string                     // the slugified basename for the operation
-		operationServers          *openapi3.Servers          // servers block on current operation level
-		operationServiceDefaults  []byte                     // JSON string representation of service-defaults on ops level
-		operationService          map[string]interface{}     // service entity in use on operation level
-		operationUpstreamDefaults []byte                     // JSON string representation of upstream-defaults on ops level
-		operationUpstream         map[string]interface{}     // upstream entity in use on operation level
-		operationRouteDefaults    []byte                     // JSON string representation of route-defaults on ops level
-		operationPluginList       *[]*map[string]interface{} // array of plugin configs, sorted by plugin name
-		operationValidatorConfig  []byte                     // JSON string representation of validator config to generate
 	)
 
+	routeCount := 0               // number of routes generated, for reporting
+	var locations entityLocations // the spec elements the entities were generated from
+
 	// Load and parse the OAS file
 	loader := openapi3.NewLoader()
 	doc, err = loader.LoadFromData(*content)
 	if err != nil {
 		return nil, fmt.Errorf("error parsing OAS3 file: [%w]", err)
 	}
+	if opts.ValidateSpec {
+		if errs = validateDocument(ctx, doc); len(errs) > 0 {
+			return nil, errs
+		}
+	}
 
 	//
 	//
@@ -455,9 +691,51 @@ func Convert(content *[]byte, opts O2kOptions) (map[string]interface{}, error) {
 	//
 	//
 
+	errs = append(errs, checkExtensions(doc.ExtensionProps, docExtensions, "", opts)...)
+
+	if kongComponents, err = getXKongComponents(doc); err != nil {
+		errs.add("/components/x-kong", err)
+		empty := make(map[string]interface{})
+		kongComponents = &empty
+	}
+	kongComponents = mergeComponents(opts.Components, kongComponents)
+	// resolve the references, so all extensions can refer to shared values
+	resolveExtensionRefs(doc.ExtensionProps.Extensions, "", kongComponents, &errs)
+
 	// collect tags to use
 	if kongTags, err = getKongTags(doc, opts.Tags); err != nil {
-		return nil, err
+		errs.add("/x-kong-tags", err)
+	}
+
+	// set the workspace to deploy to
+	workspace, err := getKongWorkspace(doc, opts.Workspace)
+	if err != nil {
+		errs.add("/x-kong-workspace", err)
+	}
+	if workspace != "" {
+		result[workspaceKey] = workspace
+	}
+
+	// collect the header for version based routing
+	if versionHeader, err = getVersionHeader(doc); err != nil {
+		errs.add("/x-kong-version-header", err)
+	}
+
+	// collect the prefix for all route paths
+	if pathPrefix, err = getPathPrefix(doc, opts); err != nil {
+		errs.add("/info/version", err)
+	}
+
+	// collect the maximum request body size, inherited by all operations
+	docBodySize, err := getMaxBodySize(doc.ExtensionProps)
+	if err != nil {
+		errs.add("/"+maxBodySizeExtension, err)
+	}
+
+	// collect the WASM filter chain of the document service
+	docFilterChain, err := getFilterChain(doc.ExtensionProps)
+	if err != nil {
+		errs.add("/"+filterChainsExtension, err)
 	}
 
 	// set document level elements
@@ -465,46 +743,96 @@ func Convert(content *[]byte, opts O2kOptions) (map[string]interface{}, error) {
 
 	// determine document name, precedence: specified -> x-kong-name -> Info.Title
 	docBaseName = opts.DocName
+	docNameLocation := "document name option"
 	if docBaseName == "" {
+		docNameLocation = "/x-kong-name"
 		if docBaseName, err = getKongName(doc.ExtensionProps); err != nil {
-			return nil, err
+			errs.add("/x-kong-name", err)
 		}
-		if docBaseName == "" {
+		if docBaseName == "" && doc.Info != nil {
+			docNameLocation = "/info/title"
 			docBaseName = doc.Info.Title
 		}
 	}
-	docBaseName = Slugify(docBaseName)
+	docBaseName, hashed := slugOrHash(docBaseName)
+	if hashed {
+		opts.Logger.Warn(unusableNameWarning, "location", docNameLocation, "name", docBaseName)
+	}
+	if docBaseName == "" {
+		// no usable name, fall back to a name derived from the spec contents
+		docBaseName = hashName(*content)
+		opts.Logger.Warn("no document name in 'x-kong-name' or 'info.title', using a hash of the spec; "+
+			"generated names and IDs will change whenever the spec changes", "name", docBaseName)
+	}
 
-	if kongComponents, err = getXKongComponents(doc); err != nil {
-		return nil, err
+	// with an ID seed, the entities are generated using the seed as document name, and
+	// renamed afterwards, so the IDs do not depend on the document name
+	docDisplayName = docBaseName
+	if idSeed, err := getIDSeed(doc, opts.IDSeed); err != nil {
+		errs.add("/x-kong-id-seed", err)
+	} else if idSeed != "" {
+		docBaseName = Slugify(idSeed)
 	}
 
 	// for defaults we keep strings, so deserializing them provides a copy right away
 	if docServiceDefaults, err = getServiceDefaults(doc.ExtensionProps, kongComponents); err != nil {
-		return nil, err
+		errs.add("", err)
+	} else if docServiceDefaults, err = setServiceOptionDefaults(docServiceDefaults, opts); err != nil {
+		errs.add("/x-kong-service-defaults", err)
 	}
 	if docUpstreamDefaults, err = getUpstreamDefaults(doc.ExtensionProps, kongComponents); err != nil {
-		return nil, err
+		errs.add("", err)
 	}
 	if docRouteDefaults, err = getRouteDefaults(doc.ExtensionProps, kongComponents); err != nil {
-		return nil, err
+		errs.add("", err)
+	}
+	if len(errs) > 0 {
+		// everything below depends on the document level, so no use to continue
+		return nil, errs
+	}
+
+	// the ACL groups of the operations inheriting the document security fail the same way,
+	// so report it once, those operations are skipped without reporting it again
+	if opts.ACLSource != "" {
+		if _, err := getACLGroups(doc, &openapi3.Operation{}, opts.ACLSource); err != nil {
+			skipped.add("/security", fmt.Errorf("failed to create ACL plugins: %w", err))
+		}
 	}
 
 	// create the top-level docService and (optional) docUpstream
-	docService, docUpstream, err = CreateKongService(docBaseName, docServers, docServiceDefaults,
+	serviceDefaults, err := setServerServiceDefaults(docServers, docServiceDefaults, kongComponents)
+	if err != nil {
+		errs.add("/servers", err)
+		return nil, errs
+	}
+	docService, docUpstream, err = CreateKongService(docBaseName, docServers, serviceDefaults,
 		docUpstreamDefaults, kongTags, opts.UUIDNamespace)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create service/upstream from document root: %w", err)
+		errs.add("/servers", fmt.Errorf("failed to create service/upstream from document root: %w", err))
+		return nil, errs
 	}
+	setFilterChain(docService, docFilterChain, opts.UUIDNamespace, docBaseName, kongTags)
 	services = append(services, docService)
+	locations.add("service", docService, "")
+	opts.Logger.Debug("created service", "name", docService["name"])
+	if hasServerWithoutHost(docServers) {
+		opts.Logger.Warn("server without a hostname, defaulting to 'localhost'", "location", "document")
+	}
 	if docUpstream != nil {
+		if err = setUpstreamOptions(docUpstream, docServers, opts); err != nil {
+			errs.add("/x-kong-upstream-defaults", fmt.Errorf("failed to create upstream from document root: %w", err))
+			return nil, errs
+		}
 		upstreams = append(upstreams, docUpstream)
+		locations.add("upstream", docUpstream, "")
+		opts.Logger.Debug("created upstream", "name", docUpstream["name"])
 	}
 
 	// attach plugins
 	docPluginList, err = getPluginsList(doc.ExtensionProps, nil, opts.UUIDNamespace, docBaseName, kongComponents, kongTags)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create plugins list from document root: %w", err)
+		errs.add("", fmt.Errorf("failed to create plugins list from document root: %w", err))
+		return nil, errs
 	}
 
 	// Extract the request-validator config from the plugin list
@@ -516,6 +844,13 @@ func Convert(content *[]byte, opts O2kOptions) (map[string]interface{}, error) {
 
 	docService["plugins"] = docPluginList
 
+	// the generated validator schemas, shared between operations
+	schemas := newSchemaCache()
+
+	// the consumer alternative security requirements use as 'anonymous'
+	anonymous := anonymousConsumer(opts.UUIDNamespace, docBaseName, kongTags)
+	anonymousUsed := false
+
 	//
 	//
 	//  Handle OAS Path level
@@ -531,15 +866,65 @@ func Convert(content *[]byte, opts O2kOptions) (map[string]interface{}, error) {
 	}
 	sort.Strings(sortedPaths)
 
-	for _, path := range sortedPaths {
+	// convertPath converts a single path item, it only reads the document level values
+	// so multiple paths can be converted concurrently.
+	convertPath := func(path string) *pathConversion {
+		conversion := &pathConversion{}
+
+		var (
+			err error
+
+			pathBaseName         string                     // the slugified basename for the path
+			pathServers          *openapi3.Servers          // servers block on current path level
+			pathServiceDefaults  []byte                     // JSON string representation of service-defaults on path level
+			pathService          map[string]interface{}     // service entity in use on path level
+			pathUpstreamDefaults []byte                     // JSON string representation of upstream-defaults on path level
+			pathUpstream         map[string]interface{}     // upstream entity in use on path level
+			pathRouteDefaults    []byte                     // JSON string representation of route-defaults on path level
+			pathPluginList       *[]*map[string]interface{} // array of plugin configs, sorted by plugin name
+			pathValidatorConfig  []byte                     // JSON string representation of validator config to generate
+
+			operationBaseName         string                     // the slugified basename for the operation
+			operationServers          *openapi3.Servers          // servers block on current operation level
+			operationServiceDefaults  []byte                     // JSON string representation of service-defaults on ops level
+			operationService          map[string]interface{}     // service entity in use on operation level
+			operationUpstreamDefaults []byte                     // JSON string representation of upstream-defaults on ops level
+			operationUpstream         map[string]interface{}     // upstream entity in use on operation level
+			operationRouteDefaults    []byte                     // JSON string representation of route-defaults on ops level
+			operationPluginList       *[]*map[string]interface{} // array of plugin configs, sorted by plugin name
+			operationValidatorConfig  []byte                     // JSON string representation of validator config to generate
+		)
+
 		pathitem := doc.Paths[path]
+		if pathitem == nil {
+			// an empty path item ('/path:' without a value) has nothing to convert
+			return conversion
+		}
+		pathPointer := jsonPointer("paths", path)
+		if extErrs := checkExtensions(pathitem.ExtensionProps, pathExtensions, pathPointer, opts); len(extErrs) > 0 {
+			conversion.skipped = append(conversion.skipped, extErrs...)
+			return conversion
+		}
+
+		var refErrs ConversionErrors
+		resolveExtensionRefs(pathitem.ExtensionProps.Extensions, pathPointer, kongComponents, &refErrs)
+		if len(refErrs) > 0 {
+			conversion.skipped = append(conversion.skipped, refErrs...)
+			return conversion
+		}
 
 		// determine path name, precedence: specified -> x-kong-name -> actual-path
 		if pathBaseName, err = getKongName(pathitem.ExtensionProps); err != nil {
-			return nil, err
+			conversion.skipped.add(pathPointer+"/x-kong-name", err)
+			return conversion
 		}
 		if pathBaseName == "" {
 			pathBaseName = Slugify(path)
+			if pathBaseName == "" && strings.Trim(path, "/") != "" {
+				// nothing usable left of the path (the root path has no name of its own)
+				pathBaseName = hashName([]byte(path))
+				opts.Logger.Warn(unusableNameWarning, "location", pathPointer, "name", pathBaseName)
+			}
 			if strings.HasSuffix(path, "/") {
 				// a common case is 2 paths, one with and one without a trailing "/" so to prevent
 				// duplicate names being generated, we add a "~" suffix as a special case to cater
@@ -547,24 +932,33 @@ func Convert(content *[]byte, opts O2kOptions) (map[string]interface{}, error) {
 				pathBaseName = pathBaseName + "~"
 			}
 		} else {
-			pathBaseName = Slugify(pathBaseName)
+			hashed := false
+			if pathBaseName, hashed = slugOrHash(pathBaseName); hashed {
+				opts.Logger.Warn(unusableNameWarning, "location", pathPointer+"/x-kong-name", "name", pathBaseName)
+			}
 		}
 		pathBaseName = docBaseName + "_" + pathBaseName
 
 		// Set up the defaults on the Path level
 		newPathService := false
 		if pathServiceDefaults, err = getServiceDefaults(pathitem.ExtensionProps, kongComponents); err != nil {
-			return nil, err
+			conversion.skipped.add(pathPointer, err)
+			return conversion
 		}
 		if pathServiceDefaults == nil {
 			pathServiceDefaults = docServiceDefaults
 		} else {
+			if pathServiceDefaults, err = setServiceOptionDefaults(pathServiceDefaults, opts); err != nil {
+				conversion.skipped.add(pathPointer+"/x-kong-service-defaults", err)
+				return conversion
+			}
 			newPathService = true
 		}
 
 		newUpstream := false
 		if pathUpstreamDefaults, err = getUpstreamDefaults(pathitem.ExtensionProps, kongComponents); err != nil {
-			return nil, err
+			conversion.skipped.add(pathPointer, err)
+			return conversion
 		}
 		if pathUpstreamDefaults == nil {
 			pathUpstreamDefaults = docUpstreamDefaults
@@ -574,7 +968,8 @@ func Convert(content *[]byte, opts O2kOptions) (map[string]interface{}, error) {
 		}
 
 		if pathRouteDefaults, err = getRouteDefaults(pathitem.ExtensionProps, kongComponents); err != nil {
-			return nil, err
+			conversion.skipped.add(pathPointer, err)
+			return conversion
 		}
 		if pathRouteDefaults == nil {
 			pathRouteDefaults = docRouteDefaults
@@ -589,42 +984,69 @@ func Convert(content *[]byte, opts O2kOptions) (map[string]interface{}, error) {
 			newPathService = true
 		}
 
+		// attach the path plugins once to a service of its own, instead of to each route
+		if opts.PluginInheritance == PluginInheritanceService && declaresPlugins(pathitem.ExtensionProps) {
+			newPathService = true
+		}
+
 		// create a new service if we need to do so
 		if newPathService {
 			// create the path-level service and (optional) upstream
+			serviceDefaults, err := setServerServiceDefaults(pathServers, pathServiceDefaults, kongComponents)
+			if err != nil {
+				conversion.skipped.add(pathPointer+"/servers", err)
+				return conversion
+			}
 			pathService, pathUpstream, err = CreateKongService(
 				pathBaseName,
 				pathServers,
-				pathServiceDefaults,
+				serviceDefaults,
 				pathUpstreamDefaults,
 				kongTags,
 				opts.UUIDNamespace)
 			if err != nil {
-				return nil, fmt.Errorf("failed to create service/updstream from path '%s': %w", path, err)
+				conversion.skipped.add(pathPointer+"/servers",
+					fmt.Errorf("failed to create service/updstream from path '%s': %w", path, err))
+				return conversion
+			}
+			if pathUpstream != nil && newUpstream {
+				if err = setUpstreamOptions(pathUpstream, pathServers, opts); err != nil {
+					conversion.skipped.add(pathPointer+"/x-kong-upstream-defaults",
+						fmt.Errorf("failed to create upstream from path '%s': %w", path, err))
+					return conversion
+				}
 			}
 
 			// collect path plugins, including the doc-level plugins since we have a new service entity
 			pathPluginList, err = getPluginsList(pathitem.ExtensionProps, docPluginList,
 				opts.UUIDNamespace, pathBaseName, kongComponents, kongTags)
 			if err != nil {
-				return nil, fmt.Errorf("failed to create plugins list from path item: %w", err)
+				conversion.skipped.add(pathPointer, fmt.Errorf("failed to create plugins list from path item: %w", err))
+				return conversion
 			}
 
 			// Extract the request-validator config from the plugin list
 			pathValidatorConfig, pathPluginList = getValidatorPlugin(pathPluginList, docValidatorConfig)
 
 			// move consumer bound plugins to doc level plugins list (multiple foreign keys)
-			foreignKeyPlugins, pathPluginList = getForeignKeyPlugins(
-				foreignKeyPlugins, pathPluginList, "service", pathService["name"].(string))
+			conversion.foreignKeyPlugins, pathPluginList = getForeignKeyPlugins(
+				conversion.foreignKeyPlugins, pathPluginList, "service", pathService["name"].(string))
 
 			pathService["plugins"] = pathPluginList
 
-			services = append(services, pathService)
+			conversion.services = append(conversion.services, pathService)
+			conversion.locations.add("service", pathService, pathPointer)
+			opts.Logger.Debug("created service", "name", pathService["name"])
+			if len(pathitem.Servers) > 0 && hasServerWithoutHost(pathServers) {
+				opts.Logger.Warn("server without a hostname, defaulting to 'localhost'", "location", path)
+			}
 			if pathUpstream != nil {
 				// we have a new upstream, but do we need it?
 				if newUpstream {
 					// we need it, so store and use it
-					upstreams = append(upstreams, pathUpstream)
+					conversion.upstreams = append(conversion.upstreams, pathUpstream)
+					conversion.locations.add("upstream", pathUpstream, pathPointer)
+					opts.Logger.Debug("created upstream", "name", pathUpstream["name"])
 				} else {
 					// we don't need it, so update service to point to 'upper' upstream
 					pathService["host"] = docService["host"]
@@ -638,13 +1060,55 @@ func Convert(content *[]byte, opts O2kOptions) (map[string]interface{}, error) {
 			pathPluginList, err = getPluginsList(pathitem.ExtensionProps, nil,
 				opts.UUIDNamespace, pathBaseName, kongComponents, kongTags)
 			if err != nil {
-				return nil, fmt.Errorf("failed to create plugins list from path item: %w", err)
+				conversion.skipped.add(pathPointer, fmt.Errorf("failed to create plugins list from path item: %w", err))
+				return conversion
 			}
 
 			// Extract the request-validator config from the plugin list
 			pathValidatorConfig, pathPluginList = getValidatorPlugin(pathPluginList, docValidatorConfig)
 		}
 
+		// a path-level request-validator only generates plugins on the operations
+		// inheriting it, track it to warn if there are none
+		pathDeclaresValidator := declaresValidator(pathitem.ExtensionProps)
+		pathValidatorUsed := false
+
+		// the maximum request body size, inherited by the operations on the path
+		pathBodySize, err := getMaxBodySize(pathitem.ExtensionProps)
+		if err != nil {
+			conversion.skipped.add(pathPointer+"/"+maxBodySizeExtension, err)
+			return conversion
+		}
+		if pathBodySize == nil {
+			pathBodySize = docBodySize
+		}
+
+		// the upstream path template, inherited by the operations on the path
+		pathUpstreamPath, err := getUpstreamPath(pathitem.ExtensionProps)
+		if err != nil {
+			conversion.skipped.add(pathPointer+"/"+upstreamPathExtension, err)
+			return conversion
+		}
+
+		// the WASM filter chain goes on the path service, if there is one, otherwise on the
+		// routes of the operations on the path
+		pathFilterChain, err := getFilterChain(pathitem.ExtensionProps)
+		if err != nil {
+			conversion.skipped.add(pathPointer+"/"+filterChainsExtension, err)
+			return conversion
+		}
+		if newPathService {
+			if pathFilterChain == nil {
+				pathFilterChain = docFilterChain
+			}
+			setFilterChain(pathService, pathFilterChain, opts.UUIDNamespace, pathBaseName, kongTags)
+			pathFilterChain = nil
+		}
+
+		// convert the path to a regex, path parameters become regex captures; it is the
+		// same for all operations on the path
+		routeRegex, pathCaptures := createRouteRegex(pathPrefix, path)
+
 		//
 		//
 		//  Handle OAS Operation level
@@ -661,19 +1125,43 @@ func Convert(content *[]byte, opts O2kOptions) (map[string]interface{}, error) {
 		}
 		sort.Strings(sortedMethods)
 
+		// only one route per path needs to match preflight requests, and only if the
+		// spec doesn't define an OPTIONS operation itself
+		preflightRequired := opts.CORSPreflight && operations["OPTIONS"] == nil
+
 		// traverse all operations
 		for _, method := range sortedMethods {
+			if ctx.Err() != nil {
+				return conversion // aborted, reported by the caller
+			}
 			operation := operations[method]
+			operationPointer := pathPointer + jsonPointer(strings.ToLower(method))
+			extErrs := checkExtensions(operation.ExtensionProps, pathExtensions, operationPointer, opts)
+			if len(extErrs) > 0 {
+				conversion.skipped = append(conversion.skipped, extErrs...)
+				continue
+			}
 
-			var operationRoutes []interface{} // the routes array we need to add to
+			var refErrs ConversionErrors
+			resolveExtensionRefs(operation.ExtensionProps.Extensions, operationPointer, kongComponents, &refErrs)
+			if len(refErrs) > 0 {
+				conversion.skipped = append(conversion.skipped, refErrs...)
+				continue
+			}
 
 			// determine operation name, precedence: specified -> operation-ID -> method-name
 			if operationBaseName, err = getKongName(operation.ExtensionProps); err != nil {
-				return nil, err
+				conversion.skipped.add(operationPointer+"/x-kong-name", err)
+				continue
 			}
+			hashed := false
 			if operationBaseName != "" {
 				// an x-kong-name was provided, so build as "doc-path-name"
-				operationBaseName = pathBaseName + "_" + Slugify(operationBaseName)
+				if operationBaseName, hashed = slugOrHash(operationBaseName); hashed {
+					opts.Logger.Warn(unusableNameWarning, "location", operationPointer+"/x-kong-name",
+						"name", operationBaseName)
+				}
+				operationBaseName = pathBaseName + "_" + operationBaseName
 			} else {
 				operationBaseName = operation.OperationID
 				if operationBaseName == "" {
@@ -681,24 +1169,35 @@ func Convert(content *[]byte, opts O2kOptions) (map[string]interface{}, error) {
 					operationBaseName = pathBaseName + "_" + Slugify(method)
 				} else {
 					// operation ID is provided, so build as "doc-operationid"
-					operationBaseName = docBaseName + "_" + Slugify(operationBaseName)
+					if operationBaseName, hashed = slugOrHash(operationBaseName); hashed {
+						opts.Logger.Warn(unusableNameWarning, "location", operationPointer+"/operationId",
+							"name", operationBaseName)
+					}
+					operationBaseName = docBaseName + "_" + operationBaseName
 				}
 			}
 
 			// Set up the defaults on the Operation level
 			newOperationService := false
 			if operationServiceDefaults, err = getServiceDefaults(operation.ExtensionProps, kongComponents); err != nil {
-				return nil, err
+				conversion.skipped.add(operationPointer, err)
+				continue
 			}
 			if operationServiceDefaults == nil {
 				operationServiceDefaults = pathServiceDefaults
 			} else {
+				operationServiceDefaults, err = setServiceOptionDefaults(operationServiceDefaults, opts)
+				if err != nil {
+					conversion.skipped.add(operationPointer+"/x-kong-service-defaults", err)
+					continue
+				}
 				newOperationService = true
 			}
 
 			newUpstream := false
 			if operationUpstreamDefaults, err = getUpstreamDefaults(operation.ExtensionProps, kongComponents); err != nil {
-				return nil, err
+				conversion.skipped.add(operationPointer, err)
+				continue
 			}
 			if operationUpstreamDefaults == nil {
 				operationUpstreamDefaults = pathUpstreamDefaults
@@ -708,7 +1207,8 @@ func Convert(content *[]byte, opts O2kOptions) (map[string]interface{}, error) {
 			}
 
 			if operationRouteDefaults, err = getRouteDefaults(operation.ExtensionProps, kongComponents); err != nil {
-				return nil, err
+				conversion.skipped.add(operationPointer, err)
+				continue
 			}
 			if operationRouteDefaults == nil {
 				operationRouteDefaults = pathRouteDefaults
@@ -723,34 +1223,51 @@ func Convert(content *[]byte, opts O2kOptions) (map[string]interface{}, error) {
 				newOperationService = true
 			}
 
+			// an operation opting out of security cannot use a service with security
+			// plugins, so it gets its own service, without them
+			securityOptOut := opts.SecurityPlugins && optsOutOfSecurity(operation)
+			if securityOptOut && hasSecurityPlugin(pathService["plugins"]) {
+				newOperationService = true
+			}
+
 			// create a new service if we need to do so
 			if newOperationService {
 				// create the operation-level service and (optional) upstream
+				serviceDefaults, err := setServerServiceDefaults(operationServers, operationServiceDefaults,
+					kongComponents)
+				if err != nil {
+					conversion.skipped.add(operationPointer+"/servers", err)
+					continue
+				}
 				operationService, operationUpstream, err = CreateKongService(
 					operationBaseName,
 					operationServers,
-					operationServiceDefaults,
+					serviceDefaults,
 					operationUpstreamDefaults,
 					kongTags,
 					opts.UUIDNamespace)
 				if err != nil {
-					return nil, fmt.Errorf("failed to create service/updstream from operation '%s %s': %w", path, method, err)
+					conversion.skipped.add(operationPointer+"/servers",
+						fmt.Errorf("failed to create service/updstream from operation '%s %s': %w", path, method, err))
+					continue
 				}
-				services = append(services, operationService)
 				if operationUpstream != nil {
 					// we have a new upstream, but do we need it?
 					if newUpstream {
-						// we need it, so store and use it
-						upstreams = append(upstreams, operationUpstream)
+						// we need it, it will be stored once the route is complete
+						if err = setUpstreamOptions(operationUpstream, operationServers, opts); err != nil {
+							conversion.skipped.add(operationPointer+"/x-kong-upstream-defaults",
+								fmt.Errorf("failed to create upstream from operation '%s %s': %w", path, method, err))
+							continue
+						}
 					} else {
 						// we don't need it, so update service to point to 'upper' upstream
 						operationService["host"] = pathService["host"]
+						operationUpstream = nil
 					}
 				}
-				operationRoutes = operationService["routes"].([]interface{})
 			} else {
 				operationService = pathService
-				operationRoutes = operationService["routes"].([]interface{})
 			}
 
 			// collect operation plugins
@@ -775,67 +1292,408 @@ func Convert(content *[]byte, opts O2kOptions) (map[string]interface{}, error) {
 					operationBaseName, kongComponents, kongTags)
 			}
 			if err != nil {
-				return nil, fmt.Errorf("failed to create plugins list from operation item: %w", err)
+				conversion.skipped.add(operationPointer, fmt.Errorf("failed to create plugins list from operation item: %w", err))
+				continue
+			}
+
+			// resolve the templates in the plugins for this operation, including the ones of the
+			// service, which cannot be resolved on the service
+			templates := &templateData{Path: path, Method: method, Name: operationBaseName, Operation: operation}
+			operationPluginList, err = resolvePluginTemplates(operationPluginList, operationService["plugins"],
+				templates, opts.UUIDNamespace, operationBaseName)
+			if err != nil {
+				conversion.skipped.add(operationPointer, err)
+				continue
+			}
+
+			if !declaresValidator(operation.ExtensionProps) {
+				if pathDeclaresValidator {
+					pathValidatorUsed = true
+				} else {
+					conversion.docValidatorUsed = true
+				}
 			}
 
 			// Extract the request-validator config from the plugin list, generate it and reinsert
 			operationValidatorConfig, operationPluginList = getValidatorPlugin(operationPluginList, pathValidatorConfig)
-			validatorPlugin := generateValidatorPlugin(operationValidatorConfig, operation, opts.UUIDNamespace,
-				operationBaseName)
-			operationPluginList = insertPlugin(operationPluginList, validatorPlugin)
+			validatorPlugin := generateValidatorPlugin(operationValidatorConfig, operation, pathCaptures,
+				opts.UUIDNamespace, operationBaseName, schemas)
+			if validatorPlugin != nil {
+				plugins := newPluginSet(operationPluginList)
+				plugins.insert(validatorPlugin)
+				operationPluginList = plugins.list()
+			}
+
+			// generate the ACL plugin from tags or scopes
+			if opts.ACLSource != "" {
+				aclGroups, err := getACLGroups(doc, operation, opts.ACLSource)
+				if err != nil {
+					if operation.Security != nil {
+						// errors in the inherited document security are reported at '/security'
+						conversion.skipped.add(operationPointer+"/security",
+							fmt.Errorf("failed to create ACL plugin for operation '%s %s': %w", path, method, err))
+					}
+					continue
+				}
+				operationPluginList = addACLPlugin(operationPluginList, aclGroups, opts.UUIDNamespace,
+					operationBaseName, kongTags)
+			}
+
+			// generate the authentication plugins from the security requirements
+			var termination *map[string]interface{}
+			if securityOptOut {
+				operationPluginList = removeInheritedSecurity(operationPluginList, operation)
+			} else if opts.SecurityPlugins {
+				security, err := addSecurityPlugins(operationPluginList, operationService["plugins"],
+					doc, operation, anonymous, opts.UUIDNamespace, operationBaseName, kongTags)
+				if err != nil {
+					conversion.skipped.add(operationPointer+"/security",
+						fmt.Errorf("failed to create security plugins for operation '%s %s': %w", path, method, err))
+					continue
+				}
+				operationPluginList = security.plugins
+				termination = security.termination
+				conversion.anonymous = conversion.anonymous || security.anonymous
+			}
+
+			// generate the request-size-limiting plugin, precedence: operation -> path -> document,
+			// and finally the limit derived from the request body schemas
+			bodySize, err := getMaxBodySize(operation.ExtensionProps)
+			if err != nil {
+				conversion.skipped.add(operationPointer+"/"+maxBodySizeExtension, err)
+				continue
+			}
+			if bodySize == nil {
+				bodySize = pathBodySize
+			}
+			if bodySize == nil {
+				bodySize = getSchemaBodySize(operation)
+			}
+			operationPluginList = addBodySizePlugin(operationPluginList, operationService["plugins"], bodySize,
+				opts.UUIDNamespace, operationBaseName, kongTags)
+
+			// generate the response-transformer plugin from the static response headers
+			if opts.ResponseHeaders {
+				headers, conflicts := getStaticResponseHeaders(operation)
+				for _, name := range conflicts {
+					opts.Logger.Warn("response header with different values between responses, ignored",
+						"location", operationPointer+"/responses", "header", name)
+				}
+				operationPluginList = addResponseHeadersPlugin(operationPluginList, operationService["plugins"],
+					headers, opts.UUIDNamespace, operationBaseName, kongTags)
+			}
+
+			// generate the request-transformer plugin rewriting the upstream path
+			upstreamPath, err := getUpstreamPath(operation.ExtensionProps)
+			if err != nil {
+				conversion.skipped.add(operationPointer+"/"+upstreamPathExtension, err)
+				continue
+			}
+			if upstreamPath == "" {
+				upstreamPath = pathUpstreamPath
+			}
+			if upstreamPath == "" && pathPrefix != "" {
+				// the backend does not serve the path prefix, so it is stripped
+				upstreamPath = prefixStrippedPath(operationService["path"], path)
+				for _, name := range []string{"request-transformer", "request-transformer-advanced"} {
+					if hasPlugin(operationPluginList, name) || hasPlugin(operationService["plugins"], name) {
+						opts.Logger.Warn("path prefix not stripped, the route already has a "+name+" plugin",
+							"location", operationPointer)
+					}
+				}
+			}
+			upstreamTemplate, err := createUpstreamPathTemplate(upstreamPath, pathCaptures)
+			if err != nil {
+				conversion.skipped.add(operationPointer+"/"+upstreamPathExtension,
+					fmt.Errorf("failed to create upstream path for operation '%s %s': %w", path, method, err))
+				continue
+			}
+			operationPluginList = addUpstreamPathPlugin(operationPluginList, operationService["plugins"],
+				upstreamTemplate, opts.PluginTier, opts.UUIDNamespace, operationBaseName, kongTags)
+
+			// the WASM filter chain of the route, a new service inherits the one of the service
+			// it replaces, or of the path
+			operationFilterChain, err := getFilterChain(operation.ExtensionProps)
+			if err != nil {
+				conversion.skipped.add(operationPointer+"/"+filterChainsExtension, err)
+				continue
+			}
+			if newOperationService {
+				serviceFilterChain := pathFilterChain
+				if serviceFilterChain == nil {
+					serviceFilterChain = getEntityFilterChain(pathService)
+				}
+				setFilterChain(operationService, serviceFilterChain, opts.UUIDNamespace, operationBaseName, kongTags)
+			} else if operationFilterChain == nil {
+				operationFilterChain = pathFilterChain
+			}
+
+			// generate the services and routes for the callback receivers
+			var callbacks *callbackConversion
+			if opts.Callbacks && len(operation.Callbacks) > 0 {
+				callbacks, err = createCallbackServices(operation.Callbacks, operationPointer+"/callbacks",
+					opts.UUIDNamespace, operationBaseName, kongComponents, kongTags)
+				if err != nil {
+					conversion.skipped.add(operationPointer+"/callbacks", err)
+					continue
+				}
+				for _, pointer := range callbacks.dynamic {
+					opts.Logger.Warn("callback URL is a runtime expression, skipped", "location", pointer)
+				}
+			}
 
 			// construct the route
 			var route map[string]interface{}
 			if operationRouteDefaults != nil {
 				_ = json.Unmarshal(operationRouteDefaults, &route)
 				delete(route, "service") // always clear foreign keys to services, not allowed
+				if hasTemplate(route) {
+					resolved, err := resolveTemplates(route, templates)
+					if err != nil {
+						conversion.skipped.add(operationPointer, fmt.Errorf("failed to resolve route defaults: %w", err))
+						continue
+					}
+					route = resolved.(map[string]interface{})
+				}
 			} else {
 				route = make(map[string]interface{})
 			}
 
 			// move consumer bound plugins to doc level plugins list (multiple foreign keys)
-			foreignKeyPlugins, operationPluginList = getForeignKeyPlugins(
-				foreignKeyPlugins, operationPluginList, "route", operationBaseName)
+			conversion.foreignKeyPlugins, operationPluginList = getForeignKeyPlugins(
+				conversion.foreignKeyPlugins, operationPluginList, "route", operationBaseName)
+			if termination != nil {
+				*conversion.foreignKeyPlugins = append(*conversion.foreignKeyPlugins, termination)
+			}
 
-			// attach the collected plugins configs to the route
-			route["plugins"] = operationPluginList
+			setFilterChain(route, operationFilterChain, opts.UUIDNamespace, operationBaseName, kongTags)
 
-			// Escape path contents for regex creation
-			charsToEscape := []string{"(", ")", ".", "+", "?", "*", "["}
-			for _, char := range charsToEscape {
-				path = strings.ReplaceAll(path, char, "\\"+char)
+			// attach the collected plugins configs to the route, or to the service if it only
+			// serves this operation and the plugins are to be attached to services
+			if newOperationService && opts.PluginInheritance == PluginInheritanceService {
+				operationService["plugins"] = operationPluginList
+				operationPluginList = &[]*map[string]interface{}{}
 			}
+			route["plugins"] = operationPluginList
 
-			// convert path parameters to regex captures
-			re, _ := regexp.Compile("{([^}]+)}")
 			regexPriority := 200 // non-regexed (no params) paths have higher precedence in OAS
-			if matches := re.FindAllStringSubmatch(path, -1); matches != nil {
+			if len(pathCaptures) > 0 {
 				regexPriority = 100
-				for _, match := range matches {
-					varName := match[1]
-					// match single segment; '/', '?', and '#' can mark the end of a segment
-					// see https://github.com/OAI/OpenAPI-Specification/issues/291#issuecomment-316593913
-					regexMatch := "(?<" + sanitizeRegexCapture(varName) + ">[^#?/]+)"
-					placeHolder := "{" + varName + "}"
-					path = strings.Replace(path, placeHolder, regexMatch, 1)
+			}
+			routePath := "~" + routeRegex
+			slashRoutePath := "" // the path of the route for the other trailing slash variant, if any
+			if !opts.PrefixMatch {
+				switch opts.TrailingSlash {
+				case TrailingSlashOptional:
+					routePath = "~" + optionalTrailingSlash(routeRegex)
+				case TrailingSlashDuplicate:
+					if slashRegex := toggleTrailingSlash(routeRegex); slashRegex != "" {
+						slashRoutePath = "~" + slashRegex + "$"
+					}
 				}
+				routePath = routePath + "$"
 			}
-			route["paths"] = []string{"~" + path + "$"}
+			if opts.PlainPaths && len(pathCaptures) == 0 {
+				// no parameters, so no need for a regex, and a plain path matches as a
+				// prefix, so also with a trailing slash
+				routePath = pathPrefix + path
+				slashRoutePath = ""
+			}
+			route["paths"] = []string{routePath}
 			route["id"] = uuid.NewV5(opts.UUIDNamespace, operationBaseName+".route").String()
 			route["name"] = operationBaseName
-			route["methods"] = []string{method}
+			methods := []string{method}
+			if opts.HeadForGet && method == "GET" && operations["HEAD"] == nil && operation.RequestBody == nil {
+				// the request-validator would reject a HEAD request without the body, so only
+				// if there is no request body
+				methods = append(methods, "HEAD")
+			}
+			if preflightRequired && (hasPlugin(operationPluginList, "cors") ||
+				hasPlugin(operationService["plugins"], "cors")) {
+				methods = append(methods, "OPTIONS")
+				preflightRequired = false
+			}
+			route["methods"] = methods
 			route["tags"] = kongTags
+			if opts.ProvenanceTags {
+				docVersion := ""
+				if doc.Info != nil {
+					docVersion = doc.Info.Version
+				}
+				routeTags := append([]string{}, kongTags...)
+				route["tags"] = append(routeTags, provenanceTags(docDisplayName, docVersion, path, operation.OperationID)...)
+			}
 			route["regex_priority"] = regexPriority
-			route["strip_path"] = false // TODO: there should be some logic around defaults etc iirc
+			// TODO: there should be some logic around defaults etc iirc
+			// Note: a path prefix is stripped by rewriting the upstream path, see
+			// prefixStrippedPath, since the regex is anchored at both ends, and strip_path
+			// would remove the entire path.
+			route["strip_path"] = false
+			if versionHeader != "" {
+				setRouteHeader(route, versionHeader, doc.Info.Version)
+			}
 
-			operationRoutes = append(operationRoutes, route)
-			operationService["routes"] = operationRoutes
+			routes := []interface{}{route}
+			conversion.locations.add("route", route, operationPointer)
+			if slashRoutePath != "" {
+				// a copy of the route, and its plugins, for the other trailing slash variant
+				slashBaseName := operationBaseName + trailingSlashSuffix
+				slashRoute := copyRouteForPath(route, slashRoutePath, opts.UUIDNamespace, slashBaseName)
+				routes = append(routes, slashRoute)
+				conversion.locations.add("route", slashRoute, operationPointer)
+				for _, plugin := range *conversion.foreignKeyPlugins {
+					if (*plugin)["route"] == operationBaseName {
+						*conversion.foreignKeyPlugins = append(*conversion.foreignKeyPlugins,
+							copyPluginForRoute(plugin, opts.UUIDNamespace, slashBaseName))
+					}
+				}
+				conversion.routeCount++
+			}
+
+			if !newOperationService && !newPathService {
+				// the doc-level service entity is shared by all paths, so its routes are added later
+				conversion.docRoutes = append(conversion.docRoutes, routes...)
+			} else {
+				operationService["routes"] = append(operationService["routes"].([]interface{}), routes...)
+			}
+			if newOperationService {
+				conversion.services = append(conversion.services, operationService)
+				conversion.locations.add("service", operationService, operationPointer)
+				opts.Logger.Debug("created service", "name", operationService["name"])
+				if operation.Servers != nil && len(*operation.Servers) > 0 && hasServerWithoutHost(operationServers) {
+					opts.Logger.Warn("server without a hostname, defaulting to 'localhost'",
+						"location", method+" "+path)
+				}
+				if operationUpstream != nil {
+					conversion.upstreams = append(conversion.upstreams, operationUpstream)
+					conversion.locations.add("upstream", operationUpstream, operationPointer)
+					opts.Logger.Debug("created upstream", "name", operationUpstream["name"])
+				}
+			}
+			conversion.routeCount++
+			for _, r := range routes {
+				createdRoute := r.(map[string]interface{})
+				opts.Logger.Debug("created route", "name", createdRoute["name"], "id", createdRoute["id"],
+					"method", method, "path", path)
+			}
+			if callbacks != nil {
+				conversion.services = append(conversion.services, callbacks.services...)
+				conversion.upstreams = append(conversion.upstreams, callbacks.upstreams...)
+				conversion.locations = append(conversion.locations, callbacks.locations...)
+				conversion.routeCount += callbacks.routeCount
+			}
+		}
+		if newPathService {
+			// the plugins with templates have been resolved on the routes
+			removeTemplatedPlugins(pathService)
+		}
+		if pathDeclaresValidator && !pathValidatorUsed {
+			opts.Logger.Warn("request-validator not inherited by any operation, ignored", "location", path)
+		}
+		return conversion
+	}
+
+	// convert the paths using a pool of workers
+	conversions := make([]*pathConversion, len(sortedPaths))
+	docValidatorUsed := false
+	indices := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < opts.Workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indices {
+				conversions[i] = convertPath(sortedPaths[i])
+			}
+		}()
+	}
+	for i := range sortedPaths {
+		if ctx.Err() != nil {
+			break
+		}
+		indices <- i
+	}
+	close(indices)
+	wg.Wait()
+	if err = ctx.Err(); err != nil {
+		return nil, fmt.Errorf("conversion aborted: %w", err)
+	}
+
+	// the plugins with templates have been resolved on the routes
+	removeTemplatedPlugins(docService)
+
+	// merge the results in order of the paths, to be deterministic in our output order
+	for _, conversion := range conversions {
+		services = append(services, conversion.services...)
+		upstreams = append(upstreams, conversion.upstreams...)
+		docService["routes"] = append(docService["routes"].([]interface{}), conversion.docRoutes...)
+		if conversion.foreignKeyPlugins != nil {
+			*foreignKeyPlugins = append(*foreignKeyPlugins, *conversion.foreignKeyPlugins...)
+		}
+		skipped = append(skipped, conversion.skipped...)
+		routeCount += conversion.routeCount
+		docValidatorUsed = docValidatorUsed || conversion.docValidatorUsed
+		anonymousUsed = anonymousUsed || conversion.anonymous
+		locations = append(locations, conversion.locations...)
+	}
+
+	// convert the OpenAPI 3.1 webhooks to routes on the webhook service
+	if doc.Extensions[webhooksKey] != nil && opts.WebhookServer == "" {
+		opts.Logger.Warn("webhooks ignored, no webhook server set", "location", "/"+webhooksKey)
+	} else if doc.Extensions[webhooksKey] != nil {
+		webhooks, err := getWebhooks(loader, doc)
+		if err != nil {
+			errs.add("/"+webhooksKey, err)
+		} else if len(webhooks) > 0 {
+			webhookService, webhookErrs := createWebhookService(webhooks, opts.WebhookServer, docValidatorConfig,
+				opts.UUIDNamespace, docBaseName, pathPrefix, kongComponents, kongTags, schemas)
+			skipped = append(skipped, webhookErrs...)
+			if webhookService != nil {
+				services = append(services, webhookService)
+				locations.add("service", webhookService, "/"+webhooksKey)
+				locations.addRoutes(webhookService, "/"+webhooksKey)
+				routeCount += len(webhookService["routes"].([]interface{}))
+				docValidatorUsed = true
+				opts.Logger.Debug("created service", "name", webhookService["name"])
+			}
+		}
+	}
+
+	// attach the inherited plugins to each route instead of the services, or give the
+	// plugins all routes of a service have once, on the service
+	for _, service := range services {
+		if opts.PluginInheritance == PluginInheritanceRoute {
+			explodeServicePlugins(service.(map[string]interface{}), opts.UUIDNamespace)
+		} else if !opts.KeepDuplicatePlugins {
+			hoistRoutePlugins(service.(map[string]interface{}), opts.UUIDNamespace)
 		}
 	}
 
 	// export arrays with services, upstreams, and plugins to the final object
 	result["services"] = services
 	result["upstreams"] = upstreams
+
+	consumerGroups, err := getConsumerGroups(kongComponents, opts.UUIDNamespace, kongTags)
+	if err != nil {
+		errs.add("/components/x-kong/consumer-groups", err)
+	}
+	if len(consumerGroups) > 0 {
+		result["consumer_groups"] = consumerGroups
+	}
+	consumers, err := getConsumers(kongComponents, consumerGroups, opts.UUIDNamespace, kongTags)
+	if err != nil {
+		errs.add("/components/x-kong/consumers", err)
+	}
+	if anonymousUsed && !getEntityNames(consumers, "username")[anonymous["username"].(string)] {
+		consumers = append(consumers, anonymous)
+		sort.Slice(consumers, func(i, j int) bool {
+			return consumers[i].(map[string]interface{})["username"].(string) <
+				consumers[j].(map[string]interface{})["username"].(string)
+		})
+	}
+	if len(consumers) > 0 {
+		result["consumers"] = consumers
+	}
 	if len(*foreignKeyPlugins) > 0 {
 		sort.Slice(*foreignKeyPlugins,
 			func(i, j int) bool {
@@ -848,6 +1706,43 @@ func Convert(content *[]byte, opts O2kOptions) (map[string]interface{}, error) {
 		result["plugins"] = foreignKeyPlugins
 	}
 
+	if err = checkConsumerReferences(result, consumers, consumerGroups); err != nil {
+		errs.add("", err)
+	}
+	if err = checkPluginTier(result, opts.PluginTier); err != nil {
+		errs.add("", err)
+	}
+	if err = checkAllowedPlugins(result, opts.AllowedPlugins, opts.DeniedPlugins); err != nil {
+		errs.add("", err)
+	}
+	if err = checkSecrets(result, opts.Secrets, opts.Logger); err != nil {
+		errs.add("", err)
+	}
+	if opts.KongVersion != "" {
+		if err = applyKongVersion(result, opts.KongVersion); err != nil {
+			errs.add("", err)
+		}
+	}
+	errs = append(errs, checkNameCollisions(locations)...)
+	if opts.BestEffort {
+		for _, skippedErr := range skipped {
+			opts.Logger.Warn("skipped invalid path or operation", "error", skippedErr)
+		}
+	} else {
+		errs = append(skipped, errs...)
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	if declaresValidator(doc.ExtensionProps) && !docValidatorUsed {
+		opts.Logger.Warn("request-validator not inherited by any operation, ignored", "location", "document")
+	}
+	if docBaseName != docDisplayName {
+		renameEntities(result, docBaseName, docDisplayName)
+	}
+
 	// we're done!
+	opts.Logger.Info("conversion complete", "services", len(services), "upstreams", len(upstreams),
+		"routes", routeCount)
 	return result, nil
 }
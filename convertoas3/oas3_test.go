@@ -1,13 +1,19 @@
 package convertoas3
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
+	"log"
 	"os"
 	"strings"
 	"testing"
 
+	"github.com/getkin/kin-openapi/openapi3"
+	uuid "github.com/satori/go.uuid"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 const fixturePath = "./oas3_testfiles/"
@@ -24,7 +30,7 @@ func Test_ConvertOas3(t *testing.T) {
 			fileNameExpected := strings.TrimSuffix(fileNameIn, ".yaml") + ".expected.json"
 			fileNameOut := strings.TrimSuffix(fileNameIn, ".yaml") + ".generated.json"
 			dataIn, _ := os.ReadFile(fixturePath + fileNameIn)
-			dataOut, err := Convert(&dataIn, O2kOptions{
+			dataOut, err := Convert(context.Background(), &dataIn, O2kOptions{
 				Tags: &[]string{"OAS3_import", "OAS3file_" + fileNameIn},
 			})
 			if err != nil {
@@ -39,3 +45,1177 @@ func Test_ConvertOas3(t *testing.T) {
 		}
 	}
 }
+
+func Test_getPathPrefix(t *testing.T) {
+	tests := []struct {
+		name        string
+		version     string
+		opts        O2kOptions
+		expected    string
+		expectError bool
+	}{
+		{"no prefix", "v1", O2kOptions{}, "", false},
+		{"explicit prefix", "v1", O2kOptions{PathPrefix: "api/"}, "/api", false},
+		{"explicit prefix precedence", "v1", O2kOptions{PathPrefix: "/api", PathPrefixFromVersion: true}, "/api", false},
+		{"from version", "v2.1.0", O2kOptions{PathPrefixFromVersion: true}, "/v2", false},
+		{"from version without v", "3.0", O2kOptions{PathPrefixFromVersion: true}, "/v3", false},
+		{"from version no major", "beta", O2kOptions{PathPrefixFromVersion: true}, "", true},
+		{"from version empty", "", O2kOptions{PathPrefixFromVersion: true}, "", true},
+	}
+
+	for _, tst := range tests {
+		doc := &openapi3.T{Info: &openapi3.Info{Version: tst.version}}
+		prefix, err := getPathPrefix(doc, tst.opts)
+		if tst.expectError {
+			assert.Error(t, err, tst.name)
+		} else {
+			assert.NoError(t, err, tst.name)
+		}
+		assert.Equal(t, tst.expected, prefix, tst.name)
+	}
+}
+
+func Test_ConvertRoutePaths(t *testing.T) {
+	spec := []byte(`
+openapi: 3.0.3
+info:
+  title: route paths
+  version: v1
+paths:
+  /users:
+    get:
+      operationId: list
+      responses:
+        "200":
+          description: OK
+  /users/{id}:
+    get:
+      operationId: get
+      responses:
+        "200":
+          description: OK
+`)
+
+	tests := []struct {
+		name     string
+		opts     O2kOptions
+		expected []string
+	}{
+		{"regex by default", O2kOptions{}, []string{"~/users$", "~/users/(?<id>[^#?/]+)$"}},
+		{
+			"plain paths", O2kOptions{PlainPaths: true, PrefixMatch: true},
+			[]string{"/users", "~/users/(?<id>[^#?/]+)"},
+		},
+		{"prefix match", O2kOptions{PrefixMatch: true}, []string{"~/users", "~/users/(?<id>[^#?/]+)"}},
+	}
+
+	for _, tst := range tests {
+		result, err := Convert(context.Background(), &spec, tst.opts)
+		if err != nil {
+			t.Errorf("%s: didn't expect error: %v", tst.name, err)
+			continue
+		}
+		routes := result["services"].([]interface{})[0].(map[string]interface{})["routes"].([]interface{})
+		paths := make([]string, len(routes))
+		for i, route := range routes {
+			paths[i] = route.(map[string]interface{})["paths"].([]string)[0]
+		}
+		assert.Equal(t, tst.expected, paths, tst.name)
+	}
+}
+
+func Test_ConvertPathPrefix(t *testing.T) {
+	spec := []byte(`
+openapi: 3.0.3
+info:
+  title: prefixed
+  version: 2.1.0
+servers:
+  - url: https://server1.com/api
+paths:
+  /users:
+    get:
+      responses:
+        "200":
+          description: OK
+  /users/{id}:
+    get:
+      parameters:
+        - name: id
+          in: path
+          required: true
+          schema:
+            type: string
+      responses:
+        "200":
+          description: OK
+  /legacy/{id}:
+    x-kong-upstream-path: /old/user.php/{id}
+    get:
+      parameters:
+        - name: id
+          in: path
+          required: true
+          schema:
+            type: string
+      responses:
+        "200":
+          description: OK
+`)
+
+	// the backend gets the service path plus the operation path, without the prefix,
+	// an x-kong-upstream-path is used as is
+	tests := []struct {
+		name     string
+		opts     O2kOptions
+		expected map[string][]string // route name: path, upstream uri
+	}{
+		{"no prefix", O2kOptions{}, map[string][]string{
+			"prefixed_legacy-id_get": {"~/legacy/(?<id>[^#?/]+)$", `/old/user.php/$(uri_captures["id"])`},
+			"prefixed_users_get":     {"~/users$", ""},
+			"prefixed_users-id_get":  {"~/users/(?<id>[^#?/]+)$", ""},
+		}},
+		{"prefix from version", O2kOptions{PathPrefixFromVersion: true}, map[string][]string{
+			"prefixed_legacy-id_get": {"~/v2/legacy/(?<id>[^#?/]+)$", `/old/user.php/$(uri_captures["id"])`},
+			"prefixed_users_get":     {"~/v2/users$", "/api/users"},
+			"prefixed_users-id_get":  {"~/v2/users/(?<id>[^#?/]+)$", `/api/users/$(uri_captures["id"])`},
+		}},
+		{"explicit prefix", O2kOptions{PathPrefix: "/internal"}, map[string][]string{
+			"prefixed_legacy-id_get": {"~/internal/legacy/(?<id>[^#?/]+)$", `/old/user.php/$(uri_captures["id"])`},
+			"prefixed_users_get":     {"~/internal/users$", "/api/users"},
+			"prefixed_users-id_get":  {"~/internal/users/(?<id>[^#?/]+)$", `/api/users/$(uri_captures["id"])`},
+		}},
+	}
+
+	for _, tst := range tests {
+		result, err := Convert(context.Background(), &spec, tst.opts)
+		if !assert.NoError(t, err, tst.name) {
+			continue
+		}
+		routes := make(map[string][]string)
+		for _, r := range result["services"].([]interface{})[0].(map[string]interface{})["routes"].([]interface{}) {
+			route := r.(map[string]interface{})
+			uri := ""
+			for _, plugin := range *route["plugins"].(*[]*map[string]interface{}) {
+				if (*plugin)["name"] == "request-transformer" {
+					replace := (*plugin)["config"].(map[string]interface{})["replace"].(map[string]interface{})
+					uri = replace["uri"].(string)
+				}
+			}
+			routes[route["name"].(string)] = []string{route["paths"].([]string)[0], uri}
+		}
+		assert.Equal(t, tst.expected, routes, tst.name)
+	}
+}
+
+func Test_ConvertCORSPreflight(t *testing.T) {
+	spec := []byte(`
+openapi: 3.0.3
+info:
+  title: cors
+  version: v1
+x-kong-plugin-cors:
+  config:
+    origins: ["*"]
+paths:
+  /users:
+    get:
+      responses:
+        "200":
+          description: OK
+    post:
+      responses:
+        "200":
+          description: OK
+  /items:
+    get:
+      responses:
+        "200":
+          description: OK
+    options:
+      responses:
+        "200":
+          description: OK
+`)
+
+	getMethods := func(result map[string]interface{}) map[string][]string {
+		methods := make(map[string][]string)
+		routes := result["services"].([]interface{})[0].(map[string]interface{})["routes"].([]interface{})
+		for _, route := range routes {
+			r := route.(map[string]interface{})
+			methods[r["name"].(string)] = r["methods"].([]string)
+		}
+		return methods
+	}
+
+	result, err := Convert(context.Background(), &spec, O2kOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]string{
+		"cors_items_get":     {"GET"},
+		"cors_items_options": {"OPTIONS"},
+		"cors_users_get":     {"GET"},
+		"cors_users_post":    {"POST"},
+	}, getMethods(result))
+
+	result, err = Convert(context.Background(), &spec, O2kOptions{CORSPreflight: true})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]string{
+		"cors_items_get":     {"GET"},
+		"cors_items_options": {"OPTIONS"},
+		"cors_users_get":     {"GET", "OPTIONS"},
+		"cors_users_post":    {"POST"},
+	}, getMethods(result))
+}
+
+func Test_ConvertACL(t *testing.T) {
+	spec := []byte(`
+openapi: 3.0.3
+info:
+  title: acl
+  version: v1
+security:
+  - oauth: [read]
+paths:
+  /users:
+    get:
+      tags: [users, admin]
+      responses:
+        "200":
+          description: OK
+    post:
+      tags: [admin]
+      security:
+        - oauth: [write, admin]
+          apikey: []
+      responses:
+        "200":
+          description: OK
+    put:
+      x-kong-plugin-acl:
+        config:
+          allow: [custom]
+      responses:
+        "200":
+          description: OK
+components:
+  securitySchemes:
+    apikey:
+      type: apiKey
+      in: header
+      name: X-Api-Key
+    oauth:
+      type: oauth2
+      flows:
+        clientCredentials:
+          tokenUrl: https://example.com/token
+          scopes:
+            read: read access
+            write: write access
+            admin: admin access
+`)
+
+	getACLs := func(result map[string]interface{}) map[string]interface{} {
+		acls := make(map[string]interface{})
+		routes := result["services"].([]interface{})[0].(map[string]interface{})["routes"].([]interface{})
+		for _, route := range routes {
+			r := route.(map[string]interface{})
+			for _, plugin := range *r["plugins"].(*[]*map[string]interface{}) {
+				if (*plugin)["name"] == "acl" {
+					acls[r["name"].(string)] = (*plugin)["config"].(map[string]interface{})["allow"]
+				}
+			}
+		}
+		return acls
+	}
+
+	result, err := Convert(context.Background(), &spec, O2kOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"acl_users_put": []interface{}{"custom"},
+	}, getACLs(result))
+
+	result, err = Convert(context.Background(), &spec, O2kOptions{ACLSource: ACLFromTags})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"acl_users_get":  []string{"admin", "users"},
+		"acl_users_post": []string{"admin"},
+		"acl_users_put":  []interface{}{"custom"},
+	}, getACLs(result))
+
+	// all scopes of a requirement are needed, an 'acl' plugin allows any of its groups
+	_, err = Convert(context.Background(), &spec, O2kOptions{ACLSource: ACLFromScopes})
+	require.Error(t, err)
+	var errs ConversionErrors
+	require.ErrorAs(t, err, &errs)
+	require.Len(t, errs, 1)
+	assert.EqualError(t, errs[0], "/paths/~1users/post/security: failed to create ACL plugin for operation "+
+		"'/users POST': expected each security requirement to need a single scope, as the 'acl' plugin "+
+		"allows any of its groups, got: 'admin, write'")
+
+	// a scope in each of the alternative requirements
+	alternatives := bytes.Replace(spec, []byte("        - oauth: [write, admin]\n"),
+		[]byte("        - oauth: [admin]\n        - oauth: [write]\n"), 1)
+	result, err = Convert(context.Background(), &alternatives, O2kOptions{ACLSource: ACLFromScopes})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"acl_users_get":  []string{"read"},
+		"acl_users_post": []string{"admin", "write"},
+		"acl_users_put":  []interface{}{"custom"},
+	}, getACLs(result))
+
+	_, err = Convert(context.Background(), &spec, O2kOptions{ACLSource: "roles"})
+	assert.Error(t, err)
+
+	// an undeclared scheme in the document security is reported once, at '/security'
+	undeclared := bytes.Replace(alternatives, []byte("  - oauth: [read]"), []byte("  - sso: [read]"), 1)
+	_, err = Convert(context.Background(), &undeclared, O2kOptions{ACLSource: ACLFromScopes})
+	require.Error(t, err)
+	require.ErrorAs(t, err, &errs)
+	require.Len(t, errs, 1)
+	assert.Equal(t, "/security", errs[0].Pointer)
+	assert.EqualError(t, errs[0], "/security: failed to create ACL plugins: security scheme 'sso' is not "+
+		"declared in '#/components/securitySchemes'")
+}
+
+func Test_ConvertResponseHeaders(t *testing.T) {
+	spec := []byte(`
+openapi: 3.0.3
+info:
+  title: headers
+  version: v1
+servers:
+  - url: https://example.com
+paths:
+  /users:
+    get:
+      responses:
+        "200":
+          description: OK
+          headers:
+            Cache-Control:
+              schema:
+                type: string
+                enum: [max-age=3600]
+            X-Rate-Limit:
+              schema:
+                type: integer
+                enum: [100]
+            X-Request-Id:
+              schema:
+                type: string
+            Content-Type:
+              schema:
+                type: string
+                enum: [application/json]
+            X-Version:
+              schema:
+                type: string
+                enum: [v1]
+        "206":
+          description: Partial
+          headers:
+            X-Version:
+              schema:
+                type: string
+                enum: [v1-partial]
+        "404":
+          description: Not found
+          headers:
+            X-Error:
+              schema:
+                type: string
+                enum: [not-found]
+    post:
+      x-kong-plugin-response-transformer:
+        config:
+          add:
+            headers: ["X-Custom:custom"]
+      responses:
+        "201":
+          description: Created
+          headers:
+            Cache-Control:
+              schema:
+                type: string
+                enum: [no-store]
+`)
+
+	getHeaders := func(result map[string]interface{}) map[string]interface{} {
+		headers := make(map[string]interface{})
+		routes := result["services"].([]interface{})[0].(map[string]interface{})["routes"].([]interface{})
+		for _, route := range routes {
+			r := route.(map[string]interface{})
+			for _, plugin := range *r["plugins"].(*[]*map[string]interface{}) {
+				if (*plugin)["name"] == "response-transformer" {
+					headers[r["name"].(string)] = (*plugin)["config"].(map[string]interface{})["add"]
+				}
+			}
+		}
+		return headers
+	}
+
+	result, err := Convert(context.Background(), &spec, O2kOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"headers_users_post": map[string]interface{}{"headers": []interface{}{"X-Custom:custom"}},
+	}, getHeaders(result))
+
+	var buf bytes.Buffer
+	result, err = Convert(context.Background(), &spec, O2kOptions{
+		ResponseHeaders: true,
+		Logger:          NewStdLogger(log.New(&buf, "", 0), LogLevelWarn),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"headers_users_get": map[string]interface{}{
+			"headers": []string{"Cache-Control:max-age=3600", "X-Rate-Limit:100"},
+		},
+		"headers_users_post": map[string]interface{}{"headers": []interface{}{"X-Custom:custom"}},
+	}, getHeaders(result))
+	assert.Equal(t, "WARN response header with different values between responses, ignored "+
+		"location=/paths/~1users/get/responses header=X-Version\n", buf.String())
+}
+
+func Test_ConvertTrailingSlash(t *testing.T) {
+	spec := []byte(`
+openapi: 3.0.3
+info:
+  title: slash
+  version: v1
+servers:
+  - url: https://example.com
+paths:
+  /users:
+    get:
+      x-kong-plugin-rate-limiting:
+        consumer: johndoe
+        config:
+          minute: 10
+      x-kong-plugin-cors:
+        config:
+          origins: ["*"]
+      responses:
+        "200":
+          description: OK
+`)
+
+	getRoutes := func(result map[string]interface{}) map[string]interface{} {
+		routes := make(map[string]interface{})
+		service := result["services"].([]interface{})[0].(map[string]interface{})
+		for _, route := range service["routes"].([]interface{}) {
+			r := route.(map[string]interface{})
+			routes[r["name"].(string)] = r["paths"]
+		}
+		return routes
+	}
+
+	result, err := Convert(context.Background(), &spec, O2kOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"slash_users_get": []string{"~/users$"}}, getRoutes(result))
+
+	result, err = Convert(context.Background(), &spec, O2kOptions{TrailingSlash: TrailingSlashOptional})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"slash_users_get": []string{"~/users/?$"}}, getRoutes(result))
+
+	result, err = Convert(context.Background(), &spec, O2kOptions{
+		TrailingSlash:        TrailingSlashDuplicate,
+		KeepDuplicatePlugins: true,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"slash_users_get":  []string{"~/users$"},
+		"slash_users_get~": []string{"~/users/$"},
+	}, getRoutes(result))
+
+	// the plugins are copied, with new IDs, including the consumer bound ones
+	routes := result["services"].([]interface{})[0].(map[string]interface{})["routes"].([]interface{})
+	plugins := *routes[0].(map[string]interface{})["plugins"].(*[]*map[string]interface{})
+	slashPlugins := *routes[1].(map[string]interface{})["plugins"].(*[]*map[string]interface{})
+	require.Len(t, plugins, 1)
+	require.Len(t, slashPlugins, 1)
+	assert.Equal(t, (*plugins[0])["config"], (*slashPlugins[0])["config"])
+	assert.NotEqual(t, (*plugins[0])["id"], (*slashPlugins[0])["id"])
+
+	docPlugins := *result["plugins"].(*[]*map[string]interface{})
+	require.Len(t, docPlugins, 2)
+	assert.Equal(t, "slash_users_get", (*docPlugins[0])["route"])
+	assert.Equal(t, "slash_users_get~", (*docPlugins[1])["route"])
+	assert.NotEqual(t, (*docPlugins[0])["id"], (*docPlugins[1])["id"])
+
+	_, err = Convert(context.Background(), &spec, O2kOptions{TrailingSlash: "loose"})
+	assert.Error(t, err)
+}
+
+func Test_ConvertHeadForGet(t *testing.T) {
+	spec := []byte(`
+openapi: 3.0.3
+info:
+  title: head
+  version: v1
+servers:
+  - url: https://example.com
+paths:
+  /users:
+    get:
+      x-kong-plugin-cors: {}
+      responses:
+        "200":
+          description: OK
+    post:
+      responses:
+        "200":
+          description: OK
+  /files:
+    get:
+      responses:
+        "200":
+          description: OK
+    head:
+      responses:
+        "200":
+          description: OK
+  /search:
+    get:
+      requestBody:
+        content:
+          application/json:
+            schema:
+              type: object
+      responses:
+        "200":
+          description: OK
+`)
+
+	getMethods := func(result map[string]interface{}) map[string]interface{} {
+		methods := make(map[string]interface{})
+		service := result["services"].([]interface{})[0].(map[string]interface{})
+		for _, route := range service["routes"].([]interface{}) {
+			r := route.(map[string]interface{})
+			methods[r["name"].(string)] = r["methods"]
+		}
+		return methods
+	}
+
+	result, err := Convert(context.Background(), &spec, O2kOptions{HeadForGet: true, CORSPreflight: true})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"head_files_get":  []string{"GET"},
+		"head_files_head": []string{"HEAD"},
+		"head_search_get": []string{"GET"},
+		"head_users_get":  []string{"GET", "HEAD", "OPTIONS"},
+		"head_users_post": []string{"POST"},
+	}, getMethods(result))
+
+	result, err = Convert(context.Background(), &spec, O2kOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"GET"}, getMethods(result)["head_users_get"])
+}
+
+func Test_ConvertPluginInheritance(t *testing.T) {
+	spec := []byte(`
+openapi: 3.0.3
+info:
+  title: inherit
+  version: v1
+servers:
+  - url: https://example.com
+x-kong-plugin-cors: {}
+paths:
+  /users:
+    x-kong-plugin-key-auth: {}
+    get:
+      responses:
+        "200":
+          description: OK
+    post:
+      x-kong-plugin-cors:
+        config:
+          origins: ["https://example.com"]
+      responses:
+        "200":
+          description: OK
+  /items:
+    get:
+      servers:
+        - url: https://items.example.com
+      x-kong-plugin-rate-limiting:
+        config:
+          minute: 10
+      responses:
+        "200":
+          description: OK
+`)
+
+	// the plugin names, by entity type and name
+	getPlugins := func(result map[string]interface{}) map[string][]string {
+		names := func(list interface{}) []string {
+			plugins := make([]string, 0)
+			if list, ok := list.(*[]*map[string]interface{}); ok {
+				for _, plugin := range *list {
+					plugins = append(plugins, (*plugin)["name"].(string))
+				}
+			}
+			return plugins
+		}
+		plugins := make(map[string][]string)
+		for _, s := range result["services"].([]interface{}) {
+			service := s.(map[string]interface{})
+			plugins["service "+service["name"].(string)] = names(service["plugins"])
+			for _, r := range service["routes"].([]interface{}) {
+				route := r.(map[string]interface{})
+				plugins["route "+route["name"].(string)] = names(route["plugins"])
+			}
+		}
+		return plugins
+	}
+
+	result, err := Convert(context.Background(), &spec, O2kOptions{KeepDuplicatePlugins: true})
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]string{
+		"service inherit":           {"cors"},
+		"service inherit_items_get": {},
+		"route inherit_items_get":   {"cors", "rate-limiting"},
+		"route inherit_users_get":   {"key-auth"},
+		"route inherit_users_post":  {"cors", "key-auth"},
+	}, getPlugins(result))
+
+	result, err = Convert(context.Background(), &spec, O2kOptions{PluginInheritance: PluginInheritanceService})
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]string{
+		"service inherit":           {"cors"},
+		"service inherit_items_get": {"cors", "rate-limiting"},
+		"route inherit_items_get":   {},
+		"service inherit_users":     {"cors", "key-auth"},
+		"route inherit_users_get":   {},
+		"route inherit_users_post":  {"cors"},
+	}, getPlugins(result))
+
+	result, err = Convert(context.Background(), &spec, O2kOptions{PluginInheritance: PluginInheritanceRoute})
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]string{
+		"service inherit":           {},
+		"service inherit_items_get": {},
+		"route inherit_items_get":   {"cors", "rate-limiting"},
+		"route inherit_users_get":   {"cors", "key-auth"},
+		"route inherit_users_post":  {"cors", "key-auth"},
+	}, getPlugins(result))
+
+	// the route plugin takes precedence over the exploded service plugin
+	service := result["services"].([]interface{})[0].(map[string]interface{})
+	for _, r := range service["routes"].([]interface{}) {
+		route := r.(map[string]interface{})
+		cors := (*route["plugins"].(*[]*map[string]interface{}))[0]
+		assert.Equal(t, createPluginID(uuid.NamespaceDNS, route["name"].(string), *cors), (*cors)["id"])
+		if route["name"] == "inherit_users_post" {
+			assert.NotNil(t, (*cors)["config"])
+		} else {
+			assert.Nil(t, (*cors)["config"])
+		}
+	}
+
+	_, err = Convert(context.Background(), &spec, O2kOptions{PluginInheritance: "everywhere"})
+	assert.ErrorContains(t, err, "invalid plugin inheritance 'everywhere'")
+}
+
+func Test_ConvertDedupPlugins(t *testing.T) {
+	spec := []byte(`
+openapi: 3.0.3
+info:
+  title: dedup
+  version: v1
+servers:
+  - url: https://example.com
+x-kong-plugin-cors: {}
+paths:
+  /users:
+    x-kong-plugin-key-auth: {}
+    x-kong-plugin-rate-limiting:
+      config:
+        minute: 10
+    get:
+      x-kong-plugin-cors:
+        config:
+          origins: ["https://example.com"]
+      responses:
+        "200":
+          description: OK
+    post:
+      x-kong-plugin-rate-limiting:
+        config:
+          minute: 5
+      x-kong-plugin-cors:
+        config:
+          origins: ["https://example.com"]
+      responses:
+        "200":
+          description: OK
+`)
+
+	getService := func(result map[string]interface{}) map[string]interface{} {
+		return result["services"].([]interface{})[0].(map[string]interface{})
+	}
+	getNames := func(list interface{}) []string {
+		names := make([]string, 0)
+		for _, plugin := range *list.(*[]*map[string]interface{}) {
+			names = append(names, (*plugin)["name"].(string))
+		}
+		return names
+	}
+
+	// key-auth is the same on all routes, the cors plugins override the service one,
+	// and the rate-limiting configs differ
+	result, err := Convert(context.Background(), &spec, O2kOptions{})
+	require.NoError(t, err)
+	service := getService(result)
+	assert.Equal(t, []string{"cors", "key-auth"}, getNames(service["plugins"]))
+	keyAuth := (*service["plugins"].(*[]*map[string]interface{}))[1]
+	assert.Equal(t, createPluginID(uuid.NamespaceDNS, "dedup", *keyAuth), (*keyAuth)["id"])
+	for _, route := range service["routes"].([]interface{}) {
+		assert.Equal(t, []string{"cors", "rate-limiting"}, getNames(route.(map[string]interface{})["plugins"]))
+	}
+
+	result, err = Convert(context.Background(), &spec, O2kOptions{KeepDuplicatePlugins: true})
+	require.NoError(t, err)
+	service = getService(result)
+	assert.Equal(t, []string{"cors"}, getNames(service["plugins"]))
+	for _, route := range service["routes"].([]interface{}) {
+		assert.Equal(t, []string{"cors", "key-auth", "rate-limiting"},
+			getNames(route.(map[string]interface{})["plugins"]))
+	}
+}
+
+func Test_ConvertTemplates(t *testing.T) {
+	spec := []byte(`
+openapi: 3.0.3
+info:
+  title: templates
+  version: v1
+x-kong-plugin-key-auth: {}
+x-kong-plugin-correlation-id:
+  config:
+    header_name: X-{{ .Operation.OperationID }}-Id
+x-kong-route-defaults:
+  hosts: ["{{ .Name }}.example.com"]
+paths:
+  /users:
+    get:
+      operationId: list-users
+      responses:
+        "200":
+          description: OK
+    post:
+      operationId: create-user
+      x-kong-plugin-correlation-id:
+        config:
+          header_name: X-Create
+      x-kong-plugin-statsd:
+        config:
+          prefix: "{{ .Method }} {{ .Path }}"
+      responses:
+        "200":
+          description: OK
+`)
+
+	result, err := Convert(context.Background(), &spec, O2kOptions{KeepDuplicatePlugins: true})
+	require.NoError(t, err)
+	service := result["services"].([]interface{})[0].(map[string]interface{})
+	servicePlugins := *service["plugins"].(*[]*map[string]interface{})
+	require.Len(t, servicePlugins, 1)
+	assert.Equal(t, "key-auth", (*servicePlugins[0])["name"])
+
+	configs := make(map[string]interface{})
+	for _, r := range service["routes"].([]interface{}) {
+		route := r.(map[string]interface{})
+		assert.Equal(t, []interface{}{route["name"].(string) + ".example.com"}, route["hosts"])
+		for _, plugin := range *route["plugins"].(*[]*map[string]interface{}) {
+			configs[route["name"].(string)+" "+(*plugin)["name"].(string)] = (*plugin)["config"]
+			assert.Equal(t, createPluginID(uuid.NamespaceDNS, route["name"].(string), *plugin), (*plugin)["id"])
+		}
+	}
+	assert.Equal(t, map[string]interface{}{
+		"templates_list-users correlation-id":  map[string]interface{}{"header_name": "X-list-users-Id"},
+		"templates_create-user correlation-id": map[string]interface{}{"header_name": "X-Create"},
+		"templates_create-user statsd":         map[string]interface{}{"prefix": "POST /users"},
+	}, configs)
+
+	spec = bytes.Replace(spec, []byte(".Method"), []byte(".Verb"), 1)
+	_, err = Convert(context.Background(), &spec, O2kOptions{})
+	assert.ErrorContains(t, err, "/paths/~1users/post: failed to resolve plugin 'statsd': failed to "+
+		"execute template '{{ .Verb }} {{ .Path }}'")
+}
+
+func Test_ConvertUnusableNames(t *testing.T) {
+	spec := []byte(`
+openapi: 3.0.3
+info:
+  title: names
+  version: v1
+servers:
+  - url: https://example.com
+paths:
+  /:
+    get:
+      responses:
+        "200":
+          description: OK
+  /🚀:
+    get:
+      responses:
+        "200":
+          description: OK
+    post:
+      operationId: 🔥
+      responses:
+        "200":
+          description: OK
+    put:
+      x-kong-name: ✨
+      responses:
+        "200":
+          description: OK
+`)
+
+	var buf bytes.Buffer
+	result, err := Convert(context.Background(), &spec, O2kOptions{
+		Logger: NewStdLogger(log.New(&buf, "", 0), LogLevelWarn),
+	})
+	require.NoError(t, err)
+
+	rocket := hashName([]byte("/🚀"))
+	names := make([]string, 0)
+	for _, route := range result["services"].([]interface{})[0].(map[string]interface{})["routes"].([]interface{}) {
+		names = append(names, route.(map[string]interface{})["name"].(string))
+	}
+	assert.Equal(t, []string{
+		"names_~_get",
+		"names_" + rocket + "_get",
+		"names_" + hashName([]byte("🔥")),
+		"names_" + rocket + "_" + hashName([]byte("✨")),
+	}, names)
+	assert.Equal(t, "WARN "+unusableNameWarning+" location=/paths/~1🚀 name="+rocket+"\n"+
+		"WARN "+unusableNameWarning+" location=/paths/~1🚀/post/operationId name="+hashName([]byte("🔥"))+"\n"+
+		"WARN "+unusableNameWarning+" location=/paths/~1🚀/put/x-kong-name name="+hashName([]byte("✨"))+"\n",
+		buf.String())
+}
+
+func Test_ConvertCancelled(t *testing.T) {
+	spec := []byte(`
+openapi: 3.0.3
+info:
+  title: cancelled
+  version: v1
+paths:
+  /users:
+    get:
+      responses:
+        "200":
+          description: OK
+`)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := Convert(ctx, &spec, O2kOptions{})
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func Test_ConvertLogger(t *testing.T) {
+	spec := []byte(`
+openapi: 3.0.3
+info:
+  title: logger
+  version: v1
+paths:
+  /users:
+    get:
+      responses:
+        "200":
+          description: OK
+`)
+
+	var buf bytes.Buffer
+	_, err := Convert(context.Background(), &spec, O2kOptions{
+		Logger: NewStdLogger(log.New(&buf, "", 0), LogLevelInfo),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "WARN server without a hostname, defaulting to 'localhost' location=document\n"+
+		"INFO conversion complete services=1 upstreams=0 routes=1\n", buf.String())
+}
+
+func Test_ConvertGenerator(t *testing.T) {
+	spec := []byte("openapi: 3.0.3\ninfo:\n  title: generator\n  version: v1\npaths: {}\n")
+
+	result, err := Convert(context.Background(), &spec, O2kOptions{})
+	assert.NoError(t, err)
+	assert.NotContains(t, result, "_info")
+
+	result, err = Convert(context.Background(), &spec, O2kOptions{Generator: "fw v1.2.3"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"generator": "fw v1.2.3"}, result["_info"])
+}
+
+func Test_ConvertDocumentName(t *testing.T) {
+	tests := []struct {
+		name     string
+		spec     string
+		docName  string
+		expected string
+		warning  bool
+	}{
+		{"from title", "info:\n  title: My API\n  version: v1\n", "", "my-api", false},
+		{"from x-kong-name", "info:\n  title: My API\n  version: v1\nx-kong-name: other\n", "", "other", false},
+		{"from option", "info:\n  title: My API\n  version: v1\n", "given", "given", false},
+		{"no info", "x-kong-tags: []\n", "", "oas-", true},
+		{"empty title", "info:\n  title: ''\n  version: v1\n", "", "oas-", true},
+		{"title without valid characters", "info:\n  title: '!!!'\n  version: v1\n", "", "oas-", true},
+		{"emoji title", "info:\n  title: '🚀🚀'\n  version: v1\n", "", "oas-", true},
+	}
+
+	for _, tst := range tests {
+		spec := []byte("openapi: 3.0.3\npaths: {}\n" + tst.spec)
+		var buf bytes.Buffer
+		result, err := Convert(context.Background(), &spec, O2kOptions{
+			DocName: tst.docName,
+			Logger:  NewStdLogger(log.New(&buf, "", 0), LogLevelWarn),
+		})
+		if !assert.NoError(t, err, tst.name) {
+			continue
+		}
+		name := result["services"].([]interface{})[0].(map[string]interface{})["name"].(string)
+		assert.True(t, strings.HasPrefix(name, tst.expected), "%s: unexpected name '%s'", tst.name, name)
+		assert.Equal(t, tst.warning, strings.Contains(buf.String(), "using a hash"), tst.name)
+	}
+
+	// a name without valid characters is hashed, so it does not change with the spec
+	spec1 := []byte("openapi: 3.0.3\ninfo:\n  title: '🚀'\n  version: v1\npaths: {}\n")
+	spec2 := []byte("openapi: 3.0.3\ninfo:\n  title: '🚀'\n  version: v2\npaths: {}\n")
+	result1, _ := Convert(context.Background(), &spec1, O2kOptions{})
+	result2, _ := Convert(context.Background(), &spec2, O2kOptions{})
+	assert.Equal(t, result1["services"], result2["services"])
+
+	// the fallback name is stable
+	spec := []byte("openapi: 3.0.3\npaths: {}\n")
+	result1, _ = Convert(context.Background(), &spec, O2kOptions{})
+	result2, _ = Convert(context.Background(), &spec, O2kOptions{})
+	assert.Equal(t, result1, result2)
+}
+
+func Test_ConvertUnusedValidator(t *testing.T) {
+	const validator = "x-kong-plugin-request-validator: {config: {body_schema: '{}'}}\n"
+	tests := []struct {
+		name     string
+		spec     string
+		location string
+	}{
+		{"doc-level, no paths", validator + "paths: {}\n", "location=document"},
+		{"doc-level, inherited", validator + "paths:\n  /a:\n    get:\n      responses: {}\n", ""},
+		{"doc-level, overridden", validator + "paths:\n  /a:\n    " + validator +
+			"    get:\n      responses: {}\n", "location=document"},
+		{"path-level, no operations", "paths:\n  /a:\n    " + validator, "location=/a"},
+		{"path-level, inherited", "paths:\n  /a:\n    " + validator + "    get:\n      responses: {}\n", ""},
+		{"path-level, overridden", "paths:\n  /a:\n    " + validator + "    get:\n      responses: {}\n      " +
+			validator, "location=/a"},
+	}
+
+	for _, tst := range tests {
+		spec := []byte("openapi: 3.0.3\ninfo:\n  title: test\n  version: v1\n" + tst.spec)
+		var buf bytes.Buffer
+		_, err := Convert(context.Background(), &spec, O2kOptions{
+			Logger: NewStdLogger(log.New(&buf, "", 0), LogLevelWarn),
+		})
+		if !assert.NoError(t, err, tst.name) {
+			continue
+		}
+		if tst.location == "" {
+			assert.NotContains(t, buf.String(), "request-validator", tst.name)
+		} else {
+			assert.Contains(t, buf.String(), "request-validator not inherited by any operation, ignored", tst.name)
+			assert.Contains(t, buf.String(), tst.location, tst.name)
+		}
+	}
+}
+
+func FuzzConvert(f *testing.F) {
+	files, err := os.ReadDir(fixturePath)
+	if err != nil {
+		f.Fatalf("failed reading test data: %v", err)
+	}
+	for _, file := range files {
+		if strings.HasSuffix(file.Name(), ".yaml") {
+			data, _ := os.ReadFile(fixturePath + file.Name())
+			f.Add(data)
+		}
+	}
+	for _, seed := range extensionFuzzSeeds {
+		f.Add([]byte("openapi: 3.0.3\ninfo:\n  title: fuzz\n  version: v1\npaths:\n  /a:\n    get:\n" +
+			"      x-kong-plugin-key-auth: " + seed + "\n      responses: {}\n"))
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		// errors are fine, panics are not
+		_, _ = Convert(context.Background(), &data, O2kOptions{})
+		_ = ValidateExtensions(&data)
+	})
+}
+
+// generateBenchmarkSpec generates a JSON spec with 4 operations per path. Every operation
+// has parameters, a request body referencing shared schemas, and plugins on the
+// document, path, and operation level.
+func generateBenchmarkSpec(pathCount int) []byte {
+	paths := make(map[string]interface{})
+	for i := 0; i < pathCount; i++ {
+		pathItem := map[string]interface{}{
+			"x-kong-plugin-rate-limiting": map[string]interface{}{
+				"config": map[string]interface{}{"minute": i, "policy": "local"},
+			},
+		}
+		for _, method := range []string{"get", "post", "put", "delete"} {
+			pathItem[method] = map[string]interface{}{
+				"x-kong-plugin-correlation-id": map[string]interface{}{},
+				"parameters": []interface{}{
+					map[string]interface{}{
+						"name": "id", "in": "path", "required": true,
+						"schema": map[string]interface{}{"type": "integer"},
+					},
+					map[string]interface{}{
+						"name": "filter", "in": "query",
+						"schema": map[string]interface{}{"$ref": "#/components/schemas/filter"},
+					},
+				},
+				"requestBody": map[string]interface{}{
+					"content": map[string]interface{}{
+						"application/json": map[string]interface{}{
+							"schema": map[string]interface{}{"$ref": "#/components/schemas/item"},
+						},
+					},
+				},
+				"responses": map[string]interface{}{"200": map[string]interface{}{"description": "OK"}},
+			}
+		}
+		paths[fmt.Sprintf("/items%d/{id}", i)] = pathItem
+	}
+
+	spec, _ := json.Marshal(map[string]interface{}{
+		"openapi": "3.0.3",
+		"info":    map[string]interface{}{"title": "benchmark", "version": "v1"},
+		"servers": []interface{}{map[string]interface{}{"url": "https://api.example.com"}},
+		"x-kong-plugin-cors": map[string]interface{}{
+			"config": map[string]interface{}{"origins": []string{"*"}, "headers": []string{"a", "b", "c"}},
+		},
+		"x-kong-plugin-key-auth": map[string]interface{}{
+			"config": map[string]interface{}{"key_names": []string{"apikey"}},
+		},
+		"x-kong-plugin-request-validator": map[string]interface{}{
+			"config": map[string]interface{}{"verbose_response": true},
+		},
+		"paths": paths,
+		"components": map[string]interface{}{
+			"schemas": map[string]interface{}{
+				"filter": map[string]interface{}{"type": "string", "maxLength": 64},
+				"item": map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"name":  map[string]interface{}{"type": "string"},
+						"owner": map[string]interface{}{"$ref": "#/components/schemas/owner"},
+						"tags":  map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}},
+					},
+				},
+				"owner": map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"id":    map[string]interface{}{"type": "integer"},
+						"email": map[string]interface{}{"type": "string", "format": "email"},
+					},
+				},
+			},
+		},
+	})
+	return spec
+}
+
+func benchmarkConvert(b *testing.B, spec []byte, opts O2kOptions) {
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := Convert(context.Background(), &spec, opts); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkConvert100Operations(b *testing.B) {
+	benchmarkConvert(b, generateBenchmarkSpec(25), O2kOptions{})
+}
+
+func BenchmarkConvert1000Operations(b *testing.B) {
+	benchmarkConvert(b, generateBenchmarkSpec(250), O2kOptions{})
+}
+
+func BenchmarkConvert4000Operations(b *testing.B) {
+	benchmarkConvert(b, generateBenchmarkSpec(1000), O2kOptions{})
+}
+
+func BenchmarkConvert4000OperationsSingleWorker(b *testing.B) {
+	benchmarkConvert(b, generateBenchmarkSpec(1000), O2kOptions{Workers: 1})
+}
+
+func BenchmarkConvertAllOptions(b *testing.B) {
+	benchmarkConvert(b, generateBenchmarkSpec(250), NewO2kOptions(
+		WithPathPrefix("/api"),
+		WithCORSPreflight(),
+		WithServiceTimeouts(1000, 2000, 3000),
+		WithUpstreamHostHeader(),
+		WithACLSource(ACLFromTags),
+		WithValidateSpec(),
+	))
+}
+
+// BenchmarkConvertFixtures converts all test fixtures, covering all features.
+func BenchmarkConvertFixtures(b *testing.B) {
+	files, err := os.ReadDir(fixturePath)
+	if err != nil {
+		b.Fatalf("failed reading test data: %v", err)
+	}
+	var specs [][]byte
+	for _, file := range files {
+		if strings.HasSuffix(file.Name(), ".yaml") {
+			data, _ := os.ReadFile(fixturePath + file.Name())
+			specs = append(specs, data)
+		}
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, spec := range specs {
+			if _, err := Convert(context.Background(), &spec, O2kOptions{}); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+func Test_ConvertWorkers(t *testing.T) {
+	files, err := os.ReadDir(fixturePath)
+	if err != nil {
+		t.Fatalf("failed reading test data: %v", err)
+	}
+	specs := map[string][]byte{"generated": generateBenchmarkSpec(50)}
+	for _, file := range files {
+		if strings.HasSuffix(file.Name(), ".yaml") {
+			specs[file.Name()], _ = os.ReadFile(fixturePath + file.Name())
+		}
+	}
+
+	// the output must not depend on the number of workers
+	for name, spec := range specs {
+		expected, err := Convert(context.Background(), &spec, O2kOptions{Workers: 1})
+		if !assert.NoError(t, err, name) {
+			continue
+		}
+		for _, workers := range []int{2, 8} {
+			result, err := Convert(context.Background(), &spec, O2kOptions{Workers: workers})
+			assert.NoError(t, err, name)
+			assert.Equal(t, expected, result, "%s: %d workers", name, workers)
+		}
+	}
+}
@@ -0,0 +1,410 @@
+package convertoas3
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Kong/fw/kongcompat"
+	uuid "github.com/satori/go.uuid"
+)
+
+// Option is a functional option to configure a conversion, see NewO2kOptions.
+type Option func(*O2kOptions)
+
+// NewO2kOptions creates a set of options for Convert, by applying the given
+// functional options in order. The result can be checked using Validate.
+func NewO2kOptions(options ...Option) O2kOptions {
+	opts := O2kOptions{}
+	for _, option := range options {
+		option(&opts)
+	}
+	return opts
+}
+
+// WithTags sets the tags to mark all generated entities with, 'x-kong-tags' will be ignored.
+func WithTags(tags []string) Option {
+	return func(opts *O2kOptions) {
+		opts.Tags = &tags
+	}
+}
+
+// WithDocName sets the base document name, used for naming entities and UUID generation.
+func WithDocName(name string) Option {
+	return func(opts *O2kOptions) {
+		opts.DocName = name
+	}
+}
+
+// WithUUIDNamespace sets the namespace for UUID generation.
+func WithUUIDNamespace(namespace uuid.UUID) Option {
+	return func(opts *O2kOptions) {
+		opts.UUIDNamespace = namespace
+	}
+}
+
+// WithPathPrefix sets the prefix to add to all route paths.
+func WithPathPrefix(prefix string) Option {
+	return func(opts *O2kOptions) {
+		opts.PathPrefix = prefix
+	}
+}
+
+// WithPathPrefixFromVersion prefixes all route paths with '/v{major}', taken from 'info.version'.
+func WithPathPrefixFromVersion() Option {
+	return func(opts *O2kOptions) {
+		opts.PathPrefixFromVersion = true
+	}
+}
+
+// WithPlainPaths uses plain (prefix) paths instead of regexes, for paths without parameters.
+// Requires WithPrefixMatch, as plain paths always match as a prefix.
+func WithPlainPaths() Option {
+	return func(opts *O2kOptions) {
+		opts.PlainPaths = true
+	}
+}
+
+// WithPrefixMatch does not anchor regex paths with a '$', so they match as a prefix.
+func WithPrefixMatch() Option {
+	return func(opts *O2kOptions) {
+		opts.PrefixMatch = true
+	}
+}
+
+// WithCORSPreflight adds the OPTIONS method to routes with a 'cors' plugin.
+func WithCORSPreflight() Option {
+	return func(opts *O2kOptions) {
+		opts.CORSPreflight = true
+	}
+}
+
+// WithServiceTimeouts sets the timeouts (in ms) on every generated service.
+func WithServiceTimeouts(connect, read, write int) Option {
+	return func(opts *O2kOptions) {
+		opts.ConnectTimeout = connect
+		opts.ReadTimeout = read
+		opts.WriteTimeout = write
+	}
+}
+
+// WithRetries sets the retries on every generated service.
+func WithRetries(retries int) Option {
+	return func(opts *O2kOptions) {
+		opts.Retries = &retries
+	}
+}
+
+// WithUpstreamAlgorithm sets the load balancing algorithm on every generated upstream.
+func WithUpstreamAlgorithm(algorithm string) Option {
+	return func(opts *O2kOptions) {
+		opts.UpstreamAlgorithm = algorithm
+	}
+}
+
+// WithUpstreamHash sets what to hash on for every generated upstream, as "type[:input]".
+// The input is required for 'header', 'cookie', 'query_arg', and 'uri_capture'. The
+// fallback is optional, pass "" to omit it.
+func WithUpstreamHash(hashOn string, hashFallback string) Option {
+	return func(opts *O2kOptions) {
+		opts.UpstreamHashOn = hashOn
+		opts.UpstreamHashFallback = hashFallback
+	}
+}
+
+// WithUpstreamHostHeader sets the 'host_header' of generated upstreams to the server hostname.
+func WithUpstreamHostHeader() Option {
+	return func(opts *O2kOptions) {
+		opts.UpstreamHostHeader = true
+	}
+}
+
+// WithPluginTier sets the Kong edition targeted; PluginTierOSS or PluginTierEnterprise.
+func WithPluginTier(tier string) Option {
+	return func(opts *O2kOptions) {
+		opts.PluginTier = tier
+	}
+}
+
+// WithAllowedPlugins sets the only plugins the output may have.
+func WithAllowedPlugins(names ...string) Option {
+	return func(opts *O2kOptions) {
+		opts.AllowedPlugins = names
+	}
+}
+
+// WithDeniedPlugins sets the plugins the output may not have.
+func WithDeniedPlugins(names ...string) Option {
+	return func(opts *O2kOptions) {
+		opts.DeniedPlugins = names
+	}
+}
+
+// WithSecrets sets the handling of literal secrets in plugin configs and consumer keys;
+// SecretsWarn, SecretsFail, or SecretsIgnore.
+func WithSecrets(mode string) Option {
+	return func(opts *O2kOptions) {
+		opts.Secrets = mode
+	}
+}
+
+// WithKongVersion sets the Kong version targeted, eg. "2.8" or "3.4".
+func WithKongVersion(version string) Option {
+	return func(opts *O2kOptions) {
+		opts.KongVersion = version
+	}
+}
+
+// WithWorkspace sets the Kong Enterprise workspace for the output.
+func WithWorkspace(workspace string) Option {
+	return func(opts *O2kOptions) {
+		opts.Workspace = workspace
+	}
+}
+
+// WithTrailingSlash sets the handling of trailing slashes in paths; TrailingSlashStrict,
+// TrailingSlashOptional, or TrailingSlashDuplicate.
+func WithTrailingSlash(handling string) Option {
+	return func(opts *O2kOptions) {
+		opts.TrailingSlash = handling
+	}
+}
+
+// WithHeadForGet adds the HEAD method to the routes of GET operations.
+func WithHeadForGet() Option {
+	return func(opts *O2kOptions) {
+		opts.HeadForGet = true
+	}
+}
+
+// WithPluginInheritance sets the strategy for attaching inherited plugins;
+// PluginInheritanceMixed, PluginInheritanceService, or PluginInheritanceRoute.
+func WithPluginInheritance(strategy string) Option {
+	return func(opts *O2kOptions) {
+		opts.PluginInheritance = strategy
+	}
+}
+
+// WithKeepDuplicatePlugins keeps plugins that all routes of a service have on each route,
+// instead of moving them to the service.
+func WithKeepDuplicatePlugins() Option {
+	return func(opts *O2kOptions) {
+		opts.KeepDuplicatePlugins = true
+	}
+}
+
+// WithACLSource generates 'acl' plugins on routes; ACLFromTags or ACLFromScopes. They
+// do nothing without an authentication plugin to identify the consumer.
+func WithACLSource(source string) Option {
+	return func(opts *O2kOptions) {
+		opts.ACLSource = source
+	}
+}
+
+// WithLogger sets the logger for conversion diagnostics.
+func WithLogger(logger Logger) Option {
+	return func(opts *O2kOptions) {
+		opts.Logger = logger
+	}
+}
+
+// WithBestEffort skips paths and operations that fail to convert, logging them as warnings.
+func WithBestEffort() Option {
+	return func(opts *O2kOptions) {
+		opts.BestEffort = true
+	}
+}
+
+// WithStrictExtensions fails on unrecognized 'x-kong-...' extensions, instead of warning.
+func WithStrictExtensions() Option {
+	return func(opts *O2kOptions) {
+		opts.StrictExtensions = true
+	}
+}
+
+// WithValidateSpec validates the spec against the OpenAPI specification before converting.
+func WithValidateSpec() Option {
+	return func(opts *O2kOptions) {
+		opts.ValidateSpec = true
+	}
+}
+
+// WithWorkers sets the number of paths converted concurrently.
+func WithWorkers(workers int) Option {
+	return func(opts *O2kOptions) {
+		opts.Workers = workers
+	}
+}
+
+// WithGenerator adds the generator to the output, as '_info.generator'.
+func WithGenerator(generator string) Option {
+	return func(opts *O2kOptions) {
+		opts.Generator = generator
+	}
+}
+
+// WithEnvironments generates a set of entities per enum value of the server variable.
+func WithEnvironments(variable string) Option {
+	return func(opts *O2kOptions) {
+		opts.EnvironmentVariable = variable
+	}
+}
+
+// WithProvenanceTags tags each route with the spec element it was generated from.
+func WithProvenanceTags() Option {
+	return func(opts *O2kOptions) {
+		opts.ProvenanceTags = true
+	}
+}
+
+// WithSecurityPlugins generates authentication plugins on routes from the 'security' requirements.
+func WithSecurityPlugins() Option {
+	return func(opts *O2kOptions) {
+		opts.SecurityPlugins = true
+	}
+}
+
+// WithResponseHeaders generates response-transformer plugins on routes from the static
+// response headers.
+func WithResponseHeaders() Option {
+	return func(opts *O2kOptions) {
+		opts.ResponseHeaders = true
+	}
+}
+
+// WithCallbacks generates services and routes for the callback receivers of the operations.
+func WithCallbacks() Option {
+	return func(opts *O2kOptions) {
+		opts.Callbacks = true
+	}
+}
+
+// WithWebhookServer sets the URL of the service receiving the OpenAPI 3.1 webhooks.
+func WithWebhookServer(server string) Option {
+	return func(opts *O2kOptions) {
+		opts.WebhookServer = server
+	}
+}
+
+// WithIDSeed sets the seed for ID generation, so IDs do not depend on the document name.
+func WithIDSeed(seed string) Option {
+	return func(opts *O2kOptions) {
+		opts.IDSeed = seed
+	}
+}
+
+// WithComponents sets the shared 'x-kong' components that references can resolve to.
+func WithComponents(components map[string]interface{}) Option {
+	return func(opts *O2kOptions) {
+		opts.Components = components
+	}
+}
+
+// Validate checks the options for invalid values and contradictory settings. It is
+// called by Convert, but can be used to report option errors before reading any input.
+func (opts O2kOptions) Validate() error {
+	if opts.ConnectTimeout < 0 || opts.ReadTimeout < 0 || opts.WriteTimeout < 0 {
+		return fmt.Errorf("expected service timeouts to be positive, got %d, %d, %d",
+			opts.ConnectTimeout, opts.ReadTimeout, opts.WriteTimeout)
+	}
+	if opts.Retries != nil && *opts.Retries < 0 {
+		return fmt.Errorf("expected retries to be positive, got %d", *opts.Retries)
+	}
+	if opts.Workers < 0 {
+		return fmt.Errorf("expected workers to be positive, got %d", opts.Workers)
+	}
+
+	if opts.UpstreamAlgorithm != "" {
+		valid := false
+		for _, allowed := range upstreamAlgorithms {
+			valid = valid || opts.UpstreamAlgorithm == allowed
+		}
+		if !valid {
+			return fmt.Errorf("invalid upstream algorithm '%s', expected one of: %s",
+				opts.UpstreamAlgorithm, strings.Join(upstreamAlgorithms, ", "))
+		}
+	}
+	// validate the hash shorthands by applying them to a scratch upstream, the inputs
+	// are required, as x-kong-upstream-defaults is not known here
+	upstream := make(map[string]interface{})
+	if opts.UpstreamHashOn != "" {
+		if err := setUpstreamHash(upstream, "hash_on", opts.UpstreamHashOn); err != nil {
+			return err
+		}
+		if upstream["hash_on"] != "none" && opts.UpstreamAlgorithm != "" &&
+			opts.UpstreamAlgorithm != "consistent-hashing" {
+			return fmt.Errorf("an upstream hash requires the upstream algorithm to be "+
+				"'consistent-hashing', got '%s'", opts.UpstreamAlgorithm)
+		}
+	}
+	if opts.UpstreamHashFallback != "" {
+		if err := setUpstreamHash(upstream, "hash_fallback", opts.UpstreamHashFallback); err != nil {
+			return err
+		}
+	}
+
+	switch opts.PluginTier {
+	case "", PluginTierOSS, PluginTierEnterprise:
+	default:
+		return fmt.Errorf("invalid plugin tier '%s', expected '%s' or '%s'",
+			opts.PluginTier, PluginTierOSS, PluginTierEnterprise)
+	}
+
+	for _, denied := range opts.DeniedPlugins {
+		for _, allowed := range opts.AllowedPlugins {
+			if denied == allowed {
+				return fmt.Errorf("expected plugin '%s' to be either allowed or denied, got both", denied)
+			}
+		}
+	}
+
+	if opts.KongVersion != "" {
+		version, err := kongcompat.ParseVersion(opts.KongVersion)
+		if err != nil {
+			return fmt.Errorf("invalid Kong version: %w", err)
+		}
+		if version.Before(kongcompat.MinimumVersion) {
+			return fmt.Errorf("invalid Kong version '%s', expected %s or later", opts.KongVersion,
+				kongcompat.MinimumVersion)
+		}
+	}
+
+	if opts.PlainPaths && !opts.PrefixMatch {
+		// Kong matches plain paths as a prefix, so they cannot match exactly
+		return fmt.Errorf("plain paths match as a prefix, so they require prefix matching")
+	}
+
+	if (opts.PathPrefix != "" || opts.PathPrefixFromVersion) && opts.PrefixMatch {
+		return fmt.Errorf("a path prefix cannot be combined with prefix matching; the prefix is " +
+			"stripped by rewriting the upstream path to the path of the operation")
+	}
+
+	switch opts.TrailingSlash {
+	case "", TrailingSlashStrict, TrailingSlashOptional, TrailingSlashDuplicate:
+	default:
+		return fmt.Errorf("invalid trailing slash handling '%s', expected '%s', '%s', or '%s'",
+			opts.TrailingSlash, TrailingSlashStrict, TrailingSlashOptional, TrailingSlashDuplicate)
+	}
+
+	switch opts.PluginInheritance {
+	case "", PluginInheritanceMixed, PluginInheritanceService, PluginInheritanceRoute:
+	default:
+		return fmt.Errorf("invalid plugin inheritance '%s', expected '%s', '%s', or '%s'",
+			opts.PluginInheritance, PluginInheritanceMixed, PluginInheritanceService, PluginInheritanceRoute)
+	}
+
+	switch opts.ACLSource {
+	case "", ACLFromTags, ACLFromScopes:
+	default:
+		return fmt.Errorf("invalid ACL source '%s', expected '%s' or '%s'",
+			opts.ACLSource, ACLFromTags, ACLFromScopes)
+	}
+
+	switch opts.Secrets {
+	case "", SecretsWarn, SecretsFail, SecretsIgnore:
+	default:
+		return fmt.Errorf("invalid secrets handling '%s', expected '%s', '%s', or '%s'",
+			opts.Secrets, SecretsWarn, SecretsFail, SecretsIgnore)
+	}
+
+	return nil
+}
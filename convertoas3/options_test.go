@@ -0,0 +1,93 @@
+package convertoas3
+
+import (
+	"testing"
+
+	uuid "github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_NewO2kOptions(t *testing.T) {
+	opts := NewO2kOptions(
+		WithTags([]string{"a", "b"}),
+		WithDocName("doc"),
+		WithUUIDNamespace(uuid.NamespaceURL),
+		WithPrefixMatch(),
+		WithServiceTimeouts(1, 2, 3),
+		WithRetries(0),
+		WithUpstreamHash("header:X-Id", "ip"),
+	)
+
+	retries := 0
+	assert.Equal(t, O2kOptions{
+		Tags:                 &[]string{"a", "b"},
+		DocName:              "doc",
+		UUIDNamespace:        uuid.NamespaceURL,
+		PrefixMatch:          true,
+		ConnectTimeout:       1,
+		ReadTimeout:          2,
+		WriteTimeout:         3,
+		Retries:              &retries,
+		UpstreamHashOn:       "header:X-Id",
+		UpstreamHashFallback: "ip",
+	}, opts)
+
+	// later options override earlier ones
+	opts = NewO2kOptions(WithDocName("first"), WithDocName("second"))
+	assert.Equal(t, "second", opts.DocName)
+}
+
+func Test_O2kOptionsValidate(t *testing.T) {
+	tests := []struct {
+		name        string
+		opts        O2kOptions
+		expectError bool
+	}{
+		{"no options", NewO2kOptions(), false},
+		{"valid options", NewO2kOptions(
+			WithUpstreamAlgorithm("consistent-hashing"),
+			WithUpstreamHash("header:X-Id", "ip"),
+			WithPluginTier(PluginTierOSS),
+			WithACLSource(ACLFromScopes),
+		), false},
+		{"fallback only", NewO2kOptions(WithUpstreamHash("", "ip")), false},
+		{"hash 'none' with other algorithm", NewO2kOptions(
+			WithUpstreamAlgorithm("round-robin"),
+			WithUpstreamHash("none", ""),
+		), false},
+		{"negative timeout", NewO2kOptions(WithServiceTimeouts(1, -1, 1)), true},
+		{"negative retries", NewO2kOptions(WithRetries(-1)), true},
+		{"negative workers", NewO2kOptions(WithWorkers(-1)), true},
+		{"bad algorithm", NewO2kOptions(WithUpstreamAlgorithm("random")), true},
+		{"bad hash", NewO2kOptions(WithUpstreamHash("body", "")), true},
+		{"allowed and denied", NewO2kOptions(WithAllowedPlugins("cors"), WithDeniedPlugins("pre-function")), false},
+		{"allowed and denied plugin", NewO2kOptions(WithAllowedPlugins("cors"), WithDeniedPlugins("cors")), true},
+		{"bad hash fallback", NewO2kOptions(WithUpstreamHash("ip", "header:")), true},
+		{"hash without input", NewO2kOptions(WithUpstreamHash("query_arg", "")), true},
+		{"hash fallback without input", NewO2kOptions(WithUpstreamHash("ip", "cookie")), true},
+		{"cookie on both", NewO2kOptions(WithUpstreamHash("cookie:sid", "cookie:sid")), false},
+		{"hash with other algorithm", NewO2kOptions(
+			WithUpstreamAlgorithm("least-connections"),
+			WithUpstreamHash("consumer", ""),
+		), true},
+		{"bad plugin tier", NewO2kOptions(WithPluginTier("free")), true},
+		{"bad Kong version", NewO2kOptions(WithKongVersion("latest")), true},
+		{"Kong version too old", NewO2kOptions(WithKongVersion("2.7")), true},
+		{"Kong version", NewO2kOptions(WithKongVersion("2.8")), false},
+		{"bad ACL source", NewO2kOptions(WithACLSource("roles")), true},
+		{"plain paths", NewO2kOptions(WithPlainPaths(), WithPrefixMatch()), false},
+		{"plain paths with exact match", NewO2kOptions(WithPlainPaths()), true},
+		{"path prefix with prefix match", NewO2kOptions(WithPathPrefixFromVersion(), WithPrefixMatch()), true},
+		{"secrets", NewO2kOptions(WithSecrets(SecretsFail)), false},
+		{"bad secrets handling", NewO2kOptions(WithSecrets("error")), true},
+	}
+
+	for _, tst := range tests {
+		err := tst.opts.Validate()
+		if tst.expectError {
+			assert.Error(t, err, tst.name)
+		} else {
+			assert.NoError(t, err, tst.name)
+		}
+	}
+}
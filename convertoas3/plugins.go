@@ -0,0 +1,346 @@
+package convertoas3
+
+import (
+	"fmt"
+	"sort"
+)
+
+const (
+	// PluginTierOSS targets Kong Gateway OSS, Enterprise-only plugins are not allowed.
+	PluginTierOSS = "oss"
+	// PluginTierEnterprise targets Kong Gateway Enterprise, all plugins are allowed.
+	PluginTierEnterprise = "enterprise"
+)
+
+// enterprisePlugins is the catalogue of bundled plugins that are only available
+// in Kong Gateway Enterprise.
+var enterprisePlugins = map[string]bool{
+	"application-registration":       true,
+	"canary":                         true,
+	"degraphql":                      true,
+	"exit-transformer":               true,
+	"forward-proxy":                  true,
+	"graphql-proxy-cache-advanced":   true,
+	"graphql-rate-limiting-advanced": true,
+	"jq":                             true,
+	"json-threat-protection":         true,
+	"jwe-decrypt":                    true,
+	"jwt-signer":                     true,
+	"kafka-log":                      true,
+	"kafka-upstream":                 true,
+	"key-auth-enc":                   true,
+	"ldap-auth-advanced":             true,
+	"mocking":                        true,
+	"mtls-auth":                      true,
+	"oas-validation":                 true,
+	"oauth2-introspection":           true,
+	"opa":                            true,
+	"openid-connect":                 true,
+	"proxy-cache-advanced":           true,
+	"rate-limiting-advanced":         true,
+	"request-transformer-advanced":   true,
+	"request-validator":              true,
+	"response-transformer-advanced":  true,
+	"route-by-header":                true,
+	"route-transformer-advanced":     true,
+	"saml":                           true,
+	"statsd-advanced":                true,
+	"tls-handshake-modifier":         true,
+	"tls-metadata-headers":           true,
+	"upstream-timeout":               true,
+	"vault-auth":                     true,
+	"websocket-size-limit":           true,
+	"websocket-validator":            true,
+	"xml-threat-protection":          true,
+}
+
+// ossPlugins is the catalogue of bundled plugins available in both Kong Gateway
+// OSS and Enterprise.
+var ossPlugins = map[string]bool{
+	"acl":                   true,
+	"acme":                  true,
+	"aws-lambda":            true,
+	"azure-functions":       true,
+	"basic-auth":            true,
+	"bot-detection":         true,
+	"correlation-id":        true,
+	"cors":                  true,
+	"datadog":               true,
+	"file-log":              true,
+	"grpc-gateway":          true,
+	"grpc-web":              true,
+	"hmac-auth":             true,
+	"http-log":              true,
+	"ip-restriction":        true,
+	"jwt":                   true,
+	"key-auth":              true,
+	"ldap-auth":             true,
+	"loggly":                true,
+	"oauth2":                true,
+	"opentelemetry":         true,
+	"post-function":         true,
+	"pre-function":          true,
+	"prometheus":            true,
+	"proxy-cache":           true,
+	"rate-limiting":         true,
+	"request-size-limiting": true,
+	"request-termination":   true,
+	"request-transformer":   true,
+	"response-ratelimiting": true,
+	"response-transformer":  true,
+	"session":               true,
+	"statsd":                true,
+	"syslog":                true,
+	"tcp-log":               true,
+	"udp-log":               true,
+	"zipkin":                true,
+}
+
+// IsEnterprisePlugin returns true if the plugin is a bundled Enterprise-only plugin.
+func IsEnterprisePlugin(name string) bool {
+	return enterprisePlugins[name]
+}
+
+// IsBundledPlugin returns true if the plugin is a bundled plugin (OSS or Enterprise).
+// Plugins not bundled are custom plugins.
+func IsBundledPlugin(name string) bool {
+	return ossPlugins[name] || enterprisePlugins[name]
+}
+
+// pluginProtocols are the protocols a plugin can be configured to run on.
+var pluginProtocols = map[string]bool{
+	"grpc":            true,
+	"grpcs":           true,
+	"http":            true,
+	"https":           true,
+	"tcp":             true,
+	"tls":             true,
+	"tls_passthrough": true,
+	"udp":             true,
+	"ws":              true,
+	"wss":             true,
+}
+
+// validatePluginFields validates the plugin entity fields of a plugin config, given at
+// the location (for error messages); 'enabled', 'protocols', 'consumer', 'consumer_group',
+// and 'ordering'. The fields in foreignKeys are not allowed, since the plugin is attached
+// to the entity generated from where it is given.
+func validatePluginFields(plugin map[string]interface{}, location string, foreignKeys ...string) error {
+	for _, key := range foreignKeys {
+		if value, found := plugin[key]; found {
+			return fmt.Errorf("expected '%s' to not set '%s', the plugin is attached where it is given, got: '%v'",
+				location, key, value)
+		}
+	}
+
+	if enabled, found := plugin["enabled"]; found {
+		if _, ok := enabled.(bool); !ok {
+			return fmt.Errorf("expected '%s/enabled' to be a boolean, got: '%v'", location, enabled)
+		}
+	}
+
+	if protocols, found := plugin["protocols"]; found {
+		list, ok := protocols.([]interface{})
+		if !ok || len(list) == 0 {
+			return fmt.Errorf("expected '%s/protocols' to be a non-empty array, got: '%v'", location, protocols)
+		}
+		for _, protocol := range list {
+			if name, ok := protocol.(string); !ok || !pluginProtocols[name] {
+				return fmt.Errorf("expected '%s/protocols' to contain valid protocols, got: '%v'", location, protocol)
+			}
+		}
+	}
+
+	for _, key := range []string{"consumer", "consumer_group"} {
+		if value, found := plugin[key]; found {
+			if name, ok := value.(string); !ok || name == "" {
+				return fmt.Errorf("expected '%s/%s' to be a non-empty string, got: '%v'", location, key, value)
+			}
+		}
+	}
+
+	if ordering, found := plugin["ordering"]; found {
+		if plugin["consumer"] != nil || plugin["consumer_group"] != nil {
+			return fmt.Errorf("expected '%s' to not set 'ordering' on a plugin bound to a consumer "+
+				"or consumer group, Kong does not support it", location)
+		}
+		if err := validatePluginOrdering(ordering, location+"/ordering"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validatePluginOrdering validates the 'ordering' of a plugin; an object with 'before'
+// and/or 'after', each an object with the phase ('access') and an array of plugin names.
+func validatePluginOrdering(ordering interface{}, location string) error {
+	object, ok := ordering.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("expected '%s' to be an object, got: '%v'", location, ordering)
+	}
+	for key, value := range object {
+		if key != "before" && key != "after" {
+			return fmt.Errorf("expected '%s' to only have 'before' and 'after', got: '%s'", location, key)
+		}
+		phases, ok := value.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("expected '%s/%s' to be an object, got: '%v'", location, key, value)
+		}
+		for phase, names := range phases {
+			if phase != "access" {
+				return fmt.Errorf("expected '%s/%s' to only have the 'access' phase, got: '%s'",
+					location, key, phase)
+			}
+			list, ok := names.([]interface{})
+			if !ok {
+				return fmt.Errorf("expected '%s/%s/%s' to be an array of plugin names, got: '%v'",
+					location, key, phase, names)
+			}
+			for _, name := range list {
+				if _, ok := name.(string); !ok {
+					return fmt.Errorf("expected '%s/%s/%s' to be an array of plugin names, got: '%v'",
+						location, key, phase, names)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// pluginRef is a plugin found in the output document, along with a description
+// of the entity it is attached to.
+type pluginRef struct {
+	plugin map[string]interface{}
+	owner  string // eg. "service 'name'", or "route 'name'"
+}
+
+// toPluginSlice returns the plugins from a plugin list. Plugin lists are either
+// *[]*map[string]interface{} (as generated) or []interface{} (empty defaults).
+func toPluginSlice(list interface{}) []map[string]interface{} {
+	result := make([]map[string]interface{}, 0)
+	switch plugins := list.(type) {
+	case *[]*map[string]interface{}:
+		if plugins != nil {
+			for _, plugin := range *plugins {
+				result = append(result, *plugin)
+			}
+		}
+	case []interface{}:
+		for _, plugin := range plugins {
+			if p, ok := plugin.(map[string]interface{}); ok {
+				result = append(result, p)
+			}
+		}
+	}
+	return result
+}
+
+// collectPlugins returns all plugins in the output document, in output order. Entities
+// that are not objects, eg. in a document written by a policy, are skipped.
+func collectPlugins(result map[string]interface{}) []pluginRef {
+	refs := make([]pluginRef, 0)
+	services, _ := result["services"].([]interface{})
+	for _, s := range services {
+		service, _ := s.(map[string]interface{})
+		for _, plugin := range toPluginSlice(service["plugins"]) {
+			refs = append(refs, pluginRef{plugin, fmt.Sprintf("service '%s'", service["name"])})
+		}
+		routes, _ := service["routes"].([]interface{})
+		for _, r := range routes {
+			route, _ := r.(map[string]interface{})
+			for _, plugin := range toPluginSlice(route["plugins"]) {
+				refs = append(refs, pluginRef{plugin, fmt.Sprintf("route '%s'", route["name"])})
+			}
+		}
+	}
+	groups, _ := result["consumer_groups"].([]interface{})
+	for _, g := range groups {
+		group, _ := g.(map[string]interface{})
+		for _, plugin := range toPluginSlice(group["plugins"]) {
+			refs = append(refs, pluginRef{plugin, fmt.Sprintf("consumer group '%s'", group["name"])})
+		}
+	}
+	for _, plugin := range toPluginSlice(result["plugins"]) {
+		refs = append(refs, pluginRef{plugin, "document"})
+	}
+	return refs
+}
+
+// checkPluginTier validates the plugins in the output document against the targeted
+// tier. Returns an error listing the Enterprise-only plugins when targeting OSS.
+func checkPluginTier(result map[string]interface{}, tier string) error {
+	switch tier {
+	case "", PluginTierEnterprise:
+		return nil
+	case PluginTierOSS:
+		// validated below
+	default:
+		return fmt.Errorf("invalid plugin tier '%s', expected '%s' or '%s'", tier, PluginTierOSS, PluginTierEnterprise)
+	}
+
+	found := make(map[string]bool)
+	for _, ref := range collectPlugins(result) {
+		name, _ := ref.plugin["name"].(string)
+		if IsEnterprisePlugin(name) {
+			found[fmt.Sprintf("'%s' (on %s)", name, ref.owner)] = true
+		}
+	}
+	if len(found) == 0 {
+		return nil
+	}
+
+	list := make([]string, 0, len(found))
+	for entry := range found {
+		list = append(list, entry)
+	}
+	sort.Strings(list)
+	return fmt.Errorf("Enterprise-only plugins are not available in Kong OSS: %v", list)
+}
+
+// checkAllowedPlugins validates the plugins in the output document against the allowed
+// and denied plugins. An empty allowed list allows all plugins. Returns an error listing
+// the plugins not allowed.
+func checkAllowedPlugins(result map[string]interface{}, allowed []string, denied []string) error {
+	if len(allowed) == 0 && len(denied) == 0 {
+		return nil
+	}
+
+	found := make(map[string]bool)
+	for _, ref := range collectPlugins(result) {
+		name, _ := ref.plugin["name"].(string)
+		valid := len(allowed) == 0
+		for _, allowedName := range allowed {
+			valid = valid || name == allowedName
+		}
+		for _, deniedName := range denied {
+			valid = valid && name != deniedName
+		}
+		if !valid {
+			found[fmt.Sprintf("'%s' (on %s)", name, ref.owner)] = true
+		}
+	}
+	if len(found) == 0 {
+		return nil
+	}
+
+	list := make([]string, 0, len(found))
+	for entry := range found {
+		list = append(list, entry)
+	}
+	sort.Strings(list)
+	return fmt.Errorf("plugins not allowed by the platform: %v", list)
+}
+
+// CheckPlugins runs the plugin checks of Convert on a declarative file; the plugin tier,
+// the allowed and denied plugins, and the literal secrets, see O2kOptions. Use it on a
+// declarative file changed after the conversion, eg. by a policy.
+func CheckPlugins(result map[string]interface{}, opts O2kOptions) error {
+	opts.setDefaults()
+	if err := checkPluginTier(result, opts.PluginTier); err != nil {
+		return err
+	}
+	if err := checkAllowedPlugins(result, opts.AllowedPlugins, opts.DeniedPlugins); err != nil {
+		return err
+	}
+	return checkSecrets(result, opts.Secrets, opts.Logger)
+}
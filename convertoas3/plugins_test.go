@@ -0,0 +1,191 @@
+package convertoas3
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_checkPluginTier(t *testing.T) {
+	spec := []byte(`
+openapi: 3.0.3
+info:
+  title: tiers
+  version: v1
+x-kong-plugin-key-auth: {}
+paths:
+  /users:
+    get:
+      x-kong-plugin-rate-limiting-advanced:
+        config:
+          limit: [10]
+          window_size: [60]
+      responses:
+        "200":
+          description: OK
+`)
+
+	_, err := Convert(context.Background(), &spec, O2kOptions{})
+	assert.NoError(t, err)
+
+	_, err = Convert(context.Background(), &spec, O2kOptions{PluginTier: PluginTierEnterprise})
+	assert.NoError(t, err)
+
+	_, err = Convert(context.Background(), &spec, O2kOptions{PluginTier: PluginTierOSS})
+	assert.EqualError(t, err, "Enterprise-only plugins are not available in Kong OSS: "+
+		"['rate-limiting-advanced' (on route 'tiers_users_get')]")
+
+	_, err = Convert(context.Background(), &spec, O2kOptions{PluginTier: "free"})
+	assert.Error(t, err)
+}
+
+func Test_checkAllowedPlugins(t *testing.T) {
+	spec := []byte(`
+openapi: 3.0.3
+info:
+  title: governance
+  version: v1
+x-kong-plugin-key-auth: {}
+paths:
+  /users:
+    get:
+      x-kong-plugin-pre-function:
+        config:
+          access: ["kong.log.err('hi')"]
+      x-kong-plugin-rate-limiting:
+        config:
+          minute: 10
+      responses:
+        "200":
+          description: OK
+`)
+
+	_, err := Convert(context.Background(), &spec, NewO2kOptions(WithAllowedPlugins("key-auth", "pre-function",
+		"rate-limiting")))
+	assert.NoError(t, err)
+
+	_, err = Convert(context.Background(), &spec, NewO2kOptions(WithDeniedPlugins("pre-function")))
+	assert.EqualError(t, err, "plugins not allowed by the platform: "+
+		"['pre-function' (on route 'governance_users_get')]")
+
+	_, err = Convert(context.Background(), &spec, NewO2kOptions(WithAllowedPlugins("key-auth")))
+	assert.EqualError(t, err, "plugins not allowed by the platform: "+
+		"['pre-function' (on route 'governance_users_get') "+
+		"'rate-limiting' (on route 'governance_users_get')]")
+
+	// both lists apply
+	_, err = Convert(context.Background(), &spec, NewO2kOptions(
+		WithAllowedPlugins("pre-function", "rate-limiting"), WithDeniedPlugins("key-auth")))
+	assert.EqualError(t, err, "plugins not allowed by the platform: ['key-auth' (on service 'governance')]")
+}
+
+func Test_CheckPlugins(t *testing.T) {
+	// as written by a policy, so decoded from JSON
+	var result map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"services": [{"name": "svc", "routes": [{"name": "route", "plugins": [
+			{"name": "rate-limiting-advanced", "config": {}},
+			{"name": "openid-connect", "config": {"client_secret": ["s3cr3t"]}}
+		]}, "not a route"]}],
+		"plugins": [{"name": "pre-function", "config": {}}]
+	}`), &result))
+
+	assert.NoError(t, CheckPlugins(result, O2kOptions{}))
+	assert.EqualError(t, CheckPlugins(result, O2kOptions{PluginTier: PluginTierOSS}),
+		"Enterprise-only plugins are not available in Kong OSS: "+
+			"['openid-connect' (on route 'route') 'rate-limiting-advanced' (on route 'route')]")
+	assert.EqualError(t, CheckPlugins(result, NewO2kOptions(WithDeniedPlugins("pre-function"))),
+		"plugins not allowed by the platform: ['pre-function' (on document)]")
+	assert.EqualError(t, CheckPlugins(result, NewO2kOptions(WithSecrets(SecretsFail))),
+		"literal secrets in plugin configs or consumer keys, use vault references, eg. "+
+			"'{vault://env/name}': ['config.client_secret[0]' of plugin 'openid-connect' (on route 'route')]")
+}
+
+func Test_IsBundledPlugin(t *testing.T) {
+	assert.True(t, IsBundledPlugin("key-auth"))
+	assert.True(t, IsBundledPlugin("openid-connect"))
+	assert.False(t, IsBundledPlugin("my-custom-plugin"))
+	assert.True(t, IsEnterprisePlugin("openid-connect"))
+	assert.False(t, IsEnterprisePlugin("key-auth"))
+}
+
+func Test_validatePluginFields(t *testing.T) {
+	tests := []struct {
+		name   string
+		plugin string
+		err    string
+	}{
+		{"valid", `{"enabled": false, "protocols": ["http", "https"], "consumer": "john"}`, ""},
+		{"ordering", `{"ordering": {"before": {"access": ["key-auth"]}}}`, ""},
+		{"service", `{"service": "other"}`,
+			"expected 'x-kong-plugin-cors' to not set 'service', the plugin is attached where it is given, got: 'other'"},
+		{"route", `{"route": "other"}`,
+			"expected 'x-kong-plugin-cors' to not set 'route', the plugin is attached where it is given, got: 'other'"},
+		{"enabled", `{"enabled": "yes"}`, "expected 'x-kong-plugin-cors/enabled' to be a boolean, got: 'yes'"},
+		{"no protocols", `{"protocols": []}`,
+			"expected 'x-kong-plugin-cors/protocols' to be a non-empty array, got: '[]'"},
+		{"protocol", `{"protocols": ["http", "ftp"]}`,
+			"expected 'x-kong-plugin-cors/protocols' to contain valid protocols, got: 'ftp'"},
+		{"consumer", `{"consumer": 42}`, "expected 'x-kong-plugin-cors/consumer' to be a non-empty string, got: '42'"},
+		{"consumer ordering", `{"consumer": "john", "ordering": {"after": {"access": ["acl"]}}}`,
+			"expected 'x-kong-plugin-cors' to not set 'ordering' on a plugin bound to a consumer or consumer " +
+				"group, Kong does not support it"},
+		{"ordering phase", `{"ordering": {"before": {"rewrite": ["acl"]}}}`,
+			"expected 'x-kong-plugin-cors/ordering/before' to only have the 'access' phase, got: 'rewrite'"},
+		{"ordering key", `{"ordering": {"first": {}}}`,
+			"expected 'x-kong-plugin-cors/ordering' to only have 'before' and 'after', got: 'first'"},
+		{"ordering names", `{"ordering": {"after": {"access": "acl"}}}`,
+			"expected 'x-kong-plugin-cors/ordering/after/access' to be an array of plugin names, got: 'acl'"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var plugin map[string]interface{}
+			require.NoError(t, json.Unmarshal([]byte(tt.plugin), &plugin))
+			err := validatePluginFields(plugin, "x-kong-plugin-cors", "service", "route")
+			if tt.err == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tt.err)
+			}
+		})
+	}
+}
+
+func Test_ConvertPluginFields(t *testing.T) {
+	spec := []byte(`
+openapi: 3.0.3
+info:
+  title: fields
+  version: v1
+paths:
+  /users:
+    get:
+      x-kong-plugin-cors:
+        enabled: false
+        protocols: [https]
+      responses:
+        "200":
+          description: OK
+`)
+
+	result, err := Convert(context.Background(), &spec, O2kOptions{})
+	require.NoError(t, err)
+	route := result["services"].([]interface{})[0].(map[string]interface{})["routes"].([]interface{})[0]
+	cors := *(*route.(map[string]interface{})["plugins"].(*[]*map[string]interface{}))[0]
+	assert.Equal(t, false, cors["enabled"])
+	assert.Equal(t, []interface{}{"https"}, cors["protocols"])
+
+	spec = bytes.Replace(spec, []byte("enabled: false"), []byte("route: other"), 1)
+	_, err = Convert(context.Background(), &spec, O2kOptions{})
+	assert.ErrorContains(t, err, "/paths/~1users/get/x-kong-plugin-cors: failed to create plugins list "+
+		"from operation item: expected 'x-kong-plugin-cors' to not set 'route'")
+
+	spec = bytes.Replace(spec, []byte("route: other"), []byte("name: key-auth"), 1)
+	_, err = Convert(context.Background(), &spec, O2kOptions{})
+	assert.ErrorContains(t, err, "/paths/~1users/get/x-kong-plugin-cors/name: failed to create plugins "+
+		"list from operation item: expected 'x-kong-plugin-cors/name' to be 'cors', got: 'key-auth'")
+}
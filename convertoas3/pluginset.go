@@ -0,0 +1,90 @@
+package convertoas3
+
+import (
+	"sort"
+)
+
+// pluginSet is a set of plugin configs, with at most one plugin per name, kept sorted
+// by plugin name so iteration (and the generated output) is deterministic. Every
+// plugin config must have a string 'name' field.
+type pluginSet struct {
+	plugins []*map[string]interface{}
+}
+
+// newPluginSet creates a pluginSet from a list of plugin configs. The list is copied, so
+// changes to the set do not alter the list. If the list has multiple plugins with the
+// same name, the last one is kept. A nil list returns an empty set.
+func newPluginSet(list *[]*map[string]interface{}) *pluginSet {
+	set := &pluginSet{plugins: make([]*map[string]interface{}, 0)}
+	if list != nil {
+		for _, plugin := range *list {
+			set.insert(plugin)
+		}
+	}
+	return set
+}
+
+func pluginName(plugin *map[string]interface{}) string {
+	return (*plugin)["name"].(string) // safe because it was previously parsed
+}
+
+// search returns the index of the plugin with the given name, and whether it was found.
+// If not found, the index is where it would be inserted.
+func (set *pluginSet) search(name string) (int, bool) {
+	i := sort.Search(len(set.plugins), func(i int) bool {
+		return pluginName(set.plugins[i]) >= name
+	})
+	return i, i < len(set.plugins) && pluginName(set.plugins[i]) == name
+}
+
+// insert adds the plugin to the set. If the set already has a plugin with the same
+// name, it is replaced, and the replaced plugin is returned. Otherwise returns nil.
+func (set *pluginSet) insert(plugin *map[string]interface{}) *map[string]interface{} {
+	i, found := set.search(pluginName(plugin))
+	if found {
+		replaced := set.plugins[i]
+		set.plugins[i] = plugin
+		return replaced
+	}
+	set.plugins = append(set.plugins, nil)
+	copy(set.plugins[i+1:], set.plugins[i:])
+	set.plugins[i] = plugin
+	return nil
+}
+
+// replace replaces the plugin with the same name in the set. Returns false, and does
+// not change the set, if there is no such plugin.
+func (set *pluginSet) replace(plugin *map[string]interface{}) bool {
+	i, found := set.search(pluginName(plugin))
+	if found {
+		set.plugins[i] = plugin
+	}
+	return found
+}
+
+// remove removes the plugin with the given name from the set, and returns it. Returns
+// nil if there is no such plugin.
+func (set *pluginSet) remove(name string) *map[string]interface{} {
+	i, found := set.search(name)
+	if !found {
+		return nil
+	}
+	removed := set.plugins[i]
+	set.plugins = append(set.plugins[:i], set.plugins[i+1:]...)
+	return removed
+}
+
+// get returns the plugin with the given name, or nil if there is no such plugin.
+func (set *pluginSet) get(name string) *map[string]interface{} {
+	if i, found := set.search(name); found {
+		return set.plugins[i]
+	}
+	return nil
+}
+
+// list returns the plugins sorted by name, as a new list.
+func (set *pluginSet) list() *[]*map[string]interface{} {
+	list := make([]*map[string]interface{}, len(set.plugins))
+	copy(list, set.plugins)
+	return &list
+}
@@ -0,0 +1,80 @@
+package convertoas3
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestPlugin(name string, id string) *map[string]interface{} {
+	return &map[string]interface{}{"name": name, "id": id}
+}
+
+func getPluginIDs(list *[]*map[string]interface{}) []string {
+	ids := make([]string, len(*list))
+	for i, plugin := range *list {
+		ids[i] = (*plugin)["id"].(string)
+	}
+	return ids
+}
+
+func Test_newPluginSet(t *testing.T) {
+	list := []*map[string]interface{}{
+		newTestPlugin("cors", "1"),
+		newTestPlugin("acl", "2"),
+		newTestPlugin("cors", "3"),
+	}
+	set := newPluginSet(&list)
+	assert.Equal(t, []string{"2", "3"}, getPluginIDs(set.list()), "sorted, last duplicate wins")
+
+	set.remove("acl")
+	assert.Len(t, list, 3, "the input list is not changed")
+
+	assert.Empty(t, *newPluginSet(nil).list())
+}
+
+func Test_pluginSetInsert(t *testing.T) {
+	tests := []struct {
+		name     string
+		plugin   string
+		expected []string
+		replaced bool
+	}{
+		{"insert first", "acl", []string{"new", "c", "r", "z"}, false},
+		{"insert middle", "request-validator", []string{"c", "r", "new", "z"}, false},
+		{"insert between", "key-auth", []string{"c", "new", "r", "z"}, false},
+		{"insert last", "zipkin2", []string{"c", "r", "z", "new"}, false},
+		{"replace existing", "rate-limiting", []string{"c", "new", "z"}, true},
+	}
+
+	for _, tst := range tests {
+		list := []*map[string]interface{}{
+			newTestPlugin("cors", "c"),
+			newTestPlugin("rate-limiting", "r"),
+			newTestPlugin("zipkin", "z"),
+		}
+		set := newPluginSet(&list)
+		replaced := set.insert(newTestPlugin(tst.plugin, "new"))
+		assert.Equal(t, tst.expected, getPluginIDs(set.list()), tst.name)
+		assert.Equal(t, tst.replaced, replaced != nil, tst.name)
+	}
+}
+
+func Test_pluginSetReplaceRemove(t *testing.T) {
+	list := []*map[string]interface{}{
+		newTestPlugin("cors", "c"),
+		newTestPlugin("rate-limiting", "r"),
+	}
+	set := newPluginSet(&list)
+
+	assert.False(t, set.replace(newTestPlugin("acl", "a")))
+	assert.Equal(t, []string{"c", "r"}, getPluginIDs(set.list()))
+	assert.True(t, set.replace(newTestPlugin("cors", "c2")))
+	assert.Equal(t, []string{"c2", "r"}, getPluginIDs(set.list()))
+
+	assert.Nil(t, set.remove("acl"))
+	assert.Equal(t, "c2", (*set.remove("cors"))["id"])
+	assert.Equal(t, []string{"r"}, getPluginIDs(set.list()))
+	assert.Nil(t, set.get("cors"))
+	assert.Equal(t, "r", (*set.get("rate-limiting"))["id"])
+}
@@ -0,0 +1,96 @@
+package convertoas3
+
+import (
+	"strconv"
+	"strings"
+
+	yaml "sigs.k8s.io/yaml/goyaml.v3"
+)
+
+// Positions locates the elements of a YAML or JSON document, eg. the spec, by their
+// JSON pointer, to annotate the file with findings.
+type Positions struct {
+	root *yaml.Node // nil if the content could not be parsed
+}
+
+// NewPositions parses the content for locating its elements. Content that cannot be
+// parsed is not an error, its elements are just not found.
+func NewPositions(content []byte) *Positions {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(content, &doc); err != nil || len(doc.Content) == 0 {
+		return &Positions{}
+	}
+	return &Positions{root: doc.Content[0]}
+}
+
+// Position returns the line and column (both 1-based) of the element at the JSON
+// pointer. For an object member, it is the position of its key. If the element does not
+// exist, the position of its closest existing parent is returned. Returns 0, 0 if the
+// pointer is not a JSON pointer, or the content could not be parsed.
+func (p *Positions) Position(pointer string) (line int, column int) {
+	if p == nil || p.root == nil || (pointer != "" && !strings.HasPrefix(pointer, "/")) {
+		return 0, 0
+	}
+
+	node := p.root
+	line, column = node.Line, node.Column
+	if pointer == "" {
+		return line, column
+	}
+	for _, segment := range strings.Split(pointer[1:], "/") {
+		token := strings.ReplaceAll(strings.ReplaceAll(segment, "~1", "/"), "~0", "~")
+		key, value := childNode(node, token)
+		if value == nil {
+			break
+		}
+		line, column = key.Line, key.Column
+		node = value
+	}
+	return line, column
+}
+
+// childNode returns the node of the object member, or array item, named by the token,
+// and the node to report its position with; the key for object members. Aliases and
+// merge keys ('<<') are followed. Returns nil values if there is no such element.
+func childNode(node *yaml.Node, token string) (*yaml.Node, *yaml.Node) {
+	if node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if key := node.Content[i]; key.Value == token && key.Tag != "!!merge" {
+				return key, resolveAlias(node.Content[i+1])
+			}
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i].Tag != "!!merge" {
+				continue
+			}
+			merged := resolveAlias(node.Content[i+1])
+			sources := []*yaml.Node{merged}
+			if merged.Kind == yaml.SequenceNode {
+				sources = merged.Content
+			}
+			for _, source := range sources {
+				if key, value := childNode(source, token); value != nil {
+					return key, value
+				}
+			}
+		}
+	case yaml.SequenceNode:
+		if index, err := strconv.Atoi(token); err == nil && index >= 0 && index < len(node.Content) {
+			item := node.Content[index]
+			return item, resolveAlias(item)
+		}
+	}
+	return nil, nil
+}
+
+// resolveAlias returns the node an alias refers to, or the node itself.
+func resolveAlias(node *yaml.Node) *yaml.Node {
+	if node.Kind == yaml.AliasNode {
+		return node.Alias
+	}
+	return node
+}
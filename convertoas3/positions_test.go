@@ -0,0 +1,52 @@
+package convertoas3
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Positions(t *testing.T) {
+	positions := NewPositions([]byte(`openapi: 3.0.3
+x-kong-service-defaults: &defaults
+  retries: 3
+paths:
+  /users/{id}:
+    get:
+      x-kong-service-defaults:
+        <<: *defaults
+        protocol: https
+      parameters:
+        - name: id
+          in: path
+`))
+
+	tests := []struct {
+		pointer string
+		line    int
+		column  int
+	}{
+		{"", 1, 1},
+		{"/openapi", 1, 1},
+		{"/x-kong-service-defaults/retries", 3, 3},
+		{"/paths/~1users~1{id}/get", 6, 5},
+		{"/paths/~1users~1{id}/get/parameters/0/in", 12, 11},
+		// merged from the anchor
+		{"/paths/~1users~1{id}/get/x-kong-service-defaults/retries", 3, 3},
+		// the closest parent
+		{"/paths/~1users~1{id}/get/parameters/1", 10, 7},
+		{"/paths/~1orders", 4, 1},
+		{"document", 0, 0},
+	}
+	for _, tst := range tests {
+		line, column := positions.Position(tst.pointer)
+		assert.Equal(t, []int{tst.line, tst.column}, []int{line, column}, tst.pointer)
+	}
+
+	// JSON is YAML too
+	line, column := NewPositions([]byte("{\n  \"paths\": {\n    \"/users\": {}\n  }\n}")).Position("/paths/~1users")
+	assert.Equal(t, []int{3, 5}, []int{line, column})
+
+	line, column = NewPositions([]byte(`paths: [`)).Position("/paths")
+	assert.Equal(t, []int{0, 0}, []int{line, column})
+}
@@ -0,0 +1,65 @@
+package convertoas3
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+const (
+	provenanceDocPrefix       = "oas-doc:"
+	provenancePathPrefix      = "oas-path:"
+	provenanceOperationPrefix = "oas-operation:"
+
+	// maxTagLength is the maximum length of a generated tag. Longer tags are truncated,
+	// and get a hash suffix to keep them unique.
+	maxTagLength = 128
+)
+
+// encodeTag returns a valid Kong tag, from the prefix and value. Kong does not allow ','
+// and '/' in tags, so those (and '%' and control characters) are percent-encoded in the
+// value. If the tag exceeds maxTagLength, it is truncated and a hash of the value is
+// appended, eg. 'oas-path:%2Fvery%2Flong~1a2b3c4d'.
+func encodeTag(prefix string, value string) string {
+	var tag strings.Builder
+	tag.WriteString(prefix)
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		if c < ' ' || c == 0x7f || c == '%' || c == ',' || c == '/' {
+			fmt.Fprintf(&tag, "%%%02X", c)
+		} else {
+			tag.WriteByte(c)
+		}
+	}
+	result := tag.String()
+	if len(result) <= maxTagLength {
+		return result
+	}
+
+	hash := sha256.Sum256([]byte(value))
+	suffix := "~" + hex.EncodeToString(hash[:])[:8]
+	cut := maxTagLength - len(suffix)
+	// do not cut through an escape sequence, or a multi-byte character
+	for cut > len(prefix) && (result[cut-1] == '%' || result[cut-2] == '%' || result[cut]&0xc0 == 0x80) {
+		cut--
+	}
+	return result[:cut] + suffix
+}
+
+// provenanceTags returns the tags identifying the spec element a route was generated
+// from; the document name and version, the path, and the operation id (if given).
+func provenanceTags(docName string, docVersion string, path string, operationID string) []string {
+	doc := docName
+	if docVersion != "" {
+		doc = docName + "@" + docVersion
+	}
+	tags := []string{
+		encodeTag(provenanceDocPrefix, doc),
+		encodeTag(provenancePathPrefix, path),
+	}
+	if operationID != "" {
+		tags = append(tags, encodeTag(provenanceOperationPrefix, operationID))
+	}
+	return tags
+}
@@ -0,0 +1,54 @@
+package convertoas3
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_encodeTag(t *testing.T) {
+	tests := []struct {
+		name     string
+		prefix   string
+		value    string
+		expected string
+	}{
+		{"plain", "oas-operation:", "getUser", "oas-operation:getUser"},
+		{"slashes", "oas-path:", "/users/{id}", "oas-path:%2Fusers%2F{id}"},
+		{"commas and percent", "oas-path:", "/a,b%c", "oas-path:%2Fa%2Cb%25c"},
+		{"spaces are allowed", "oas-doc:", "my api@1.0", "oas-doc:my api@1.0"},
+		{"utf-8 is kept", "oas-path:", "/café", "oas-path:%2Fcafé"},
+	}
+
+	for _, tst := range tests {
+		assert.Equal(t, tst.expected, encodeTag(tst.prefix, tst.value), tst.name)
+	}
+}
+
+func Test_encodeTagLength(t *testing.T) {
+	long := strings.Repeat("/segment", 40)
+	tag := encodeTag(provenancePathPrefix, long)
+	assert.Len(t, tag, maxTagLength)
+	assert.True(t, strings.HasPrefix(tag, "oas-path:%2Fsegment%2Fsegment"))
+
+	// the hash keeps long tags with the same prefix unique
+	assert.NotEqual(t, tag, encodeTag(provenancePathPrefix, long+"/other"))
+
+	// escapes are not cut
+	for i := 0; i < 4; i++ {
+		tag = encodeTag(provenancePathPrefix, strings.Repeat("a", i)+long)
+		assert.LessOrEqual(t, len(tag), maxTagLength)
+		hashStart := strings.LastIndex(tag, "~")
+		assert.NotContains(t, tag[hashStart-2:hashStart], "%")
+	}
+}
+
+func Test_provenanceTags(t *testing.T) {
+	assert.Equal(t,
+		[]string{"oas-doc:petstore@1.2.0", "oas-path:%2Fusers%2F{id}", "oas-operation:getUser"},
+		provenanceTags("petstore", "1.2.0", "/users/{id}", "getUser"))
+	assert.Equal(t,
+		[]string{"oas-doc:petstore", "oas-path:%2Fusers"},
+		provenanceTags("petstore", "", "/users", ""))
+}
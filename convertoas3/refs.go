@@ -0,0 +1,122 @@
+package convertoas3
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// xKongRefPrefix is the prefix of the references in 'x-kong-...' extensions, they can
+// only refer to entries of the '/components/x-kong' object.
+const xKongRefPrefix = "#/components/x-kong/"
+
+// lookupXKongRef returns the value the '$ref' pointer refers to in the components. The
+// pointer can walk into objects and, by index, into arrays, to any depth. The value
+// returned can be an object, an array, or a scalar.
+func lookupXKongRef(ref interface{}, components *map[string]interface{}) (string, interface{}, error) {
+	pointer, ok := ref.(string)
+	if !ok {
+		return "", nil, fmt.Errorf("expected '$ref' pointer to be a string")
+	}
+	if !strings.HasPrefix(pointer, xKongRefPrefix) {
+		return "", nil, fmt.Errorf("all 'x-kong-...' references must be at '#/components/x-kong/...'")
+	}
+
+	path := "#/components/x-kong"
+	var current interface{} = *components
+	for _, segment := range strings.Split(strings.TrimPrefix(pointer, xKongRefPrefix), "/") {
+		token := strings.ReplaceAll(strings.ReplaceAll(segment, "~1", "/"), "~0", "~")
+		switch container := current.(type) {
+		case map[string]interface{}:
+			value, found := container[token]
+			if !found {
+				return "", nil, fmt.Errorf("reference '%s' not found", pointer)
+			}
+			current = value
+		case []interface{}:
+			index, err := strconv.Atoi(token)
+			if err != nil || strconv.Itoa(index) != token || index < 0 || index >= len(container) {
+				return "", nil, fmt.Errorf("reference '%s' not found, '%s' has no entry '%s'", pointer, path, segment)
+			}
+			current = container[index]
+		default:
+			return "", nil, fmt.Errorf("reference '%s' not found, expected '%s' to be a JSON object or array",
+				pointer, path)
+		}
+		path = path + "/" + segment
+	}
+	return pointer, current, nil
+}
+
+// dereferenceJSONValue returns a copy of the value, with the '$ref' objects in it replaced
+// by the values they refer to in the components. References in the values referred to
+// are resolved as well, so a reference can point to another reference. Errors are
+// pointerErrors, locating the '$ref' in the value that could not be resolved.
+func dereferenceJSONValue(value interface{}, components *map[string]interface{}) (interface{}, error) {
+	return dereferenceValue(value, "", "", components, nil)
+}
+
+// dereferenceValue resolves the references in the value, at the location (a pointer
+// relative to the value passed to dereferenceJSONValue, or a '#/components/x-kong/...'
+// pointer for values referred to). The origin is the relative location of the '$ref'
+// that led into the components, if any. Resolving holds the references being resolved,
+// to detect circular references.
+func dereferenceValue(
+	value interface{},
+	location string,
+	origin string,
+	components *map[string]interface{},
+	resolving []string,
+) (interface{}, error) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if ref, found := v["$ref"]; found {
+			refLocation := location + "/$ref"
+			refOrigin := origin
+			if refOrigin == "" {
+				refOrigin = refLocation
+			}
+			pointer, target, err := lookupXKongRef(ref, components)
+			if err == nil {
+				for _, seen := range resolving {
+					if seen == pointer {
+						err = fmt.Errorf("circular reference '%s'", pointer)
+					}
+				}
+			}
+			if err != nil {
+				if origin != "" {
+					// report the location in the components, the error is in there
+					err = fmt.Errorf("%w, at '%s'", err, refLocation)
+				}
+				return nil, &pointerError{refOrigin, err}
+			}
+			chain := append(append([]string{}, resolving...), pointer)
+			return dereferenceValue(target, pointer, refOrigin, components, chain)
+		}
+
+		result := make(map[string]interface{}, len(v))
+		for key, child := range v {
+			resolved, err := dereferenceValue(child, location+jsonPointer(key), origin, components, resolving)
+			if err != nil {
+				return nil, err
+			}
+			result[key] = resolved
+		}
+		return result, nil
+
+	case []interface{}:
+		result := make([]interface{}, len(v))
+		for i, child := range v {
+			resolved, err := dereferenceValue(child, location+"/"+strconv.Itoa(i), origin, components, resolving)
+			if err != nil {
+				return nil, err
+			}
+			result[i] = resolved
+		}
+		return result, nil
+
+	default:
+		return value, nil
+	}
+}
@@ -0,0 +1,140 @@
+package convertoas3
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_dereferenceJSONValue(t *testing.T) {
+	var components map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"origins": ["https://a.example.com", "https://b.example.com"],
+		"timeout": 5000,
+		"plugins": {
+			"cors": {"config": {"origins": {"$ref": "#/components/x-kong/origins"}}},
+			"alias": {"$ref": "#/components/x-kong/plugins/cors"},
+			"list": [{"name": "cors"}, {"name": "key-auth"}]
+		},
+		"a~b/c": "escaped",
+		"missing": {"$ref": "#/components/x-kong/nothing"},
+		"loop-a": {"$ref": "#/components/x-kong/loop-b"},
+		"loop-b": {"$ref": "#/components/x-kong/loop-a"}
+	}`), &components))
+
+	corsConfig := map[string]interface{}{
+		"config": map[string]interface{}{
+			"origins": []interface{}{"https://a.example.com", "https://b.example.com"},
+		},
+	}
+	tests := []struct {
+		name     string
+		value    string
+		expected interface{}
+		pointer  string
+		err      string
+	}{
+		{"no references", `{"config": {"minute": 10}}`,
+			map[string]interface{}{"config": map[string]interface{}{"minute": float64(10)}}, "", ""},
+		{"object", `{"$ref": "#/components/x-kong/plugins/cors"}`, corsConfig, "", ""},
+		{"array", `{"$ref": "#/components/x-kong/origins"}`,
+			[]interface{}{"https://a.example.com", "https://b.example.com"}, "", ""},
+		{"scalar", `{"$ref": "#/components/x-kong/timeout"}`, float64(5000), "", ""},
+		{"array entry", `{"$ref": "#/components/x-kong/plugins/list/1/name"}`, "key-auth", "", ""},
+		{"escaped", `{"$ref": "#/components/x-kong/a~0b~1c"}`, "escaped", "", ""},
+		{"reference to reference", `{"$ref": "#/components/x-kong/plugins/alias"}`, corsConfig, "", ""},
+		{"nested", `{"config": {"read_timeout": {"$ref": "#/components/x-kong/timeout"}}}`,
+			map[string]interface{}{"config": map[string]interface{}{"read_timeout": float64(5000)}}, "", ""},
+		{"in array", `[{"$ref": "#/components/x-kong/timeout"}]`, []interface{}{float64(5000)}, "", ""},
+
+		{"not found", `{"config": {"origins": {"$ref": "#/components/x-kong/nothing"}}}`, nil,
+			"/config/origins/$ref", "reference '#/components/x-kong/nothing' not found"},
+		{"not a string", `{"$ref": 1}`, nil, "/$ref", "expected '$ref' pointer to be a string"},
+		{"outside components", `{"$ref": "#/components/schemas/user"}`, nil, "/$ref",
+			"all 'x-kong-...' references must be at '#/components/x-kong/...'"},
+		{"bad index", `[{"$ref": "#/components/x-kong/plugins/list/2"}]`, nil, "/0/$ref",
+			"reference '#/components/x-kong/plugins/list/2' not found, " +
+				"'#/components/x-kong/plugins/list' has no entry '2'"},
+		{"into scalar", `{"$ref": "#/components/x-kong/timeout/value"}`, nil, "/$ref",
+			"reference '#/components/x-kong/timeout/value' not found, " +
+				"expected '#/components/x-kong/timeout' to be a JSON object or array"},
+		{"not found in components", `{"$ref": "#/components/x-kong/missing"}`, nil, "/$ref",
+			"reference '#/components/x-kong/nothing' not found, at '#/components/x-kong/missing/$ref'"},
+		{"circular", `{"$ref": "#/components/x-kong/loop-a"}`, nil, "/$ref",
+			"circular reference '#/components/x-kong/loop-a', at '#/components/x-kong/loop-b/$ref'"},
+	}
+
+	for _, tst := range tests {
+		var value interface{}
+		require.NoError(t, json.Unmarshal([]byte(tst.value), &value), tst.name)
+		result, err := dereferenceJSONValue(value, &components)
+		if tst.err == "" {
+			assert.NoError(t, err, tst.name)
+			assert.Equal(t, tst.expected, result, tst.name)
+			continue
+		}
+		assert.EqualError(t, err, tst.err, tst.name)
+		var pErr *pointerError
+		if assert.True(t, errors.As(err, &pErr), tst.name) {
+			assert.Equal(t, tst.pointer, pErr.pointer, tst.name)
+		}
+	}
+}
+
+func Test_ConvertReferences(t *testing.T) {
+	spec := []byte(`
+openapi: 3.0.3
+info:
+  title: references
+  version: 1.0.0
+servers:
+  - url: https://api.example.com
+x-kong-tags:
+  $ref: '#/components/x-kong/tags'
+components:
+  x-kong:
+    tags: [team-a, public]
+    origins: [https://example.com]
+    upstream-path: /v2/users
+    cors:
+      config:
+        origins:
+          $ref: '#/components/x-kong/origins'
+    shared-cors:
+      $ref: '#/components/x-kong/cors'
+paths:
+  /users:
+    x-kong-upstream-path:
+      $ref: '#/components/x-kong/upstream-path'
+    get:
+      operationId: getUsers
+      x-kong-plugin-cors:
+        $ref: '#/components/x-kong/shared-cors'
+    post:
+      operationId: createUser
+      x-kong-plugin-cors:
+        $ref: '#/components/x-kong/missing'
+`)
+
+	_, err := Convert(context.Background(), &spec, O2kOptions{})
+	assert.EqualError(t, err, "/paths/~1users/post/x-kong-plugin-cors/$ref: "+
+		"reference '#/components/x-kong/missing' not found")
+
+	result, err := Convert(context.Background(), &spec, O2kOptions{BestEffort: true})
+	require.NoError(t, err)
+	service := result["services"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, []string{"team-a", "public"}, service["tags"])
+	routes := service["routes"].([]interface{})
+	require.Len(t, routes, 1)
+	var plugins []map[string]interface{}
+	data, _ := json.Marshal(routes[0].(map[string]interface{})["plugins"])
+	require.NoError(t, json.Unmarshal(data, &plugins))
+	require.Len(t, plugins, 2)
+	assert.Equal(t, "cors", plugins[0]["name"])
+	assert.Equal(t, []interface{}{"https://example.com"}, plugins[0]["config"].(map[string]interface{})["origins"])
+	assert.Equal(t, "request-transformer", plugins[1]["name"]) // from the upstream path
+}
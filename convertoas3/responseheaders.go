@@ -0,0 +1,125 @@
+package convertoas3
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	uuid "github.com/satori/go.uuid"
+)
+
+// getStaticHeaderValue returns the value of a response header with a static value; a
+// schema with an enum of a single value. Returns false if the value is not static.
+func getStaticHeaderValue(header *openapi3.HeaderRef) (string, bool) {
+	if header == nil || header.Value == nil || header.Value.Schema == nil || header.Value.Schema.Value == nil {
+		return "", false
+	}
+	enum := header.Value.Schema.Value.Enum
+	if len(enum) != 1 || enum[0] == nil {
+		return "", false
+	}
+	if value, ok := enum[0].(string); ok {
+		return value, true
+	}
+	value, err := json.Marshal(enum[0])
+	if err != nil {
+		return "", false
+	}
+	return string(value), true
+}
+
+// isSuccessResponse returns true if the response code is a 2xx code, or the '2XX' range.
+func isSuccessResponse(code string) bool {
+	return len(code) == 3 && code[0] == '2'
+}
+
+// getStaticResponseHeaders returns the static headers of the success (2xx) responses of
+// the operation, as 'name:value', sorted by name. The 'Content-Type' header is ignored,
+// as OAS requires. Headers with different values between the responses are returned as
+// conflicts, and not included.
+func getStaticResponseHeaders(operation *openapi3.Operation) (headers []string, conflicts []string) {
+	names := make(map[string]string)  // by lowercase name, as first given
+	values := make(map[string]string) // by lowercase name
+	conflicting := make(map[string]bool)
+
+	codes := make([]string, 0, len(operation.Responses))
+	for code := range operation.Responses {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+
+	for _, code := range codes {
+		response := operation.Responses[code]
+		if !isSuccessResponse(code) || response == nil || response.Value == nil {
+			continue
+		}
+		for name, header := range response.Value.Headers {
+			key := strings.ToLower(name)
+			if key == "content-type" {
+				continue
+			}
+			value, static := getStaticHeaderValue(header)
+			if !static {
+				continue
+			}
+			if existing, found := values[key]; found {
+				if existing != value {
+					conflicting[key] = true
+				}
+				continue
+			}
+			names[key] = name
+			values[key] = value
+		}
+	}
+
+	headers = make([]string, 0, len(values))
+	for key, value := range values {
+		if conflicting[key] {
+			conflicts = append(conflicts, names[key])
+			continue
+		}
+		headers = append(headers, names[key]+":"+value)
+	}
+	sort.Strings(headers)
+	sort.Strings(conflicts)
+	return headers, conflicts
+}
+
+// addResponseHeadersPlugin adds a 'response-transformer' plugin setting the headers
+// ('name:value') on the responses to the list, unless the list or the service already
+// has one, or there are no headers. The headers are both replaced and added, so they
+// are set whether the upstream returns them or not. The list remains sorted by plugin
+// name.
+func addResponseHeadersPlugin(
+	list *[]*map[string]interface{},
+	servicePlugins interface{},
+	headers []string,
+	uuidNamespace uuid.UUID,
+	baseName string,
+	tags []string,
+) *[]*map[string]interface{} {
+	if len(headers) == 0 || hasPlugin(list, "response-transformer") ||
+		hasPlugin(servicePlugins, "response-transformer") {
+		return list
+	}
+
+	plugin := map[string]interface{}{
+		"name": "response-transformer",
+		"config": map[string]interface{}{
+			"replace": map[string]interface{}{
+				"headers": headers,
+			},
+			"add": map[string]interface{}{
+				"headers": headers,
+			},
+		},
+		"tags": tags,
+	}
+	plugin["id"] = createPluginID(uuidNamespace, baseName, plugin)
+
+	plugins := newPluginSet(list)
+	plugins.insert(&plugin)
+	return plugins.list()
+}
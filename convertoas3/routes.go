@@ -0,0 +1,148 @@
+package convertoas3
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+// pathParameterRegex matches the parameter placeholders in a path, eg. '{id}'.
+var pathParameterRegex = regexp.MustCompile("{([^}]+)}")
+
+// createRouteRegex creates the regex (without the '~' prefix) to match the path of a
+// route. Path parameters are converted to named captures, everything else is escaped
+// to match literally. The prefix (if any) is added as a literal. Returns the regex
+// and a map of parameter names to their (sanitized and unique) capture names. The
+// map is empty if the path has no parameters.
+func createRouteRegex(prefix string, path string) (string, map[string]string) {
+	captures := make(map[string]string)
+	matches := pathParameterRegex.FindAllStringSubmatchIndex(path, -1)
+	if matches == nil {
+		return regexp.QuoteMeta(prefix + path), captures
+	}
+
+	usedNames := make(map[string]bool)
+
+	var regex strings.Builder
+	regex.WriteString(regexp.QuoteMeta(prefix))
+	lastEnd := 0
+	for _, match := range matches {
+		// match[0]:match[1] is the full placeholder, match[2]:match[3] is the variable name
+		regex.WriteString(regexp.QuoteMeta(path[lastEnd:match[0]]))
+		varName := path[match[2]:match[3]]
+		captureName := uniqueCaptureName(sanitizeRegexCapture(varName), usedNames)
+		if _, found := captures[varName]; !found {
+			captures[varName] = captureName
+		}
+		// match single segment; '/', '?', and '#' can mark the end of a segment
+		// see https://github.com/OAI/OpenAPI-Specification/issues/291#issuecomment-316593913
+		regex.WriteString("(?<" + captureName + ">[^#?/]+)")
+		lastEnd = match[1]
+	}
+	regex.WriteString(regexp.QuoteMeta(path[lastEnd:]))
+
+	return regex.String(), captures
+}
+
+// uniqueCaptureName returns the name, with a numeric suffix added if it was
+// used before. Since sanitizing can map different parameter names to the same
+// capture name (eg. 'user-id' and 'user_id'), and duplicate captures are invalid.
+// The returned name will be added to the usedNames map.
+func uniqueCaptureName(name string, usedNames map[string]bool) string {
+	unique := name
+	for i := 2; usedNames[unique]; i++ {
+		suffix := "_" + strconv.Itoa(i)
+		if len(name)+len(suffix) > maxRegexCaptureLength {
+			unique = name[:maxRegexCaptureLength-len(suffix)] + suffix
+		} else {
+			unique = name + suffix
+		}
+	}
+	usedNames[unique] = true
+	return unique
+}
+
+const (
+	// TrailingSlashStrict matches the route paths exactly as given, with or without a
+	// trailing slash.
+	TrailingSlashStrict = "strict"
+	// TrailingSlashOptional matches the route paths with and without a trailing slash,
+	// using a single route with an optional trailing slash in the regex.
+	TrailingSlashOptional = "optional"
+	// TrailingSlashDuplicate matches the route paths with and without a trailing slash,
+	// using an additional route for the other variant.
+	TrailingSlashDuplicate = "duplicate"
+)
+
+// trailingSlashSuffix is added to the names of the routes generated for the trailing slash
+// variant of a path, see TrailingSlashDuplicate.
+const trailingSlashSuffix = "~"
+
+// optionalTrailingSlash returns the route regex, matching the path with and without a
+// trailing slash. The root path is returned as is.
+func optionalTrailingSlash(regex string) string {
+	trimmed := strings.TrimSuffix(regex, "/")
+	if trimmed == "" {
+		return regex
+	}
+	return trimmed + "/?"
+}
+
+// toggleTrailingSlash returns the route regex for the other trailing slash variant of the
+// path; without the trailing slash if it has one, with one otherwise. Returns "" for the
+// root path, which has no other variant.
+func toggleTrailingSlash(regex string) string {
+	if strings.HasSuffix(regex, "/") {
+		return strings.TrimSuffix(regex, "/")
+	}
+	return regex + "/"
+}
+
+// copyRouteForPath returns a copy of the route, matching the path regex instead, named
+// after the baseName. The plugins are copied as well, with new IDs derived from the
+// original ones.
+func copyRouteForPath(
+	route map[string]interface{},
+	regex string,
+	uuidNamespace uuid.UUID,
+	baseName string,
+) map[string]interface{} {
+	routeCopy := make(map[string]interface{}, len(route))
+	for key, value := range route {
+		routeCopy[key] = deepCopy(value)
+	}
+	routeCopy["paths"] = []string{regex}
+	routeCopy["id"] = uuid.NewV5(uuidNamespace, baseName+".route").String()
+	routeCopy["name"] = baseName
+	if chain := getEntityFilterChain(routeCopy); chain != nil {
+		chain["id"] = uuid.NewV5(uuidNamespace, baseName+".filter-chain").String()
+	}
+
+	if plugins, ok := route["plugins"].(*[]*map[string]interface{}); ok && plugins != nil {
+		pluginsCopy := make([]*map[string]interface{}, len(*plugins))
+		for i, plugin := range *plugins {
+			pluginsCopy[i] = copyPluginForRoute(plugin, uuidNamespace, "")
+		}
+		routeCopy["plugins"] = &pluginsCopy
+	}
+	return routeCopy
+}
+
+// copyPluginForRoute returns a copy of the plugin, with a new ID derived from the
+// original one. If routeName is given, it is set as the route the plugin applies to.
+func copyPluginForRoute(
+	plugin *map[string]interface{},
+	uuidNamespace uuid.UUID,
+	routeName string,
+) *map[string]interface{} {
+	pluginCopy := deepCopy(plugin).(*map[string]interface{})
+	if id, ok := (*plugin)["id"].(string); ok {
+		(*pluginCopy)["id"] = uuid.NewV5(uuidNamespace, id+trailingSlashSuffix).String()
+	}
+	if routeName != "" {
+		(*pluginCopy)["route"] = routeName
+	}
+	return pluginCopy
+}
@@ -0,0 +1,103 @@
+package convertoas3
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func Test_createRouteRegex(t *testing.T) {
+	tests := []struct {
+		name     string
+		prefix   string
+		path     string
+		regex    string
+		captures map[string]string
+	}{
+		{"plain path", "", "/users", "/users", map[string]string{}},
+		{"escapes dots", "", "/files/report.json", `/files/report\.json`, map[string]string{}},
+		{"escapes well-known", "", "/.well-known/jwks.json", `/\.well-known/jwks\.json`, map[string]string{}},
+		{"escapes other metachars", "", "/a+b|c$[d]^e\\", `/a\+b\|c\$\[d\]\^e\\`, map[string]string{}},
+		{
+			"parameter", "", "/users/{id}",
+			"/users/(?<id>[^#?/]+)", map[string]string{"id": "id"},
+		},
+		{
+			"parameter and literals", "", "/users/{id}.json",
+			`/users/(?<id>[^#?/]+)\.json`, map[string]string{"id": "id"},
+		},
+		{
+			"escapes prefix", "/v1.0", "/users/{id}",
+			`/v1\.0/users/(?<id>[^#?/]+)`, map[string]string{"id": "id"},
+		},
+		{
+			"sanitizes capture names", "", "/users/{user-id}/{userId[0]}",
+			"/users/(?<user_id>[^#?/]+)/(?<userid_0>[^#?/]+)",
+			map[string]string{"user-id": "user_id", "userId[0]": "userid_0"},
+		},
+		{
+			"deduplicates capture names", "", "/users/{user-id}/{user_id}",
+			"/users/(?<user_id>[^#?/]+)/(?<user_id_2>[^#?/]+)",
+			map[string]string{"user-id": "user_id", "user_id": "user_id_2"},
+		},
+	}
+
+	for _, tst := range tests {
+		regex, captures := createRouteRegex(tst.prefix, tst.path)
+		if regex != tst.regex {
+			t.Errorf("%s: expected regex '%s', but got '%s'", tst.name, tst.regex, regex)
+		}
+		if diff := cmp.Diff(captures, tst.captures); diff != "" {
+			t.Errorf("%s: %s", tst.name, diff)
+		}
+	}
+}
+
+func Test_sanitizeRegexCapture(t *testing.T) {
+	tests := []struct {
+		in  string
+		out string
+	}{
+		{"id", "id"},
+		{"user-id", "user_id"},
+		{"-to-do", "to_do"},
+		{"1st", "a1st"},
+		{"😀", "param"},
+		{"a-very-long-parameter-name-exceeding-limits", "a_very_long_parameter_name_excee"},
+	}
+
+	for _, tst := range tests {
+		if result := sanitizeRegexCapture(tst.in); result != tst.out {
+			t.Errorf("'%s': expected '%s', but got '%s'", tst.in, tst.out, result)
+		}
+	}
+}
+
+func Benchmark_createRouteRegex(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		createRouteRegex("/v1", "/users/{user-id}/orders/{order_id}.json")
+	}
+}
+
+func Test_trailingSlash(t *testing.T) {
+	tests := []struct {
+		regex    string
+		optional string
+		toggled  string
+	}{
+		{"/users", "/users/?", "/users/"},
+		{"/users/", "/users/?", "/users"},
+		{"/users/(?<id>[^#?/]+)", "/users/(?<id>[^#?/]+)/?", "/users/(?<id>[^#?/]+)/"},
+		{"/", "/", ""},
+	}
+
+	for _, tst := range tests {
+		if optional := optionalTrailingSlash(tst.regex); optional != tst.optional {
+			t.Errorf("%s: expected optional regex '%s', but got '%s'", tst.regex, tst.optional, optional)
+		}
+		if toggled := toggleTrailingSlash(tst.regex); toggled != tst.toggled {
+			t.Errorf("%s: expected toggled regex '%s', but got '%s'", tst.regex, tst.toggled, toggled)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package convertoas3
+
+import (
+	"bytes"
+	_ "embed" // for embedding the extensions schema
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/xeipuuv/gojsonschema"
+	"sigs.k8s.io/yaml"
+)
+
+// ExtensionsSchema is the JSON Schema (draft-07) describing the 'x-kong-...' extensions
+// supported in an OpenAPI spec. It validates the spec itself, so it can also be used
+// by editors.
+//
+//go:embed schema/x-kong-extensions.schema.json
+var ExtensionsSchema []byte
+
+// ignoredSchemaErrors are the error types that only summarize other errors reported.
+var ignoredSchemaErrors = map[string]bool{
+	"condition_then": true,
+	"condition_else": true,
+	"number_all_of":  true,
+	"number_any_of":  true,
+	"number_not":     true,
+}
+
+// operationKeys are the keys of the operations in an OpenAPI path item.
+var operationKeys = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+
+// ValidateExtensions validates the 'x-kong-...' extensions in an OpenAPI spec (JSON or
+// YAML) against the ExtensionsSchema. References to '#/components/x-kong/...' are
+// resolved first, so the referenced objects are validated where they are used. If
+// invalid, the errors are returned as ConversionErrors.
+func ValidateExtensions(content *[]byte) error {
+	jsonContent, err := yaml.YAMLToJSON(*content)
+	if err != nil {
+		return fmt.Errorf("error parsing OAS3 file: [%w]", err)
+	}
+	var doc map[string]interface{}
+	if err = json.Unmarshal(jsonContent, &doc); err != nil {
+		return fmt.Errorf("error parsing OAS3 file: [%w]", err)
+	}
+
+	// resolve all references, validation can only be done on the referenced objects
+	var errs ConversionErrors
+	components := make(map[string]interface{})
+	if docComponents, ok := doc["components"].(map[string]interface{}); ok {
+		if xKong, ok := docComponents["x-kong"].(map[string]interface{}); ok {
+			components = xKong
+		}
+	}
+	resolveExtensionRefs(doc, "", &components, &errs)
+	if paths, ok := doc["paths"].(map[string]interface{}); ok {
+		for path, pathitem := range paths {
+			if pathitem, ok := pathitem.(map[string]interface{}); ok {
+				pathPointer := jsonPointer("paths", path)
+				resolveExtensionRefs(pathitem, pathPointer, &components, &errs)
+				for _, method := range operationKeys {
+					if operation, ok := pathitem[method].(map[string]interface{}); ok {
+						resolveExtensionRefs(operation, pathPointer+jsonPointer(method), &components, &errs)
+					}
+				}
+			}
+		}
+	}
+
+	schema, err := gojsonschema.NewSchema(gojsonschema.NewBytesLoader(ExtensionsSchema))
+	if err != nil {
+		return fmt.Errorf("failed to load extensions schema: %w", err)
+	}
+	result, err := schema.Validate(gojsonschema.NewGoLoader(doc))
+	if err != nil {
+		return fmt.Errorf("failed to validate extensions: %w", err)
+	}
+	for _, resultErr := range result.Errors() {
+		if ignoredSchemaErrors[resultErr.Type()] {
+			continue
+		}
+		// the context is the path to the value, starting at "(root)"
+		pointer := jsonPointer(strings.Split(resultErr.Context().String("\x00"), "\x00")[1:]...)
+		if resultErr.Type() == "invalid_property_name" {
+			// only the 'x-kong-...' property names are restricted
+			name := fmt.Sprint(resultErr.Details()["property"])
+			errs.add(pointer+jsonPointer(name), fmt.Errorf("unknown extension '%s'", name))
+			continue
+		}
+		description := strings.TrimPrefix(resultErr.Description(), resultErr.Field()+" ")
+		errs.add(pointer, fmt.Errorf("%s", description))
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	sort.SliceStable(errs, func(i, j int) bool {
+		return errs[i].Pointer < errs[j].Pointer
+	})
+	errs.Locate(*content, "")
+	return errs
+}
+
+// resolveExtensionRefs replaces the '$ref' objects in the 'x-kong-...' extensions of the
+// object by the values referenced, see dereferenceJSONValue. Values still encoded as JSON,
+// as in openapi3.ExtensionProps, are decoded if they hold a reference. Errors are added
+// to errs, and the extension is removed. Pointer is the location of the object.
+func resolveExtensionRefs(
+	object map[string]interface{},
+	pointer string,
+	components *map[string]interface{},
+	errs *ConversionErrors,
+) {
+	for key, value := range object {
+		if !strings.HasPrefix(key, kongExtensionPrefix) {
+			continue
+		}
+		if raw, ok := value.(json.RawMessage); ok {
+			if !bytes.Contains(raw, []byte(`"$ref"`)) {
+				continue
+			}
+			value = nil
+			if err := json.Unmarshal(raw, &value); err != nil {
+				continue // reported by the extension readers
+			}
+		}
+		resolved, err := dereferenceJSONValue(value, components)
+		if err != nil {
+			errs.add(pointer+jsonPointer(key), err)
+			delete(object, key)
+			continue
+		}
+		object[key] = resolved
+	}
+}
@@ -0,0 +1,109 @@
+package convertoas3
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ExtensionsSchema(t *testing.T) {
+	var schema map[string]interface{}
+	assert.NoError(t, json.Unmarshal(ExtensionsSchema, &schema))
+}
+
+func Test_ValidateExtensions(t *testing.T) {
+	tests := []struct {
+		name     string
+		spec     string
+		expected []string // "pointer: message"
+	}{
+		{
+			"valid extensions",
+			`
+x-kong-tags: [a, b]
+x-kong-plugin-key-auth:
+  enabled: true
+  config:
+    key_names: [apikey]
+x-kong-service-defaults:
+  $ref: "#/components/x-kong/service"
+paths:
+  /users:
+    x-kong-name: users
+    get:
+      x-kong-route-defaults:
+        strip_path: true
+components:
+  x-kong:
+    service:
+      retries: 3
+    consumers:
+      john:
+        groups: [gold]
+`,
+			nil,
+		},
+		{
+			"invalid extensions",
+			`
+x-kong-tags: not-an-array
+x-kong-plugin_rate-limiting: {}
+paths:
+  /users:
+    get:
+      x-kong-name: 123
+      x-kong-upstream-defaults:
+        algorithm: random
+      x-kong-plugin-cors:
+        route: other
+`,
+			[]string{
+				"/paths/~1users/get/x-kong-name: Invalid type. Expected: string, given: integer",
+				"/paths/~1users/get/x-kong-plugin-cors/route: False always fails validation",
+				"/paths/~1users/get/x-kong-upstream-defaults/algorithm: " +
+					"must be one of the following: \"round-robin\", \"least-connections\", " +
+					"\"consistent-hashing\"",
+				"/x-kong-plugin_rate-limiting: unknown extension 'x-kong-plugin_rate-limiting'",
+				"/x-kong-tags: Invalid type. Expected: array, given: string",
+			},
+		},
+		{
+			"references are resolved",
+			`
+x-kong-service-defaults:
+  $ref: "#/components/x-kong/service"
+x-kong-route-defaults:
+  $ref: "#/components/x-kong/missing"
+components:
+  x-kong:
+    service:
+      retries: many
+`,
+			[]string{
+				"/x-kong-route-defaults/$ref: reference '#/components/x-kong/missing' not found",
+				"/x-kong-service-defaults/retries: Invalid type. Expected: integer, given: string",
+			},
+		},
+	}
+
+	for _, tst := range tests {
+		spec := []byte("openapi: 3.0.3\ninfo:\n  title: schema\n  version: v1\n" + tst.spec)
+		err := ValidateExtensions(&spec)
+		if tst.expected == nil {
+			assert.NoError(t, err, tst.name)
+			continue
+		}
+		var errs ConversionErrors
+		if !errors.As(err, &errs) {
+			t.Errorf("%s: expected ConversionErrors, got: %v", tst.name, err)
+			continue
+		}
+		messages := make([]string, len(errs))
+		for i, e := range errs {
+			messages[i] = e.Error()
+		}
+		assert.Equal(t, tst.expected, messages, tst.name)
+	}
+}
@@ -0,0 +1,135 @@
+package convertoas3
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// SecretsWarn logs a warning for each literal secret in the plugin configs and
+	// consumer keys.
+	SecretsWarn = "warn"
+	// SecretsFail fails the conversion listing the literal secrets in the plugin configs
+	// and consumer keys.
+	SecretsFail = "fail"
+	// SecretsIgnore does not scan the plugin configs and consumer keys for secrets.
+	SecretsIgnore = "ignore"
+)
+
+// secretFieldNames are the last words of plugin config fields that hold secrets, eg.
+// 'client_secret', 'redis_password', or 'api-key'. Fields like 'key_names' do not match.
+var secretFieldNames = map[string]bool{
+	"secret":   true,
+	"secrets":  true,
+	"password": true,
+	"passwd":   true,
+	"key":      true,
+	"apikey":   true,
+	"token":    true,
+}
+
+// deckEnvReference matches a decK environment variable reference, eg.
+// '${{ env "DECK_REDIS_PASSWORD" }}', which is not a literal secret either.
+var deckEnvReference = regexp.MustCompile(`^\$\{\{\s*env\s+"DECK_[^"]+"\s*\}\}$`)
+
+// isSecretField returns true if the name of a plugin config field suggests it holds a
+// secret.
+func isSecretField(name string) bool {
+	words := strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return r == '_' || r == '-' || r == '.'
+	})
+	return len(words) > 0 && secretFieldNames[words[len(words)-1]]
+}
+
+// isLiteralSecret returns true if the value is a non-empty string that is neither a
+// vault reference nor a decK environment variable reference.
+func isLiteralSecret(value string) bool {
+	return value != "" && !vaultReference.MatchString(value) && !deckEnvReference.MatchString(value)
+}
+
+// findSecrets returns the dotted paths of the secret fields with literal values in a
+// plugin config value, eg. 'config.redis.password'.
+func findSecrets(value interface{}, path string, secret bool) []string {
+	found := make([]string, 0)
+	switch v := value.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			found = append(found, findSecrets(v[key], path+"."+key, isSecretField(key))...)
+		}
+	case *map[string]interface{}:
+		if v != nil {
+			found = append(found, findSecrets(*v, path, secret)...)
+		}
+	case []interface{}:
+		for i, item := range v {
+			found = append(found, findSecrets(item, fmt.Sprintf("%s[%d]", path, i), secret)...)
+		}
+	case []string:
+		for i, item := range v {
+			found = append(found, findSecrets(item, fmt.Sprintf("%s[%d]", path, i), secret)...)
+		}
+	case string:
+		if secret && isLiteralSecret(v) {
+			found = append(found, path)
+		}
+	}
+	return found
+}
+
+// checkSecrets scans the configs of the plugins in the output document for fields
+// named like secrets, eg. 'client_secret' or 'redis_password', and the key-auth
+// credentials of the consumers, for literal values, which would leak the credentials
+// into the declarative file. With SecretsWarn (the default) a warning is logged for
+// each, with SecretsFail an error is returned listing them. Vault references, eg.
+// '{vault://env/redis-password}', are not reported.
+func checkSecrets(result map[string]interface{}, mode string, logger Logger) error {
+	if mode == SecretsIgnore {
+		return nil
+	}
+
+	list := make([]string, 0)
+	for _, ref := range collectPlugins(result) {
+		name, _ := ref.plugin["name"].(string)
+		for _, field := range findSecrets(ref.plugin["config"], "config", false) {
+			if mode == SecretsFail {
+				list = append(list, fmt.Sprintf("'%s' of plugin '%s' (on %s)", field, name, ref.owner))
+			} else {
+				logger.Warn("literal secret in plugin config, use a vault reference",
+					"plugin", name, "location", ref.owner, "field", field)
+			}
+		}
+	}
+	consumers, _ := result["consumers"].([]interface{})
+	for _, c := range consumers {
+		consumer, _ := c.(map[string]interface{})
+		username, _ := consumer["username"].(string)
+		credentials, _ := consumer["keyauth_credentials"].([]interface{})
+		for i, credential := range credentials {
+			fields, _ := credential.(map[string]interface{})
+			key, _ := fields["key"].(string)
+			if !isLiteralSecret(key) {
+				continue
+			}
+			if mode == SecretsFail {
+				list = append(list, fmt.Sprintf("'keys[%d]' of consumer '%s'", i, username))
+			} else {
+				logger.Warn("literal key in consumer credentials, use a vault reference", "consumer", username,
+					"location", jsonPointer("components", "x-kong", "consumers", username, "keys", strconv.Itoa(i)))
+			}
+		}
+	}
+	if len(list) == 0 {
+		return nil
+	}
+	sort.Strings(list)
+	return fmt.Errorf("literal secrets in plugin configs or consumer keys, use vault references, eg. "+
+		"'{vault://env/name}': %v", list)
+}
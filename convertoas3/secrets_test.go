@@ -0,0 +1,119 @@
+package convertoas3
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_isSecretField(t *testing.T) {
+	for _, name := range []string{"secret", "client_secret", "redis_password", "api-key", "aws_key", "Access.Token"} {
+		assert.True(t, isSecretField(name), name)
+	}
+	for _, name := range []string{"key_names", "key_in_header", "secret_is_base64", "hide_credentials", "keys", ""} {
+		assert.False(t, isSecretField(name), name)
+	}
+}
+
+func Test_findSecrets(t *testing.T) {
+	config := map[string]interface{}{
+		"client_secret":  []interface{}{"s3cr3t", "{vault://env/client-secret}"},
+		"session_secret": `${{ env "DECK_SESSION_SECRET" }}`,
+		"redis": map[string]interface{}{
+			"host":     "redis",
+			"password": "hunter2",
+		},
+		"key_names":        []string{"apikey"},
+		"secret_is_base64": true,
+		"aws_key":          "",
+	}
+	assert.Equal(t, []string{"config.client_secret[0]", "config.redis.password"},
+		findSecrets(config, "config", false))
+}
+
+func Test_ConvertSecrets(t *testing.T) {
+	spec := []byte(`
+openapi: 3.0.3
+info:
+  title: secrets
+  version: v1
+servers:
+  - url: https://api.example.com
+x-kong-plugin-rate-limiting:
+  config:
+    minute: 10
+    redis_password: hunter2
+paths:
+  /users:
+    get:
+      x-kong-plugin-aws-lambda:
+        config:
+          aws_key: '{vault://aws/key}'
+          aws_secret: s3cr3t
+      responses:
+        "200":
+          description: OK
+`)
+
+	var buf bytes.Buffer
+	_, err := Convert(context.Background(), &spec, O2kOptions{
+		Logger: NewStdLogger(log.New(&buf, "", 0), LogLevelWarn),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "WARN literal secret in plugin config, use a vault reference "+
+		"plugin=rate-limiting location=service 'secrets' field=config.redis_password\n"+
+		"WARN literal secret in plugin config, use a vault reference "+
+		"plugin=aws-lambda location=route 'secrets_users_get' field=config.aws_secret\n", buf.String())
+
+	_, err = Convert(context.Background(), &spec, NewO2kOptions(WithSecrets(SecretsFail)))
+	assert.EqualError(t, err, "literal secrets in plugin configs or consumer keys, use vault references, "+
+		"eg. '{vault://env/name}': "+
+		"['config.aws_secret' of plugin 'aws-lambda' (on route 'secrets_users_get') "+
+		"'config.redis_password' of plugin 'rate-limiting' (on service 'secrets')]")
+
+	buf.Reset()
+	_, err = Convert(context.Background(), &spec, O2kOptions{
+		Secrets: SecretsIgnore,
+		Logger:  NewStdLogger(log.New(&buf, "", 0), LogLevelWarn),
+	})
+	require.NoError(t, err)
+	assert.Empty(t, buf.String())
+}
+
+func Test_ConvertSecretsConsumerKeys(t *testing.T) {
+	spec := []byte(`
+openapi: 3.0.3
+info:
+  title: secrets
+  version: v1
+servers:
+  - url: https://api.example.com
+paths: {}
+components:
+  x-kong:
+    consumers:
+      alice:
+        keys:
+          - '{vault://env/alice-key}'
+          - key: alice-literal-key
+      bob:
+        keys:
+          - ${{ env "DECK_BOB_KEY" }}
+`)
+
+	var buf bytes.Buffer
+	_, err := Convert(context.Background(), &spec, O2kOptions{
+		Logger: NewStdLogger(log.New(&buf, "", 0), LogLevelWarn),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "WARN literal key in consumer credentials, use a vault reference "+
+		"consumer=alice location=/components/x-kong/consumers/alice/keys/1\n", buf.String())
+
+	_, err = Convert(context.Background(), &spec, NewO2kOptions(WithSecrets(SecretsFail)))
+	assert.EqualError(t, err, "literal secrets in plugin configs or consumer keys, use vault references, "+
+		"eg. '{vault://env/name}': ['keys[1]' of consumer 'alice']")
+}
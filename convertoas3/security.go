@@ -0,0 +1,569 @@
+package convertoas3
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	uuid "github.com/satori/go.uuid"
+)
+
+// securityPluginNames are the bundled plugins that authenticate requests, and 'acl',
+// which requires an authenticated consumer.
+var securityPluginNames = []string{
+	"acl", "basic-auth", "hmac-auth", "jwt", "key-auth", "key-auth-enc", "ldap-auth", "ldap-auth-advanced",
+	"mtls-auth", "oauth2", "oauth2-introspection", "openid-connect",
+}
+
+// optsOutOfSecurity returns true if the operation disables the document level security,
+// using 'security: []'.
+func optsOutOfSecurity(operation *openapi3.Operation) bool {
+	return operation.Security != nil && len(*operation.Security) == 0
+}
+
+// hasSecurityPlugin returns true if the plugin list contains a security plugin, see
+// securityPluginNames.
+func hasSecurityPlugin(list interface{}) bool {
+	for _, name := range securityPluginNames {
+		if hasPlugin(list, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// removeInheritedSecurity removes the security plugins from the list, that are not
+// declared on the operation itself, but inherited from the path or document.
+func removeInheritedSecurity(
+	list *[]*map[string]interface{},
+	operation *openapi3.Operation,
+) *[]*map[string]interface{} {
+	if !hasSecurityPlugin(list) {
+		return list
+	}
+	plugins := newPluginSet(list)
+	for _, name := range securityPluginNames {
+		if _, declared := operation.ExtensionProps.Extensions[pluginPrefix+name]; !declared {
+			plugins.remove(name)
+		}
+	}
+	return plugins.list()
+}
+
+// getSecurityRequirements returns the security requirements of an operation. The
+// operation level overrides the document level.
+func getSecurityRequirements(doc *openapi3.T, operation *openapi3.Operation) openapi3.SecurityRequirements {
+	if operation.Security != nil {
+		return *operation.Security
+	}
+	return doc.Security
+}
+
+// getSecurityScheme returns the security scheme declared in the components.
+func getSecurityScheme(doc *openapi3.T, schemeName string) (*openapi3.SecurityScheme, error) {
+	schemeRef := doc.Components.SecuritySchemes[schemeName]
+	if schemeRef == nil || schemeRef.Value == nil {
+		return nil, fmt.Errorf("security scheme '%s' is not declared in "+
+			"'#/components/securitySchemes'", schemeName)
+	}
+	return schemeRef.Value, nil
+}
+
+// securityPrefix is the prefix of the security scheme extensions, eg.
+// 'x-kong-security-key-auth', that add Kong specific config to the generated plugin.
+const securityPrefix = "x-kong-security-"
+
+// getSecurityExtension returns the 'x-kong-security-<plugin>' extension of the scheme,
+// validated to be an object. Returns nil if there is none.
+func getSecurityExtension(schemeName string, scheme *openapi3.SecurityScheme, pluginName string) (
+	map[string]interface{}, error,
+) {
+	value := scheme.ExtensionProps.Extensions[securityPrefix+pluginName]
+	if value == nil {
+		return nil, nil
+	}
+	var extension map[string]interface{}
+	if err := decodeExtension(value, &extension); err != nil {
+		return nil, fmt.Errorf("expected '%s%s' of security scheme '%s' to be an object: %w",
+			securityPrefix, pluginName, schemeName, err)
+	}
+	return extension, nil
+}
+
+// mergeSecurityExtension merges the 'x-kong-security-<plugin>' extension of the scheme
+// into the generated plugin. The 'config' fields of the extension take precedence,
+// except for the list fields given, which are appended to the generated ones.
+func mergeSecurityExtension(
+	plugin map[string]interface{},
+	schemeName string,
+	scheme *openapi3.SecurityScheme,
+	appendFields ...string,
+) (map[string]interface{}, error) {
+	pluginName := plugin["name"].(string)
+	extension, err := getSecurityExtension(schemeName, scheme, pluginName)
+	if err != nil || extension == nil {
+		return plugin, err
+	}
+
+	config := plugin["config"].(map[string]interface{})
+	if extension["config"] != nil {
+		extConfig, ok := extension["config"].(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("expected 'config' in '%s%s' of security scheme '%s' to be an object",
+				securityPrefix, pluginName, schemeName)
+		}
+		generated := make(map[string]interface{}, len(appendFields))
+		for _, field := range appendFields {
+			generated[field] = config[field]
+		}
+		for key, value := range extConfig {
+			config[key] = value
+		}
+		for _, field := range appendFields {
+			if extConfig[field] == nil {
+				continue
+			}
+			extList, ok := extConfig[field].([]interface{})
+			if !ok {
+				return nil, fmt.Errorf("expected 'config.%s' in '%s%s' of security scheme '%s' to be an array",
+					field, securityPrefix, pluginName, schemeName)
+			}
+			config[field] = appendUnique(generated[field], extList)
+		}
+	}
+	for key, value := range extension {
+		switch key {
+		case "config":
+		case "name", "id", "tags":
+			return nil, fmt.Errorf("expected '%s%s' of security scheme '%s' to not set '%s'",
+				securityPrefix, pluginName, schemeName, key)
+		default:
+			plugin[key] = value
+		}
+	}
+	return plugin, nil
+}
+
+// appendUnique returns the list with the values appended, skipping the values already
+// in it. A nil list is treated as empty.
+func appendUnique(list interface{}, values []interface{}) []interface{} {
+	result, _ := list.([]interface{})
+	result = append([]interface{}{}, result...)
+	for _, value := range values {
+		found := false
+		for _, existing := range result {
+			found = found || reflect.DeepEqual(existing, value)
+		}
+		if !found {
+			result = append(result, value)
+		}
+	}
+	return result
+}
+
+// createKeyAuthPlugin returns a 'key-auth' plugin for an apiKey security scheme. The
+// key is only accepted by the scheme's 'name', and only where the scheme's 'in' says.
+// The 'key_names' of 'x-kong-security-key-auth' are accepted as well.
+func createKeyAuthPlugin(schemeName string, scheme *openapi3.SecurityScheme) (map[string]interface{}, error) {
+	if scheme.Name == "" {
+		return nil, fmt.Errorf("expected apiKey security scheme '%s' to have a 'name'", schemeName)
+	}
+	config := map[string]interface{}{
+		"key_names":     []interface{}{scheme.Name},
+		"key_in_header": false,
+		"key_in_query":  false,
+		"key_in_body":   false,
+	}
+	switch scheme.In {
+	case "header":
+		config["key_in_header"] = true
+	case "query":
+		config["key_in_query"] = true
+	default:
+		return nil, fmt.Errorf("expected apiKey security scheme '%s' to be 'in' the 'header' or 'query' "+
+			"(supported by key-auth), got: '%s'", schemeName, scheme.In)
+	}
+	plugin := map[string]interface{}{
+		"name":   "key-auth",
+		"config": config,
+	}
+	return mergeSecurityExtension(plugin, schemeName, scheme, "key_names")
+}
+
+// jwtHintsExtension is the extension on an http bearer JWT security scheme, with the
+// hints to configure the plugin validating the tokens.
+const jwtHintsExtension = "x-kong-jwt"
+
+// jwtHints are the hints of the 'x-kong-jwt' extension.
+type jwtHints struct {
+	// Plugin validating the tokens; 'jwt' (the default), or 'openid-connect' (the
+	// default if an issuer is given).
+	Plugin         string   `json:"plugin"`
+	Issuer         string   `json:"issuer"`           // issuer of the tokens, 'openid-connect' only
+	Audiences      []string `json:"audiences"`        // accepted audiences, 'openid-connect' only
+	ClaimsToVerify []string `json:"claims_to_verify"` // 'jwt' only, eg. 'exp'
+	KeyClaimName   string   `json:"key_claim_name"`   // 'jwt' only, the claim identifying the consumer credential
+}
+
+// isJWTScheme returns true if the http bearer scheme uses JWTs; either the
+// 'bearerFormat' says so, or it has JWT hints or plugin config.
+func isJWTScheme(scheme *openapi3.SecurityScheme) bool {
+	extensions := scheme.ExtensionProps.Extensions
+	return strings.EqualFold(scheme.BearerFormat, "jwt") || extensions[jwtHintsExtension] != nil ||
+		extensions[securityPrefix+"jwt"] != nil || extensions[securityPrefix+"openid-connect"] != nil
+}
+
+// getJWTHints returns the 'x-kong-jwt' hints of the scheme, validated. Returns nil if
+// there are none.
+func getJWTHints(schemeName string, scheme *openapi3.SecurityScheme) (*jwtHints, error) {
+	value := scheme.ExtensionProps.Extensions[jwtHintsExtension]
+	if value == nil {
+		return nil, nil
+	}
+	var raw json.RawMessage
+	if err := decodeExtension(value, &raw); err != nil {
+		return nil, fmt.Errorf("expected '%s' of security scheme '%s' to be an object: %w",
+			jwtHintsExtension, schemeName, err)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.DisallowUnknownFields()
+	var hints jwtHints
+	if err := decoder.Decode(&hints); err != nil {
+		return nil, fmt.Errorf("expected '%s' of security scheme '%s' to be an object with "+
+			"'plugin', 'issuer', 'audiences', 'claims_to_verify', and 'key_claim_name': %w",
+			jwtHintsExtension, schemeName, err)
+	}
+
+	switch {
+	case hints.Plugin == "" && hints.Issuer != "":
+		hints.Plugin = "openid-connect"
+	case hints.Plugin == "":
+		hints.Plugin = "jwt"
+	case hints.Plugin != "jwt" && hints.Plugin != "openid-connect":
+		return nil, fmt.Errorf("expected 'plugin' in '%s' of security scheme '%s' to be 'jwt' or "+
+			"'openid-connect', got: '%s'", jwtHintsExtension, schemeName, hints.Plugin)
+	}
+	if hints.Plugin == "jwt" && (hints.Issuer != "" || len(hints.Audiences) > 0) {
+		return nil, fmt.Errorf("'issuer' and 'audiences' in '%s' of security scheme '%s' require "+
+			"the 'openid-connect' plugin, the 'jwt' plugin cannot verify them", jwtHintsExtension, schemeName)
+	}
+	if hints.Plugin == "openid-connect" && (len(hints.ClaimsToVerify) > 0 || hints.KeyClaimName != "") {
+		return nil, fmt.Errorf("'claims_to_verify' and 'key_claim_name' in '%s' of security scheme '%s' "+
+			"are only supported by the 'jwt' plugin", jwtHintsExtension, schemeName)
+	}
+	if hints.Plugin == "openid-connect" && hints.Issuer == "" {
+		return nil, fmt.Errorf("expected '%s' of security scheme '%s' to have an 'issuer' for the "+
+			"'openid-connect' plugin", jwtHintsExtension, schemeName)
+	}
+	return &hints, nil
+}
+
+// createJWTPlugin returns a 'jwt' or 'openid-connect' plugin for an http bearer JWT
+// security scheme, configured from the 'x-kong-jwt' hints, and the 'x-kong-security-...'
+// config of the plugin. Without either, the plugin would reject all tokens, so it fails.
+func createJWTPlugin(schemeName string, scheme *openapi3.SecurityScheme) (map[string]interface{}, error) {
+	hints, err := getJWTHints(schemeName, scheme)
+	if err != nil {
+		return nil, err
+	}
+	if hints == nil {
+		switch {
+		case scheme.ExtensionProps.Extensions[securityPrefix+"jwt"] != nil:
+			hints = &jwtHints{Plugin: "jwt"}
+		case scheme.ExtensionProps.Extensions[securityPrefix+"openid-connect"] != nil:
+			hints = &jwtHints{Plugin: "openid-connect"}
+		default:
+			return nil, fmt.Errorf("expected http bearer JWT security scheme '%s' to have '%s' hints "+
+				"(eg. the 'issuer'), or an 'x-kong-security-jwt' config", schemeName, jwtHintsExtension)
+		}
+	}
+
+	config := make(map[string]interface{})
+	if hints.Plugin == "openid-connect" {
+		config["auth_methods"] = []interface{}{"bearer"}
+		if hints.Issuer != "" {
+			config["issuer"] = hints.Issuer
+		}
+		if len(hints.Audiences) > 0 {
+			config["audience_required"] = toInterfaceList(hints.Audiences)
+		}
+	} else {
+		if len(hints.ClaimsToVerify) > 0 {
+			config["claims_to_verify"] = toInterfaceList(hints.ClaimsToVerify)
+		}
+		if hints.KeyClaimName != "" {
+			config["key_claim_name"] = hints.KeyClaimName
+		}
+	}
+	plugin := map[string]interface{}{
+		"name":   hints.Plugin,
+		"config": config,
+	}
+	return mergeSecurityExtension(plugin, schemeName, scheme)
+}
+
+// oidcClientExtension is the extension on an openIdConnect security scheme, with the
+// client credentials of the openid-connect plugin.
+const oidcClientExtension = "x-kong-oidc-client"
+
+// vaultReference matches a Kong vault reference, eg. '{vault://env/client-secret}'.
+var vaultReference = regexp.MustCompile(`^\{vault://[^{}]+\}$`)
+
+// oidcClient are the client credentials of the 'x-kong-oidc-client' extension.
+type oidcClient struct {
+	ID     string `json:"id"`
+	Secret string `json:"secret"` // must be a vault reference, not to leak it in the spec
+}
+
+// getOIDCClient returns the 'x-kong-oidc-client' credentials of the scheme, validated.
+// Returns nil if there are none.
+func getOIDCClient(schemeName string, scheme *openapi3.SecurityScheme) (*oidcClient, error) {
+	value := scheme.ExtensionProps.Extensions[oidcClientExtension]
+	if value == nil {
+		return nil, nil
+	}
+	var raw json.RawMessage
+	if err := decodeExtension(value, &raw); err != nil {
+		return nil, fmt.Errorf("expected '%s' of security scheme '%s' to be an object: %w",
+			oidcClientExtension, schemeName, err)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.DisallowUnknownFields()
+	var client oidcClient
+	if err := decoder.Decode(&client); err != nil {
+		return nil, fmt.Errorf("expected '%s' of security scheme '%s' to be an object with 'id' and 'secret': %w",
+			oidcClientExtension, schemeName, err)
+	}
+	if client.ID == "" {
+		return nil, fmt.Errorf("expected '%s' of security scheme '%s' to have an 'id'", oidcClientExtension, schemeName)
+	}
+	if client.Secret != "" && !vaultReference.MatchString(client.Secret) {
+		return nil, fmt.Errorf("expected the 'secret' in '%s' of security scheme '%s' to be a vault reference, "+
+			"eg. '{vault://env/client-secret}', not to store it in the spec", oidcClientExtension, schemeName)
+	}
+	return &client, nil
+}
+
+// createOIDCPlugin returns an 'openid-connect' plugin for an openIdConnect security
+// scheme. The 'openIdConnectUrl' (the discovery document) is the issuer, the scopes
+// are required, and the client credentials are taken from 'x-kong-oidc-client'. The
+// 'scopes_required' of 'x-kong-security-openid-connect' are required as well.
+func createOIDCPlugin(schemeName string, scheme *openapi3.SecurityScheme, scopes []string) (
+	map[string]interface{}, error,
+) {
+	if scheme.OpenIdConnectUrl == "" {
+		return nil, fmt.Errorf("expected openIdConnect security scheme '%s' to have an 'openIdConnectUrl'",
+			schemeName)
+	}
+	client, err := getOIDCClient(schemeName, scheme)
+	if err != nil {
+		return nil, err
+	}
+
+	config := map[string]interface{}{
+		"issuer": scheme.OpenIdConnectUrl,
+	}
+	if len(scopes) > 0 {
+		config["scopes_required"] = toInterfaceList(scopes)
+	}
+	if client != nil {
+		config["client_id"] = []interface{}{client.ID}
+		if client.Secret != "" {
+			config["client_secret"] = []interface{}{client.Secret}
+		}
+	}
+	plugin := map[string]interface{}{
+		"name":   "openid-connect",
+		"config": config,
+	}
+	return mergeSecurityExtension(plugin, schemeName, scheme, "scopes_required")
+}
+
+// toInterfaceList returns the strings as a list of interfaces, like decoded JSON.
+func toInterfaceList(values []string) []interface{} {
+	result := make([]interface{}, len(values))
+	for i, value := range values {
+		result[i] = value
+	}
+	return result
+}
+
+// createSecurityPlugin returns the authentication plugin for a security scheme, or nil
+// if the scheme type is not supported. The scopes are those required by the security
+// requirement.
+func createSecurityPlugin(schemeName string, scheme *openapi3.SecurityScheme, scopes []string) (
+	map[string]interface{}, error,
+) {
+	switch {
+	case scheme.Type == "apiKey":
+		return createKeyAuthPlugin(schemeName, scheme)
+	case scheme.Type == "http" && strings.EqualFold(scheme.Scheme, "basic"):
+		plugin := map[string]interface{}{
+			"name":   "basic-auth",
+			"config": map[string]interface{}{},
+		}
+		return mergeSecurityExtension(plugin, schemeName, scheme)
+	case scheme.Type == "http" && strings.EqualFold(scheme.Scheme, "bearer") && isJWTScheme(scheme):
+		return createJWTPlugin(schemeName, scheme)
+	case scheme.Type == "openIdConnect":
+		return createOIDCPlugin(schemeName, scheme, scopes)
+	default:
+		return nil, nil
+	}
+}
+
+// getRequirementPlugins returns the authentication plugins for a single security
+// requirement, sorted by scheme name. Schemes of unsupported types have no plugin.
+func getRequirementPlugins(doc *openapi3.T, requirement openapi3.SecurityRequirement) (
+	[]map[string]interface{}, error,
+) {
+	schemeNames := make([]string, 0, len(requirement))
+	for schemeName := range requirement {
+		schemeNames = append(schemeNames, schemeName)
+	}
+	sort.Strings(schemeNames)
+
+	plugins := make([]map[string]interface{}, 0, len(schemeNames))
+	for _, schemeName := range schemeNames {
+		scheme, err := getSecurityScheme(doc, schemeName)
+		if err != nil {
+			return nil, err
+		}
+		plugin, err := createSecurityPlugin(schemeName, scheme, requirement[schemeName])
+		if err != nil {
+			return nil, err
+		}
+		if plugin != nil {
+			plugin["scheme"] = schemeName // removed before use, for error messages
+			plugins = append(plugins, plugin)
+		}
+	}
+	return plugins, nil
+}
+
+// anonymousSuffix is appended to the document name, for the username of the anonymous
+// consumer.
+const anonymousSuffix = "_anonymous"
+
+// anonymousConsumer returns the consumer that alternative security requirements use
+// as 'anonymous', for requests that fail the authentication of one of them.
+func anonymousConsumer(uuidNamespace uuid.UUID, docBaseName string, tags []string) map[string]interface{} {
+	username := docBaseName + anonymousSuffix
+	return map[string]interface{}{
+		"id":       uuid.NewV5(uuidNamespace, username+".consumer").String(),
+		"username": username,
+		"tags":     tags,
+	}
+}
+
+// securityPlugins are the plugins generated from the security requirements of an
+// operation.
+type securityPlugins struct {
+	plugins     *[]*map[string]interface{} // the route plugins, including the authentication plugins
+	termination *map[string]interface{}    // rejects the anonymous consumer on the route, if not nil
+	anonymous   bool                       // the anonymous consumer is used
+}
+
+// addSecurityPlugins adds the authentication plugins for the security requirements of
+// the operation to the list. Plugins already in the list, or on the service (eg. from
+// 'x-kong-plugin-...'), take precedence, and are used as is. The list remains sorted by
+// plugin name.
+//
+// Alternative requirements (OR) each must use a single scheme. Their plugins are
+// chained by setting 'config.anonymous' to the anonymous consumer, so a request
+// failing one plugin can pass another. The 'termination' plugin then rejects requests
+// that failed all of them, unless an empty requirement ('{}') makes security optional.
+func addSecurityPlugins(
+	list *[]*map[string]interface{},
+	servicePlugins interface{},
+	doc *openapi3.T,
+	operation *openapi3.Operation,
+	anonymous map[string]interface{},
+	uuidNamespace uuid.UUID,
+	baseName string,
+	tags []string,
+) (*securityPlugins, error) {
+	result := &securityPlugins{plugins: list}
+
+	optional := false
+	alternatives := make([][]map[string]interface{}, 0)
+	for _, requirement := range getSecurityRequirements(doc, operation) {
+		if len(requirement) == 0 {
+			optional = true
+			continue
+		}
+		plugins, err := getRequirementPlugins(doc, requirement)
+		if err != nil {
+			return nil, err
+		}
+		if len(plugins) > 0 {
+			alternatives = append(alternatives, plugins)
+		}
+	}
+	if len(alternatives) == 0 {
+		return result, nil
+	}
+
+	chained := optional || len(alternatives) > 1
+	added := make(map[string]string)
+	generated := make([]map[string]interface{}, 0)
+	for _, plugins := range alternatives {
+		if chained && len(plugins) > 1 {
+			return nil, fmt.Errorf("expected alternative security requirements to use a single scheme each, "+
+				"got '%s' and '%s' combined", plugins[0]["scheme"], plugins[1]["scheme"])
+		}
+		for _, plugin := range plugins {
+			pluginName := plugin["name"].(string)
+			schemeName := plugin["scheme"].(string)
+			delete(plugin, "scheme")
+			if other, found := added[pluginName]; found {
+				return nil, fmt.Errorf("security schemes '%s' and '%s' both require a '%s' plugin, "+
+					"which is not supported", other, schemeName, pluginName)
+			}
+			added[pluginName] = schemeName
+			generated = append(generated, plugin)
+		}
+	}
+
+	set := newPluginSet(list)
+	inserted := false
+	for i := range generated {
+		plugin := generated[i]
+		pluginName := plugin["name"].(string)
+		if hasPlugin(list, pluginName) || hasPlugin(servicePlugins, pluginName) {
+			continue
+		}
+		if chained {
+			plugin["config"].(map[string]interface{})["anonymous"] = anonymous["id"]
+			result.anonymous = true
+		}
+		plugin["tags"] = tags
+		plugin["id"] = createPluginID(uuidNamespace, baseName, plugin)
+		set.insert(&plugin)
+		inserted = true
+	}
+	if inserted {
+		result.plugins = set.list()
+	}
+
+	if result.anonymous && !optional {
+		termination := map[string]interface{}{
+			"name":     "request-termination",
+			"consumer": anonymous["username"],
+			"route":    baseName,
+			"config": map[string]interface{}{
+				"status_code": 401,
+				"message":     "Unauthorized",
+			},
+			"tags": tags,
+		}
+		termination["id"] = createPluginID(uuidNamespace, baseName+".anonymous", termination)
+		result.termination = &termination
+	}
+	return result, nil
+}
@@ -0,0 +1,445 @@
+package convertoas3
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	uuid "github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// routePlugin returns the plugin of the first route, by name, or nil if not found.
+func routePlugin(t *testing.T, result map[string]interface{}, name string) map[string]interface{} {
+	t.Helper()
+	service := result["services"].([]interface{})[0].(map[string]interface{})
+	route := service["routes"].([]interface{})[0].(map[string]interface{})
+	plugins, _ := route["plugins"].(*[]*map[string]interface{})
+	if plugins == nil {
+		return nil
+	}
+	for _, plugin := range *plugins {
+		if (*plugin)["name"] == name {
+			return *plugin
+		}
+	}
+	return nil
+}
+
+// indentLines indents each line of the YAML snippet.
+func indentLines(snippet string, indent string) string {
+	lines := strings.SplitAfter(snippet, "\n")
+	for i, line := range lines {
+		if line != "" {
+			lines[i] = indent + line
+		}
+	}
+	return strings.Join(lines, "")
+}
+
+const securitySpec = `openapi: 3.0.3
+info:
+  title: security
+  version: v1
+paths:
+  /a:
+    get:
+      security:
+      - scheme: []
+      responses: {}
+components:
+  securitySchemes:
+    scheme:
+`
+
+func Test_SecurityKeyAuth(t *testing.T) {
+	tests := []struct {
+		name     string
+		scheme   string
+		expected map[string]interface{}
+		wantErr  string
+	}{
+		{
+			name:   "header",
+			scheme: "type: apiKey\nin: header\nname: X-Api-Key\n",
+			expected: map[string]interface{}{
+				"key_names": []interface{}{"X-Api-Key"}, "key_in_header": true, "key_in_query": false, "key_in_body": false,
+			},
+		},
+		{
+			name:   "query",
+			scheme: "type: apiKey\nin: query\nname: api_key\n",
+			expected: map[string]interface{}{
+				"key_names": []interface{}{"api_key"}, "key_in_header": false, "key_in_query": true, "key_in_body": false,
+			},
+		},
+		{
+			name: "merged with x-kong-security-key-auth",
+			scheme: "type: apiKey\nin: header\nname: X-Api-Key\n" +
+				"x-kong-security-key-auth:\n  config:\n    key_names: [other, X-Api-Key]\n    hide_credentials: true\n",
+			expected: map[string]interface{}{
+				"key_names": []interface{}{"X-Api-Key", "other"}, "key_in_header": true, "key_in_query": false,
+				"key_in_body": false, "hide_credentials": true,
+			},
+		},
+		{
+			name:    "cookie",
+			scheme:  "type: apiKey\nin: cookie\nname: session\n",
+			wantErr: "expected apiKey security scheme 'scheme' to be 'in' the 'header' or 'query'",
+		},
+		{
+			name:    "invalid extension",
+			scheme:  "type: apiKey\nin: header\nname: key\nx-kong-security-key-auth:\n  config: [1]\n",
+			wantErr: "expected 'config' in 'x-kong-security-key-auth' of security scheme 'scheme' to be an object",
+		},
+	}
+
+	for _, tst := range tests {
+		t.Run(tst.name, func(t *testing.T) {
+			spec := []byte(securitySpec + indentLines(tst.scheme, "      "))
+			result, err := Convert(context.Background(), &spec, O2kOptions{SecurityPlugins: true})
+			if tst.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tst.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			plugin := routePlugin(t, result, "key-auth")
+			require.NotNil(t, plugin)
+			assert.Equal(t, tst.expected, plugin["config"])
+		})
+	}
+}
+
+func Test_SecurityPluginPrecedence(t *testing.T) {
+	spec := []byte(securitySpec + "      type: apiKey\n      in: header\n      name: X-Api-Key\n")
+
+	// not generated unless enabled
+	result, err := Convert(context.Background(), &spec, O2kOptions{})
+	require.NoError(t, err)
+	assert.Nil(t, routePlugin(t, result, "key-auth"))
+
+	// an explicit plugin on the service takes precedence
+	spec = append([]byte("x-kong-plugin-key-auth: {}\n"), spec...)
+	result, err = Convert(context.Background(), &spec, O2kOptions{SecurityPlugins: true})
+	require.NoError(t, err)
+	assert.Nil(t, routePlugin(t, result, "key-auth"))
+}
+
+func Test_SecurityOptOut(t *testing.T) {
+	spec := []byte(`openapi: 3.0.3
+info:
+  title: security
+  version: v1
+x-kong-plugin-key-auth: {}
+security:
+- apiKey: []
+paths:
+  /a:
+    get:
+      responses: {}
+    post:
+      security: []
+      responses: {}
+  /b:
+    x-kong-plugin-basic-auth: {}
+    get:
+      security: []
+      x-kong-plugin-hmac-auth: {}
+      responses: {}
+components:
+  securitySchemes:
+    apiKey:
+      type: apiKey
+      in: header
+      name: X-Api-Key
+`)
+	result, err := Convert(context.Background(), &spec, O2kOptions{SecurityPlugins: true})
+	require.NoError(t, err)
+
+	routes := make(map[string]map[string]interface{})
+	for _, s := range result["services"].([]interface{}) {
+		service := s.(map[string]interface{})
+		for _, r := range service["routes"].([]interface{}) {
+			route := r.(map[string]interface{})
+			route["service"] = service
+			routes[route["name"].(string)] = route
+		}
+	}
+	require.Len(t, routes, 3)
+
+	// inherits the service level key-auth
+	route := routes["security_a_get"]
+	assert.Equal(t, "security", route["service"].(map[string]interface{})["name"])
+
+	// opted out, gets its own service without key-auth
+	route = routes["security_a_post"]
+	service := route["service"].(map[string]interface{})
+	assert.Equal(t, "security_a_post", service["name"])
+	assert.False(t, hasSecurityPlugin(service["plugins"]))
+	assert.False(t, hasSecurityPlugin(route["plugins"]))
+
+	// opted out, keeps only the plugins declared on the operation
+	route = routes["security_b_get"]
+	assert.False(t, hasPlugin(route["plugins"], "key-auth"))
+	assert.False(t, hasPlugin(route["plugins"], "basic-auth"))
+	assert.True(t, hasPlugin(route["plugins"], "hmac-auth"))
+}
+
+func Test_SecurityAlternatives(t *testing.T) {
+	const schemes = `components:
+  securitySchemes:
+    apiKey:
+      type: apiKey
+      in: header
+      name: X-Api-Key
+    queryKey:
+      type: apiKey
+      in: query
+      name: key
+    basic:
+      type: http
+      scheme: basic
+`
+	tests := []struct {
+		name        string
+		security    string
+		plugins     []string
+		anonymous   bool
+		termination bool
+		wantErr     string
+	}{
+		{"single", "- apiKey: []\n", []string{"key-auth"}, false, false, ""},
+		{"combined", "- apiKey: []\n  basic: []\n", []string{"basic-auth", "key-auth"}, false, false, ""},
+		{"alternatives", "- apiKey: []\n- basic: []\n", []string{"basic-auth", "key-auth"}, true, true, ""},
+		{"optional", "- apiKey: []\n- {}\n", []string{"key-auth"}, true, false, ""},
+		{
+			"combined alternatives", "- apiKey: []\n  basic: []\n- basic: []\n", nil, false, false,
+			"expected alternative security requirements to use a single scheme each, got 'apiKey' and 'basic' combined",
+		},
+		{
+			"same plugin", "- apiKey: []\n- queryKey: []\n", nil, false, false,
+			"security schemes 'apiKey' and 'queryKey' both require a 'key-auth' plugin",
+		},
+	}
+
+	for _, tst := range tests {
+		t.Run(tst.name, func(t *testing.T) {
+			spec := []byte("openapi: 3.0.3\ninfo:\n  title: security\n  version: v1\n" +
+				"paths:\n  /a:\n    get:\n      responses: {}\n      security:\n" + indentLines(tst.security, "      ") +
+				schemes)
+			result, err := Convert(context.Background(), &spec, O2kOptions{SecurityPlugins: true})
+			if tst.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tst.wantErr)
+				return
+			}
+			require.NoError(t, err)
+
+			anonymous := anonymousConsumer(uuid.NamespaceDNS, "security", []string{})
+			for _, name := range tst.plugins {
+				plugin := routePlugin(t, result, name)
+				require.NotNil(t, plugin, name)
+				if tst.anonymous {
+					assert.Equal(t, anonymous["id"], plugin["config"].(map[string]interface{})["anonymous"])
+				} else {
+					assert.NotContains(t, plugin["config"], "anonymous")
+				}
+			}
+
+			if tst.anonymous {
+				assert.Equal(t, []interface{}{anonymous}, result["consumers"])
+			} else {
+				assert.NotContains(t, result, "consumers")
+			}
+			if tst.termination {
+				plugins := *result["plugins"].(*[]*map[string]interface{})
+				require.Len(t, plugins, 1)
+				assert.Equal(t, "request-termination", (*plugins[0])["name"])
+				assert.Equal(t, "security_anonymous", (*plugins[0])["consumer"])
+				assert.Equal(t, "security_a_get", (*plugins[0])["route"])
+			} else {
+				assert.NotContains(t, result, "plugins")
+			}
+		})
+	}
+}
+
+func Test_SecurityAlternativesIDSeed(t *testing.T) {
+	spec := []byte(`openapi: 3.0.3
+info:
+  title: Renamed
+  version: v1
+x-kong-id-seed: seed
+paths:
+  /a:
+    get:
+      security:
+      - apiKey: []
+      - basic: []
+      responses: {}
+components:
+  securitySchemes:
+    apiKey:
+      type: apiKey
+      in: header
+      name: X-Api-Key
+    basic:
+      type: http
+      scheme: basic
+`)
+	result, err := Convert(context.Background(), &spec, O2kOptions{SecurityPlugins: true})
+	require.NoError(t, err)
+
+	// the ID follows the seed, the username the document name
+	expected := anonymousConsumer(uuid.NamespaceDNS, "seed", []string{})
+	expected["username"] = "renamed_anonymous"
+	assert.Equal(t, []interface{}{expected}, result["consumers"])
+	plugins := *result["plugins"].(*[]*map[string]interface{})
+	assert.Equal(t, "renamed_anonymous", (*plugins[0])["consumer"])
+	assert.Equal(t, "renamed_a_get", (*plugins[0])["route"])
+}
+
+func Test_SecurityJWT(t *testing.T) {
+	tests := []struct {
+		name     string
+		scheme   string
+		plugin   string
+		expected map[string]interface{}
+		wantErr  string
+	}{
+		{
+			name: "jwt hints",
+			scheme: "type: http\nscheme: bearer\nbearerFormat: JWT\n" +
+				"x-kong-jwt:\n  claims_to_verify: [exp]\n  key_claim_name: kid\n",
+			plugin:   "jwt",
+			expected: map[string]interface{}{"claims_to_verify": []interface{}{"exp"}, "key_claim_name": "kid"},
+		},
+		{
+			name: "issuer implies openid-connect",
+			scheme: "type: http\nscheme: bearer\nbearerFormat: JWT\n" +
+				"x-kong-jwt:\n  issuer: https://idp.example.com\n  audiences: [api]\n",
+			plugin: "openid-connect",
+			expected: map[string]interface{}{
+				"auth_methods": []interface{}{"bearer"}, "issuer": "https://idp.example.com",
+				"audience_required": []interface{}{"api"},
+			},
+		},
+		{
+			name:     "jwt config only",
+			scheme:   "type: http\nscheme: bearer\nx-kong-security-jwt:\n  config:\n    key_claim_name: iss\n",
+			plugin:   "jwt",
+			expected: map[string]interface{}{"key_claim_name": "iss"},
+		},
+		{
+			name:    "no hints",
+			scheme:  "type: http\nscheme: bearer\nbearerFormat: JWT\n",
+			wantErr: "expected http bearer JWT security scheme 'scheme' to have 'x-kong-jwt' hints",
+		},
+		{
+			name:    "audiences with jwt",
+			scheme:  "type: http\nscheme: bearer\nx-kong-jwt:\n  plugin: jwt\n  audiences: [api]\n",
+			wantErr: "'issuer' and 'audiences' in 'x-kong-jwt' of security scheme 'scheme' require the 'openid-connect' plugin",
+		},
+		{
+			name:    "key claim with openid-connect",
+			scheme:  "type: http\nscheme: bearer\nx-kong-jwt:\n  issuer: https://idp\n  key_claim_name: kid\n",
+			wantErr: "'claims_to_verify' and 'key_claim_name' in 'x-kong-jwt' of security scheme 'scheme' are only",
+		},
+		{
+			name:    "openid-connect without issuer",
+			scheme:  "type: http\nscheme: bearer\nx-kong-jwt:\n  plugin: openid-connect\n",
+			wantErr: "expected 'x-kong-jwt' of security scheme 'scheme' to have an 'issuer'",
+		},
+		{
+			name:    "unknown hint",
+			scheme:  "type: http\nscheme: bearer\nx-kong-jwt:\n  isuer: https://idp\n",
+			wantErr: "unknown field \"isuer\"",
+		},
+	}
+
+	for _, tst := range tests {
+		t.Run(tst.name, func(t *testing.T) {
+			spec := []byte(securitySpec + indentLines(tst.scheme, "      "))
+			result, err := Convert(context.Background(), &spec, O2kOptions{SecurityPlugins: true})
+			if tst.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tst.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			plugin := routePlugin(t, result, tst.plugin)
+			require.NotNil(t, plugin)
+			assert.Equal(t, tst.expected, plugin["config"])
+		})
+	}
+}
+
+func Test_SecurityOpenIDConnect(t *testing.T) {
+	const discovery = "https://idp.example.com/.well-known/openid-configuration"
+	tests := []struct {
+		name     string
+		scheme   string
+		expected map[string]interface{}
+		wantErr  string
+	}{
+		{
+			name:     "discovery url",
+			scheme:   "type: openIdConnect\nopenIdConnectUrl: " + discovery + "\n",
+			expected: map[string]interface{}{"issuer": discovery},
+		},
+		{
+			name: "client credentials and scopes",
+			scheme: "type: openIdConnect\nopenIdConnectUrl: " + discovery + "\n" +
+				"x-kong-oidc-client:\n  id: my-api\n  secret: '{vault://env/my-api-secret}'\n" +
+				"x-kong-security-openid-connect:\n  config:\n    scopes_required: [openid]\n",
+			expected: map[string]interface{}{
+				"issuer":          discovery,
+				"client_id":       []interface{}{"my-api"},
+				"client_secret":   []interface{}{"{vault://env/my-api-secret}"},
+				"scopes_required": []interface{}{"openid"},
+			},
+		},
+		{
+			name: "plain secret",
+			scheme: "type: openIdConnect\nopenIdConnectUrl: " + discovery +
+				"\nx-kong-oidc-client:\n  id: a\n  secret: s3cr3t\n",
+			wantErr: "expected the 'secret' in 'x-kong-oidc-client' of security scheme 'scheme' to be a vault reference",
+		},
+		{
+			name:    "no client id",
+			scheme:  "type: openIdConnect\nopenIdConnectUrl: " + discovery + "\nx-kong-oidc-client: {}\n",
+			wantErr: "expected 'x-kong-oidc-client' of security scheme 'scheme' to have an 'id'",
+		},
+		{
+			name:    "no discovery url",
+			scheme:  "type: openIdConnect\n",
+			wantErr: "expected openIdConnect security scheme 'scheme' to have an 'openIdConnectUrl'",
+		},
+	}
+
+	for _, tst := range tests {
+		t.Run(tst.name, func(t *testing.T) {
+			spec := []byte(securitySpec + indentLines(tst.scheme, "      "))
+			result, err := Convert(context.Background(), &spec, O2kOptions{SecurityPlugins: true})
+			if tst.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tst.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			plugin := routePlugin(t, result, "openid-connect")
+			require.NotNil(t, plugin)
+			assert.Equal(t, tst.expected, plugin["config"])
+		})
+	}
+
+	// the required scopes of the security requirement are added
+	spec := []byte(strings.Replace(securitySpec, "- scheme: []", "- scheme: [read, write]", 1) +
+		"      type: openIdConnect\n      openIdConnectUrl: " + discovery + "\n")
+	result, err := Convert(context.Background(), &spec, O2kOptions{SecurityPlugins: true})
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{"read", "write"},
+		routePlugin(t, result, "openid-connect")["config"].(map[string]interface{})["scopes_required"])
+}
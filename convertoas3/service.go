@@ -1,9 +1,12 @@
 package convertoas3
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"net"
 	"net/url"
+	"regexp"
 	"strconv"
 	"strings"
 
@@ -16,75 +19,420 @@ const (
 	httpsScheme = "https"
 )
 
-// parseServerUris parses the server uri's after rendering the template variables.
-// result will always have at least 1 entry, but not necessarily a hostname/port/scheme
-func parseServerUris(servers *openapi3.Servers) ([]*url.URL, error) {
-	var targets []*url.URL
+// upstreamAlgorithms are the allowed values for the upstream 'algorithm' field.
+var upstreamAlgorithms = []string{"round-robin", "least-connections", "consistent-hashing"}
+
+// upstreamHashTypes are the allowed values for the upstream 'hash_on' and 'hash_fallback'
+// fields. The value is the field suffix for the required input, or "" if none is required.
+// A cookie is always set on 'hash_on_cookie', Kong uses it for both fields.
+var upstreamHashTypes = map[string]string{
+	"none":        "",
+	"consumer":    "",
+	"ip":          "",
+	"path":        "",
+	"header":      "header",
+	"cookie":      "cookie",
+	"query_arg":   "query_arg",
+	"uri_capture": "uri_capture",
+}
 
-	if servers == nil || len(*servers) == 0 {
-		uriObject, _ := url.ParseRequestURI("/") // path '/' is the default for empty server blocks
-		targets = make([]*url.URL, 1)
-		targets[0] = uriObject
+// setServiceOptionDefaults adds the timeouts and retries from the options to the
+// service defaults (JSON string), unless already set in those defaults. Returns the
+// updated JSON string, or the original (possibly nil) if there is nothing to add.
+// Returns an error if the defaults are not a JSON object.
+func setServiceOptionDefaults(serviceDefaults []byte, opts O2kOptions) ([]byte, error) {
+	optionDefaults := make(map[string]interface{})
+	if opts.ConnectTimeout != 0 {
+		optionDefaults["connect_timeout"] = opts.ConnectTimeout
+	}
+	if opts.ReadTimeout != 0 {
+		optionDefaults["read_timeout"] = opts.ReadTimeout
+	}
+	if opts.WriteTimeout != 0 {
+		optionDefaults["write_timeout"] = opts.WriteTimeout
+	}
+	if opts.Retries != nil {
+		optionDefaults["retries"] = *opts.Retries
+	}
+	if len(optionDefaults) == 0 {
+		return serviceDefaults, nil
+	}
 
-	} else {
-		targets = make([]*url.URL, len(*servers))
+	service := make(map[string]interface{})
+	if serviceDefaults != nil {
+		if err := json.Unmarshal(serviceDefaults, &service); err != nil {
+			return nil, fmt.Errorf("expected 'x-kong-service-defaults' to be a JSON object: %w", err)
+		}
+	}
+	for key, value := range optionDefaults {
+		if service[key] == nil {
+			service[key] = value
+		}
+	}
+	return json.Marshal(service)
+}
 
-		for i, server := range *servers {
-			uriString := server.URL
-			for name, svar := range server.Variables {
-				uriString = strings.ReplaceAll(uriString, "{"+name+"}", svar.Default)
+// setServerServiceDefaults returns the service defaults (JSON string) with the
+// 'x-kong-service-defaults' of the servers merged into them, so a server can override eg.
+// a timeout or client certificate. The servers share a single service, so their merged
+// defaults must be the same. Returns the original defaults if no server has any.
+func setServerServiceDefaults(
+	servers *openapi3.Servers,
+	serviceDefaults []byte,
+	components *map[string]interface{},
+) ([]byte, error) {
+	if servers == nil {
+		return serviceDefaults, nil
+	}
+	found := false
+	var merged []byte
+	for i, server := range *servers {
+		if server == nil {
+			continue // reported when parsing the server uris
+		}
+		pointer := fmt.Sprintf("/%d", i) + jsonPointer("x-kong-service-defaults")
+		serverDefaults, err := getServiceDefaults(server.ExtensionProps, components)
+		if err != nil {
+			return nil, &pointerError{pointer, err}
+		}
+		service := make(map[string]interface{})
+		if serviceDefaults != nil {
+			_ = json.Unmarshal(serviceDefaults, &service)
+		}
+		if serverDefaults != nil {
+			found = true
+			overrides := make(map[string]interface{})
+			_ = json.Unmarshal(serverDefaults, &overrides)
+			for key, value := range overrides {
+				service[key] = value
 			}
+		}
+		serverMerged, _ := json.Marshal(service)
+		if merged != nil && !bytes.Equal(serverMerged, merged) {
+			return nil, &pointerError{pointer, fmt.Errorf("expected the 'x-kong-service-defaults' of all " +
+				"servers to be the same, since they share a service")}
+		}
+		merged = serverMerged
+	}
+	if !found {
+		return serviceDefaults, nil
+	}
+	return merged, nil
+}
 
-			uriObject, err := url.ParseRequestURI(uriString)
-			if err != nil {
-				return targets, fmt.Errorf("failed to parse uri '%s'; %w", uriString, err)
-			}
+// setUpstreamHash sets the hash field ('hash_on' or 'hash_fallback') on the upstream,
+// from a "type[:input]" value. The input is set on the matching field, eg. for
+// "header:X-Id" the 'hash_on_header' field is set to "X-Id". A type that requires an
+// input can omit it, only if the upstream already has the input field.
+func setUpstreamHash(upstream map[string]interface{}, field string, value string) error {
+	hashType, input, hasInput := strings.Cut(value, ":")
+	inputField, valid := upstreamHashTypes[hashType]
+	if !valid {
+		return fmt.Errorf("invalid '%s' value '%s'", field, hashType)
+	}
+	if inputField == "" {
+		if hasInput {
+			return fmt.Errorf("'%s' value '%s' does not take an input", field, hashType)
+		}
+		upstream[field] = hashType
+		return nil
+	}
 
-			if uriObject.Path == "" {
-				uriObject.Path = "/" // path '/' is the default
-			}
+	inputKey := field + "_" + inputField
+	if inputField == "cookie" {
+		inputKey = "hash_on_cookie"
+	}
+	if !hasInput {
+		if upstream[inputKey] == nil {
+			return fmt.Errorf("'%s' value '%s' requires an input, eg. '%s:name'", field, hashType, hashType)
+		}
+	} else {
+		if input == "" {
+			return fmt.Errorf("'%s' value '%s' requires an input, eg. '%s:name'", field, hashType, hashType)
+		}
+		if existing := upstream[inputKey]; existing != nil && existing != input {
+			return fmt.Errorf("'%s' value '%s' conflicts with '%s' value '%v'", field, value, inputKey, existing)
+		}
+		upstream[inputKey] = input
+	}
+	upstream[field] = hashType
+	return nil
+}
 
-			targets[i] = uriObject
+// setUpstreamOptions applies the load balancing and host header options to the upstream,
+// unless already set (by x-kong-upstream-defaults). It also validates the final algorithm
+// and hash settings, where hash settings can use the "type[:input]" shorthand.
+func setUpstreamOptions(upstream map[string]interface{}, servers *openapi3.Servers, opts O2kOptions) error {
+	if upstream["host_header"] == nil && opts.UpstreamHostHeader {
+		targets, err := ParseServerUris(servers)
+		if err != nil {
+			return err
+		}
+		if hostname := targets[0].Hostname(); hostname != "" {
+			upstream["host_header"] = hostname
 		}
 	}
 
+	if upstream["algorithm"] == nil && opts.UpstreamAlgorithm != "" {
+		upstream["algorithm"] = opts.UpstreamAlgorithm
+	}
+	if upstream["hash_on"] == nil && opts.UpstreamHashOn != "" {
+		upstream["hash_on"] = opts.UpstreamHashOn
+	}
+	if upstream["hash_fallback"] == nil && opts.UpstreamHashFallback != "" {
+		upstream["hash_fallback"] = opts.UpstreamHashFallback
+	}
+
+	for _, field := range []string{"hash_on", "hash_fallback"} {
+		if upstream[field] == nil {
+			continue
+		}
+		value, ok := upstream[field].(string)
+		if !ok {
+			return fmt.Errorf("expected '%s' to be a string", field)
+		}
+		if err := setUpstreamHash(upstream, field, value); err != nil {
+			return err
+		}
+	}
+
+	hashing := (upstream["hash_on"] != nil && upstream["hash_on"] != "none")
+	if upstream["algorithm"] == nil {
+		if hashing {
+			upstream["algorithm"] = "consistent-hashing"
+		}
+		return nil
+	}
+
+	algorithm, ok := upstream["algorithm"].(string)
+	if !ok {
+		return fmt.Errorf("expected 'algorithm' to be a string")
+	}
+	valid := false
+	for _, allowed := range upstreamAlgorithms {
+		valid = valid || algorithm == allowed
+	}
+	if !valid {
+		return fmt.Errorf("invalid 'algorithm' value '%s', expected one of: %s",
+			algorithm, strings.Join(upstreamAlgorithms, ", "))
+	}
+	if hashing && algorithm != "consistent-hashing" {
+		return fmt.Errorf("'hash_on' requires 'algorithm' to be 'consistent-hashing', got '%s'", algorithm)
+	}
+	return nil
+}
+
+// ParseServerUris parses the server URLs after rendering the template variables, see
+// SetServerDefaults for completing them. The result always has at least 1 entry (path
+// '/' for an empty server block), but not necessarily a hostname, port or scheme. Returns
+// an error if a server is missing, has a variable without a default, or cannot be parsed.
+func ParseServerUris(servers *openapi3.Servers) ([]*url.URL, error) {
+	if servers == nil || len(*servers) == 0 {
+		// path '/' is the default for empty server blocks
+		return []*url.URL{{Path: "/"}}, nil
+	}
+
+	targets := make([]*url.URL, len(*servers))
+	for i, server := range *servers {
+		if server == nil {
+			return nil, fmt.Errorf("expected server %d to be an object", i)
+		}
+		uriString, err := substituteServerVariables(server)
+		if err != nil {
+			return nil, fmt.Errorf("failed to render server %d: %w", i, err)
+		}
+		uriObject, err := parseServerURL(uriString)
+		if err != nil {
+			return nil, err
+		}
+
+		if uriObject.Path == "" {
+			uriObject.Path = "/" // path '/' is the default
+		}
+
+		targets[i] = uriObject
+	}
 	return targets, nil
 }
 
-// setServerDefaults sets the scheme and port if missing and inferable.
-// It's set based on; scheme given, port (80/443), default-scheme. In that order.
-func setServerDefaults(targets []*url.URL, schemeDefault string) {
+// serverVariablePattern matches the variable placeholders in a server URL.
+var serverVariablePattern = regexp.MustCompile(`\{[^{}/]*\}`)
+
+// substituteServerVariables returns the server URL with the variables replaced by their
+// defaults. IPv6 addresses are put in brackets, unless the URL already has them around
+// the variable, so a variable can hold the host, eg. 'http://{host}:8080'. Returns an
+// error if a default is not one of the enum values of its variable, or if the URL has
+// a placeholder without a variable.
+func substituteServerVariables(server *openapi3.Server) (string, error) {
+	uriString := server.URL
+	for name, svar := range server.Variables {
+		if svar == nil {
+			continue
+		}
+		valid := len(svar.Enum) == 0
+		for _, allowed := range svar.Enum {
+			valid = valid || svar.Default == allowed
+		}
+		if !valid {
+			return "", fmt.Errorf("expected the default of variable '%s' to be one of '%s', got: '%s'",
+				name, strings.Join(svar.Enum, "', '"), svar.Default)
+		}
+		placeholder := "{" + name + "}"
+		value := svar.Default
+		if ip := net.ParseIP(value); ip != nil && strings.Contains(value, ":") &&
+			!strings.Contains(uriString, "["+placeholder+"]") {
+			value = "[" + value + "]"
+		}
+		uriString = strings.ReplaceAll(uriString, placeholder, value)
+	}
+	if placeholder := serverVariablePattern.FindString(uriString); placeholder != "" {
+		return "", fmt.Errorf("expected variable '%s' in '%s' to be defined with a default",
+			strings.Trim(placeholder, "{}"), server.URL)
+	}
+	return uriString, nil
+}
+
+// parseServerURL parses a server URL, which can be absolute, eg. 'http://[::1]:8080/api',
+// scheme relative, eg. '//my_service.mesh/api', or a path, eg. '/api'.
+func parseServerURL(uriString string) (*url.URL, error) {
+	var (
+		uriObject *url.URL
+		err       error
+	)
+	if strings.HasPrefix(uriString, "//") {
+		// ParseRequestURI would take the host as part of the path
+		uriObject, err = url.Parse(uriString)
+	} else {
+		uriObject, err = url.ParseRequestURI(uriString)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse uri '%s'; %w", uriString, err)
+	}
+	if isUnixSocket(uriObject.Scheme) {
+		return nil, unixSocketError(uriString)
+	}
+	if strings.Count(uriObject.Host, ":") > 1 && !strings.HasPrefix(uriObject.Host, "[") {
+		// without brackets, the port cannot be told apart from the address
+		return nil, fmt.Errorf("expected the IPv6 address in '%s' to be enclosed in brackets, "+
+			"eg. 'http://[::1]:8080'", uriString)
+	}
+	if strings.Contains(uriObject.Hostname(), "%") {
+		return nil, fmt.Errorf("expected the host of '%s' to not have an IPv6 zone, Kong does not "+
+			"support them", uriString)
+	}
+	return uriObject, nil
+}
+
+// isUnixSocket returns true if the URL scheme addresses a unix domain socket, eg.
+// 'unix:///var/run/app.sock' or 'http+unix://%2Fvar%2Frun%2Fapp.sock/api'.
+func isUnixSocket(scheme string) bool {
+	scheme = strings.ToLower(scheme)
+	return scheme == "unix" || strings.HasSuffix(scheme, "+unix")
+}
+
+// unixSocketError returns the error for a server or service host on a unix domain socket.
+// Kong services only connect to hosts (or upstreams) and ports.
+func unixSocketError(location string) error {
+	return fmt.Errorf("expected '%s' to be a host, Kong services cannot connect to unix domain sockets; "+
+		"listen on a (local) port in the sidecar, eg. 'http://127.0.0.1:8080'", location)
+}
+
+// hasServerWithoutHost returns true if any of the servers has no hostname, in which
+// case 'localhost' will be used by SetServerDefaults.
+func hasServerWithoutHost(servers *openapi3.Servers) bool {
+	targets, err := ParseServerUris(servers)
+	if err != nil {
+		return false
+	}
 	for _, target := range targets {
-		// set the hostname if unset
 		if target.Host == "" {
-			target.Host = "localhost"
+			return true
 		}
+	}
+	return false
+}
 
-		// set the scheme if unset
-		if target.Scheme == "" {
-			// detect scheme from the port
-			switch target.Port() {
-			case "80":
-				target.Scheme = httpScheme
+// serviceSchemes are the URL schemes Kong services accept as 'protocol', with the port
+// used if a server URL has none, or 0 if the scheme has no default port.
+var serviceSchemes = map[string]int{
+	"grpc":            80,
+	"grpcs":           443,
+	"http":            80,
+	"https":           443,
+	"tcp":             0,
+	"tls":             0,
+	"tls_passthrough": 0,
+	"udp":             0,
+	"ws":              80,
+	"wss":             443,
+}
 
-			case "443":
-				target.Scheme = httpsScheme
+// parsePort parses a port number, which must be in the range 1-65535.
+func parsePort(port string) (int, error) {
+	number, err := strconv.Atoi(port)
+	if err != nil || number < 1 || number > 65535 {
+		return 0, fmt.Errorf("expected port to be a number in the range 1-65535, got: '%s'", port)
+	}
+	return number, nil
+}
 
-			default:
-				target.Scheme = schemeDefault
-			}
+// inferSchemeAndPort returns the scheme and port of a server URL. A missing scheme is
+// inferred from the port (80/443), or is the schemeDefault otherwise. A missing port is
+// the default port of the scheme. Returns an error if Kong services do not support the
+// scheme, or if the port is invalid or cannot be inferred.
+func inferSchemeAndPort(target *url.URL, schemeDefault string) (string, int, error) {
+	scheme := strings.ToLower(target.Scheme)
+	if scheme == "" {
+		switch target.Port() {
+		case "80":
+			scheme = httpScheme
+		case "443":
+			scheme = httpsScheme
+		default:
+			scheme = strings.ToLower(schemeDefault)
 		}
+	}
+	defaultPort, supported := serviceSchemes[scheme]
+	if !supported {
+		return "", 0, fmt.Errorf("expected the scheme of '%s' to be one supported by Kong services, got: '%s'",
+			target.String(), scheme)
+	}
 
-		// set the port if unset (but a host is given)
-		if target.Host != "" && target.Port() == "" {
-			if target.Scheme == httpScheme {
-				target.Host = target.Host + ":80"
-			}
-			if target.Scheme == httpsScheme {
-				target.Host = target.Host + ":443"
-			}
+	if target.Port() == "" {
+		if defaultPort == 0 {
+			return "", 0, fmt.Errorf("expected '%s' to have a port, scheme '%s' has no default port",
+				target.String(), scheme)
 		}
+		return scheme, defaultPort, nil
+	}
+	port, err := parsePort(target.Port())
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid port in '%s': %w", target.String(), err)
 	}
+	return scheme, port, nil
+}
+
+// SetServerDefaults sets the hostname ('localhost'), scheme and port of the targets, as
+// returned by ParseServerUris, if missing, see inferSchemeAndPort. Returns an error if
+// the scheme or port of any of the targets is invalid.
+func SetServerDefaults(targets []*url.URL, schemeDefault string) error {
+	for _, target := range targets {
+		// set the hostname if unset
+		if target.Host == "" {
+			target.Host = "localhost"
+		}
+
+		scheme, port, err := inferSchemeAndPort(target, schemeDefault)
+		if err != nil {
+			return err
+		}
+		target.Scheme = scheme
+		if target.Port() == "" {
+			// JoinHostPort adds the brackets to IPv6 addresses
+			target.Host = net.JoinHostPort(target.Hostname(), strconv.Itoa(port))
+		}
+	}
+	return nil
 }
 
 func parseDefaultTargets(targets interface{}, tags []string) ([]map[string]interface{}, error) {
@@ -151,12 +499,14 @@ func createKongUpstream(
 	// no target array provided, so take from servers
 
 	// the server urls, will have minimum 1 entry on success
-	targets, err := parseServerUris(servers)
+	targets, err := ParseServerUris(servers)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate upstream: %w", err)
 	}
 
-	setServerDefaults(targets, httpsScheme)
+	if err = SetServerDefaults(targets, httpsScheme); err != nil {
+		return nil, fmt.Errorf("failed to generate upstream: %w", err)
+	}
 
 	// now add the targets to the upstream
 	upstreamTargets := make([]map[string]interface{}, len(targets))
@@ -194,6 +544,10 @@ func CreateKongService(
 		service = make(map[string]interface{})
 	}
 
+	if host, ok := service["host"].(string); ok && strings.HasPrefix(strings.ToLower(host), "unix:") {
+		return nil, nil, fmt.Errorf("failed to create service: %w", unixSocketError(host))
+	}
+
 	// add id, name and tags to the service
 	service["id"] = uuid.NewV5(uuidNamespace, baseName+".service").String()
 	service["name"] = baseName
@@ -202,7 +556,7 @@ func CreateKongService(
 	service["routes"] = make([]interface{}, 0)
 
 	// the server urls, will have minimum 1 entry on success
-	targets, err := parseServerUris(servers)
+	targets, err := ParseServerUris(servers)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to create service: %w", err)
 	}
@@ -212,7 +566,9 @@ func CreateKongService(
 	if service["protocol"] != nil {
 		scheme = service["protocol"].(string)
 	}
-	setServerDefaults(targets, scheme)
+	if err = SetServerDefaults(targets, scheme); err != nil {
+		return nil, nil, fmt.Errorf("failed to create service: %w", err)
+	}
 
 	if service["protocol"] == nil {
 		scheme = targets[0].Scheme
@@ -222,17 +578,8 @@ func CreateKongService(
 		service["path"] = targets[0].Path
 	}
 	if service["port"] == nil {
-		if targets[0].Port() != "" {
-			// port is provided, so parse it
-			service["port"], _ = strconv.ParseInt(targets[0].Port(), 10, 16)
-		} else {
-			// no port provided, so set it based on scheme, where https/443 is the default
-			if scheme != httpScheme {
-				service["port"] = 443
-			} else {
-				service["port"] = 80
-			}
-		}
+		// the defaults have been set, so the port is always there
+		_, service["port"], _ = inferSchemeAndPort(targets[0], scheme)
 	}
 
 	// we need an upstream if;
@@ -1,14 +1,17 @@
 package convertoas3
 
 import (
+	"encoding/json"
 	"net/url"
+	"strconv"
 	"testing"
 
 	"github.com/getkin/kin-openapi/openapi3"
 	"github.com/google/go-cmp/cmp"
+	uuid "github.com/satori/go.uuid"
 )
 
-func Test_parseServerUris(t *testing.T) {
+func Test_ParseServerUris(t *testing.T) {
 	// basics
 
 	servers := &openapi3.Servers{
@@ -29,7 +32,7 @@ func Test_parseServerUris(t *testing.T) {
 			Path:   "/bitter/sweet",
 		},
 	}
-	targets, err := parseServerUris(servers)
+	targets, err := ParseServerUris(servers)
 	if err != nil {
 		t.Errorf("did not expect error: %v", err)
 	}
@@ -61,7 +64,7 @@ func Test_parseServerUris(t *testing.T) {
 			Path:   "/chocolate/cookie",
 		},
 	}
-	targets, err = parseServerUris(servers)
+	targets, err = ParseServerUris(servers)
 	if err != nil {
 		t.Errorf("did not expect error: %v", err)
 	}
@@ -78,7 +81,7 @@ func Test_parseServerUris(t *testing.T) {
 			URL: "not really a url...",
 		},
 	}
-	_, err = parseServerUris(servers)
+	_, err = ParseServerUris(servers)
 	if err == nil {
 		t.Error("expected an error")
 	}
@@ -90,7 +93,7 @@ func Test_parseServerUris(t *testing.T) {
 			Path: "/",
 		},
 	}
-	targets, err = parseServerUris(&openapi3.Servers{})
+	targets, err = ParseServerUris(&openapi3.Servers{})
 	if err != nil {
 		t.Errorf("did not expect error: %v", err)
 	}
@@ -105,36 +108,495 @@ func Test_parseServerUris(t *testing.T) {
 			Path: "/",
 		},
 	}
-	targets, err = parseServerUris(nil)
+	targets, err = ParseServerUris(nil)
 	if err != nil {
 		t.Errorf("did not expect error: %v", err)
 	}
 	if diff := cmp.Diff(targets, expected); diff != "" {
 		t.Errorf(diff)
 	}
+
+	// returns an error, and no targets, for bad servers
+
+	errorTests := []struct {
+		name    string
+		servers *openapi3.Servers
+	}{
+		{"nil server", &openapi3.Servers{{URL: "https://konghq.com"}, nil}},
+		{"empty url", &openapi3.Servers{{URL: ""}}},
+		{"relative url", &openapi3.Servers{{URL: "api/v1"}}},
+		{"undefined variable", &openapi3.Servers{{URL: "https://{region}.konghq.com"}}},
+		{
+			"nil variable", &openapi3.Servers{{
+				URL:       "https://{region}.konghq.com",
+				Variables: map[string]*openapi3.ServerVariable{"region": nil},
+			}},
+		},
+		{
+			"default not in enum", &openapi3.Servers{{
+				URL: "https://{region}.konghq.com",
+				Variables: map[string]*openapi3.ServerVariable{
+					"region": {Default: "us", Enum: []string{"eu", "ap"}},
+				},
+			}},
+		},
+		{
+			"variable renders a bad url", &openapi3.Servers{{
+				URL:       "https://konghq.com:{port}",
+				Variables: map[string]*openapi3.ServerVariable{"port": {Default: "http"}},
+			}},
+		},
+	}
+	for _, tst := range errorTests {
+		targets, err := ParseServerUris(tst.servers)
+		if err == nil {
+			t.Errorf("%s: expected an error", tst.name)
+		}
+		if targets != nil {
+			t.Errorf("%s: expected no targets, got: %v", tst.name, targets)
+		}
+	}
+
+	// handles IPv6 and unusual hosts
+
+	hostTests := []struct {
+		name        string
+		server      *openapi3.Server
+		host        string
+		expectError bool
+	}{
+		{"IPv6 with port", &openapi3.Server{URL: "http://[::1]:8080/api"}, "[::1]:8080", false},
+		{"IPv6 without port", &openapi3.Server{URL: "http://[2001:db8::1]/api"}, "[2001:db8::1]", false},
+		{"underscores", &openapi3.Server{URL: "http://my_service.mesh_local:8080/api"}, "my_service.mesh_local:8080", false},
+		{"scheme relative", &openapi3.Server{URL: "//my_service/api"}, "my_service", false},
+		{
+			"IPv6 variable", &openapi3.Server{
+				URL:       "http://{host}:8080/api",
+				Variables: map[string]*openapi3.ServerVariable{"host": {Default: "fd00::1"}},
+			}, "[fd00::1]:8080", false,
+		},
+		{
+			"IPv6 variable in brackets", &openapi3.Server{
+				URL:       "http://[{host}]:8080/api",
+				Variables: map[string]*openapi3.ServerVariable{"host": {Default: "fd00::1"}},
+			}, "[fd00::1]:8080", false,
+		},
+		{"IPv6 without brackets", &openapi3.Server{URL: "http://::1:8080/api"}, "", true},
+		{"IPv6 zone", &openapi3.Server{URL: "http://[fe80::1%25eth0]:8080/api"}, "", true},
+		{"unix socket", &openapi3.Server{URL: "unix:///var/run/app.sock"}, "", true},
+		{"http over unix socket", &openapi3.Server{URL: "http+unix://%2Fvar%2Frun%2Fapp.sock/api"}, "", true},
+	}
+	for _, tst := range hostTests {
+		targets, err := ParseServerUris(&openapi3.Servers{tst.server})
+		if tst.expectError {
+			if err == nil {
+				t.Errorf("%s: expected an error", tst.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: did not expect error: %v", tst.name, err)
+			continue
+		}
+		if targets[0].Host != tst.host || targets[0].Path != "/api" {
+			t.Errorf("%s: expected host '%s' and path '/api', but got '%s' and '%s'", tst.name, tst.host,
+				targets[0].Host, targets[0].Path)
+		}
+	}
 }
 
-func Test_setServerDefaults(t *testing.T) {
+func Test_SetServerDefaults(t *testing.T) {
 	defaultTests := []struct {
 		name      string
 		inURL     string
-		outPort   string
+		outHost   string
 		outScheme string
 	}{
-		{"adds default scheme", "//host/path", "443", "https"},
-		{"adds port 80 for http", "http://host/path", "80", "http"},
-		{"adds port 443 for https", "https://host/path", "443", "https"},
+		{"adds default scheme", "//host/path", "host:443", "https"},
+		{"adds port 80 for http", "http://host/path", "host:80", "http"},
+		{"adds port 443 for https", "https://host/path", "host:443", "https"},
+		{"adds localhost", "/path", "localhost:443", "https"},
+		{"adds port for ws", "ws://host/path", "host:80", "ws"},
+		{"keeps high port", "//host:40000/path", "host:40000", "https"},
+		{"adds port to IPv6 host", "http://[::1]/path", "[::1]:80", "http"},
+		{"keeps IPv6 port", "//[::1]:80/path", "[::1]:80", "http"},
 	}
 
 	for _, tst := range defaultTests {
 		inURL, _ := url.Parse(tst.inURL)
 		urls := []*url.URL{inURL}
-		setServerDefaults(urls, "https")
-		if urls[0].Port() != tst.outPort {
-			t.Errorf("%s: expected port to be '%s', but got '%s'", tst.name, tst.outPort, urls[0].Port())
+		if err := SetServerDefaults(urls, "https"); err != nil {
+			t.Errorf("%s: did not expect error: %v", tst.name, err)
+			continue
+		}
+		if urls[0].Host != tst.outHost {
+			t.Errorf("%s: expected host to be '%s', but got '%s'", tst.name, tst.outHost, urls[0].Host)
 		}
 		if urls[0].Scheme != tst.outScheme {
 			t.Errorf("%s: expected scheme to be '%s', but got '%s'", tst.name, tst.outScheme, urls[0].Scheme)
 		}
 	}
+
+	// returns an error for any of the targets
+	first, _ := url.Parse("https://host/path")
+	second, _ := url.Parse("ftp://host/path")
+	if err := SetServerDefaults([]*url.URL{first, second}, "https"); err == nil {
+		t.Error("expected an error")
+	}
+}
+
+func Test_inferSchemeAndPort(t *testing.T) {
+	tests := []struct {
+		name          string
+		inURL         string
+		schemeDefault string
+		outScheme     string
+		outPort       int
+		expectError   bool
+	}{
+		{"scheme given", "http://host", "https", "http", 80, false},
+		{"scheme from port 80", "//host:80", "https", "http", 80, false},
+		{"scheme from port 443", "//host:443", "http", "https", 443, false},
+		{"default scheme", "//host:8080", "http", "http", 8080, false},
+		{"upper case default scheme", "//host", "HTTPS", "https", 443, false},
+		{"port above 32767", "https://host:40000", "https", "https", 40000, false},
+		{"highest port", "https://host:65535", "https", "https", 65535, false},
+		{"grpcs port", "grpcs://host", "https", "grpcs", 443, false},
+		{"tcp with port", "tcp://host:5432", "https", "tcp", 5432, false},
+		{"IPv6 without port", "http://[::1]", "https", "http", 80, false},
+		{"IPv6 with port", "http://[fe80::1]:8080", "https", "http", 8080, false},
+		{"port too high", "https://host:65536", "https", "", 0, true},
+		{"port zero", "https://host:0", "https", "", 0, true},
+		{"unknown scheme", "ftp://host", "https", "", 0, true},
+		{"unknown default scheme", "//host", "ftp", "", 0, true},
+		{"no default port", "tcp://host", "https", "", 0, true},
+	}
+
+	for _, tst := range tests {
+		inURL, err := url.Parse(tst.inURL)
+		if err != nil {
+			t.Fatalf("%s: bad test URL: %v", tst.name, err)
+		}
+		scheme, port, err := inferSchemeAndPort(inURL, tst.schemeDefault)
+		if tst.expectError {
+			if err == nil {
+				t.Errorf("%s: expected an error", tst.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: did not expect error: %v", tst.name, err)
+			continue
+		}
+		if scheme != tst.outScheme || port != tst.outPort {
+			t.Errorf("%s: expected '%s' and %d, but got '%s' and %d", tst.name, tst.outScheme, tst.outPort,
+				scheme, port)
+		}
+	}
+}
+
+func Test_CreateKongServicePort(t *testing.T) {
+	tests := []struct {
+		name        string
+		url         string
+		defaults    string
+		port        int
+		expectError bool
+	}{
+		{"port above 32767", "https://host:40000/", "", 40000, false},
+		{"default port", "http://host/", "", 80, false},
+		{"port from defaults", "https://host:40000/", `{"port":8443}`, 8443, false},
+		{"port out of range", "https://host:70000/", "", 0, true},
+		{"unsupported scheme", "ftp://host/", "", 0, true},
+		{"unix socket in defaults", "http://host/", `{"host":"unix:/var/run/app.sock"}`, 0, true},
+	}
+
+	for _, tst := range tests {
+		var defaults []byte
+		if tst.defaults != "" {
+			defaults = []byte(tst.defaults)
+		}
+		service, _, err := CreateKongService("svc", &openapi3.Servers{{URL: tst.url}}, defaults, nil, nil,
+			uuid.NamespaceDNS)
+		if tst.expectError {
+			if err == nil {
+				t.Errorf("%s: expected an error", tst.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: did not expect error: %v", tst.name, err)
+			continue
+		}
+		port, _ := json.Marshal(service["port"])
+		if string(port) != strconv.Itoa(tst.port) {
+			t.Errorf("%s: expected port %d, but got %s", tst.name, tst.port, port)
+		}
+	}
+}
+
+func Test_setServiceOptionDefaults(t *testing.T) {
+	retries := 0
+	tests := []struct {
+		name        string
+		defaults    []byte
+		opts        O2kOptions
+		expected    []byte
+		expectError bool
+	}{
+		{"no options, no defaults", nil, O2kOptions{}, nil, false},
+		{"no options", []byte(`{"retries":5}`), O2kOptions{}, []byte(`{"retries":5}`), false},
+		{
+			"options, no defaults", nil,
+			O2kOptions{ConnectTimeout: 1, ReadTimeout: 2, WriteTimeout: 3, Retries: &retries},
+			[]byte(`{"connect_timeout":1,"read_timeout":2,"retries":0,"write_timeout":3}`), false,
+		},
+		{
+			"defaults take precedence", []byte(`{"retries":5,"read_timeout":10}`),
+			O2kOptions{ConnectTimeout: 1, ReadTimeout: 2, Retries: &retries},
+			[]byte(`{"connect_timeout":1,"read_timeout":10,"retries":5}`), false,
+		},
+		{"invalid defaults", []byte(`[5]`), O2kOptions{Retries: &retries}, nil, true},
+	}
+
+	for _, tst := range tests {
+		result, err := setServiceOptionDefaults(tst.defaults, tst.opts)
+		if tst.expectError {
+			if err == nil {
+				t.Errorf("%s: expected an error", tst.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: did not expect error: %v", tst.name, err)
+			continue
+		}
+		if string(result) != string(tst.expected) {
+			t.Errorf("%s: expected '%s', but got '%s'", tst.name, tst.expected, result)
+		}
+	}
+}
+
+func Test_setUpstreamOptions(t *testing.T) {
+	tests := []struct {
+		name        string
+		upstream    map[string]interface{}
+		servers     *openapi3.Servers
+		opts        O2kOptions
+		expected    map[string]interface{}
+		expectError bool
+	}{
+		{
+			"no options", map[string]interface{}{}, nil, O2kOptions{},
+			map[string]interface{}{}, false,
+		},
+		{
+			"sets algorithm", map[string]interface{}{}, nil, O2kOptions{UpstreamAlgorithm: "least-connections"},
+			map[string]interface{}{"algorithm": "least-connections"}, false,
+		},
+		{
+			"defaults take precedence", map[string]interface{}{"algorithm": "round-robin"}, nil,
+			O2kOptions{UpstreamAlgorithm: "least-connections"},
+			map[string]interface{}{"algorithm": "round-robin"}, false,
+		},
+		{
+			"hash shorthand implies consistent-hashing", map[string]interface{}{}, nil,
+			O2kOptions{UpstreamHashOn: "header:X-User-Id", UpstreamHashFallback: "ip"},
+			map[string]interface{}{
+				"algorithm":      "consistent-hashing",
+				"hash_on":        "header",
+				"hash_on_header": "X-User-Id",
+				"hash_fallback":  "ip",
+			}, false,
+		},
+		{
+			"shorthand in defaults", map[string]interface{}{"hash_on": "query_arg:user"}, nil, O2kOptions{},
+			map[string]interface{}{
+				"algorithm":         "consistent-hashing",
+				"hash_on":           "query_arg",
+				"hash_on_query_arg": "user",
+			}, false,
+		},
+		{
+			"input in separate field", map[string]interface{}{"hash_on": "cookie", "hash_on_cookie": "c"}, nil,
+			O2kOptions{},
+			map[string]interface{}{
+				"algorithm":      "consistent-hashing",
+				"hash_on":        "cookie",
+				"hash_on_cookie": "c",
+			}, false,
+		},
+		{
+			"cookie fallback", map[string]interface{}{}, nil,
+			O2kOptions{UpstreamHashOn: "header:X-User-Id", UpstreamHashFallback: "cookie:sid"},
+			map[string]interface{}{
+				"algorithm":      "consistent-hashing",
+				"hash_on":        "header",
+				"hash_on_header": "X-User-Id",
+				"hash_fallback":  "cookie",
+				"hash_on_cookie": "sid",
+			}, false,
+		},
+		{
+			"input from defaults", map[string]interface{}{"hash_on_header": "X-User-Id"}, nil,
+			O2kOptions{UpstreamHashOn: "header"},
+			map[string]interface{}{
+				"algorithm":      "consistent-hashing",
+				"hash_on":        "header",
+				"hash_on_header": "X-User-Id",
+			}, false,
+		},
+		{"missing hash input", map[string]interface{}{}, nil, O2kOptions{UpstreamHashOn: "header"}, nil, true},
+		{
+			"different cookies", map[string]interface{}{}, nil,
+			O2kOptions{UpstreamHashOn: "cookie:a", UpstreamHashFallback: "cookie:b"}, nil, true,
+		},
+		{"bad algorithm", map[string]interface{}{}, nil, O2kOptions{UpstreamAlgorithm: "random"}, nil, true},
+		{"bad hash type", map[string]interface{}{}, nil, O2kOptions{UpstreamHashOn: "body"}, nil, true},
+		{"unexpected hash input", map[string]interface{}{}, nil, O2kOptions{UpstreamHashOn: "ip:1.2.3.4"}, nil, true},
+		{"empty hash input", map[string]interface{}{}, nil, O2kOptions{UpstreamHashOn: "header:"}, nil, true},
+		{
+			"hash with wrong algorithm", map[string]interface{}{}, nil,
+			O2kOptions{UpstreamHashOn: "ip", UpstreamAlgorithm: "round-robin"}, nil, true,
+		},
+		{
+			"host header from first server", map[string]interface{}{},
+			&openapi3.Servers{{URL: "https://server1.com:8443/path"}, {URL: "https://server2.com"}},
+			O2kOptions{UpstreamHostHeader: true},
+			map[string]interface{}{"host_header": "server1.com"}, false,
+		},
+		{
+			"host header from defaults", map[string]interface{}{"host_header": "other.com"},
+			&openapi3.Servers{{URL: "https://server1.com"}},
+			O2kOptions{UpstreamHostHeader: true},
+			map[string]interface{}{"host_header": "other.com"}, false,
+		},
+		{
+			"no host header without hostname", map[string]interface{}{}, nil,
+			O2kOptions{UpstreamHostHeader: true},
+			map[string]interface{}{}, false,
+		},
+	}
+
+	for _, tst := range tests {
+		err := setUpstreamOptions(tst.upstream, tst.servers, tst.opts)
+		if tst.expectError {
+			if err == nil {
+				t.Errorf("%s: expected an error", tst.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: did not expect error: %v", tst.name, err)
+		}
+		if diff := cmp.Diff(tst.upstream, tst.expected); diff != "" {
+			t.Errorf("%s: %s", tst.name, diff)
+		}
+	}
+}
+
+func Test_setServerServiceDefaults(t *testing.T) {
+	withDefaults := func(url string, defaults string) *openapi3.Server {
+		server := &openapi3.Server{URL: url}
+		if defaults != "" {
+			server.Extensions = map[string]interface{}{"x-kong-service-defaults": json.RawMessage(defaults)}
+		}
+		return server
+	}
+	components := map[string]interface{}{
+		"mtls": map[string]interface{}{"client_certificate": map[string]interface{}{"id": "cert"}},
+	}
+
+	tests := []struct {
+		name        string
+		servers     *openapi3.Servers
+		defaults    []byte
+		expected    []byte
+		expectError bool
+	}{
+		{"no servers", nil, []byte(`{"retries":5}`), []byte(`{"retries":5}`), false},
+		{
+			"no server defaults", &openapi3.Servers{withDefaults("https://server1.com", "")},
+			[]byte(`{"retries":5}`), []byte(`{"retries":5}`), false,
+		},
+		{
+			"server defaults take precedence",
+			&openapi3.Servers{withDefaults("https://server1.com", `{"connect_timeout":10}`)},
+			[]byte(`{"connect_timeout":5,"retries":5}`), []byte(`{"connect_timeout":10,"retries":5}`), false,
+		},
+		{
+			"server defaults by reference",
+			&openapi3.Servers{withDefaults("https://server1.com", `{"$ref":"#/components/x-kong/mtls"}`)},
+			nil, []byte(`{"client_certificate":{"id":"cert"}}`), false,
+		},
+		{
+			"same on all servers",
+			&openapi3.Servers{
+				withDefaults("https://server1.com", `{"retries":1}`),
+				withDefaults("https://server2.com", `{"retries":1}`),
+			},
+			nil, []byte(`{"retries":1}`), false,
+		},
+		{
+			"different per server",
+			&openapi3.Servers{
+				withDefaults("https://server1.com", `{"retries":1}`),
+				withDefaults("https://server2.com", ""),
+			},
+			nil, nil, true,
+		},
+		{
+			"not an object", &openapi3.Servers{withDefaults("https://server1.com", `true`)},
+			nil, nil, true,
+		},
+	}
+
+	for _, tst := range tests {
+		result, err := setServerServiceDefaults(tst.servers, tst.defaults, &components)
+		if tst.expectError {
+			if err == nil {
+				t.Errorf("%s: expected an error", tst.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: did not expect error: %v", tst.name, err)
+		}
+		if string(result) != string(tst.expected) {
+			t.Errorf("%s: expected '%s', but got '%s'", tst.name, tst.expected, result)
+		}
+	}
+}
+
+func Test_CreateKongServiceHosts(t *testing.T) {
+	service, upstream, err := CreateKongService("svc", &openapi3.Servers{{URL: "http://[::1]:8080/api"}},
+		nil, nil, nil, uuid.NamespaceDNS)
+	if err != nil {
+		t.Fatalf("did not expect error: %v", err)
+	}
+	if service["host"] != "::1" || upstream != nil {
+		t.Errorf("expected host '::1' without upstream, but got '%v'", service["host"])
+	}
+
+	service, _, err = CreateKongService("svc", &openapi3.Servers{{URL: "http://my_service.mesh/api"}},
+		nil, nil, nil, uuid.NamespaceDNS)
+	if err != nil {
+		t.Fatalf("did not expect error: %v", err)
+	}
+	if service["host"] != "my_service.mesh" {
+		t.Errorf("expected host 'my_service.mesh', but got '%v'", service["host"])
+	}
+
+	_, upstream, err = CreateKongService("svc", &openapi3.Servers{
+		{URL: "http://[fd00::1]/api"},
+		{URL: "https://[fd00::2]:8443/api"},
+	}, nil, nil, nil, uuid.NamespaceDNS)
+	if err != nil {
+		t.Fatalf("did not expect error: %v", err)
+	}
+	targets := upstream["targets"].([]map[string]interface{})
+	if targets[0]["target"] != "[fd00::1]:80" || targets[1]["target"] != "[fd00::2]:8443" {
+		t.Errorf("expected IPv6 targets with ports, but got '%v' and '%v'", targets[0]["target"],
+			targets[1]["target"])
+	}
 }
@@ -0,0 +1,89 @@
+package convertoas3
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+// literalKeys are the keys with values that are data, not part of the spec structure,
+// so extensions are not looked for inside them.
+var literalKeys = map[string]bool{
+	"example": true,
+	"default": true,
+	"enum":    true,
+}
+
+// keepExtension returns true if the extension is in the keep list. An entry ending in
+// '*' matches all extensions with that prefix, eg. 'x-kong-plugin-*'.
+func keepExtension(name string, keep []string) bool {
+	for _, k := range keep {
+		if name == k || (strings.HasSuffix(k, "*") && strings.HasPrefix(name, strings.TrimSuffix(k, "*"))) {
+			return true
+		}
+	}
+	return false
+}
+
+// stripKongExtensions removes the 'x-kong-...' extensions from the value, recursively,
+// except the ones to keep. Returns true if any extension was kept. The keys of a
+// 'properties' object are property names, and not removed.
+func stripKongExtensions(value interface{}, parentKey string, keep []string) bool {
+	kept := false
+	switch value := value.(type) {
+	case map[string]interface{}:
+		for key, child := range value {
+			switch {
+			case parentKey != "properties" && strings.HasPrefix(key, kongExtensionPrefix):
+				if keepExtension(key, keep) {
+					kept = true
+				} else {
+					delete(value, key)
+				}
+			case !literalKeys[key]:
+				kept = stripKongExtensions(child, key, keep) || kept
+			}
+		}
+	case []interface{}:
+		for _, child := range value {
+			kept = stripKongExtensions(child, parentKey, keep) || kept
+		}
+	}
+	return kept
+}
+
+// StripExtensions returns an OpenAPI spec (JSON or YAML) with all 'x-kong-...'
+// extensions, and '/components/x-kong', removed. So the spec can be published, eg. to a
+// developer portal, without the gateway configuration. The extensions in keep are
+// retained, see keepExtension, and if any is found '/components/x-kong' is retained as
+// well, since they may reference it.
+func StripExtensions(content *[]byte, keep ...string) (map[string]interface{}, error) {
+	for _, k := range keep {
+		if !strings.HasPrefix(k, kongExtensionPrefix) {
+			return nil, fmt.Errorf("expected the extensions to keep to start with '%s', got: '%s'",
+				kongExtensionPrefix, k)
+		}
+	}
+
+	jsonContent, err := yaml.YAMLToJSON(*content)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing OAS3 file: [%w]", err)
+	}
+	var doc map[string]interface{}
+	if err = json.Unmarshal(jsonContent, &doc); err != nil {
+		return nil, fmt.Errorf("error parsing OAS3 file: [%w]", err)
+	}
+
+	if stripKongExtensions(doc, "", keep) {
+		return doc, nil
+	}
+	if components, ok := doc["components"].(map[string]interface{}); ok {
+		delete(components, "x-kong")
+		if len(components) == 0 {
+			delete(doc, "components")
+		}
+	}
+	return doc, nil
+}
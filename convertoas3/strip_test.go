@@ -0,0 +1,144 @@
+package convertoas3
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_StripExtensions(t *testing.T) {
+	content := []byte(`
+openapi: 3.0.3
+info:
+  title: Learn Service
+  version: 1.0.0
+x-kong-name: learn
+x-kong-plugin-key-auth:
+  $ref: '#/components/x-kong/auth'
+x-logo: logo.png
+paths:
+  /learn:
+    x-kong-route-defaults:
+      strip_path: true
+    get:
+      x-kong-plugin-cors: {}
+      parameters:
+        - name: id
+          in: query
+          x-kong-name: ignored
+          schema:
+            type: object
+            properties:
+              x-kong-id:
+                type: string
+            example:
+              x-kong-id: abc
+      responses:
+        "200":
+          description: OK
+components:
+  x-kong:
+    auth:
+      config: {}
+`)
+	doc, err := StripExtensions(&content)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"openapi": "3.0.3",
+		"info":    map[string]interface{}{"title": "Learn Service", "version": "1.0.0"},
+		"x-logo":  "logo.png",
+		"paths": map[string]interface{}{
+			"/learn": map[string]interface{}{
+				"get": map[string]interface{}{
+					"parameters": []interface{}{
+						map[string]interface{}{
+							"name": "id",
+							"in":   "query",
+							"schema": map[string]interface{}{
+								"type": "object",
+								"properties": map[string]interface{}{
+									"x-kong-id": map[string]interface{}{"type": "string"},
+								},
+								"example": map[string]interface{}{"x-kong-id": "abc"},
+							},
+						},
+					},
+					"responses": map[string]interface{}{
+						"200": map[string]interface{}{"description": "OK"},
+					},
+				},
+			},
+		},
+	}, doc)
+
+	invalid := []byte("{")
+	_, err = StripExtensions(&invalid)
+	assert.Error(t, err)
+}
+
+func Test_StripExtensionsKeep(t *testing.T) {
+	content := []byte(`
+openapi: 3.0.3
+x-kong-name: learn
+x-kong-plugin-key-auth:
+  $ref: '#/components/x-kong/auth'
+x-kong-plugin-cors: {}
+x-kong-tags: [a]
+components:
+  x-kong:
+    auth:
+      config: {}
+`)
+	tests := []struct {
+		name string
+		keep []string
+		want map[string]interface{}
+		err  string
+	}{
+		{
+			name: "exact name",
+			keep: []string{"x-kong-name"},
+			want: map[string]interface{}{
+				"openapi":     "3.0.3",
+				"x-kong-name": "learn",
+				"components": map[string]interface{}{
+					"x-kong": map[string]interface{}{"auth": map[string]interface{}{"config": map[string]interface{}{}}},
+				},
+			},
+		},
+		{
+			name: "prefix",
+			keep: []string{"x-kong-plugin-*"},
+			want: map[string]interface{}{
+				"openapi":                "3.0.3",
+				"x-kong-plugin-key-auth": map[string]interface{}{"$ref": "#/components/x-kong/auth"},
+				"x-kong-plugin-cors":     map[string]interface{}{},
+				"components": map[string]interface{}{
+					"x-kong": map[string]interface{}{"auth": map[string]interface{}{"config": map[string]interface{}{}}},
+				},
+			},
+		},
+		{
+			name: "not found",
+			keep: []string{"x-kong-route-defaults"},
+			want: map[string]interface{}{"openapi": "3.0.3"},
+		},
+		{
+			name: "not an x-kong extension",
+			keep: []string{"x-logo"},
+			err:  "expected the extensions to keep to start with 'x-kong-', got: 'x-logo'",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := StripExtensions(&content, tt.keep...)
+			if tt.err != "" {
+				assert.EqualError(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, doc)
+		})
+	}
+}
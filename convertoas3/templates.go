@@ -0,0 +1,141 @@
+package convertoas3
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	uuid "github.com/satori/go.uuid"
+)
+
+// templateData is the data available to the Go templates in the values of 'x-kong-...'
+// plugins and route defaults, eg. '{{ .Operation.OperationID }}'. They are resolved per
+// route.
+type templateData struct {
+	Path      string              // the path of the operation, eg. '/users/{id}'
+	Method    string              // the method of the operation, in upper case
+	Name      string              // the name of the route
+	Operation *openapi3.Operation // the operation, eg. '.Operation.OperationID'
+}
+
+// hasTemplate returns true if any of the strings in the value has a template placeholder.
+func hasTemplate(value interface{}) bool {
+	switch v := value.(type) {
+	case string:
+		return strings.Contains(v, "{{")
+	case map[string]interface{}:
+		for _, entry := range v {
+			if hasTemplate(entry) {
+				return true
+			}
+		}
+	case *map[string]interface{}:
+		return v != nil && hasTemplate(*v)
+	case []interface{}:
+		for _, entry := range v {
+			if hasTemplate(entry) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// resolveTemplates returns a copy of the value, with the templates in its strings
+// executed using the data. Strings without placeholders are copied as is.
+func resolveTemplates(value interface{}, data *templateData) (interface{}, error) {
+	switch v := value.(type) {
+	case string:
+		if !strings.Contains(v, "{{") {
+			return v, nil
+		}
+		tmpl, err := template.New("").Option("missingkey=error").Parse(v)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse template '%s': %w", v, err)
+		}
+		var result strings.Builder
+		if err = tmpl.Execute(&result, data); err != nil {
+			return nil, fmt.Errorf("failed to execute template '%s': %w", v, err)
+		}
+		return result.String(), nil
+	case map[string]interface{}:
+		object := make(map[string]interface{}, len(v))
+		for key, entry := range v {
+			resolved, err := resolveTemplates(entry, data)
+			if err != nil {
+				return nil, err
+			}
+			object[key] = resolved
+		}
+		return object, nil
+	case []interface{}:
+		list := make([]interface{}, len(v))
+		for i, entry := range v {
+			resolved, err := resolveTemplates(entry, data)
+			if err != nil {
+				return nil, err
+			}
+			list[i] = resolved
+		}
+		return list, nil
+	}
+	return deepCopy(value), nil
+}
+
+// resolvePluginTemplates resolves the templates in the plugins of a route. The plugins
+// of its service with templates cannot be resolved on the service, so they are added to
+// the route, unless the route has a plugin with the same name, with IDs derived from the
+// route name. Returns the new list, sorted by plugin name.
+func resolvePluginTemplates(
+	list *[]*map[string]interface{},
+	servicePlugins interface{},
+	data *templateData,
+	uuidNamespace uuid.UUID,
+	baseName string,
+) (*[]*map[string]interface{}, error) {
+	plugins := newPluginSet(nil)
+	if service, ok := servicePlugins.(*[]*map[string]interface{}); ok && service != nil {
+		for _, plugin := range *service {
+			if hasTemplate(plugin) {
+				pluginCopy := deepCopy(plugin).(*map[string]interface{})
+				(*pluginCopy)["id"] = createPluginID(uuidNamespace, baseName, *pluginCopy)
+				plugins.insert(pluginCopy)
+			}
+		}
+	}
+	if list != nil {
+		for _, plugin := range *list {
+			plugins.insert(plugin)
+		}
+	}
+
+	for _, plugin := range *plugins.list() {
+		if !hasTemplate(plugin) {
+			continue
+		}
+		resolved, err := resolveTemplates(*plugin, data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve plugin '%s': %w", pluginName(plugin), err)
+		}
+		resolvedPlugin := resolved.(map[string]interface{})
+		plugins.replace(&resolvedPlugin)
+	}
+	return plugins.list(), nil
+}
+
+// removeTemplatedPlugins removes the plugins with templates from the service, those
+// have been resolved on its routes.
+func removeTemplatedPlugins(service map[string]interface{}) {
+	plugins, ok := service["plugins"].(*[]*map[string]interface{})
+	if !ok || plugins == nil {
+		return
+	}
+	kept := make([]*map[string]interface{}, 0, len(*plugins))
+	for _, plugin := range *plugins {
+		if !hasTemplate(plugin) {
+			kept = append(kept, plugin)
+		}
+	}
+	service["plugins"] = &kept
+}
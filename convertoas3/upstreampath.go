@@ -0,0 +1,102 @@
+package convertoas3
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	uuid "github.com/satori/go.uuid"
+)
+
+const upstreamPathExtension = "x-kong-upstream-path"
+
+// getUpstreamPath returns the 'x-kong-upstream-path' extension, the path template to
+// send requests to upstream, eg. '/v2/users/{id}'. Returns "" if there is none.
+func getUpstreamPath(props openapi3.ExtensionProps) (string, error) {
+	value := props.Extensions[upstreamPathExtension]
+	if value == nil {
+		return "", nil
+	}
+	var upstreamPath string
+	if err := decodeExtension(value, &upstreamPath); err != nil {
+		return "", fmt.Errorf("expected '%s' to be a string: %w", upstreamPathExtension, err)
+	}
+	if !strings.HasPrefix(upstreamPath, "/") {
+		return "", fmt.Errorf("expected '%s' to start with '/', got: '%s'", upstreamPathExtension, upstreamPath)
+	}
+	return upstreamPath, nil
+}
+
+// prefixStrippedPath returns the upstream path for an operation when the route paths
+// have a path prefix; the service path followed by the path of the operation, so the
+// backend gets the path without the prefix, eg. '/api/users/{id}' for the operation path
+// '/users/{id}' of a service with path '/api/'.
+func prefixStrippedPath(servicePath interface{}, path string) string {
+	prefix, _ := servicePath.(string)
+	return strings.TrimSuffix(prefix, "/") + path
+}
+
+// createUpstreamPathTemplate converts the upstream path template to a Kong template. Path
+// parameters, eg. '{id}', are replaced by the route regex capture of the parameter,
+// '$(uri_captures["id"])'. The captures map parameter names to capture names, see
+// createRouteRegex. Parameters not in the route path are an error.
+func createUpstreamPathTemplate(upstreamPath string, captures map[string]string) (string, error) {
+	var template strings.Builder
+	lastEnd := 0
+	for _, match := range pathParameterRegex.FindAllStringSubmatchIndex(upstreamPath, -1) {
+		// match[0]:match[1] is the full placeholder, match[2]:match[3] is the variable name
+		varName := upstreamPath[match[2]:match[3]]
+		captureName, found := captures[varName]
+		if !found {
+			return "", fmt.Errorf("expected '%s' parameter '%s' to be a parameter of the path",
+				upstreamPathExtension, varName)
+		}
+		template.WriteString(upstreamPath[lastEnd:match[0]])
+		template.WriteString(`$(uri_captures["` + captureName + `"])`)
+		lastEnd = match[1]
+	}
+	template.WriteString(upstreamPath[lastEnd:])
+	return template.String(), nil
+}
+
+// addUpstreamPathPlugin adds a plugin rewriting the upstream path to the template to
+// the list; 'request-transformer-advanced' when targeting Enterprise, 'request-transformer'
+// otherwise. Unless the template is empty, or the list or the service already has a
+// request-transformer plugin. The list remains sorted by plugin name.
+func addUpstreamPathPlugin(
+	list *[]*map[string]interface{},
+	servicePlugins interface{},
+	template string,
+	pluginTier string,
+	uuidNamespace uuid.UUID,
+	baseName string,
+	tags []string,
+) *[]*map[string]interface{} {
+	if template == "" {
+		return list
+	}
+	for _, name := range []string{"request-transformer", "request-transformer-advanced"} {
+		if hasPlugin(list, name) || hasPlugin(servicePlugins, name) {
+			return list
+		}
+	}
+
+	name := "request-transformer"
+	if pluginTier == PluginTierEnterprise {
+		name = "request-transformer-advanced"
+	}
+	plugin := map[string]interface{}{
+		"name": name,
+		"config": map[string]interface{}{
+			"replace": map[string]interface{}{
+				"uri": template,
+			},
+		},
+		"tags": tags,
+	}
+	plugin["id"] = createPluginID(uuidNamespace, baseName, plugin)
+
+	plugins := newPluginSet(list)
+	plugins.insert(&plugin)
+	return plugins.list()
+}
@@ -0,0 +1,59 @@
+package convertoas3
+
+import (
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_getUpstreamPath(t *testing.T) {
+	upstreamPath, err := getUpstreamPath(openapi3.ExtensionProps{})
+	require.NoError(t, err)
+	assert.Equal(t, "", upstreamPath)
+
+	upstreamPath, err = getUpstreamPath(openapi3.ExtensionProps{Extensions: map[string]interface{}{
+		upstreamPathExtension: "/v2/{id}",
+	}})
+	require.NoError(t, err)
+	assert.Equal(t, "/v2/{id}", upstreamPath)
+
+	_, err = getUpstreamPath(openapi3.ExtensionProps{Extensions: map[string]interface{}{
+		upstreamPathExtension: "v2/{id}",
+	}})
+	assert.ErrorContains(t, err, "to start with '/'")
+
+	_, err = getUpstreamPath(openapi3.ExtensionProps{Extensions: map[string]interface{}{
+		upstreamPathExtension: 42,
+	}})
+	assert.ErrorContains(t, err, "to be a string")
+}
+
+func Test_createUpstreamPathTemplate(t *testing.T) {
+	_, captures := createRouteRegex("", "/users/{user-id}/items/{item}")
+
+	tests := []struct {
+		name         string
+		upstreamPath string
+		expected     string
+		err          string
+	}{
+		{"empty", "", "", ""},
+		{"static", "/healthz", "/healthz", ""},
+		{"parameters", "/v2/{item}/{user-id}", `/v2/$(uri_captures["item"])/$(uri_captures["user_id"])`, ""},
+		{"repeated", "/{item}-{item}", `/$(uri_captures["item"])-$(uri_captures["item"])`, ""},
+		{"unknown parameter", "/v2/{id}", "", "parameter 'id' to be a parameter of the path"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			template, err := createUpstreamPathTemplate(tt.upstreamPath, captures)
+			if tt.err != "" {
+				assert.ErrorContains(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, template)
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package convertoas3
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// validateDocument runs the OpenAPI validation of kin-openapi on the document. Unlike
+// doc.Validate, it does not stop at the first error, and every error found gets a
+// JSON pointer to the element that failed.
+func validateDocument(ctx context.Context, doc *openapi3.T) ConversionErrors {
+	var errs ConversionErrors
+
+	if doc.OpenAPI == "" {
+		errs.add("/openapi", errors.New("value of openapi must be a non-empty string"))
+	}
+	if err := doc.Components.Validate(ctx); err != nil {
+		errs.add("/components", err)
+	}
+	if doc.Info == nil {
+		errs.add("/info", errors.New("must be an object"))
+	} else if err := doc.Info.Validate(ctx); err != nil {
+		errs.add("/info", err)
+	}
+
+	// validate the operations first, and only validate a path item as a whole if all
+	// of its operations are valid, to report the most specific location
+	sortedPaths := make([]string, 0, len(doc.Paths))
+	for path := range doc.Paths {
+		sortedPaths = append(sortedPaths, path)
+	}
+	sort.Strings(sortedPaths)
+	pathsValid := true
+	for _, path := range sortedPaths {
+		pathitem := doc.Paths[path]
+		pathPointer := jsonPointer("paths", path)
+		operationsValid := true
+		if pathitem != nil {
+			operations := pathitem.Operations()
+			sortedMethods := make([]string, 0, len(operations))
+			for method := range operations {
+				sortedMethods = append(sortedMethods, method)
+			}
+			sort.Strings(sortedMethods)
+			for _, method := range sortedMethods {
+				if err := operations[method].Validate(ctx); err != nil {
+					errs.add(pathPointer+jsonPointer(strings.ToLower(method)), err)
+					operationsValid = false
+				}
+			}
+		}
+		if operationsValid {
+			if err := (openapi3.Paths{path: pathitem}).Validate(ctx); err != nil {
+				errs.add(pathPointer, err)
+				operationsValid = false
+			}
+		}
+		pathsValid = pathsValid && operationsValid
+	}
+	if pathsValid {
+		// checks across paths; conflicting paths and duplicate operation IDs
+		if err := doc.Paths.Validate(ctx); err != nil {
+			errs.add("/paths", err)
+		}
+	}
+
+	if err := doc.Security.Validate(ctx); err != nil {
+		errs.add("/security", err)
+	}
+	if err := doc.Servers.Validate(ctx); err != nil {
+		errs.add("/servers", err)
+	}
+	if err := doc.Tags.Validate(ctx); err != nil {
+		errs.add("/tags", err)
+	}
+	if doc.ExternalDocs != nil {
+		if err := doc.ExternalDocs.Validate(ctx); err != nil {
+			errs.add("/externalDocs", err)
+		}
+	}
+
+	return errs
+}
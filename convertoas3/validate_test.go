@@ -0,0 +1,49 @@
+package convertoas3
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ConvertValidateSpec(t *testing.T) {
+	spec := []byte(`
+openapi: 3.0.3
+info:
+  title: validate
+  version: v1
+paths:
+  /users/{id}:
+    get:
+      responses:
+        "200":
+          description: OK
+  /orders:
+    get:
+      parameters:
+        - name: limit
+          in: query
+          schema:
+            type: integr
+      responses:
+        "200":
+          description: OK
+    post:
+      responses: {}
+`)
+
+	// structurally invalid specs are converted without validation
+	_, err := Convert(context.Background(), &spec, O2kOptions{})
+	assert.NoError(t, err)
+
+	_, err = Convert(context.Background(), &spec, O2kOptions{ValidateSpec: true})
+	var errs ConversionErrors
+	if assert.ErrorAs(t, err, &errs) {
+		pointers := make([]string, len(errs))
+		for i, e := range errs {
+			pointers[i] = e.Pointer
+		}
+		assert.Equal(t, []string{"/paths/~1orders/get", "/paths/~1orders/post", "/paths/~1users~1{id}"}, pointers)
+	}
+}
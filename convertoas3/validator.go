@@ -29,8 +29,13 @@ func getDefaultParamStyle(givenStyle string, paramType string) string {
 
 // generateParameterSchema returns the given schema if there is one, a generated
 // schema if it was specified, or nil if there is none.
-// Parameters include path, query, and headers
-func generateParameterSchema(operation *openapi3.Operation) *[]map[string]interface{} {
+// Parameters include path, query, and headers. The pathCaptures map the path parameter
+// names to the regex capture names used in the route.
+func generateParameterSchema(
+	operation *openapi3.Operation,
+	pathCaptures map[string]string,
+	schemas *schemaCache,
+) *[]map[string]interface{} {
 	parameters := operation.Parameters
 	if parameters == nil {
 		return nil
@@ -57,14 +62,18 @@ func generateParameterSchema(operation *openapi3.Operation) *[]map[string]interf
 			paramConf["explode"] = explode
 			paramConf["in"] = paramValue.In
 			if paramValue.In == "path" {
-				paramConf["name"] = sanitizeRegexCapture(paramValue.Name)
+				if captureName, found := pathCaptures[paramValue.Name]; found {
+					paramConf["name"] = captureName
+				} else {
+					paramConf["name"] = sanitizeRegexCapture(paramValue.Name)
+				}
 			} else {
 				paramConf["name"] = paramValue.Name
 			}
 			paramConf["required"] = paramValue.Required
 			paramConf["style"] = getDefaultParamStyle(paramValue.Style, paramValue.In)
 
-			schema := extractSchema(paramValue.Schema)
+			schema := schemas.extractSchema(paramValue.Schema)
 			if schema != "" {
 				paramConf["schema"] = schema
 			}
@@ -79,7 +88,7 @@ func generateParameterSchema(operation *openapi3.Operation) *[]map[string]interf
 
 // generateBodySchema returns the given schema if there is one, a generated
 // schema if it was specified, or "" if there is none.
-func generateBodySchema(operation *openapi3.Operation) string {
+func generateBodySchema(operation *openapi3.Operation, schemas *schemaCache) string {
 	requestBody := operation.RequestBody
 	if requestBody == nil {
 		return ""
@@ -97,7 +106,7 @@ func generateBodySchema(operation *openapi3.Operation) string {
 
 	for contentType, content := range content {
 		if strings.Contains(strings.ToLower(contentType), "application/json") {
-			return extractSchema((*content).Schema)
+			return schemas.extractSchema((*content).Schema)
 		}
 	}
 
@@ -137,11 +146,19 @@ func generateContentTypes(operation *openapi3.Operation) *[]string {
 	return &list
 }
 
+// declaresValidator returns true if the extensions declare a request-validator plugin.
+func declaresValidator(props openapi3.ExtensionProps) bool {
+	_, found := props.Extensions[pluginPrefix+"request-validator"]
+	return found
+}
+
 // generateValidatorPlugin generates the validator plugin configuration, based
 // on the JSON snippet, and the OAS inputs. This can return nil
 func generateValidatorPlugin(configJSON []byte, operation *openapi3.Operation,
+	pathCaptures map[string]string,
 	uuidNamespace uuid.UUID,
 	baseName string,
+	schemas *schemaCache,
 ) *map[string]interface{} {
 	if len(configJSON) == 0 {
 		return nil
@@ -160,7 +177,7 @@ func generateValidatorPlugin(configJSON []byte, operation *openapi3.Operation,
 	}
 
 	if config["parameter_schema"] == nil {
-		parameterSchema := generateParameterSchema(operation)
+		parameterSchema := generateParameterSchema(operation, pathCaptures, schemas)
 		if parameterSchema != nil {
 			config["parameter_schema"] = parameterSchema
 			config["version"] = JSONSchemaVersion
@@ -168,7 +185,7 @@ func generateValidatorPlugin(configJSON []byte, operation *openapi3.Operation,
 	}
 
 	if config["body_schema"] == nil {
-		bodySchema := generateBodySchema(operation)
+		bodySchema := generateBodySchema(operation, schemas)
 		if bodySchema != "" {
 			config["body_schema"] = bodySchema
 			config["version"] = JSONSchemaVersion
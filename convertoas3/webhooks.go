@@ -0,0 +1,139 @@
+package convertoas3
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	uuid "github.com/satori/go.uuid"
+)
+
+// webhooksKey is the OpenAPI 3.1 'webhooks' section. The OpenAPI 3.0 parser keeps it as
+// an extension.
+const webhooksKey = "webhooks"
+
+// getWebhooks returns the webhooks of an OpenAPI 3.1 document, by name, with the
+// references resolved against the components of the document. Returns nil if there
+// are none.
+func getWebhooks(loader *openapi3.Loader, doc *openapi3.T) (map[string]*openapi3.PathItem, error) {
+	value := doc.Extensions[webhooksKey]
+	if value == nil {
+		return nil, nil
+	}
+	var webhooks map[string]*openapi3.PathItem
+	if err := decodeExtension(value, &webhooks); err != nil {
+		return nil, fmt.Errorf("expected '%s' to be an object with path items: %w", webhooksKey, err)
+	}
+	if len(webhooks) == 0 {
+		return nil, nil
+	}
+
+	// the loader only resolves references in the paths of a document, so wrap them in one
+	wrapper := &openapi3.T{
+		OpenAPI:    doc.OpenAPI,
+		Info:       doc.Info,
+		Components: doc.Components,
+		Paths:      make(openapi3.Paths, len(webhooks)),
+	}
+	for name, pathitem := range webhooks {
+		if pathitem != nil {
+			wrapper.Paths["/"+name] = pathitem
+		}
+	}
+	if err := loader.ResolveRefsIn(wrapper, nil); err != nil {
+		return nil, fmt.Errorf("failed to resolve references in '%s': %w", webhooksKey, err)
+	}
+	return webhooks, nil
+}
+
+// createWebhookService creates the service receiving the webhooks, on the server URL,
+// with a route '/webhooks/<name>' for each webhook operation. Each route gets a
+// request-validator plugin built from the request body, using the validator config
+// given on the operation, or the document level one, if any.
+func createWebhookService(
+	webhooks map[string]*openapi3.PathItem,
+	server string,
+	docValidatorConfig []byte,
+	uuidNamespace uuid.UUID,
+	docBaseName string,
+	pathPrefix string,
+	components *map[string]interface{},
+	tags []string,
+	schemas *schemaCache,
+) (map[string]interface{}, ConversionErrors) {
+	var errs ConversionErrors
+
+	serviceBaseName := docBaseName + "_" + webhooksKey
+	service, _, err := CreateKongService(serviceBaseName, &openapi3.Servers{{URL: server}},
+		nil, nil, tags, uuidNamespace)
+	if err != nil {
+		errs.add("/"+webhooksKey, fmt.Errorf("failed to create the webhook service: %w", err))
+		return nil, errs
+	}
+
+	if docValidatorConfig == nil {
+		docValidatorConfig, _ = json.Marshal(map[string]interface{}{
+			"name": "request-validator",
+			"tags": tags,
+		})
+	}
+
+	names := make([]string, 0, len(webhooks))
+	for name := range webhooks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		pathitem := webhooks[name]
+		if pathitem == nil {
+			continue
+		}
+		operations := pathitem.Operations()
+		methods := make([]string, 0, len(operations))
+		for method := range operations {
+			methods = append(methods, method)
+		}
+		sort.Strings(methods)
+
+		for _, method := range methods {
+			operation := operations[method]
+			operationPointer := jsonPointer(webhooksKey, name, strings.ToLower(method))
+			nameSlug, _ := slugOrHash(name)
+			routeBaseName := serviceBaseName + "_" + nameSlug + "_" + strings.ToLower(method)
+
+			plugins, err := getPluginsList(operation.ExtensionProps, nil, uuidNamespace, routeBaseName,
+				components, tags)
+			if err != nil {
+				errs.add(operationPointer, fmt.Errorf("failed to create plugins list from webhook: %w", err))
+				continue
+			}
+			var validatorConfig []byte
+			validatorConfig, plugins = getValidatorPlugin(plugins, docValidatorConfig)
+			if validatorPlugin := generateValidatorPlugin(validatorConfig, operation, nil, uuidNamespace,
+				routeBaseName, schemas); validatorPlugin != nil {
+				pluginSet := newPluginSet(plugins)
+				pluginSet.insert(validatorPlugin)
+				plugins = pluginSet.list()
+			}
+
+			// Kong matches regex paths against the decoded path, so the name is not escaped
+			routePath := "~" + regexp.QuoteMeta(pathPrefix+"/"+webhooksKey+"/"+name) + "$"
+			route := map[string]interface{}{
+				"id":             uuid.NewV5(uuidNamespace, routeBaseName+".route").String(),
+				"name":           routeBaseName,
+				"methods":        []string{method},
+				"paths":          []string{routePath},
+				"plugins":        plugins,
+				"regex_priority": 200,
+				"strip_path":     false,
+				"tags":           tags,
+			}
+			service["routes"] = append(service["routes"].([]interface{}), route)
+		}
+	}
+	return service, errs
+}
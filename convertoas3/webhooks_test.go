@@ -0,0 +1,161 @@
+package convertoas3
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const webhooksSpec = `
+openapi: 3.1.0
+info:
+  title: webhooks
+  version: v1
+servers:
+  - url: https://api.example.com
+paths:
+  /pets:
+    get:
+      responses:
+        "200":
+          description: OK
+webhooks:
+  newPet:
+    post:
+      requestBody:
+        content:
+          application/json:
+            schema:
+              $ref: '#/components/schemas/Pet'
+      responses:
+        "200":
+          description: OK
+  petDeleted:
+    post:
+      x-kong-plugin-key-auth:
+        config:
+          key_names: [x-hook-key]
+      responses:
+        "200":
+          description: OK
+components:
+  schemas:
+    Pet:
+      type: object
+      required: [name]
+      properties:
+        name:
+          type: string
+`
+
+func Test_ConvertWebhooks(t *testing.T) {
+	spec := []byte(webhooksSpec)
+
+	var buf bytes.Buffer
+	result, err := Convert(context.Background(), &spec, O2kOptions{
+		Logger: NewStdLogger(log.New(&buf, "", 0), LogLevelWarn),
+	})
+	require.NoError(t, err)
+	assert.Len(t, result["services"], 1)
+	assert.Equal(t, "WARN webhooks ignored, no webhook server set location=/webhooks\n", buf.String())
+
+	result, err = Convert(context.Background(), &spec, O2kOptions{WebhookServer: "http://hooks.internal:8080"})
+	require.NoError(t, err)
+	services := result["services"].([]interface{})
+	require.Len(t, services, 2)
+	service := services[1].(map[string]interface{})
+	assert.Equal(t, "webhooks_webhooks", service["name"])
+	assert.Equal(t, "hooks.internal", service["host"])
+	assert.EqualValues(t, 8080, service["port"])
+
+	routes := service["routes"].([]interface{})
+	require.Len(t, routes, 2)
+
+	newPet := routes[0].(map[string]interface{})
+	assert.Equal(t, "webhooks_webhooks_newpet_post", newPet["name"])
+	assert.Equal(t, []string{"~/webhooks/newPet$"}, newPet["paths"])
+	plugins := *newPet["plugins"].(*[]*map[string]interface{})
+	require.Len(t, plugins, 1)
+	assert.Equal(t, "request-validator", (*plugins[0])["name"])
+	config := (*plugins[0])["config"].(map[string]interface{})
+	assert.JSONEq(t, `{"$ref":"#/definitions/Pet","definitions":{"Pet":{`+
+		`"type":"object","required":["name"],"properties":{"name":{"type":"string"}}}}}`,
+		config["body_schema"].(string))
+
+	petDeleted := routes[1].(map[string]interface{})
+	assert.Equal(t, []string{"~/webhooks/petDeleted$"}, petDeleted["paths"])
+	plugins = *petDeleted["plugins"].(*[]*map[string]interface{})
+	require.Len(t, plugins, 1)
+	assert.Equal(t, "key-auth", (*plugins[0])["name"])
+}
+
+func Test_ConvertWebhooksNames(t *testing.T) {
+	spec := []byte(`
+openapi: 3.1.0
+info:
+  title: webhooks
+  version: v1
+paths: {}
+webhooks:
+  new pet.v2:
+    post:
+      responses:
+        "200":
+          description: OK
+`)
+	result, err := Convert(context.Background(), &spec, O2kOptions{WebhookServer: "http://hooks.internal"})
+	require.NoError(t, err)
+	services := result["services"].([]interface{})
+	service := services[len(services)-1].(map[string]interface{})
+	route := service["routes"].([]interface{})[0].(map[string]interface{})
+	// Kong matches the decoded path, so the name is not percent-encoded
+	assert.Equal(t, []string{`~/webhooks/new pet\.v2$`}, route["paths"])
+}
+
+func Test_ConvertWebhooksOpenAPI31Schemas(t *testing.T) {
+	spec := func(schema string) []byte {
+		return []byte(`
+openapi: 3.1.0
+info:
+  title: webhooks
+  version: v1
+paths: {}
+webhooks:
+  newPet:
+    post:
+      requestBody:
+        content:
+          application/json:
+            schema:
+              type: object
+              properties:
+                name:
+                  ` + schema + `
+      responses:
+        "200":
+          description: OK
+`)
+	}
+
+	// the OpenAPI 3.0 parser does not accept type arrays
+	content := spec(`type: [string, "null"]`)
+	_, err := Convert(context.Background(), &content, O2kOptions{WebhookServer: "http://hooks.internal"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "/webhooks: expected 'webhooks' to be an object with path items")
+
+	// other keywords are passed on as is, the request-validator ignores them
+	content = spec(`const: Rex`)
+	result, err := Convert(context.Background(), &content, O2kOptions{WebhookServer: "http://hooks.internal"})
+	require.NoError(t, err)
+	services := result["services"].([]interface{})
+	service := services[len(services)-1].(map[string]interface{})
+	route := service["routes"].([]interface{})[0].(map[string]interface{})
+	plugins := *route["plugins"].(*[]*map[string]interface{})
+	require.Len(t, plugins, 1)
+	config := (*plugins[0])["config"].(map[string]interface{})
+	assert.JSONEq(t, `{"type":"object","properties":{"name":{"const":"Rex"}}}`, config["body_schema"].(string))
+}
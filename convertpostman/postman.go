@@ -0,0 +1,312 @@
+// Package convertpostman converts Postman collections (v2.1) to Kong declarative
+// configuration. The collection is converted to an OpenAPI document first, which is
+// then converted by convertoas3.
+package convertpostman
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/Kong/fw/convertoas3"
+	"github.com/Kong/fw/importer"
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+const schemaPrefix = "https://schema.getpostman.com/json/collection/v2."
+
+// variableRegex matches Postman variables, eg. '{{baseUrl}}'.
+var variableRegex = regexp.MustCompile(`{{\s*([^}\s]+)\s*}}`)
+
+// collection is the part of a Postman collection used for the conversion.
+type collection struct {
+	Info struct {
+		Name        string          `json:"name"`
+		Description json.RawMessage `json:"description"`
+		Version     json.RawMessage `json:"version"`
+		Schema      string          `json:"schema"`
+	} `json:"info"`
+	Item     []item          `json:"item"`
+	Variable []keyValue      `json:"variable"`
+	Auth     json.RawMessage `json:"auth"`
+}
+
+// item is either a folder (with items) or a request.
+type item struct {
+	Name        string          `json:"name"`
+	Description json.RawMessage `json:"description"`
+	Item        []item          `json:"item"`
+	Request     json.RawMessage `json:"request"`
+}
+
+// request is a Postman request. The URL is either a string, or a urlObject.
+type request struct {
+	Method      string          `json:"method"`
+	URL         json.RawMessage `json:"url"`
+	Description json.RawMessage `json:"description"`
+	Auth        json.RawMessage `json:"auth"`
+}
+
+type urlObject struct {
+	Raw      string          `json:"raw"`
+	Protocol string          `json:"protocol"`
+	Host     json.RawMessage `json:"host"` // a string, or a list of segments
+	Port     string          `json:"port"`
+	Path     json.RawMessage `json:"path"` // a string, or a list of segments
+	Query    []keyValue      `json:"query"`
+}
+
+type keyValue struct {
+	Key         string          `json:"key"`
+	Value       interface{}     `json:"value"`
+	Disabled    bool            `json:"disabled"`
+	Description json.RawMessage `json:"description"`
+}
+
+// IsCollection returns true if the content is a Postman collection (v2.x).
+func IsCollection(content []byte) bool {
+	var c collection
+	if err := json.Unmarshal(content, &c); err != nil {
+		return false
+	}
+	return strings.HasPrefix(c.Info.Schema, schemaPrefix)
+}
+
+// text returns the text of a description, which is either a string, or an object
+// with a 'content' field. Returns "" for anything else.
+func text(raw json.RawMessage) string {
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		return s
+	}
+	var object struct {
+		Content string `json:"content"`
+	}
+	if json.Unmarshal(raw, &object) == nil {
+		return object.Content
+	}
+	return ""
+}
+
+// hasAuth returns true if the auth object configures authentication.
+func hasAuth(raw json.RawMessage) bool {
+	var auth struct {
+		Type string `json:"type"`
+	}
+	return json.Unmarshal(raw, &auth) == nil && auth.Type != "" && auth.Type != "noauth"
+}
+
+// joinSegments returns a string, or a list of string segments, joined by the separator.
+// Segments can also be objects with a 'value' field.
+func joinSegments(raw json.RawMessage, separator string) string {
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		return s
+	}
+	var segments []interface{}
+	if json.Unmarshal(raw, &segments) != nil {
+		return ""
+	}
+	parts := make([]string, 0, len(segments))
+	for _, segment := range segments {
+		switch v := segment.(type) {
+		case string:
+			parts = append(parts, v)
+		case map[string]interface{}:
+			parts = append(parts, fmt.Sprint(v["value"]))
+		}
+	}
+	return strings.Join(parts, separator)
+}
+
+// url returns the URL, with variables still in place. It is built from the parts if
+// there is a host, otherwise the raw URL is used.
+func (u urlObject) url() string {
+	host := joinSegments(u.Host, ".")
+	if host == "" {
+		return u.Raw
+	}
+	result := host
+	if u.Protocol != "" {
+		result = u.Protocol + "://" + result
+	}
+	if u.Port != "" {
+		result += ":" + u.Port
+	}
+	if path := joinSegments(u.Path, "/"); path != "" {
+		result += "/" + strings.TrimPrefix(path, "/")
+	}
+	return result
+}
+
+// parseRequest returns the request, its URL (with variables still in place), and the
+// names of the enabled query parameters. The request is either an object, or only
+// a URL string.
+func parseRequest(raw json.RawMessage) (*request, string, []string, error) {
+	req := &request{}
+	var rawURL string
+	var query []string
+	if json.Unmarshal(raw, &rawURL) != nil {
+		if err := json.Unmarshal(raw, req); err != nil {
+			return nil, "", nil, fmt.Errorf("expected 'request' to be an object or string: %w", err)
+		}
+		if json.Unmarshal(req.URL, &rawURL) != nil {
+			var u urlObject
+			if err := json.Unmarshal(req.URL, &u); err != nil {
+				return nil, "", nil, fmt.Errorf("expected 'url' to be an object or string: %w", err)
+			}
+			rawURL = u.url()
+			for _, param := range u.Query {
+				if !param.Disabled && param.Key != "" {
+					query = append(query, param.Key)
+				}
+			}
+		}
+	}
+	if query == nil {
+		query = importer.QueryNames(rawURL)
+	}
+	if req.Method == "" {
+		req.Method = "GET"
+	}
+	return req, rawURL, query, nil
+}
+
+// splitURL splits a URL, with the variables resolved, into the server and the OpenAPI
+// path, see importer.SplitURL. Path segments that are an unresolved variable, eg.
+// '{{id}}', are converted to path parameters as well.
+func splitURL(rawURL string) (string, string, error) {
+	scheme, rest, found := strings.Cut(rawURL, "://")
+	if !found {
+		scheme, rest = "", rawURL
+	}
+	host, path, _ := strings.Cut(rest, "/")
+	if variableRegex.MatchString(host) {
+		return "", "", fmt.Errorf("undefined variable in the host '%s'", host)
+	}
+
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if match := variableRegex.FindStringSubmatch(segment); match != nil && match[0] == segment {
+			segments[i] = ":" + match[1]
+		}
+	}
+	rawURL = host + "/" + strings.Join(segments, "/")
+	if scheme != "" {
+		rawURL = scheme + "://" + rawURL
+	}
+	return importer.SplitURL(rawURL)
+}
+
+// resolveVariables replaces the '{{name}}' variables that are defined. Undefined
+// variables are left in place.
+func resolveVariables(s string, variables map[string]string) string {
+	return variableRegex.ReplaceAllStringFunc(s, func(match string) string {
+		name := variableRegex.FindStringSubmatch(match)[1]
+		if value, found := variables[name]; found {
+			return value
+		}
+		return match
+	})
+}
+
+// converter holds the state while converting a collection.
+type converter struct {
+	doc       *importer.Document
+	logger    convertoas3.Logger
+	variables map[string]string
+}
+
+// addRequest adds the request as an operation to the document. The folders are the
+// names of the enclosing folders, used as tags.
+func (c *converter) addRequest(it item, folders []string, location string) error {
+	req, rawURL, query, err := parseRequest(it.Request)
+	if err != nil {
+		return err
+	}
+	server, path, err := splitURL(resolveVariables(rawURL, c.variables))
+	if err != nil {
+		return err
+	}
+	if hasAuth(req.Auth) {
+		c.logger.Warn("authentication of a request is not converted, ignored", "location", location)
+	}
+
+	operation := openapi3.NewOperation()
+	operation.Summary = it.Name
+	operation.Description = text(req.Description)
+	if operation.Description == "" {
+		operation.Description = text(it.Description)
+	}
+	operation.Tags = folders
+	operation.OperationID = importer.OperationID(append(append([]string{}, folders...), it.Name)...)
+	importer.AddQueryParameters(operation, query)
+	return c.doc.AddOperation([]string{server}, path, req.Method, operation)
+}
+
+// addItems adds the requests in the items, recursing into folders.
+func (c *converter) addItems(items []item, folders []string, location string) convertoas3.ConversionErrors {
+	var errs convertoas3.ConversionErrors
+	for i, it := range items {
+		itemLocation := fmt.Sprintf("%s/item/%d", location, i)
+		if it.Request == nil {
+			// copy, so the folders of sibling items do not share the same array
+			subFolders := append(append([]string{}, folders...), it.Name)
+			errs = append(errs, c.addItems(it.Item, subFolders, itemLocation)...)
+			continue
+		}
+		if err := c.addRequest(it, folders, itemLocation); err != nil {
+			errs = append(errs, &convertoas3.ConversionError{Pointer: itemLocation, Err: err})
+		}
+	}
+	return errs
+}
+
+// ToOpenAPI converts a Postman collection (v2.1) to an OpenAPI document. Folders are
+// converted to tags, requests to operations, and collection variables are resolved in
+// the request URLs. Unresolved variables in a path become path parameters. What cannot
+// be converted (eg. authentication) is logged as a warning.
+func ToOpenAPI(content *[]byte, logger convertoas3.Logger) (*openapi3.T, error) {
+	if logger == nil {
+		logger = convertoas3.NopLogger()
+	}
+
+	var c collection
+	if err := json.Unmarshal(*content, &c); err != nil {
+		return nil, fmt.Errorf("failed to parse Postman collection: %w", err)
+	}
+	if !strings.HasPrefix(c.Info.Schema, schemaPrefix) {
+		return nil, fmt.Errorf("expected a Postman collection v2.x, got schema '%s'", c.Info.Schema)
+	}
+
+	conv := converter{
+		doc:       importer.NewDocument(c.Info.Name, text(c.Info.Description), text(c.Info.Version)),
+		logger:    logger,
+		variables: make(map[string]string),
+	}
+	for _, variable := range c.Variable {
+		if variable.Value != nil {
+			conv.variables[variable.Key] = fmt.Sprint(variable.Value)
+		}
+	}
+	if hasAuth(c.Auth) {
+		logger.Warn("authentication of the collection is not converted, ignored", "location", "/auth")
+	}
+
+	if errs := conv.addItems(c.Item, []string{}, ""); len(errs) > 0 {
+		return nil, errs
+	}
+	return conv.doc.OpenAPI(), nil
+}
+
+// Convert converts a Postman collection (v2.1) to a Kong declarative file, see
+// ToOpenAPI, and convertoas3.Convert for the options.
+func Convert(ctx context.Context, content *[]byte, opts convertoas3.O2kOptions) (map[string]interface{}, error) {
+	doc, err := ToOpenAPI(content, opts.Logger)
+	if err != nil {
+		return nil, err
+	}
+	return convertoas3.ConvertDocument(ctx, doc, opts)
+}
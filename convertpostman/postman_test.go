@@ -0,0 +1,105 @@
+package convertpostman
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"os"
+	"testing"
+
+	"github.com/Kong/fw/convertoas3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_IsCollection(t *testing.T) {
+	content, err := os.ReadFile("testdata/collection.json")
+	require.NoError(t, err)
+
+	assert.True(t, IsCollection(content))
+	assert.False(t, IsCollection([]byte(`{"info": {"schema": "other"}}`)))
+	assert.False(t, IsCollection([]byte("openapi: 3.0.3")))
+}
+
+func Test_ToOpenAPI(t *testing.T) {
+	content, err := os.ReadFile("testdata/collection.json")
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	doc, err := ToOpenAPI(&content, convertoas3.NewStdLogger(log.New(&buf, "", 0), convertoas3.LogLevelWarn))
+	require.NoError(t, err)
+
+	assert.Equal(t, "Pet Store", doc.Info.Title)
+	assert.Equal(t, "The pet store API", doc.Info.Description)
+	assert.Equal(t, "https://petstore.example.com", doc.Servers[0].URL)
+	assert.Len(t, doc.Paths, 3)
+
+	list := doc.Paths["/v1/pets"].Get
+	assert.Equal(t, "pets-list-pets", list.OperationID)
+	assert.Equal(t, []string{"Pets"}, list.Tags)
+	assert.Len(t, list.Parameters, 1)
+	assert.Equal(t, "limit", list.Parameters[0].Value.Name)
+
+	get := doc.Paths["/v1/pets/{petId}"]
+	assert.Equal(t, "Returns a single pet", get.Get.Description)
+	assert.Equal(t, "petId", get.Parameters[0].Value.Name)
+	assert.Equal(t, "path", get.Parameters[0].Value.In)
+
+	// a variable that is not defined becomes a path parameter
+	assert.Equal(t, "pets-delete-pet", get.Delete.OperationID)
+
+	// a request with another server gets a path level server
+	health := doc.Paths["/health"]
+	assert.Equal(t, "https://status.example.com", health.Servers[0].URL)
+	assert.Equal(t, "health", health.Get.OperationID)
+
+	assert.Equal(t, "WARN authentication of the collection is not converted, ignored location=/auth\n", buf.String())
+}
+
+func Test_ToOpenAPIErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		collection string
+		expected   string
+	}{
+		{
+			name:       "not a collection",
+			collection: `{"info": {"name": "x"}}`,
+			expected:   "expected a Postman collection v2.x, got schema ''",
+		},
+		{
+			name: "undefined host variable",
+			collection: `{"info": {"schema": "` + schemaPrefix + `1.0/collection.json"},
+				"item": [{"name": "a", "request": "{{host}}/a"}]}`,
+			expected: "/item/0: undefined variable in the host '{{host}}'",
+		},
+		{
+			name: "duplicate request",
+			collection: `{"info": {"schema": "` + schemaPrefix + `1.0/collection.json"},
+				"item": [{"name": "a", "request": "http://x/a"}, {"name": "b", "request": "http://x/a"}]}`,
+			expected: "/item/1: duplicate operation 'GET /a'",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content := []byte(tt.collection)
+			_, err := ToOpenAPI(&content, nil)
+			assert.EqualError(t, err, tt.expected)
+		})
+	}
+}
+
+func Test_Convert(t *testing.T) {
+	content, err := os.ReadFile("testdata/collection.json")
+	require.NoError(t, err)
+
+	result, err := Convert(context.Background(), &content, convertoas3.O2kOptions{})
+	require.NoError(t, err)
+
+	services := result["services"].([]interface{})
+	assert.Len(t, services, 2)
+	service := services[0].(map[string]interface{})
+	assert.Equal(t, "pet-store", service["name"])
+	assert.Equal(t, "petstore.example.com", service["host"])
+	assert.Len(t, service["routes"], 3)
+}
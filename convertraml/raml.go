@@ -0,0 +1,220 @@
+// Package convertraml converts RAML 1.0 specs to Kong declarative configuration. The
+// resources become paths, and their methods operations. The spec is converted to an
+// OpenAPI document first, which is then converted by convertoas3.
+//
+// Resource types and traits are not expanded, and '!include' is not supported.
+package convertraml
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Kong/fw/convertoas3"
+	"github.com/Kong/fw/importer"
+	"github.com/getkin/kin-openapi/openapi3"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	headerPrefix = "#%RAML "
+	version10    = "1.0"
+)
+
+// methods are the RAML methods, in the order of the operations in a resource.
+var methods = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace", "connect"}
+
+// header returns the RAML header line, eg. '#%RAML 1.0', or "" if there is none.
+func header(content []byte) string {
+	content = bytes.TrimPrefix(content, []byte("\ufeff"))
+	line, _, _ := bytes.Cut(content, []byte("\n"))
+	line = bytes.TrimSpace(line)
+	if !bytes.HasPrefix(line, []byte(headerPrefix)) {
+		return ""
+	}
+	return string(line)
+}
+
+// IsSpec returns true if the content is a RAML spec, of any version.
+func IsSpec(content []byte) bool {
+	return header(content) != ""
+}
+
+// text returns a string value, or "" for anything else, eg. a RAML 'description' that
+// is a map.
+func text(value interface{}) string {
+	s, _ := value.(string)
+	return s
+}
+
+// jsonPointer returns the JSON pointer to a key in the spec.
+func jsonPointer(keys ...string) string {
+	var pointer strings.Builder
+	for _, key := range keys {
+		pointer.WriteString("/")
+		pointer.WriteString(strings.ReplaceAll(strings.ReplaceAll(key, "~", "~0"), "/", "~1"))
+	}
+	return pointer.String()
+}
+
+// baseURI returns the baseUri of the spec, with the parameters replaced by the version
+// or the parameter defaults. Without a scheme, the first of 'protocols' is used.
+func baseURI(spec map[string]interface{}, version string) (string, error) {
+	uri := text(spec["baseUri"])
+	if uri == "" {
+		return "", nil
+	}
+	if version != "" {
+		uri = strings.ReplaceAll(uri, "{version}", version)
+	}
+	parameters, _ := spec["baseUriParameters"].(map[string]interface{})
+	for name, p := range parameters {
+		param, _ := p.(map[string]interface{})
+		value, found := param["default"]
+		if !found {
+			value, found = param["example"]
+		}
+		if found {
+			uri = strings.ReplaceAll(uri, "{"+name+"}", fmt.Sprint(value))
+		}
+	}
+	if strings.Contains(uri, "{") {
+		return "", fmt.Errorf("expected the parameters in 'baseUri' to have a default, got: '%s'", uri)
+	}
+
+	if !strings.Contains(uri, "://") {
+		scheme := importer.DefaultScheme
+		if protocols, _ := spec["protocols"].([]interface{}); len(protocols) > 0 {
+			scheme = strings.ToLower(text(protocols[0]))
+		}
+		uri = scheme + "://" + uri
+	}
+	return strings.TrimSuffix(uri, "/"), nil
+}
+
+// sortedResources returns the nested resources (the keys starting with '/'), sorted.
+func sortedResources(node map[string]interface{}) []string {
+	var names []string
+	for key := range node {
+		if strings.HasPrefix(key, "/") {
+			names = append(names, key)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// converter holds the state of a conversion.
+type converter struct {
+	doc     *importer.Document
+	servers []string
+	logger  convertoas3.Logger
+	errs    convertoas3.ConversionErrors
+}
+
+// addResource adds the methods of a resource, and its nested resources, recursively.
+// The keys are the keys of the resource in the spec, for the JSON pointers.
+func (c *converter) addResource(path string, keys []string, node map[string]interface{}) {
+	location := jsonPointer(keys...)
+	if node["type"] != nil || node["is"] != nil {
+		c.logger.Warn("resource types and traits are not expanded", "location", location)
+	}
+
+	// the '{mediaTypeExtension}' parameter is reserved, for '.json' etc.
+	openAPIPath := strings.ReplaceAll(path, "{mediaTypeExtension}", "")
+	for _, method := range methods {
+		value, found := node[method]
+		if !found {
+			continue
+		}
+		m, _ := value.(map[string]interface{}) // a method without properties is null
+		if m != nil && m["is"] != nil {
+			c.logger.Warn("traits are not expanded", "location", jsonPointer(append(keys, method)...))
+		}
+
+		operation := openapi3.NewOperation()
+		if displayName := text(m["displayName"]); displayName != "" {
+			operation.OperationID = importer.OperationID(displayName)
+			operation.Summary = displayName
+		} else {
+			operation.OperationID = importer.OperationID(method, path)
+		}
+		operation.Description = text(m["description"])
+		if operation.Description == "" {
+			operation.Description = text(node["description"])
+		}
+		if parameters, ok := m["queryParameters"].(map[string]interface{}); ok {
+			names := make([]string, 0, len(parameters))
+			for name := range parameters {
+				names = append(names, strings.TrimSuffix(name, "?")) // 'name?' is optional
+			}
+			importer.AddQueryParameters(operation, names)
+		}
+		if err := c.doc.AddOperation(c.servers, openAPIPath, method, operation); err != nil {
+			c.errs = append(c.errs, &convertoas3.ConversionError{
+				Pointer: jsonPointer(append(keys, method)...),
+				Err:     err,
+			})
+		}
+	}
+
+	for _, name := range sortedResources(node) {
+		child, _ := node[name].(map[string]interface{})
+		c.addResource(path+name, append(append([]string{}, keys...), name), child)
+	}
+}
+
+// ToOpenAPI converts a RAML 1.0 spec to an OpenAPI document. The baseUri becomes the
+// server, resources become paths (nested resources are appended to the path of their
+// parent), and methods operations, with their query parameters. Resources using
+// resource types or traits are logged as a warning, since those are not expanded.
+func ToOpenAPI(content *[]byte, logger convertoas3.Logger) (*openapi3.T, error) {
+	if logger == nil {
+		logger = convertoas3.NopLogger()
+	}
+
+	if h := header(*content); strings.TrimSpace(strings.TrimPrefix(h, headerPrefix)) != version10 {
+		return nil, fmt.Errorf("expected a RAML 1.0 spec, got header '%s'", h)
+	}
+	var spec map[string]interface{}
+	if err := yaml.Unmarshal(*content, &spec); err != nil {
+		return nil, fmt.Errorf("failed to parse RAML spec: %w", err)
+	}
+
+	version := ""
+	if spec["version"] != nil {
+		version = fmt.Sprint(spec["version"])
+	}
+	server, err := baseURI(spec, version)
+	if err != nil {
+		return nil, err
+	}
+	c := converter{
+		doc:    importer.NewDocument(text(spec["title"]), text(spec["description"]), version),
+		logger: logger,
+	}
+	if server != "" {
+		c.servers = []string{server}
+	}
+
+	for _, name := range sortedResources(spec) {
+		resource, _ := spec[name].(map[string]interface{})
+		c.addResource(name, []string{name}, resource)
+	}
+	if len(c.errs) > 0 {
+		return nil, c.errs
+	}
+	return c.doc.OpenAPI(), nil
+}
+
+// Convert converts a RAML 1.0 spec to a Kong declarative file, see ToOpenAPI, and
+// convertoas3.Convert for the options.
+func Convert(ctx context.Context, content *[]byte, opts convertoas3.O2kOptions) (map[string]interface{}, error) {
+	doc, err := ToOpenAPI(content, opts.Logger)
+	if err != nil {
+		return nil, err
+	}
+	return convertoas3.ConvertDocument(ctx, doc, opts)
+}
@@ -0,0 +1,105 @@
+package convertraml
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"os"
+	"testing"
+
+	"github.com/Kong/fw/convertoas3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_IsSpec(t *testing.T) {
+	content, err := os.ReadFile("testdata/users.raml")
+	require.NoError(t, err)
+
+	assert.True(t, IsSpec(content))
+	assert.True(t, IsSpec([]byte("#%RAML 0.8\ntitle: Old")))
+	assert.False(t, IsSpec([]byte("openapi: 3.0.3")))
+}
+
+func Test_ToOpenAPI(t *testing.T) {
+	content, err := os.ReadFile("testdata/users.raml")
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	doc, err := ToOpenAPI(&content, convertoas3.NewStdLogger(log.New(&buf, "", 0), convertoas3.LogLevelWarn))
+	require.NoError(t, err)
+
+	assert.Equal(t, "Users API", doc.Info.Title)
+	assert.Equal(t, "Manages the users.", doc.Info.Description)
+	assert.Equal(t, "v2", doc.Info.Version)
+	assert.Equal(t, "https://api.example.com/v2", doc.Servers[0].URL)
+	assert.Len(t, doc.Paths, 4)
+
+	users := doc.Paths["/users"]
+	assert.Equal(t, "list-users", users.Get.OperationID)
+	assert.Equal(t, "The users.", users.Get.Description)
+	assert.Len(t, users.Get.Parameters, 2)
+	assert.Equal(t, "active", users.Get.Parameters[0].Value.Name)
+	assert.Equal(t, "role", users.Get.Parameters[1].Value.Name)
+	assert.Equal(t, "post-users", users.Post.OperationID)
+
+	user := doc.Paths["/users/{userId}"]
+	assert.Equal(t, "userId", user.Parameters[0].Value.Name)
+	assert.Equal(t, "Returns a user.", user.Get.Description)
+	assert.NotNil(t, user.Delete)
+	assert.NotNil(t, doc.Paths["/users/{userId}/avatar"].Get)
+	assert.NotNil(t, doc.Paths["/health"].Get)
+
+	assert.Contains(t, buf.String(), "resource types and traits are not expanded")
+	assert.Contains(t, buf.String(), "traits are not expanded")
+}
+
+func Test_ToOpenAPIErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		spec string
+		err  string
+	}{
+		{
+			name: "RAML 0.8",
+			spec: "#%RAML 0.8\ntitle: Old",
+			err:  "expected a RAML 1.0 spec, got header '#%RAML 0.8'",
+		},
+		{
+			name: "baseUri parameter without default",
+			spec: "#%RAML 1.0\ntitle: API\nbaseUri: https://{env}.example.com",
+			err:  "expected the parameters in 'baseUri' to have a default, got: 'https://{env}.example.com'",
+		},
+		{
+			name: "duplicate operationId is not an error",
+			spec: "#%RAML 1.0\ntitle: API\n/a:\n  get:\n    displayName: Get\n/b:\n  get:\n    displayName: Get",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec := []byte(tt.spec)
+			_, err := ToOpenAPI(&spec, nil)
+			if tt.err == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tt.err)
+			}
+		})
+	}
+}
+
+func Test_Convert(t *testing.T) {
+	content, err := os.ReadFile("testdata/users.raml")
+	require.NoError(t, err)
+
+	result, err := Convert(context.Background(), &content, convertoas3.O2kOptions{})
+	require.NoError(t, err)
+
+	services := result["services"].([]interface{})
+	require.Len(t, services, 1)
+	service := services[0].(map[string]interface{})
+	assert.Equal(t, "users-api", service["name"])
+	assert.Equal(t, "api.example.com", service["host"])
+	assert.Equal(t, "/v2", service["path"])
+	assert.Len(t, service["routes"], 6)
+}
@@ -0,0 +1,212 @@
+// Package deckfile provides a typed representation of the generated Kong declarative
+// file, with the same structure (and JSON/YAML field names) as decK's 'file.Content'.
+// Tools embedding the converter can use it directly, instead of round-tripping the
+// generated file through YAML.
+package deckfile
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/Kong/fw/convertoas3"
+)
+
+// Content is a Kong declarative file, like decK's 'file.Content'.
+type Content struct {
+	FormatVersion  string          `json:"_format_version,omitempty"`
+	Info           *Info           `json:"_info,omitempty"`
+	Workspace      string          `json:"_workspace,omitempty"`
+	Services       []Service       `json:"services"`
+	Upstreams      []Upstream      `json:"upstreams"`
+	Plugins        []Plugin        `json:"plugins,omitempty"`
+	Consumers      []Consumer      `json:"consumers,omitempty"`
+	ConsumerGroups []ConsumerGroup `json:"consumer_groups,omitempty"`
+}
+
+// Info is the '_info' section of the file.
+type Info struct {
+	SelectorTags []string               `json:"select_tags,omitempty"`
+	Defaults     map[string]interface{} `json:"defaults,omitempty"`
+	Generator    string                 `json:"generator,omitempty"`
+}
+
+// Reference is a reference to another entity, by id or name.
+type Reference struct {
+	ID   *string `json:"id,omitempty"`
+	Name *string `json:"name,omitempty"`
+}
+
+// Service is a Kong service, with its routes and plugins.
+type Service struct {
+	ID                *string                  `json:"id,omitempty"`
+	Name              *string                  `json:"name,omitempty"`
+	URL               *string                  `json:"url,omitempty"`
+	Protocol          *string                  `json:"protocol,omitempty"`
+	Host              *string                  `json:"host,omitempty"`
+	Port              *int                     `json:"port,omitempty"`
+	Path              *string                  `json:"path,omitempty"`
+	Retries           *int                     `json:"retries,omitempty"`
+	ConnectTimeout    *int                     `json:"connect_timeout,omitempty"`
+	ReadTimeout       *int                     `json:"read_timeout,omitempty"`
+	WriteTimeout      *int                     `json:"write_timeout,omitempty"`
+	ClientCertificate *Reference               `json:"client_certificate,omitempty"`
+	CACertificates    []string                 `json:"ca_certificates,omitempty"`
+	TLSVerify         *bool                    `json:"tls_verify,omitempty"`
+	TLSVerifyDepth    *int                     `json:"tls_verify_depth,omitempty"`
+	Enabled           *bool                    `json:"enabled,omitempty"`
+	Tags              []string                 `json:"tags"`
+	Routes            []Route                  `json:"routes"`
+	Plugins           []Plugin                 `json:"plugins"`
+	FilterChains      []FilterChain            `json:"filter_chains,omitempty"`
+	DegraphqlRoutes   []map[string]interface{} `json:"degraphql_routes,omitempty"`
+}
+
+// Route is a Kong route, with its plugins.
+type Route struct {
+	ID                      *string             `json:"id,omitempty"`
+	Name                    *string             `json:"name,omitempty"`
+	Protocols               []string            `json:"protocols,omitempty"`
+	Methods                 []string            `json:"methods,omitempty"`
+	Hosts                   []string            `json:"hosts,omitempty"`
+	Paths                   []string            `json:"paths,omitempty"`
+	Headers                 map[string][]string `json:"headers,omitempty"`
+	SNIs                    []string            `json:"snis,omitempty"`
+	HTTPSRedirectStatusCode *int                `json:"https_redirect_status_code,omitempty"`
+	RegexPriority           *int                `json:"regex_priority,omitempty"`
+	StripPath               *bool               `json:"strip_path,omitempty"`
+	PathHandling            *string             `json:"path_handling,omitempty"`
+	PreserveHost            *bool               `json:"preserve_host,omitempty"`
+	RequestBuffering        *bool               `json:"request_buffering,omitempty"`
+	ResponseBuffering       *bool               `json:"response_buffering,omitempty"`
+	Tags                    []string            `json:"tags"`
+	Plugins                 []Plugin            `json:"plugins"`
+	FilterChains            []FilterChain       `json:"filter_chains,omitempty"`
+}
+
+// FilterChain is a chain of WASM filters on a service or route (Kong 3.4+).
+type FilterChain struct {
+	ID      *string  `json:"id,omitempty"`
+	Name    *string  `json:"name,omitempty"`
+	Enabled *bool    `json:"enabled,omitempty"`
+	Filters []Filter `json:"filters"`
+	Tags    []string `json:"tags"`
+}
+
+// Filter is a WASM filter in a filter chain. The config is a string or a JSON value,
+// depending on the filter.
+type Filter struct {
+	Name    *string     `json:"name,omitempty"`
+	Config  interface{} `json:"config,omitempty"`
+	Enabled *bool       `json:"enabled,omitempty"`
+}
+
+// Upstream is a Kong upstream, with its targets.
+type Upstream struct {
+	ID                     *string                `json:"id,omitempty"`
+	Name                   *string                `json:"name,omitempty"`
+	Algorithm              *string                `json:"algorithm,omitempty"`
+	HashOn                 *string                `json:"hash_on,omitempty"`
+	HashFallback           *string                `json:"hash_fallback,omitempty"`
+	HashOnHeader           *string                `json:"hash_on_header,omitempty"`
+	HashFallbackHeader     *string                `json:"hash_fallback_header,omitempty"`
+	HashOnCookie           *string                `json:"hash_on_cookie,omitempty"`
+	HashOnCookiePath       *string                `json:"hash_on_cookie_path,omitempty"`
+	HashOnQueryArg         *string                `json:"hash_on_query_arg,omitempty"`
+	HashFallbackQueryArg   *string                `json:"hash_fallback_query_arg,omitempty"`
+	HashOnURICapture       *string                `json:"hash_on_uri_capture,omitempty"`
+	HashFallbackURICapture *string                `json:"hash_fallback_uri_capture,omitempty"`
+	HostHeader             *string                `json:"host_header,omitempty"`
+	Slots                  *int                   `json:"slots,omitempty"`
+	Healthchecks           map[string]interface{} `json:"healthchecks,omitempty"`
+	ClientCertificate      *Reference             `json:"client_certificate,omitempty"`
+	Tags                   []string               `json:"tags"`
+	Targets                []Target               `json:"targets"`
+}
+
+// Target is a target of an upstream.
+type Target struct {
+	ID     *string  `json:"id,omitempty"`
+	Target *string  `json:"target,omitempty"`
+	Weight *int     `json:"weight,omitempty"`
+	Tags   []string `json:"tags"`
+}
+
+// Plugin is a Kong plugin. On the top level the entities it applies to are given by
+// name (or id) in Service, Route, Consumer, and ConsumerGroup.
+type Plugin struct {
+	ID            *string                `json:"id,omitempty"`
+	Name          *string                `json:"name,omitempty"`
+	InstanceName  *string                `json:"instance_name,omitempty"`
+	Config        map[string]interface{} `json:"config,omitempty"`
+	Enabled       *bool                  `json:"enabled,omitempty"`
+	Protocols     []string               `json:"protocols,omitempty"`
+	Ordering      map[string]interface{} `json:"ordering,omitempty"`
+	Service       *string                `json:"service,omitempty"`
+	Route         *string                `json:"route,omitempty"`
+	Consumer      *string                `json:"consumer,omitempty"`
+	ConsumerGroup *string                `json:"consumer_group,omitempty"`
+	Tags          []string               `json:"tags"`
+}
+
+// Consumer is a Kong consumer, with its ACL groups, key-auth credentials, and consumer
+// group memberships.
+type Consumer struct {
+	ID                 *string     `json:"id,omitempty"`
+	Username           *string     `json:"username,omitempty"`
+	CustomID           *string     `json:"custom_id,omitempty"`
+	Tags               []string    `json:"tags"`
+	Groups             []Reference `json:"groups,omitempty"`
+	ACLs               []ACLGroup  `json:"acls,omitempty"`
+	KeyAuthCredentials []KeyAuth   `json:"keyauth_credentials,omitempty"`
+}
+
+// KeyAuth is a key-auth credential of a consumer.
+type KeyAuth struct {
+	ID   *string  `json:"id,omitempty"`
+	Key  *string  `json:"key,omitempty"`
+	Tags []string `json:"tags"`
+}
+
+// ACLGroup is an ACL group of a consumer.
+type ACLGroup struct {
+	ID    *string  `json:"id,omitempty"`
+	Group *string  `json:"group,omitempty"`
+	Tags  []string `json:"tags"`
+}
+
+// ConsumerGroup is a Kong consumer group, with its plugins.
+type ConsumerGroup struct {
+	ID      *string  `json:"id,omitempty"`
+	Name    *string  `json:"name,omitempty"`
+	Tags    []string `json:"tags"`
+	Plugins []Plugin `json:"plugins"`
+}
+
+// FromMap returns the typed content of a generated declarative file, as returned by
+// the converters. Returns an error if it has fields that are not part of the typed
+// structure (eg. unknown fields in 'x-kong-service-defaults'), instead of dropping them.
+func FromMap(deckData map[string]interface{}) (*Content, error) {
+	data, err := json.Marshal(deckData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize the declarative file: %w", err)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	var content Content
+	if err := decoder.Decode(&content); err != nil {
+		return nil, fmt.Errorf("failed to convert the declarative file to typed content: %w", err)
+	}
+	return &content, nil
+}
+
+// Convert converts an OpenAPI spec (JSON or YAML) to the typed content of a Kong
+// declarative file. See convertoas3.Convert for the options and the errors returned.
+func Convert(ctx context.Context, content *[]byte, opts convertoas3.O2kOptions) (*Content, error) {
+	deckData, err := convertoas3.Convert(ctx, content, opts)
+	if err != nil {
+		return nil, err
+	}
+	return FromMap(deckData)
+}
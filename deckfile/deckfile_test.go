@@ -0,0 +1,89 @@
+package deckfile
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Kong/fw/convertoas3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const fixturePath = "../convertoas3/oas3_testfiles/"
+
+// invalidFixtures generate fields that are not Kong entity fields.
+var invalidFixtures = map[string]string{
+	"15-circular-requestBody-schema.yaml": `json: unknown field "version"`, // on the request-validator plugin
+}
+
+func Test_FromMapRoundTrip(t *testing.T) {
+	files, err := filepath.Glob(fixturePath + "*.yaml")
+	require.NoError(t, err)
+	require.NotEmpty(t, files)
+
+	for _, file := range files {
+		spec, err := os.ReadFile(file)
+		require.NoError(t, err)
+		deckData, err := convertoas3.Convert(context.Background(), &spec, convertoas3.O2kOptions{
+			Tags: &[]string{"OAS3_import"},
+		})
+		require.NoError(t, err, file)
+
+		content, err := FromMap(deckData)
+		if expected, found := invalidFixtures[filepath.Base(file)]; found {
+			assert.ErrorContains(t, err, expected, file)
+			continue
+		}
+		if !assert.NoError(t, err, file) {
+			continue
+		}
+		expected, _ := json.Marshal(deckData)
+		actual, _ := json.Marshal(content)
+		assert.JSONEq(t, string(expected), string(actual), "%s: the typed content should be lossless", file)
+	}
+}
+
+func Test_Convert(t *testing.T) {
+	spec := []byte(`
+openapi: 3.0.3
+info:
+  title: Learn Service
+  version: 1.0.0
+servers:
+  - url: https://server1.com
+  - url: https://server2.com
+paths:
+  /tracks:
+    get:
+      x-kong-plugin-key-auth:
+        enabled: true
+      responses:
+        "200":
+          description: OK
+`)
+	content, err := Convert(context.Background(), &spec, convertoas3.O2kOptions{})
+	require.NoError(t, err)
+
+	assert.Equal(t, "3.0", content.FormatVersion)
+	require.Len(t, content.Services, 1)
+	service := content.Services[0]
+	assert.Equal(t, "learn-service", *service.Name)
+	assert.Equal(t, "learn-service.upstream", *service.Host)
+	require.Len(t, service.Routes, 1)
+	assert.False(t, *service.Routes[0].StripPath)
+	require.Len(t, service.Routes[0].Plugins, 1)
+	assert.Equal(t, "key-auth", *service.Routes[0].Plugins[0].Name)
+	require.Len(t, content.Upstreams, 1)
+	assert.Len(t, content.Upstreams[0].Targets, 2)
+}
+
+func Test_FromMapUnknownField(t *testing.T) {
+	_, err := FromMap(map[string]interface{}{
+		"services": []interface{}{map[string]interface{}{"name": "s", "hostname": "example.com"}},
+	})
+	assert.EqualError(t, err,
+		`failed to convert the declarative file to typed content: json: unknown field "hostname"`)
+}
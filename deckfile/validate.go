@@ -0,0 +1,125 @@
+package deckfile
+
+import (
+	_ "embed" // for embedding the declarative config schema
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/xeipuuv/gojsonschema"
+)
+
+// Schema is the JSON Schema (draft-07) of a Kong declarative file, with the constraints
+// of the Kong entity schemas on the fields generated by the converters, eg. port ranges
+// and protocol values.
+//
+//go:embed schema/kong-declarative.schema.json
+var Schema []byte
+
+// ignoredSchemaErrors are the error types that only summarize other errors reported.
+var ignoredSchemaErrors = map[string]bool{
+	"condition_then": true,
+	"condition_else": true,
+	"number_all_of":  true,
+	"number_not":     true,
+}
+
+// entityKinds are the entity types of the entity arrays, by key, and the field naming
+// the entities.
+var entityKinds = map[string][2]string{
+	"services":        {"service", "name"},
+	"routes":          {"route", "name"},
+	"upstreams":       {"upstream", "name"},
+	"targets":         {"target", "target"},
+	"plugins":         {"plugin", "name"},
+	"consumers":       {"consumer", "username"},
+	"consumer_groups": {"consumer group", "name"},
+	"filter_chains":   {"filter chain", "name"},
+}
+
+// Validate validates a declarative file, as returned by the converters, against the
+// schema, or the vendored Schema if it is nil. Returns the violations, sorted, each
+// with the entity it was found on (the innermost one), eg. "service 'users': port:
+// Must be less than or equal to 65535". Returns an error if the schema cannot be used.
+func Validate(deckData map[string]interface{}, schema []byte) ([]string, error) {
+	if schema == nil {
+		schema = Schema
+	}
+	loadedSchema, err := gojsonschema.NewSchema(gojsonschema.NewBytesLoader(schema))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load the declarative config schema: %w", err)
+	}
+
+	// validate a generic copy, so the entities can be looked up by their location
+	data, err := json.Marshal(deckData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize the declarative file: %w", err)
+	}
+	var content interface{}
+	if err = json.Unmarshal(data, &content); err != nil {
+		return nil, fmt.Errorf("failed to serialize the declarative file: %w", err)
+	}
+	result, err := loadedSchema.Validate(gojsonschema.NewGoLoader(content))
+	if err != nil {
+		return nil, fmt.Errorf("failed to validate the declarative file: %w", err)
+	}
+
+	violations := make([]string, 0)
+	for _, resultErr := range result.Errors() {
+		if ignoredSchemaErrors[resultErr.Type()] {
+			continue
+		}
+		// the context is the path to the value, starting at "(root)"
+		tokens := strings.Split(resultErr.Context().String("\x00"), "\x00")[1:]
+		entity, field := describeLocation(content, tokens)
+		description := strings.TrimPrefix(resultErr.Description(), resultErr.Field()+" ")
+		if field != "" {
+			description = field + ": " + description
+		}
+		violations = append(violations, entity+": "+description)
+	}
+	sort.Strings(violations)
+	return violations, nil
+}
+
+// describeLocation returns the innermost entity at the location (the tokens of the path
+// to a value), eg. "route 'users_get'", and the path to the value within that entity,
+// eg. "protocols.0". Entities without a name are described by their index.
+func describeLocation(content interface{}, tokens []string) (string, string) {
+	entity := "document"
+	fieldStart := 0
+	value := content
+	for i, token := range tokens {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			value = v[token]
+		case []interface{}:
+			index, err := strconv.Atoi(token)
+			if err != nil || index < 0 || index >= len(v) {
+				return entity, strings.Join(tokens[fieldStart:], ".")
+			}
+			value = v[index]
+			if i == 0 {
+				continue
+			}
+			if kind, found := entityKinds[tokens[i-1]]; found {
+				object, _ := value.(map[string]interface{})
+				name, _ := object[kind[1]].(string)
+				if name == "" {
+					name, _ = object["id"].(string)
+				}
+				if name != "" {
+					entity = fmt.Sprintf("%s '%s'", kind[0], name)
+				} else {
+					entity = fmt.Sprintf("%s #%d", kind[0], index+1)
+				}
+				fieldStart = i + 1
+			}
+		default:
+			return entity, strings.Join(tokens[fieldStart:], ".")
+		}
+	}
+	return entity, strings.Join(tokens[fieldStart:], ".")
+}
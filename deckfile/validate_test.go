@@ -0,0 +1,67 @@
+package deckfile
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Kong/fw/convertoas3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ValidateFixtures(t *testing.T) {
+	files, err := filepath.Glob(fixturePath + "*.yaml")
+	require.NoError(t, err)
+	require.NotEmpty(t, files)
+
+	for _, file := range files {
+		spec, err := os.ReadFile(file)
+		require.NoError(t, err)
+		deckData, err := convertoas3.Convert(context.Background(), &spec, convertoas3.O2kOptions{})
+		require.NoError(t, err, file)
+
+		violations, err := Validate(deckData, nil)
+		require.NoError(t, err, file)
+		assert.Empty(t, violations, file)
+	}
+}
+
+func Test_Validate(t *testing.T) {
+	var deckData map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"_format_version": "3.0",
+		"services": [{
+			"name": "users",
+			"host": "example.com",
+			"port": 70000,
+			"routes": [{
+				"name": "users_get",
+				"protocols": ["http", "gopher"],
+				"plugins": [{"name": "cors", "enabled": "yes"}]
+			}, {
+				"paths": ["users"]
+			}]
+		}],
+		"upstreams": [{
+			"name": "users.upstream",
+			"targets": [{"target": "example.com:443", "weight": -1}]
+		}]
+	}`), &deckData))
+
+	violations, err := Validate(deckData, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"plugin 'cors': enabled: Invalid type. Expected: boolean, given: string",
+		"route #2: paths.0: Does not match pattern '^~?/'",
+		"route 'users_get': protocols.1: must be one of the following: \"http\", \"https\", " +
+			"\"grpc\", \"grpcs\", \"tcp\", \"tls\", \"tls_passthrough\", \"udp\", \"ws\", \"wss\"",
+		"service 'users': port: Must be less than or equal to 65535",
+		"target 'example.com:443': weight: Must be greater than or equal to 0",
+	}, violations)
+
+	_, err = Validate(deckData, []byte("not a schema"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,100 @@
+package filebasics
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+const backupSuffix = ".bak"
+
+// CheckOutput returns an error if the output file cannot be written with the given
+// options, so it can be reported before doing any work.
+func CheckOutput(filename string, opts OutputOptions) error {
+	if err := opts.validateStyle(); err != nil {
+		return err
+	}
+	if filename == "-" || !opts.NoClobber {
+		return nil
+	}
+	if _, err := os.Stat(filename); err == nil {
+		return fmt.Errorf("output file '%s' already exists", filename)
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to check output file '%s': %w", filename, err)
+	}
+	return nil
+}
+
+// writeFile writes the output using the write function. Writes to stdout if
+// filename == "-". A file is written to a temporary file in the same directory first,
+// which then replaces the file. So the file is never left partially written, and it
+// is left untouched if writing fails. The file mode of an existing file is retained.
+func writeFile(filename string, opts OutputOptions, write func(w io.Writer) error) error {
+	if filename == "-" {
+		w := bufio.NewWriter(os.Stdout)
+		if err := write(w); err != nil {
+			return err
+		}
+		return w.Flush()
+	}
+
+	if err := CheckOutput(filename, opts); err != nil {
+		return err
+	}
+	var mode fs.FileMode = 0o644
+	existing, err := os.Stat(filename)
+	if err == nil {
+		mode = existing.Mode().Perm()
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(filename), "."+filepath.Base(filename)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create output file '%s': %w", filename, err)
+	}
+	defer os.Remove(tmp.Name()) // fails silently once renamed
+
+	w := bufio.NewWriter(tmp)
+	err = write(w)
+	if err == nil {
+		err = w.Flush()
+	}
+	if err == nil {
+		err = tmp.Chmod(mode)
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write to output file '%s': %w", filename, err)
+	}
+
+	if opts.Backup && existing != nil {
+		if err = copyFile(filename, filename+backupSuffix, mode); err != nil {
+			return fmt.Errorf("failed to create backup of output file '%s': %w", filename, err)
+		}
+	}
+	if err = os.Rename(tmp.Name(), filename); err != nil {
+		return fmt.Errorf("failed to write to output file '%s': %w", filename, err)
+	}
+	return nil
+}
+
+// copyFile copies the contents of a file, replacing the target if it exists.
+func copyFile(source string, target string, mode fs.FileMode) error {
+	data, err := os.ReadFile(source)
+	if err != nil {
+		return err
+	}
+	if err = os.WriteFile(target, data, mode); err != nil {
+		return err
+	}
+	// WriteFile does not change the mode of an existing file
+	return os.Chmod(target, mode)
+}
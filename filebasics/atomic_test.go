@@ -0,0 +1,66 @@
+package filebasics
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_writeFile(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "out.yaml")
+	writeString := func(str string) func(w io.Writer) error {
+		return func(w io.Writer) error {
+			_, err := io.WriteString(w, str)
+			return err
+		}
+	}
+	readString := func(filename string) string {
+		data, _ := os.ReadFile(filename)
+		return string(data)
+	}
+
+	// new file
+	assert.NoError(t, writeFile(filename, OutputOptions{}, writeString("first")))
+	assert.Equal(t, "first", readString(filename))
+	assert.NoFileExists(t, filename+backupSuffix)
+
+	// a failing write leaves the file untouched
+	err := writeFile(filename, OutputOptions{}, func(w io.Writer) error {
+		_, _ = io.WriteString(w, "partial")
+		return errors.New("failed")
+	})
+	assert.Error(t, err)
+	assert.Equal(t, "first", readString(filename))
+
+	// replace, retaining the mode
+	assert.NoError(t, os.Chmod(filename, 0o640))
+	assert.NoError(t, writeFile(filename, OutputOptions{}, writeString("second")))
+	assert.Equal(t, "second", readString(filename))
+	info, _ := os.Stat(filename)
+	assert.Equal(t, os.FileMode(0o640), info.Mode().Perm())
+
+	// with backup
+	assert.NoError(t, writeFile(filename, OutputOptions{Backup: true}, writeString("third")))
+	assert.Equal(t, "third", readString(filename))
+	assert.Equal(t, "second", readString(filename+backupSuffix))
+
+	// no clobber
+	err = writeFile(filename, OutputOptions{NoClobber: true}, writeString("fourth"))
+	assert.EqualError(t, err, "output file '"+filename+"' already exists")
+	assert.Equal(t, "third", readString(filename))
+	assert.NoError(t, writeFile(filename+".new", OutputOptions{NoClobber: true}, writeString("fourth")))
+	assert.Equal(t, "fourth", readString(filename+".new"))
+
+	// no temporary files are left behind
+	entries, _ := os.ReadDir(dir)
+	names := make([]string, len(entries))
+	for i, entry := range entries {
+		names[i] = entry.Name()
+	}
+	assert.ElementsMatch(t, []string{"out.yaml", "out.yaml.bak", "out.yaml.new"}, names)
+}
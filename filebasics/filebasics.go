@@ -1,13 +1,13 @@
 package filebasics
 
 import (
-	"encoding/json"
+	"bytes"
+	"compress/gzip"
 	"fmt"
 	"io"
 	"log"
 	"os"
-
-	"sigs.k8s.io/yaml"
+	"strings"
 )
 
 const (
@@ -15,8 +15,17 @@ const (
 )
 
 // MustReadFile reads file contents. Will panic if reading fails.
-// Reads from stdin if filename == "-"
+// Reads from stdin if filename == "-". Gzipped contents are decompressed.
 func MustReadFile(filename string) *[]byte {
+	body, err := ReadFile(filename)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return body
+}
+
+// ReadFile reads file contents, see MustReadFile.
+func ReadFile(filename string) (*[]byte, error) {
 	var (
 		body []byte
 		err  error
@@ -28,60 +37,83 @@ func MustReadFile(filename string) *[]byte {
 		body, err = os.ReadFile(filename)
 	}
 
+	if err == nil && IsGzipped(filename, body) {
+		body, err = gunzip(body, 0)
+	}
+
 	if err != nil {
-		log.Fatalf("unable to read file: %v", err)
+		return nil, fmt.Errorf("unable to read file: %w", err)
 	}
-	return &body
+	return &body, nil
 }
 
 // mustWriteFile writes the output to a file. Will panic if writing fails.
-// Writes to stdout if filename == "-"
+// Writes to stdout if filename == "-". See MustWriteSerialized for how the file is written.
 func MustWriteFile(filename string, content *[]byte) {
-	var f *os.File
-	var err error
-
-	if filename != "-" {
-		// write to file
-		f, err = os.Create(filename)
-		if err != nil {
-			log.Fatalf("failed to create output file '%s'", filename)
-		}
-		defer f.Close()
-	} else {
-		// writing to stdout
-		f = os.Stdout
-	}
-	_, err = f.Write(*content)
-	if err != nil {
-		log.Fatalf(fmt.Sprintf("failed to write to output file '%s'; %%w", filename), err)
+	if err := WriteFile(filename, content); err != nil {
+		log.Fatal(err)
 	}
 }
 
-// mustSerialize will serialize the result as a JSON/YAML. Will panic
-// if serializing fails.
-func MustSerialize(content map[string]interface{}, asYaml bool) *[]byte {
-	var (
-		str []byte
-		err error
-	)
+// WriteFile writes the output to a file, see MustWriteFile.
+func WriteFile(filename string, content *[]byte) error {
+	return writeFile(filename, OutputOptions{}, func(w io.Writer) error {
+		_, err := w.Write(*content)
+		return err
+	})
+}
 
-	if asYaml {
-		str, err = yaml.Marshal(content)
-		if err != nil {
-			log.Fatal("failed to yaml-serialize the resulting file; %w", err)
-		}
-	} else {
-		str, err = json.MarshalIndent(content, "", defaultJSONIndent)
-		if err != nil {
-			log.Fatal("failed to json-serialize the resulting file; %w", err)
-		}
+// mustSerialize will serialize the result as a JSON/YAML, using the style from the
+// options. Will panic if serializing fails.
+func MustSerialize(content map[string]interface{}, opts OutputOptions) *[]byte {
+	var buf bytes.Buffer
+	if err := WriteSerialized(&buf, content, opts); err != nil {
+		log.Fatal(err)
 	}
-
+	str := buf.Bytes()
 	return &str
 }
 
+// OutputOptions defines the options for writing the serialized output.
+type OutputOptions struct {
+	AsYaml    bool // Serialize as YAML, instead of JSON
+	Compress  bool // Gzip the output. Also done if the filename has a '.gz' suffix
+	Backup    bool // Keep a copy of an existing output file, with a '.bak' suffix
+	NoClobber bool // Fail if the output file already exists
+
+	// Serialization style; the defaults are a 2 space indent, and keys sorted by name.
+	JSONIndent  int      // Number of spaces to indent JSON with
+	JSONCompact bool     // Serialize JSON without any whitespace, JSONIndent is ignored
+	YAMLIndent  int      // Number of spaces to indent YAML with, this also indents arrays in objects
+	KeyOrder    []string // Object keys to serialize first, in this order, before the sorted remaining keys
+}
+
 // MustWriteSerializedFile will serialize the data and write it to a file. Will
 // panic if it fails. Writes to stdout if filename == "-"
 func MustWriteSerializedFile(filename string, content map[string]interface{}, asYaml bool) {
-	MustWriteFile(filename, MustSerialize(content, asYaml))
+	MustWriteSerialized(filename, content, OutputOptions{AsYaml: asYaml})
+}
+
+// MustWriteSerialized will serialize the data and write it to a file. Will panic if it
+// fails. Writes to stdout if filename == "-"
+// The data is written while serializing, see WriteSerialized. It is written to a
+// temporary file first, which replaces the output file only once complete.
+func MustWriteSerialized(filename string, content map[string]interface{}, opts OutputOptions) {
+	if err := WriteSerializedFile(filename, content, opts); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// WriteSerializedFile will serialize the data and write it to a file, see MustWriteSerialized.
+func WriteSerializedFile(filename string, content map[string]interface{}, opts OutputOptions) error {
+	return writeFile(filename, opts, func(w io.Writer) error {
+		if !opts.Compress && !strings.HasSuffix(strings.ToLower(filename), gzipSuffix) {
+			return WriteSerialized(w, content, opts)
+		}
+		gz := gzip.NewWriter(w)
+		if err := WriteSerialized(gz, content, opts); err != nil {
+			return err
+		}
+		return gz.Close()
+	})
 }
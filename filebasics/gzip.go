@@ -0,0 +1,44 @@
+package filebasics
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const gzipSuffix = ".gz"
+
+// gzipMagic are the first bytes of gzipped data.
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// IsGzipped returns true if the data starts with the gzip magic bytes, or the
+// filename has a '.gz' suffix.
+func IsGzipped(filename string, data []byte) bool {
+	return bytes.HasPrefix(data, gzipMagic) || strings.HasSuffix(strings.ToLower(filename), gzipSuffix)
+}
+
+// gunzip decompresses the data. If maxSize > 0, it fails if the decompressed data is
+// larger than maxSize bytes.
+func gunzip(data []byte, maxSize int64) ([]byte, error) {
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress: %w", err)
+	}
+	defer r.Close()
+
+	var reader io.Reader = r
+	if maxSize > 0 {
+		// read 1 byte more than allowed, to detect data that is too large
+		reader = io.LimitReader(r, maxSize+1)
+	}
+	result, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress: %w", err)
+	}
+	if maxSize > 0 && int64(len(result)) > maxSize {
+		return nil, fmt.Errorf("decompressed size exceeds the maximum of %d bytes", maxSize)
+	}
+	return result, nil
+}
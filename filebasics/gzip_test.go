@@ -0,0 +1,71 @@
+package filebasics
+
+import (
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func gzipData(t *testing.T, data string) []byte {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	_, err := w.Write([]byte(data))
+	assert.NoError(t, err)
+	assert.NoError(t, w.Close())
+	return buf.Bytes()
+}
+
+func Test_IsGzipped(t *testing.T) {
+	assert.True(t, IsGzipped("spec.yaml", gzipData(t, "openapi: 3.0.3")))
+	assert.True(t, IsGzipped("spec.yaml.GZ", []byte("openapi: 3.0.3")))
+	assert.False(t, IsGzipped("spec.yaml", []byte("openapi: 3.0.3")))
+	assert.False(t, IsGzipped("-", nil))
+}
+
+func Test_gunzip(t *testing.T) {
+	data := strings.Repeat("openapi: 3.0.3\n", 10)
+	result, err := gunzip(gzipData(t, data), 0)
+	assert.NoError(t, err)
+	assert.Equal(t, data, string(result))
+
+	result, err = gunzip(gzipData(t, data), int64(len(data)))
+	assert.NoError(t, err)
+	assert.Equal(t, data, string(result))
+
+	_, err = gunzip(gzipData(t, data), int64(len(data)-1))
+	assert.Error(t, err)
+
+	_, err = gunzip([]byte(data), 0)
+	assert.Error(t, err)
+}
+
+func Test_CompressedFiles(t *testing.T) {
+	dir := t.TempDir()
+	content := map[string]interface{}{
+		"_format_version": "3.0",
+		"services":        []interface{}{map[string]interface{}{"name": "one"}},
+	}
+
+	// compressed by option, or by extension
+	for _, tst := range []struct {
+		filename string
+		opts     OutputOptions
+	}{
+		{"compressed.yaml", OutputOptions{AsYaml: true, Compress: true}},
+		{"extension.json.gz", OutputOptions{}},
+	} {
+		filename := filepath.Join(dir, tst.filename)
+		MustWriteSerialized(filename, content, tst.opts)
+
+		raw, err := os.ReadFile(filename)
+		assert.NoError(t, err)
+		assert.True(t, bytes.HasPrefix(raw, gzipMagic), tst.filename)
+		assert.Equal(t, string(*MustSerialize(content, tst.opts)), string(*MustReadFile(filename)),
+			tst.filename)
+	}
+}
@@ -0,0 +1,223 @@
+package filebasics
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+// WriteSerialized serializes the content as JSON/YAML, and writes it to the writer.
+// Arrays on the top level (services, upstreams, etc.) are serialized and written one
+// entry at a time, so the serialized output is never in memory as a whole. The output
+// is identical to serializing the content as a whole.
+func WriteSerialized(w io.Writer, content map[string]interface{}, opts OutputOptions) error {
+	if err := opts.validateStyle(); err != nil {
+		return err
+	}
+
+	keys := make([]string, 0, len(content))
+	for key := range content {
+		keys = append(keys, key)
+	}
+	keys = orderKeys(keys, opts.KeyOrder)
+
+	if opts.AsYaml {
+		if err := writeYAML(w, content, keys, opts); err != nil {
+			return fmt.Errorf("failed to yaml-serialize the resulting file; %w", err)
+		}
+	} else {
+		if err := writeJSON(w, content, keys, opts); err != nil {
+			return fmt.Errorf("failed to json-serialize the resulting file; %w", err)
+		}
+	}
+	return nil
+}
+
+// getEntries returns the entries of a non-empty array, or a pointer to one. Returns
+// nil for anything else.
+func getEntries(value interface{}) []interface{} {
+	v := reflect.ValueOf(value)
+	if v.Kind() == reflect.Ptr && !v.IsNil() {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Slice || v.Len() == 0 {
+		return nil
+	}
+	entries := make([]interface{}, v.Len())
+	for i := range entries {
+		entries[i] = v.Index(i).Interface()
+	}
+	return entries
+}
+
+// writeYAML writes the top level keys in the given order.
+func writeYAML(w io.Writer, content map[string]interface{}, keys []string, opts OutputOptions) error {
+	if len(keys) == 0 {
+		_, err := io.WriteString(w, "{}\n")
+		return err
+	}
+
+	marshal := yaml.Marshal
+	entryIndent := ""
+	if opts.hasYAMLStyle() {
+		marshal = opts.marshalStyledYAML
+		entryIndent = strings.Repeat(" ", opts.yamlIndent())
+	}
+
+	for _, key := range keys {
+		entries := getEntries(content[key])
+		if entries == nil {
+			// not an array, or an empty one, serialize as a whole
+			str, err := marshal(map[string]interface{}{key: content[key]})
+			if err != nil {
+				return err
+			}
+			if _, err = w.Write(str); err != nil {
+				return err
+			}
+			continue
+		}
+
+		name, err := marshal(key)
+		if err != nil {
+			return err
+		}
+		if _, err = io.WriteString(w, strings.TrimSuffix(string(name), "\n")+":\n"); err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			// a single entry list, serializes the same as the entry in the full list
+			str, err := marshal([]interface{}{entry})
+			if err != nil {
+				return err
+			}
+			if entryIndent != "" {
+				str = indentLines(str, entryIndent)
+			}
+			if _, err = w.Write(str); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// indentLines adds the indent to the start of every line.
+func indentLines(str []byte, indent string) []byte {
+	lines := bytes.SplitAfter(str, []byte("\n"))
+	var result bytes.Buffer
+	for _, line := range lines {
+		if len(line) > 0 {
+			result.WriteString(indent)
+			result.Write(line)
+		}
+	}
+	return result.Bytes()
+}
+
+// writeJSON writes the top level keys in the given order.
+func writeJSON(w io.Writer, content map[string]interface{}, keys []string, opts OutputOptions) error {
+	if len(keys) == 0 {
+		_, err := io.WriteString(w, "{}")
+		return err
+	}
+
+	write := func(strs ...string) error {
+		for _, str := range strs {
+			if _, err := io.WriteString(w, str); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	indent, newline, space := opts.jsonIndent(), "\n", " "
+	if opts.JSONCompact {
+		newline, space = "", ""
+	}
+
+	if err := write("{", newline); err != nil {
+		return err
+	}
+	for i, key := range keys {
+		name, err := json.Marshal(key)
+		if err != nil {
+			return err
+		}
+		if err = write(indent, string(name), ":", space); err != nil {
+			return err
+		}
+
+		entries := getEntries(content[key])
+		if entries == nil {
+			// not an array, or an empty one, serialize as a whole
+			str, err := opts.marshalJSON(content[key], indent)
+			if err != nil {
+				return err
+			}
+			if err = write(string(str)); err != nil {
+				return err
+			}
+		} else {
+			if err = write("[", newline); err != nil {
+				return err
+			}
+			entryIndent := indent + indent
+			for j, entry := range entries {
+				str, err := opts.marshalJSON(entry, entryIndent)
+				if err != nil {
+					return err
+				}
+				separator := "," + newline
+				if j == len(entries)-1 {
+					separator = newline
+				}
+				if err = write(entryIndent, string(str), separator); err != nil {
+					return err
+				}
+			}
+			if err = write(indent, "]"); err != nil {
+				return err
+			}
+		}
+
+		separator := "," + newline
+		if i == len(keys)-1 {
+			separator = newline
+		}
+		if err = write(separator); err != nil {
+			return err
+		}
+	}
+	return write("}")
+}
+
+// orderKeys sorts the keys, with the keys from the order list first, in that order.
+func orderKeys(keys []string, order []string) []string {
+	sort.Strings(keys)
+	if len(order) == 0 {
+		return keys
+	}
+
+	position := make(map[string]int, len(order))
+	for i, key := range order {
+		if _, found := position[key]; !found {
+			position[key] = i
+		}
+	}
+	sort.SliceStable(keys, func(i, j int) bool {
+		pi, foundI := position[keys[i]]
+		pj, foundJ := position[keys[j]]
+		if foundI && foundJ {
+			return pi < pj
+		}
+		return foundI && !foundJ
+	})
+	return keys
+}
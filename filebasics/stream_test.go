@@ -0,0 +1,163 @@
+package filebasics
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"sigs.k8s.io/yaml"
+)
+
+func Test_WriteSerialized(t *testing.T) {
+	plugin := map[string]interface{}{"name": "cors", "config": map[string]interface{}{"origins": []string{"*"}}}
+	tests := []struct {
+		name    string
+		content map[string]interface{}
+	}{
+		{"empty", map[string]interface{}{}},
+		{"scalars only", map[string]interface{}{"_format_version": "3.0", "_workspace": "ws"}},
+		{"full", map[string]interface{}{
+			"_format_version": "3.0",
+			"services": []interface{}{
+				map[string]interface{}{
+					"name":   "one",
+					"routes": []interface{}{map[string]interface{}{"paths": []string{"~/a$"}}},
+				},
+				map[string]interface{}{"name": "two <&>", "plugins": &[]*map[string]interface{}{&plugin}},
+			},
+			"upstreams":     []interface{}{},
+			"plugins":       &[]*map[string]interface{}{&plugin, &plugin},
+			"consumers":     []map[string]interface{}{{"username": "me"}},
+			"needs: quotes": []interface{}{"a", 1, true, nil},
+			"object":        map[string]interface{}{"key": []interface{}{}},
+		}},
+	}
+
+	for _, tst := range tests {
+		expectedYAML, _ := yaml.Marshal(tst.content)
+		expectedJSON, _ := json.MarshalIndent(tst.content, "", "  ")
+		expectedCompact, _ := json.Marshal(tst.content)
+		expectedIndent, _ := json.MarshalIndent(tst.content, "", "    ")
+
+		for _, style := range []struct {
+			opts     OutputOptions
+			expected []byte
+		}{
+			{OutputOptions{AsYaml: true}, expectedYAML},
+			{OutputOptions{}, expectedJSON},
+			{OutputOptions{JSONCompact: true}, expectedCompact},
+			{OutputOptions{JSONIndent: 4}, expectedIndent},
+		} {
+			var buf bytes.Buffer
+			err := WriteSerialized(&buf, tst.content, style.opts)
+			assert.NoError(t, err, tst.name)
+			assert.Equal(t, string(style.expected), buf.String(),
+				"%s: %+v, expected the same output as serializing as a whole", tst.name, style.opts)
+		}
+	}
+}
+
+func Test_WriteSerializedStyle(t *testing.T) {
+	content := map[string]interface{}{
+		"_format_version": "3.0",
+		"services": []interface{}{
+			map[string]interface{}{
+				"id":      "abc",
+				"name":    "one",
+				"port":    443,
+				"retries": 1.5,
+				"routes":  []interface{}{map[string]interface{}{"name": "r", "paths": []string{"~/a$"}}},
+			},
+		},
+	}
+	keyOrder := []string{"name", "id"}
+
+	tests := []struct {
+		name     string
+		opts     OutputOptions
+		expected string
+	}{
+		{
+			"yaml indent", OutputOptions{AsYaml: true, YAMLIndent: 4},
+			`_format_version: "3.0"
+services:
+    - id: abc
+      name: one
+      port: 443
+      retries: 1.5
+      routes:
+        - name: r
+          paths:
+            - ~/a$
+`,
+		},
+		{
+			"yaml key order", OutputOptions{AsYaml: true, KeyOrder: keyOrder},
+			`_format_version: "3.0"
+services:
+  - name: one
+    id: abc
+    port: 443
+    retries: 1.5
+    routes:
+      - name: r
+        paths:
+          - ~/a$
+`,
+		},
+		{
+			"json key order", OutputOptions{KeyOrder: keyOrder, JSONIndent: 1},
+			`{
+ "_format_version": "3.0",
+ "services": [
+  {
+   "name": "one",
+   "id": "abc",
+   "port": 443,
+   "retries": 1.5,
+   "routes": [
+    {
+     "name": "r",
+     "paths": [
+      "~/a$"
+     ]
+    }
+   ]
+  }
+ ]
+}`,
+		},
+		{
+			"json compact key order", OutputOptions{KeyOrder: keyOrder, JSONCompact: true},
+			`{"_format_version":"3.0","services":[{"name":"one","id":"abc","port":443,"retries":1.5,` +
+				`"routes":[{"name":"r","paths":["~/a$"]}]}]}`,
+		},
+	}
+
+	for _, tst := range tests {
+		result := string(*MustSerialize(content, tst.opts))
+		assert.Equal(t, tst.expected, result, tst.name)
+	}
+
+	// the styles must not alter the data
+	for _, opts := range []OutputOptions{tests[0].opts, tests[1].opts} {
+		var parsed map[string]interface{}
+		assert.NoError(t, yaml.Unmarshal(*MustSerialize(content, opts), &parsed))
+		expected, _ := yaml.Marshal(content)
+		result, _ := yaml.Marshal(parsed)
+		assert.Equal(t, string(expected), string(result))
+	}
+
+	// invalid styles
+	for _, opts := range []OutputOptions{{JSONIndent: -1}, {YAMLIndent: 1}, {YAMLIndent: -2}} {
+		assert.Error(t, WriteSerialized(&bytes.Buffer{}, content, opts), "%+v", opts)
+		assert.Error(t, CheckOutput("-", opts), "%+v", opts)
+	}
+}
+
+func Test_orderKeys(t *testing.T) {
+	keys := []string{"tags", "id", "name", "b", "a"}
+	assert.Equal(t, []string{"a", "b", "id", "name", "tags"}, orderKeys(keys, nil))
+	assert.Equal(t, []string{"name", "id", "a", "b", "tags"}, orderKeys(keys, []string{"name", "other", "id", "name"}))
+}
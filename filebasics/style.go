@@ -0,0 +1,215 @@
+package filebasics
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	yaml3 "sigs.k8s.io/yaml/goyaml.v3"
+)
+
+// validateStyle checks the serialization style options.
+func (opts OutputOptions) validateStyle() error {
+	if opts.JSONIndent < 0 {
+		return fmt.Errorf("expected JSON indent to be positive, got %d", opts.JSONIndent)
+	}
+	if opts.YAMLIndent < 0 || opts.YAMLIndent == 1 {
+		return fmt.Errorf("expected YAML indent to be 2 or more, got %d", opts.YAMLIndent)
+	}
+	return nil
+}
+
+// jsonIndent returns the string to indent JSON with.
+func (opts OutputOptions) jsonIndent() string {
+	if opts.JSONCompact {
+		return ""
+	}
+	if opts.JSONIndent == 0 {
+		return defaultJSONIndent
+	}
+	return strings.Repeat(" ", opts.JSONIndent)
+}
+
+// yamlIndent returns the number of spaces to indent YAML with.
+func (opts OutputOptions) yamlIndent() int {
+	if opts.YAMLIndent == 0 {
+		return len(defaultJSONIndent)
+	}
+	return opts.YAMLIndent
+}
+
+// hasYAMLStyle returns true if YAML output differs from the default style.
+func (opts OutputOptions) hasYAMLStyle() bool {
+	return opts.YAMLIndent != 0 || len(opts.KeyOrder) > 0
+}
+
+// toGeneric returns the value as decoded from JSON, so only maps, slices, and scalars
+// remain. Numbers are decoded as json.Number, to retain them as is.
+func toGeneric(value interface{}) (interface{}, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var result interface{}
+	err = decoder.Decode(&result)
+	return result, err
+}
+
+// marshalJSON serializes the value as JSON, lines after the first one are prefixed.
+func (opts OutputOptions) marshalJSON(value interface{}, prefix string) ([]byte, error) {
+	if len(opts.KeyOrder) == 0 {
+		if opts.JSONCompact {
+			return json.Marshal(value)
+		}
+		return json.MarshalIndent(value, prefix, opts.jsonIndent())
+	}
+
+	generic, err := toGeneric(value)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err = opts.encodeJSON(&buf, generic, prefix); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// encodeJSON writes a generic value as JSON, with the object keys in order.
+func (opts OutputOptions) encodeJSON(buf *bytes.Buffer, value interface{}, prefix string) error {
+	indent, newline, space := opts.jsonIndent(), "\n", " "
+	if opts.JSONCompact {
+		newline, space = "", ""
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if len(v) == 0 {
+			buf.WriteString("{}")
+			return nil
+		}
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		buf.WriteString("{" + newline)
+		for i, key := range orderKeys(keys, opts.KeyOrder) {
+			name, _ := json.Marshal(key)
+			buf.WriteString(prefix + indent + string(name) + ":" + space)
+			if err := opts.encodeJSON(buf, v[key], prefix+indent); err != nil {
+				return err
+			}
+			if i < len(keys)-1 {
+				buf.WriteString(",")
+			}
+			buf.WriteString(newline)
+		}
+		buf.WriteString(prefix + "}")
+
+	case []interface{}:
+		if len(v) == 0 {
+			buf.WriteString("[]")
+			return nil
+		}
+		buf.WriteString("[" + newline)
+		for i, entry := range v {
+			buf.WriteString(prefix + indent)
+			if err := opts.encodeJSON(buf, entry, prefix+indent); err != nil {
+				return err
+			}
+			if i < len(v)-1 {
+				buf.WriteString(",")
+			}
+			buf.WriteString(newline)
+		}
+		buf.WriteString(prefix + "]")
+
+	default:
+		str, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		buf.Write(str)
+	}
+	return nil
+}
+
+// marshalStyledYAML serializes the value as YAML, with the indent and key order from
+// the options.
+func (opts OutputOptions) marshalStyledYAML(value interface{}) ([]byte, error) {
+	generic, err := toGeneric(value)
+	if err != nil {
+		return nil, err
+	}
+	node, err := opts.toYAMLNode(generic)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	encoder := yaml3.NewEncoder(&buf)
+	encoder.SetIndent(opts.yamlIndent())
+	if err = encoder.Encode(node); err != nil {
+		return nil, err
+	}
+	if err = encoder.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// toYAMLNode converts a generic value to a YAML node, with the object keys in order.
+func (opts OutputOptions) toYAMLNode(value interface{}) (*yaml3.Node, error) {
+	node := &yaml3.Node{}
+	switch v := value.(type) {
+	case map[string]interface{}:
+		node.Kind = yaml3.MappingNode
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		for _, key := range orderKeys(keys, opts.KeyOrder) {
+			keyNode := &yaml3.Node{}
+			if err := keyNode.Encode(key); err != nil {
+				return nil, err
+			}
+			valueNode, err := opts.toYAMLNode(v[key])
+			if err != nil {
+				return nil, err
+			}
+			node.Content = append(node.Content, keyNode, valueNode)
+		}
+
+	case []interface{}:
+		node.Kind = yaml3.SequenceNode
+		for _, entry := range v {
+			entryNode, err := opts.toYAMLNode(entry)
+			if err != nil {
+				return nil, err
+			}
+			node.Content = append(node.Content, entryNode)
+		}
+
+	case json.Number:
+		var number interface{}
+		if i, err := v.Int64(); err == nil {
+			number = i
+		} else if f, err := v.Float64(); err == nil {
+			number = f
+		} else {
+			return nil, err
+		}
+		if err := node.Encode(number); err != nil {
+			return nil, err
+		}
+
+	default:
+		if err := node.Encode(v); err != nil {
+			return nil, err
+		}
+	}
+	return node, nil
+}
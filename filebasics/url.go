@@ -0,0 +1,141 @@
+package filebasics
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultURLTimeout = 30 * time.Second
+	DefaultURLMaxSize = 64 * 1024 * 1024 // 64 MB
+)
+
+// URLOptions defines the options for reading from a URL.
+type URLOptions struct {
+	Headers            []string      // Headers to add to the request, as "Name: value", eg. for authentication
+	Timeout            time.Duration // Timeout for the entire request, defaults to DefaultURLTimeout
+	MaxSize            int64         // Maximum size of the response body in bytes, defaults to DefaultURLMaxSize
+	CACertFile         string        // PEM file with CA certificates to trust, in addition to the system ones
+	InsecureSkipVerify bool          // Do not verify the server certificate
+}
+
+// IsURL returns true if the filename is an http(s) URL.
+func IsURL(filename string) bool {
+	lower := strings.ToLower(filename)
+	return strings.HasPrefix(lower, "http://") || strings.HasPrefix(lower, "https://")
+}
+
+// newHTTPClient returns a client with the TLS settings from the options.
+func newHTTPClient(opts URLOptions) (*http.Client, error) {
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: opts.InsecureSkipVerify, //nolint:gosec // explicitly requested by the user
+	}
+	if opts.CACertFile != "" {
+		pem, err := os.ReadFile(opts.CACertFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA certificates: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no valid certificates found in '%s'", opts.CACertFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	return &http.Client{Transport: transport, Timeout: opts.Timeout}, nil
+}
+
+// ReadURL reads the body from an http(s) URL. Fails if the response status is not 2xx,
+// or the body is larger than the maximum size. A gzipped body is decompressed, subject
+// to the same maximum size.
+func ReadURL(ctx context.Context, url string, opts URLOptions) ([]byte, error) {
+	if opts.Timeout == 0 {
+		opts.Timeout = DefaultURLTimeout
+	}
+	if opts.MaxSize == 0 {
+		opts.MaxSize = DefaultURLMaxSize
+	}
+
+	client, err := newHTTPClient(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL '%s': %w", url, err)
+	}
+	for _, header := range opts.Headers {
+		name, value, found := strings.Cut(header, ":")
+		if !found || strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf("expected header to be formatted as 'Name: value', got: '%s'", header)
+		}
+		req.Header.Add(strings.TrimSpace(name), strings.TrimSpace(value))
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read '%s': %w", url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("failed to read '%s': %s", url, resp.Status)
+	}
+	if resp.ContentLength > opts.MaxSize {
+		return nil, fmt.Errorf("failed to read '%s': size %d exceeds the maximum of %d bytes",
+			url, resp.ContentLength, opts.MaxSize)
+	}
+
+	// read 1 byte more than allowed, to detect bodies without a content-length that are too large
+	body, err := io.ReadAll(io.LimitReader(resp.Body, opts.MaxSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read '%s': %w", url, err)
+	}
+	if int64(len(body)) > opts.MaxSize {
+		return nil, fmt.Errorf("failed to read '%s': size exceeds the maximum of %d bytes", url, opts.MaxSize)
+	}
+	if IsGzipped(req.URL.Path, body) {
+		if body, err = gunzip(body, opts.MaxSize); err != nil {
+			return nil, fmt.Errorf("failed to read '%s': %w", url, err)
+		}
+	}
+	return body, nil
+}
+
+// MustReadFileOrURL reads from an http(s) URL if the filename is a URL, see ReadURL.
+// Otherwise it reads the file, see MustReadFile. Will panic if reading fails.
+func MustReadFileOrURL(ctx context.Context, filename string, opts URLOptions) *[]byte {
+	body, err := ReadFileOrURL(ctx, filename, opts)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return body
+}
+
+// ReadFileOrURL reads from an http(s) URL or a file, see MustReadFileOrURL.
+func ReadFileOrURL(ctx context.Context, filename string, opts URLOptions) (*[]byte, error) {
+	if !IsURL(filename) {
+		return ReadFile(filename)
+	}
+
+	body, err := ReadURL(ctx, filename, opts)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read file: %w", err)
+	}
+	return &body, nil
+}
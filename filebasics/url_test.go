@@ -0,0 +1,103 @@
+package filebasics
+
+import (
+	"context"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_IsURL(t *testing.T) {
+	assert.True(t, IsURL("http://example.com/spec.yaml"))
+	assert.True(t, IsURL("HTTPS://example.com/spec.yaml"))
+	assert.False(t, IsURL("spec.yaml"))
+	assert.False(t, IsURL("-"))
+	assert.False(t, IsURL("ftp://example.com/spec.yaml"))
+}
+
+func Test_ReadURL(t *testing.T) {
+	handler := http.NewServeMux()
+	handler.HandleFunc("/spec", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte("openapi: 3.0.3"))
+	})
+	handler.HandleFunc("/spec.gz", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(gzipData(t, strings.Repeat("x", 100)))
+	})
+	handler.HandleFunc("/large", func(w http.ResponseWriter, r *http.Request) {
+		w.(http.Flusher).Flush() // no content-length
+		_, _ = w.Write([]byte(strings.Repeat("x", 100)))
+	})
+	handler.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	})
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	auth := []string{"Authorization: Bearer secret"}
+	tests := []struct {
+		name     string
+		path     string
+		opts     URLOptions
+		expected string
+		errorMsg string
+	}{
+		{"with header", "/spec", URLOptions{Headers: auth}, "openapi: 3.0.3", ""},
+		{"without header", "/spec", URLOptions{}, "", "401 Unauthorized"},
+		{"bad header", "/spec", URLOptions{Headers: []string{"Bearer secret"}}, "", "'Name: value'"},
+		{"not found", "/other", URLOptions{}, "", "404 Not Found"},
+		{"gzipped", "/spec.gz", URLOptions{}, strings.Repeat("x", 100), ""},
+		{"gzipped exceeds max size", "/spec.gz", URLOptions{MaxSize: 50}, "", "decompressed size exceeds"},
+		{"within max size", "/large", URLOptions{MaxSize: 100}, strings.Repeat("x", 100), ""},
+		{"exceeds max size", "/large", URLOptions{MaxSize: 99}, "", "exceeds the maximum of 99 bytes"},
+		{"content-length exceeds max size", "/spec", URLOptions{Headers: auth, MaxSize: 5}, "", "size 14 exceeds"},
+		{"timeout", "/slow", URLOptions{Timeout: 50 * time.Millisecond}, "", "Timeout"},
+	}
+
+	for _, tst := range tests {
+		body, err := ReadURL(context.Background(), server.URL+tst.path, tst.opts)
+		if tst.errorMsg != "" {
+			if assert.Error(t, err, tst.name) {
+				assert.Contains(t, err.Error(), tst.errorMsg, tst.name)
+			}
+		} else {
+			assert.NoError(t, err, tst.name)
+			assert.Equal(t, tst.expected, string(body), tst.name)
+		}
+	}
+}
+
+func Test_ReadURLTLS(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("openapi: 3.0.3"))
+	}))
+	defer server.Close()
+
+	// self-signed, so fails by default
+	_, err := ReadURL(context.Background(), server.URL, URLOptions{})
+	assert.Error(t, err)
+
+	body, err := ReadURL(context.Background(), server.URL, URLOptions{InsecureSkipVerify: true})
+	assert.NoError(t, err)
+	assert.Equal(t, "openapi: 3.0.3", string(body))
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	assert.NoError(t, os.WriteFile(caFile, caPEM, 0o600))
+	body, err = ReadURL(context.Background(), server.URL, URLOptions{CACertFile: caFile})
+	assert.NoError(t, err)
+	assert.Equal(t, "openapi: 3.0.3", string(body))
+
+	_, err = ReadURL(context.Background(), server.URL, URLOptions{CACertFile: caFile + ".missing"})
+	assert.Error(t, err)
+}
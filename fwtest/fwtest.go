@@ -0,0 +1,97 @@
+// Package fwtest helps writing regression tests for specs converted by fw. A test
+// converts a spec fixture, and compares the result to a golden Kong declarative file:
+//
+//	func TestMySpec(t *testing.T) {
+//		fwtest.AssertGolden(t, "testdata/my-spec.yaml", "testdata/my-spec.kong.yaml", convert.Options{})
+//	}
+//
+// Differences are reported as a diff of the entities, showing only the parts that
+// differ. To (re)create the golden files, run the tests with the environment variable
+// FW_UPDATE_GOLDEN=1, or pass the test flag '-update-golden' to the packages using
+// fwtest:
+//
+//	go test ./mypackage -update-golden
+package fwtest
+
+import (
+	"context"
+	"flag"
+	"os"
+	"testing"
+
+	"github.com/Kong/fw/convert"
+	"github.com/Kong/fw/golden"
+)
+
+// UpdateEnv is the environment variable that, if set to a non-empty value, makes
+// AssertGolden write the golden files instead of comparing against them.
+const UpdateEnv = "FW_UPDATE_GOLDEN"
+
+// updateGolden is the test flag '-update-golden', an alternative to UpdateEnv.
+var updateGolden = flag.Bool("update-golden", false, "write the golden files, instead of comparing against them")
+
+// updating returns true if the golden files should be written.
+func updating() bool {
+	return *updateGolden || os.Getenv(UpdateEnv) != ""
+}
+
+// Convert converts the spec file, using the options, and returns the generated Kong
+// declarative file. The test fails immediately if the spec cannot be read or converted.
+func Convert(t testing.TB, specFile string, opts convert.Options) map[string]interface{} {
+	t.Helper()
+	spec, err := os.ReadFile(specFile)
+	if err != nil {
+		t.Fatalf("failed to read spec '%s': %v", specFile, err)
+	}
+	result, err := convert.Convert(context.Background(), spec, opts)
+	if err != nil {
+		t.Fatalf("failed to convert spec '%s': %v", specFile, err)
+	}
+	deckData, err := golden.Normalize(result.Content)
+	if err != nil {
+		t.Fatalf("failed to serialize the conversion of '%s': %v", specFile, err)
+	}
+	return deckData
+}
+
+// ReadGolden reads a Kong declarative file, JSON or YAML. The test fails immediately if
+// it cannot be read or parsed.
+func ReadGolden(t testing.TB, goldenFile string) map[string]interface{} {
+	t.Helper()
+	deckData, err := golden.Read(goldenFile)
+	if err != nil {
+		t.Fatalf("failed to read golden file '%s': %v (set %s=1 to create it)", goldenFile, err, UpdateEnv)
+	}
+	return deckData
+}
+
+// Diff returns the differences between two Kong declarative files, with '-' for the
+// expected and '+' for the actual values. Returns "" if they are equal.
+func Diff(expected map[string]interface{}, actual map[string]interface{}) string {
+	return golden.Diff(expected, actual)
+}
+
+// AssertGolden converts the spec file and compares the result to the golden file (JSON
+// or YAML, by its extension). It reports the differences and returns false if they are
+// not equal. With FW_UPDATE_GOLDEN set, or the '-update-golden' test flag, the golden
+// file is written instead.
+func AssertGolden(t testing.TB, specFile string, goldenFile string, opts convert.Options) bool {
+	t.Helper()
+	actual := Convert(t, specFile, opts)
+
+	if updating() {
+		if err := golden.Write(goldenFile, actual); err != nil {
+			t.Fatalf("failed to write golden file '%s': %v", goldenFile, err)
+		}
+		return true
+	}
+
+	expected := ReadGolden(t, goldenFile)
+	if diff := Diff(expected, actual); diff != "" {
+		t.Errorf("conversion of '%s' differs from golden file '%s' (-expected +actual):\n%s\n"+
+			"if the change is intended, set %s=1 (or pass -update-golden) to update the golden file",
+			specFile, goldenFile, diff, UpdateEnv)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,93 @@
+package fwtest
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Kong/fw/convert"
+	"github.com/stretchr/testify/assert"
+)
+
+// recorder is a testing.TB recording the failures, instead of failing the test.
+type recorder struct {
+	testing.TB
+	errors []string
+	fatal  bool
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func (r *recorder) Fatalf(format string, args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+	r.fatal = true
+	panic(r) // stop the helper, like t.FailNow does
+}
+
+// run runs the function with the recorder, recovering from a Fatalf.
+func (r *recorder) run(f func()) {
+	defer func() {
+		if p := recover(); p != nil && p != r {
+			panic(p)
+		}
+	}()
+	f()
+}
+
+func Test_AssertGolden(t *testing.T) {
+	AssertGolden(t, "testdata/learnservice.yaml", "testdata/learnservice.kong.yaml", convert.Options{})
+}
+
+func Test_AssertGoldenDiff(t *testing.T) {
+	golden := filepath.Join(t.TempDir(), "golden.yaml")
+	t.Setenv(UpdateEnv, "1")
+	assert.True(t, AssertGolden(t, "testdata/learnservice.yaml", golden, convert.Options{}))
+	t.Setenv(UpdateEnv, "")
+
+	content, _ := os.ReadFile(golden)
+	content = []byte(string(content) + "_workspace: other\n")
+	assert.NoError(t, os.WriteFile(golden, content, 0o600))
+
+	r := &recorder{TB: t}
+	r.run(func() {
+		assert.False(t, AssertGolden(r, "testdata/learnservice.yaml", golden, convert.Options{}))
+	})
+	assert.False(t, r.fatal)
+	if assert.Len(t, r.errors, 1) {
+		assert.Regexp(t, `-.*"_workspace":\s*string\("other"\)`, r.errors[0])
+		assert.Contains(t, r.errors[0], "set FW_UPDATE_GOLDEN=1 (or pass -update-golden) to update the golden file")
+	}
+}
+
+func Test_AssertGoldenUpdateFlag(t *testing.T) {
+	golden := filepath.Join(t.TempDir(), "golden.json")
+	*updateGolden = true
+	defer func() { *updateGolden = false }()
+	assert.True(t, AssertGolden(t, "testdata/learnservice.yaml", golden, convert.Options{}))
+
+	*updateGolden = false
+	assert.True(t, AssertGolden(t, "testdata/learnservice.yaml", golden, convert.Options{}))
+}
+
+func Test_AssertGoldenMissing(t *testing.T) {
+	r := &recorder{TB: t}
+	r.run(func() {
+		AssertGolden(r, "testdata/learnservice.yaml", "testdata/missing.yaml", convert.Options{})
+	})
+	assert.True(t, r.fatal)
+	assert.Contains(t, r.errors[0], "failed to read golden file 'testdata/missing.yaml'")
+}
+
+func Test_ConvertInvalidSpec(t *testing.T) {
+	r := &recorder{TB: t}
+	r.run(func() {
+		Convert(r, "testdata/missing-spec.yaml", convert.Options{})
+	})
+	assert.True(t, r.fatal)
+	assert.Contains(t, r.errors[0], "failed to read spec 'testdata/missing-spec.yaml'")
+}
@@ -0,0 +1,68 @@
+// Package golden compares generated Kong declarative files to golden files, as used
+// by the 'fwtest' package and by 'fw convert --expect'.
+package golden
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/Kong/fw/filebasics"
+	"github.com/google/go-cmp/cmp"
+	"sigs.k8s.io/yaml"
+)
+
+// IsYaml returns true if the golden file is YAML, based on its extension, ignoring a
+// '.gz' suffix.
+func IsYaml(filename string) bool {
+	switch filepath.Ext(strings.TrimSuffix(strings.ToLower(filename), ".gz")) {
+	case ".yaml", ".yml":
+		return true
+	default:
+		return false
+	}
+}
+
+// Normalize returns the declarative file with only JSON types; maps, slices, strings,
+// float64s, bools, and nils. So a converted file compares equal to the same file read
+// back from disk.
+func Normalize(deckData map[string]interface{}) (map[string]interface{}, error) {
+	data, err := json.Marshal(deckData)
+	if err != nil {
+		return nil, err
+	}
+	var result map[string]interface{}
+	err = json.Unmarshal(data, &result)
+	return result, err
+}
+
+// Read reads a golden file, JSON or YAML, possibly gzipped.
+func Read(filename string) (map[string]interface{}, error) {
+	content, err := filebasics.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	data, err := yaml.YAMLToJSON(*content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse golden file '%s': %w", filename, err)
+	}
+	var deckData map[string]interface{}
+	if err := json.Unmarshal(data, &deckData); err != nil {
+		return nil, fmt.Errorf("failed to parse golden file '%s': %w", filename, err)
+	}
+	return deckData, nil
+}
+
+// Write writes the declarative file as a golden file, as YAML or JSON based on its
+// extension.
+func Write(filename string, deckData map[string]interface{}) error {
+	return filebasics.WriteSerializedFile(filename, deckData, filebasics.OutputOptions{AsYaml: IsYaml(filename)})
+}
+
+// Diff returns the differences between two Kong declarative files, with '-' for the
+// expected and '+' for the actual values. Returns "" if they are equal. Both should be
+// normalized, see Normalize.
+func Diff(expected map[string]interface{}, actual map[string]interface{}) string {
+	return cmp.Diff(expected, actual)
+}
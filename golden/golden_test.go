@@ -0,0 +1,43 @@
+package golden
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_WriteRead(t *testing.T) {
+	deckData, err := Normalize(map[string]interface{}{
+		"_format_version": "3.0",
+		"services": []map[string]interface{}{
+			{"name": "svc", "port": 443, "tags": []string{"a"}},
+		},
+	})
+	require.NoError(t, err)
+
+	for _, name := range []string{"golden.yaml", "golden.json"} {
+		t.Run(name, func(t *testing.T) {
+			filename := filepath.Join(t.TempDir(), name)
+			require.NoError(t, Write(filename, deckData))
+			read, err := Read(filename)
+			require.NoError(t, err)
+			assert.Empty(t, Diff(deckData, read))
+		})
+	}
+}
+
+func Test_Diff(t *testing.T) {
+	expected := map[string]interface{}{"_format_version": "3.0"}
+	actual := map[string]interface{}{"_format_version": "1.1"}
+	diff := Diff(expected, actual)
+	assert.Regexp(t, `-.*"_format_version":\s*string\("3\.0"\)`, diff)
+	assert.Regexp(t, `\+.*"_format_version":\s*string\("1\.1"\)`, diff)
+}
+
+func Test_IsYaml(t *testing.T) {
+	assert.True(t, IsYaml("kong.yaml"))
+	assert.True(t, IsYaml("kong.YML.gz"))
+	assert.False(t, IsYaml("kong.json"))
+}
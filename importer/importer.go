@@ -0,0 +1,197 @@
+// Package importer has the parts shared by the converters for input formats other than
+// OpenAPI. These build an OpenAPI document as the intermediate model, which is then
+// converted by convertoas3.
+package importer
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/Kong/fw/convertoas3"
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// DefaultScheme is the scheme used for URLs without one.
+const DefaultScheme = "https"
+
+// pathParameterRegex matches the parameters in an OpenAPI path, eg. '{id}'.
+var pathParameterRegex = regexp.MustCompile(`{([^}/]+)}`)
+
+// Document builds an OpenAPI document from operations on servers.
+type Document struct {
+	doc          *openapi3.T
+	servers      []string        // the document level servers, the servers of the first operation
+	operationIDs map[string]bool // the operationIds in use
+}
+
+// NewDocument returns a new, empty, document.
+func NewDocument(title string, description string, version string) *Document {
+	if version == "" {
+		version = "1.0.0"
+	}
+	return &Document{
+		doc: &openapi3.T{
+			ExtensionProps: openapi3.ExtensionProps{Extensions: make(map[string]interface{})},
+			OpenAPI:        "3.0.3",
+			Info: &openapi3.Info{
+				Title:       title,
+				Description: description,
+				Version:     version,
+			},
+			Paths: openapi3.Paths{},
+		},
+		operationIDs: make(map[string]bool),
+	}
+}
+
+// OpenAPI returns the OpenAPI document built.
+func (d *Document) OpenAPI() *openapi3.T {
+	return d.doc
+}
+
+// uniqueOperationID returns the operationId, made unique by adding a counter if needed.
+func (d *Document) uniqueOperationID(base string) string {
+	id := base
+	for i := 2; d.operationIDs[id]; i++ {
+		id = fmt.Sprintf("%s-%d", base, i)
+	}
+	d.operationIDs[id] = true
+	return id
+}
+
+// newServers returns the servers for the URLs.
+func newServers(urls []string) openapi3.Servers {
+	servers := make(openapi3.Servers, len(urls))
+	for i, serverURL := range urls {
+		servers[i] = &openapi3.Server{URL: serverURL}
+	}
+	return servers
+}
+
+// serverURLs returns the URLs of the servers.
+func serverURLs(servers openapi3.Servers) []string {
+	urls := make([]string, len(servers))
+	for i, server := range servers {
+		urls[i] = server.URL
+	}
+	return urls
+}
+
+// equalURLs returns true if both lists have the same URLs, in the same order.
+func equalURLs(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// AddOperation adds the operation for the method on the path, served from the servers.
+// The servers of the first operation become the document level servers, paths on other
+// servers get path level servers. The parameters in a new path are declared on the path
+// item, and the operationId is made unique if needed. Fails if the path is already used
+// with other servers, or already has an operation for the method.
+func (d *Document) AddOperation(servers []string, path string, method string, operation *openapi3.Operation) error {
+	if d.servers == nil && len(servers) > 0 {
+		d.servers = servers
+		d.doc.Servers = newServers(servers)
+	}
+
+	pathItem := d.doc.Paths[path]
+	if pathItem == nil {
+		pathItem = &openapi3.PathItem{}
+		if !equalURLs(servers, d.servers) {
+			pathItem.Servers = newServers(servers)
+		}
+		for _, match := range pathParameterRegex.FindAllStringSubmatch(path, -1) {
+			pathItem.Parameters = append(pathItem.Parameters, &openapi3.ParameterRef{
+				Value: openapi3.NewPathParameter(match[1]).WithSchema(openapi3.NewStringSchema()),
+			})
+		}
+		d.doc.Paths[path] = pathItem
+	} else if (len(pathItem.Servers) > 0 && !equalURLs(serverURLs(pathItem.Servers), servers)) ||
+		(len(pathItem.Servers) == 0 && !equalURLs(servers, d.servers)) {
+		return fmt.Errorf("path '%s' is already used with other servers", path)
+	}
+
+	method = strings.ToUpper(method)
+	if pathItem.GetOperation(method) != nil {
+		return fmt.Errorf("duplicate operation '%s %s'", method, path)
+	}
+	if operation.OperationID != "" {
+		operation.OperationID = d.uniqueOperationID(operation.OperationID)
+	}
+	if operation.Responses == nil {
+		operation.AddResponse(0, openapi3.NewResponse().WithDescription("default response"))
+	}
+	pathItem.SetOperation(method, operation)
+	return nil
+}
+
+// OperationID returns an operationId made from the names, eg. folder and request names.
+func OperationID(names ...string) string {
+	return convertoas3.Slugify(strings.Join(names, " "))
+}
+
+// AddQueryParameters declares the query parameters on the operation, sorted by name.
+// Duplicates are only added once.
+func AddQueryParameters(operation *openapi3.Operation, names []string) {
+	sorted := append([]string{}, names...)
+	sort.Strings(sorted)
+	for i, name := range sorted {
+		if name == "" || (i > 0 && sorted[i-1] == name) {
+			continue
+		}
+		operation.AddParameter(openapi3.NewQueryParameter(name).WithSchema(openapi3.NewStringSchema()))
+	}
+}
+
+// QueryNames returns the names of the query parameters in a URL.
+func QueryNames(rawURL string) []string {
+	var names []string
+	if _, rawQuery, found := strings.Cut(rawURL, "?"); found {
+		rawQuery, _, _ = strings.Cut(rawQuery, "#")
+		for _, param := range strings.Split(rawQuery, "&") {
+			if name, _, _ := strings.Cut(param, "="); name != "" {
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
+
+// SplitURL splits a URL into the server (scheme, host and port) and the path. The
+// scheme defaults to DefaultScheme, and the query and fragment are dropped. Path
+// segments ':name' are converted to path parameters '{name}'.
+func SplitURL(rawURL string) (string, string, error) {
+	rawURL, _, _ = strings.Cut(rawURL, "#")
+	rawURL, _, _ = strings.Cut(rawURL, "?")
+
+	scheme := DefaultScheme
+	if before, after, found := strings.Cut(rawURL, "://"); found {
+		scheme, rawURL = strings.ToLower(before), after
+	}
+	host, path, _ := strings.Cut(rawURL, "/")
+	if host == "" {
+		return "", "", fmt.Errorf("expected the URL to have a host")
+	}
+	server := scheme + "://" + host
+	if _, err := url.ParseRequestURI(server); err != nil {
+		return "", "", fmt.Errorf("invalid server '%s': %w", server, err)
+	}
+
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") && len(segment) > 1 {
+			segments[i] = "{" + segment[1:] + "}"
+		}
+	}
+	return server, "/" + strings.Join(segments, "/"), nil
+}
@@ -0,0 +1,69 @@
+package importer
+
+import (
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_SplitURL(t *testing.T) {
+	tests := []struct {
+		name           string
+		url            string
+		expectedServer string
+		expectedPath   string
+		wantErr        bool
+	}{
+		{"full", "http://example.com:8080/users/:id?x=1#top", "http://example.com:8080", "/users/{id}", false},
+		{"no scheme", "example.com/users", "https://example.com", "/users", false},
+		{"no path", "https://example.com", "https://example.com", "/", false},
+		{"no host", "https:///users", "", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, path, err := SplitURL(tt.url)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedServer, server)
+			assert.Equal(t, tt.expectedPath, path)
+		})
+	}
+}
+
+func Test_QueryNames(t *testing.T) {
+	assert.Equal(t, []string{"a", "b"}, QueryNames("http://x/y?a=1&b#c"))
+	assert.Nil(t, QueryNames("http://x/y"))
+}
+
+func Test_DocumentAddOperation(t *testing.T) {
+	doc := NewDocument("title", "", "")
+	assert.Equal(t, "1.0.0", doc.OpenAPI().Info.Version)
+
+	newOperation := func(id string) *openapi3.Operation {
+		operation := openapi3.NewOperation()
+		operation.OperationID = id
+		return operation
+	}
+	require.NoError(t, doc.AddOperation([]string{"https://a"}, "/users/{id}", "get", newOperation("get-user")))
+	require.NoError(t, doc.AddOperation([]string{"https://a"}, "/users/{id}", "delete", newOperation("get-user")))
+	require.NoError(t, doc.AddOperation([]string{"https://b"}, "/health", "get", newOperation("health")))
+
+	openapi := doc.OpenAPI()
+	assert.Equal(t, "https://a", openapi.Servers[0].URL)
+	users := openapi.Paths["/users/{id}"]
+	assert.Empty(t, users.Servers)
+	assert.Equal(t, "id", users.Parameters[0].Value.Name)
+	assert.Equal(t, "get-user", users.Get.OperationID)
+	assert.Equal(t, "get-user-2", users.Delete.OperationID)
+	assert.Equal(t, "https://b", openapi.Paths["/health"].Servers[0].URL)
+
+	assert.EqualError(t, doc.AddOperation([]string{"https://a"}, "/users/{id}", "GET", newOperation("")),
+		"duplicate operation 'GET /users/{id}'")
+	assert.EqualError(t, doc.AddOperation([]string{"https://b"}, "/users/{id}", "PUT", newOperation("")),
+		"path '/users/{id}' is already used with other servers")
+}
@@ -0,0 +1,183 @@
+package kongcompat
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Feature is a feature of Kong declarative configurations, that older Kong versions do
+// not support.
+type Feature string
+
+// The features gated by Kong version, see Since for the first version supporting each.
+const (
+	FeatureRegexPrefix    Feature = "regex-prefix"    // regex paths marked by a '~' prefix
+	FeatureWebSockets     Feature = "ws-protocols"    // the 'ws' and 'wss' protocols
+	FeatureExpressions    Feature = "expressions"     // routes matching on an 'expression'
+	FeaturePluginOrdering Feature = "plugin-ordering" // the dynamic 'ordering' of plugins
+	FeatureFilterChains   Feature = "filter-chains"   // WASM filter chains on services and routes
+)
+
+// Version is a Kong Gateway version, as its major and minor version.
+type Version struct {
+	Major int
+	Minor int
+}
+
+// capability is the first Kong version supporting a feature, and whether its problems
+// are phrased in plural, eg. "filter chains (on ...) require".
+type capability struct {
+	since  Version
+	plural bool
+}
+
+// capabilities is the matrix of the features, and the Kong versions supporting them.
+var capabilities = map[Feature]capability{
+	FeatureRegexPrefix:    {Version{3, 0}, false},
+	FeatureWebSockets:     {Version{3, 0}, false},
+	FeatureExpressions:    {Version{3, 0}, false},
+	FeaturePluginOrdering: {Version{3, 0}, false},
+	FeatureFilterChains:   {Version{3, 4}, true},
+}
+
+// MinimumVersion is the oldest Kong version configurations can be generated for.
+var MinimumVersion = Version{2, 8}
+
+// ParseVersion parses a Kong version, eg. "3.4", "3.4.1", or "3.4.1.0-enterprise-edition".
+// Only the major and minor version are used.
+func ParseVersion(version string) (Version, error) {
+	major, rest, _ := strings.Cut(strings.TrimSpace(version), ".")
+	minor, _, _ := strings.Cut(rest, ".")
+	majorNumber, err := strconv.Atoi(major)
+	if err != nil || majorNumber < 0 {
+		return Version{}, fmt.Errorf("expected Kong version to be in 'major.minor' format, eg. '3.4', got: '%s'",
+			version)
+	}
+	minorNumber, err := strconv.Atoi(minor)
+	if err != nil || minorNumber < 0 {
+		return Version{}, fmt.Errorf("expected Kong version to be in 'major.minor' format, eg. '3.4', got: '%s'",
+			version)
+	}
+	return Version{majorNumber, minorNumber}, nil
+}
+
+// String returns the version in "major.minor" format.
+func (v Version) String() string {
+	return fmt.Sprintf("%d.%d", v.Major, v.Minor)
+}
+
+// Before returns true if the version is older than the other version.
+func (v Version) Before(other Version) bool {
+	return v.Major < other.Major || (v.Major == other.Major && v.Minor < other.Minor)
+}
+
+// Supports returns true if the Kong version supports the feature.
+func (v Version) Supports(feature Feature) bool {
+	return !v.Before(Since(feature))
+}
+
+// Since returns the first Kong version supporting the feature.
+func Since(feature Feature) Version {
+	return capabilities[feature].since
+}
+
+// FeatureUse is a use of a version gated feature in a declarative configuration.
+type FeatureUse struct {
+	Feature Feature
+	Owner   string // the entity using the feature, eg. "route 'users'"
+	Subject string // what uses the feature, eg. "regex path '~/users$'"
+}
+
+// FindFeatures returns the uses of the version gated features in the declarative
+// configuration, in order of the entities.
+func FindFeatures(content map[string]interface{}) []FeatureUse {
+	uses := make([]FeatureUse, 0)
+
+	checkPlugins := func(owner string, entity map[string]interface{}) {
+		plugins, _ := entity["plugins"].([]interface{})
+		for _, p := range plugins {
+			plugin, _ := p.(map[string]interface{})
+			if plugin["ordering"] != nil {
+				uses = append(uses, FeatureUse{FeaturePluginOrdering, owner,
+					fmt.Sprintf("ordering of plugin '%v'", plugin["name"])})
+			}
+		}
+	}
+	checkEntity := func(owner string, entity map[string]interface{}) {
+		checkPlugins(owner, entity)
+		if chains, _ := entity["filter_chains"].([]interface{}); len(chains) > 0 {
+			uses = append(uses, FeatureUse{FeatureFilterChains, owner, "filter chains"})
+		}
+		// services have a 'protocol', routes have 'protocols'
+		protocols, _ := entity["protocols"].([]interface{})
+		if protocol, ok := entity["protocol"]; ok {
+			protocols = append(protocols, protocol)
+		}
+		for _, protocol := range protocols {
+			if protocol == "ws" || protocol == "wss" {
+				uses = append(uses, FeatureUse{FeatureWebSockets, owner, fmt.Sprintf("protocol '%v'", protocol)})
+			}
+		}
+	}
+
+	checkPlugins("document", content)
+	services, _ := content["services"].([]interface{})
+	for _, s := range services {
+		service, _ := s.(map[string]interface{})
+		checkEntity(fmt.Sprintf("service '%v'", service["name"]), service)
+
+		routes, _ := service["routes"].([]interface{})
+		for _, r := range routes {
+			route, _ := r.(map[string]interface{})
+			owner := fmt.Sprintf("route '%v'", route["name"])
+			checkEntity(owner, route)
+			if route["expression"] != nil {
+				uses = append(uses, FeatureUse{FeatureExpressions, owner, "expression"})
+			}
+			paths, _ := route["paths"].([]interface{})
+			for _, path := range paths {
+				if p, _ := path.(string); strings.HasPrefix(p, "~") {
+					uses = append(uses, FeatureUse{FeatureRegexPrefix, owner, fmt.Sprintf("regex path '%s'", p)})
+				}
+			}
+		}
+	}
+	consumers, _ := content["consumers"].([]interface{})
+	for _, c := range consumers {
+		consumer, _ := c.(map[string]interface{})
+		checkPlugins(fmt.Sprintf("consumer '%v'", consumer["username"]), consumer)
+	}
+	groups, _ := content["consumer_groups"].([]interface{})
+	for _, g := range groups {
+		group, _ := g.(map[string]interface{})
+		checkPlugins(fmt.Sprintf("consumer group '%v'", group["name"]), group)
+	}
+	return uses
+}
+
+// CheckVersion validates the declarative configuration against the capabilities of the
+// Kong version. Returns a sorted list of the features used that the version does not
+// support, which is empty if there are none.
+func CheckVersion(content map[string]interface{}, version Version) []string {
+	problems := make(map[string]bool)
+	for _, use := range FindFeatures(content) {
+		if version.Supports(use.Feature) {
+			continue
+		}
+		verb := "requires"
+		if capabilities[use.Feature].plural {
+			verb = "require"
+		}
+		problems[fmt.Sprintf("%s (on %s) %s Kong %s or later", use.Subject, use.Owner, verb,
+			Since(use.Feature))] = true
+	}
+
+	result := make([]string, 0, len(problems))
+	for problem := range problems {
+		result = append(result, problem)
+	}
+	sort.Strings(result)
+	return result
+}
@@ -0,0 +1,152 @@
+// Package kongcompat checks Kong declarative configurations for compatibility
+// with a specific Kong Gateway, as reported by its Admin API.
+package kongcompat
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const defaultTimeout = 10 * time.Second
+
+// GatewayInfo describes the capabilities of a Kong Gateway.
+type GatewayInfo struct {
+	Version string          // the Kong version, eg. "3.4.1.0-enterprise-edition"
+	Plugins map[string]bool // the plugins available on the gateway
+}
+
+// MajorVersion returns the major version of the gateway, or 0 if it cannot be determined.
+func (info *GatewayInfo) MajorVersion() int {
+	major, _, _ := strings.Cut(info.Version, ".")
+	result, err := strconv.Atoi(major)
+	if err != nil {
+		return 0
+	}
+	return result
+}
+
+// MinorVersion returns the minor version of the gateway, or 0 if it cannot be determined.
+func (info *GatewayInfo) MinorVersion() int {
+	_, rest, _ := strings.Cut(info.Version, ".")
+	minor, _, _ := strings.Cut(rest, ".")
+	result, err := strconv.Atoi(minor)
+	if err != nil {
+		return 0
+	}
+	return result
+}
+
+// FetchGatewayInfo queries the root endpoint of the Kong Admin API at 'addr' for
+// the version and available plugins. The headers are added to the request, each
+// in "name:value" format.
+func FetchGatewayInfo(ctx context.Context, addr string, headers []string) (*GatewayInfo, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(addr, "/")+"/", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request for '%s': %w", addr, err)
+	}
+	for _, header := range headers {
+		name, value, found := strings.Cut(header, ":")
+		if !found {
+			return nil, fmt.Errorf("expected header '%s' to be in 'name:value' format", header)
+		}
+		req.Header.Add(strings.TrimSpace(name), strings.TrimSpace(value))
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query Kong Admin API at '%s': %w", addr, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected response from Kong Admin API at '%s': %s", addr, resp.Status)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response from Kong Admin API at '%s': %w", addr, err)
+	}
+
+	var root struct {
+		Version string `json:"version"`
+		Plugins struct {
+			AvailableOnServer map[string]interface{} `json:"available_on_server"`
+		} `json:"plugins"`
+	}
+	if err = json.Unmarshal(body, &root); err != nil {
+		return nil, fmt.Errorf("failed to parse response from Kong Admin API at '%s': %w", addr, err)
+	}
+
+	info := &GatewayInfo{
+		Version: root.Version,
+		Plugins: make(map[string]bool),
+	}
+	for name := range root.Plugins.AvailableOnServer {
+		info.Plugins[name] = true
+	}
+	return info, nil
+}
+
+// Check validates the declarative configuration against the gateway. Returns a
+// sorted list of problems found, which is empty if the configuration is compatible.
+func Check(content map[string]interface{}, info *GatewayInfo) []string {
+	problems := make(map[string]bool)
+	major := info.MajorVersion()
+
+	// format version; 3.0 is required for Kong 3.x, and not supported before
+	formatVersion, _ := content["_format_version"].(string)
+	if major >= 3 && formatVersion != "3.0" {
+		problems[fmt.Sprintf("format version '%s' is not supported by Kong %s, expected '3.0'",
+			formatVersion, info.Version)] = true
+	}
+	if major > 0 && major < 3 && formatVersion == "3.0" {
+		problems[fmt.Sprintf("format version '3.0' is not supported by Kong %s", info.Version)] = true
+	}
+
+	checkPlugins := func(owner string, entity map[string]interface{}) {
+		plugins, _ := entity["plugins"].([]interface{})
+		for _, p := range plugins {
+			plugin, _ := p.(map[string]interface{})
+			name, _ := plugin["name"].(string)
+			if !info.Plugins[name] {
+				problems[fmt.Sprintf("plugin '%s' (on %s) is not available on the gateway", name, owner)] = true
+			}
+		}
+	}
+
+	checkPlugins("document", content)
+	services, _ := content["services"].([]interface{})
+	for _, s := range services {
+		service, _ := s.(map[string]interface{})
+		checkPlugins(fmt.Sprintf("service '%v'", service["name"]), service)
+
+		routes, _ := service["routes"].([]interface{})
+		for _, r := range routes {
+			route, _ := r.(map[string]interface{})
+			checkPlugins(fmt.Sprintf("route '%v'", route["name"]), route)
+		}
+	}
+
+	// the features not supported by the version
+	if version, err := ParseVersion(info.Version); err == nil && major > 0 {
+		for _, problem := range CheckVersion(content, version) {
+			problems[problem] = true
+		}
+	}
+
+	result := make([]string, 0, len(problems))
+	for problem := range problems {
+		result = append(result, problem)
+	}
+	sort.Strings(result)
+	return result
+}
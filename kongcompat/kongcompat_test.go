@@ -0,0 +1,149 @@
+package kongcompat
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_FetchGatewayInfo(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Kong-Admin-Token") != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{
+			"version": "3.4.1",
+			"plugins": {
+				"available_on_server": {
+					"key-auth": {"version": "3.4.1", "priority": 1250},
+					"cors": {"version": "3.4.1", "priority": 2000}
+				}
+			}
+		}`))
+	}))
+	defer server.Close()
+
+	info, err := FetchGatewayInfo(context.Background(), server.URL, []string{"Kong-Admin-Token: secret"})
+	assert.NoError(t, err)
+	assert.Equal(t, &GatewayInfo{
+		Version: "3.4.1",
+		Plugins: map[string]bool{"key-auth": true, "cors": true},
+	}, info)
+	assert.Equal(t, 3, info.MajorVersion())
+	assert.Equal(t, 4, info.MinorVersion())
+
+	_, err = FetchGatewayInfo(context.Background(), server.URL, nil)
+	assert.Error(t, err)
+
+	_, err = FetchGatewayInfo(context.Background(), server.URL, []string{"bad header"})
+	assert.Error(t, err)
+}
+
+func Test_Check(t *testing.T) {
+	var content map[string]interface{}
+	_ = json.Unmarshal([]byte(`{
+		"_format_version": "3.0",
+		"services": [{
+			"name": "svc",
+			"plugins": [{"name": "cors"}],
+			"routes": [{
+				"name": "route1",
+				"paths": ["~/users$"],
+				"plugins": [{"name": "openid-connect"}],
+				"filter_chains": [{"filters": [{"name": "my-filter"}]}]
+			}]
+		}]
+	}`), &content)
+
+	info := &GatewayInfo{Version: "3.4.1", Plugins: map[string]bool{"cors": true}}
+	assert.Equal(t, []string{
+		"plugin 'openid-connect' (on route 'route1') is not available on the gateway",
+	}, Check(content, info))
+
+	info = &GatewayInfo{Version: "2.8.1", Plugins: map[string]bool{"cors": true, "openid-connect": true}}
+	assert.Equal(t, []string{
+		"filter chains (on route 'route1') require Kong 3.4 or later",
+		"format version '3.0' is not supported by Kong 2.8.1",
+		"regex path '~/users$' (on route 'route1') requires Kong 3.0 or later",
+	}, Check(content, info))
+
+	info = &GatewayInfo{Version: "3.3.0", Plugins: map[string]bool{"cors": true, "openid-connect": true}}
+	assert.Equal(t, []string{
+		"filter chains (on route 'route1') require Kong 3.4 or later",
+	}, Check(content, info))
+
+	info = &GatewayInfo{Version: "3.4.0", Plugins: map[string]bool{"cors": true, "openid-connect": true}}
+	assert.Empty(t, Check(content, info))
+}
+
+func Test_ParseVersion(t *testing.T) {
+	tests := []struct {
+		version  string
+		expected Version
+	}{
+		{"2.8", Version{2, 8}},
+		{"3.4.1", Version{3, 4}},
+		{"3.4.1.0-enterprise-edition", Version{3, 4}},
+		{" 3.10 ", Version{3, 10}},
+	}
+	for _, tst := range tests {
+		version, err := ParseVersion(tst.version)
+		assert.NoError(t, err, tst.version)
+		assert.Equal(t, tst.expected, version, tst.version)
+	}
+
+	for _, version := range []string{"", "3", "latest", "3.x", "-1.0"} {
+		_, err := ParseVersion(version)
+		assert.Error(t, err, version)
+	}
+}
+
+func Test_VersionSupports(t *testing.T) {
+	assert.False(t, Version{2, 8}.Supports(FeatureRegexPrefix))
+	assert.True(t, Version{3, 0}.Supports(FeatureRegexPrefix))
+	assert.False(t, Version{3, 3}.Supports(FeatureFilterChains))
+	assert.True(t, Version{3, 4}.Supports(FeatureFilterChains))
+	assert.True(t, Version{4, 0}.Supports(FeatureFilterChains))
+	assert.Equal(t, "3.4", Since(FeatureFilterChains).String())
+}
+
+func Test_CheckVersion(t *testing.T) {
+	var content map[string]interface{}
+	_ = json.Unmarshal([]byte(`{
+		"_format_version": "3.0",
+		"plugins": [{"name": "cors", "ordering": {"before": {"access": ["key-auth"]}}}],
+		"services": [{
+			"name": "svc",
+			"protocol": "ws",
+			"routes": [{
+				"name": "route1",
+				"protocols": ["wss"],
+				"expression": "http.path == \"/users\"",
+				"filter_chains": [{"filters": [{"name": "my-filter"}]}]
+			}]
+		}],
+		"consumers": [{
+			"username": "user1",
+			"plugins": [{"name": "rate-limiting", "ordering": {"after": {"access": ["cors"]}}}]
+		}]
+	}`), &content)
+
+	assert.Len(t, FindFeatures(content), 6)
+	assert.Equal(t, []string{
+		"expression (on route 'route1') requires Kong 3.0 or later",
+		"filter chains (on route 'route1') require Kong 3.4 or later",
+		"ordering of plugin 'cors' (on document) requires Kong 3.0 or later",
+		"ordering of plugin 'rate-limiting' (on consumer 'user1') requires Kong 3.0 or later",
+		"protocol 'ws' (on service 'svc') requires Kong 3.0 or later",
+		"protocol 'wss' (on route 'route1') requires Kong 3.0 or later",
+	}, CheckVersion(content, Version{2, 8}))
+	assert.Equal(t, []string{
+		"filter chains (on route 'route1') require Kong 3.4 or later",
+	}, CheckVersion(content, Version{3, 0}))
+	assert.Empty(t, CheckVersion(content, Version{3, 7}))
+}
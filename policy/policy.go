@@ -0,0 +1,129 @@
+// Package policy evaluates user supplied policies on a generated Kong declarative file,
+// for platform governance, eg. "every route must have rate-limiting". A policy is an
+// external command, so any policy engine can be used, eg. OPA ('opa eval') or CUE
+// ('cue vet'). The command gets the declarative file as JSON on stdin, and:
+//
+//   - rejects it by exiting with a non-zero exit code, with the violations on stderr,
+//   - rejects it by writing a non-empty JSON array of violations to stdout,
+//   - mutates it by writing the new declarative file, a JSON object, to stdout,
+//   - or accepts it as is, by writing nothing, or an empty JSON array.
+package policy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// The exit codes of the shell for commands that could not be run.
+const (
+	shellNotExecutable = 126
+	shellNotFound      = 127
+)
+
+// Violations is the error returned when a policy rejects the declarative file.
+type Violations struct {
+	Policy   string   // the policy command
+	Messages []string // the violations reported by the policy
+}
+
+func (v *Violations) Error() string {
+	return fmt.Sprintf("policy '%s' rejected the configuration:\n  - %s",
+		v.Policy, strings.Join(v.Messages, "\n  - "))
+}
+
+// shellCommand returns the command to run the policy command line with the shell, so
+// it can hold arguments, quotes, and pipes.
+func shellCommand(ctx context.Context, command string) *exec.Cmd {
+	if runtime.GOOS == "windows" {
+		return exec.CommandContext(ctx, "cmd", "/C", command)
+	}
+	return exec.CommandContext(ctx, "sh", "-c", command)
+}
+
+// outputLines returns the non-empty lines of the output.
+func outputLines(output []byte) []string {
+	lines := make([]string, 0)
+	for _, line := range strings.Split(string(output), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// Evaluate runs the policy command on the declarative file. Returns the declarative
+// file to use; the one passed in, or the one written by the policy. Returns a
+// *Violations error if the policy rejects the declarative file.
+func Evaluate(ctx context.Context, command string, content map[string]interface{}) (map[string]interface{}, error) {
+	input, err := json.Marshal(content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize the configuration for policy '%s': %w", command, err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := shellCommand(ctx, command)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) || ctx.Err() != nil {
+			return nil, fmt.Errorf("failed to run policy '%s': %w", command, err)
+		}
+		if code := exitErr.ExitCode(); code == shellNotFound || code == shellNotExecutable {
+			return nil, fmt.Errorf("failed to run policy '%s': %s", command,
+				strings.Join(outputLines(stderr.Bytes()), "; "))
+		}
+		messages := outputLines(stderr.Bytes())
+		if len(messages) == 0 {
+			messages = outputLines(stdout.Bytes())
+		}
+		if len(messages) == 0 {
+			messages = []string{fmt.Sprintf("exited with code %d", exitErr.ExitCode())}
+		}
+		return nil, &Violations{Policy: command, Messages: messages}
+	}
+
+	output := bytes.TrimSpace(stdout.Bytes())
+	if len(output) == 0 {
+		return content, nil
+	}
+	var result interface{}
+	if err := json.Unmarshal(output, &result); err != nil {
+		return nil, fmt.Errorf("expected policy '%s' to write a JSON object or array, got: '%s'",
+			command, firstLine(output))
+	}
+	switch value := result.(type) {
+	case map[string]interface{}:
+		return value, nil
+	case []interface{}:
+		if len(value) == 0 {
+			return content, nil
+		}
+		messages := make([]string, len(value))
+		for i, violation := range value {
+			if message, ok := violation.(string); ok {
+				messages[i] = message
+			} else {
+				data, _ := json.Marshal(violation)
+				messages[i] = string(data)
+			}
+		}
+		return nil, &Violations{Policy: command, Messages: messages}
+	default:
+		return nil, fmt.Errorf("expected policy '%s' to write a JSON object or array, got: '%s'",
+			command, firstLine(output))
+	}
+}
+
+// firstLine returns the first line of the output, for error messages.
+func firstLine(output []byte) string {
+	line, _, _ := strings.Cut(string(output), "\n")
+	return line
+}
@@ -0,0 +1,67 @@
+package policy
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Evaluate(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the policy commands use a POSIX shell")
+	}
+	content := map[string]interface{}{
+		"_format_version": "3.0",
+		"services":        []interface{}{map[string]interface{}{"name": "users"}},
+	}
+	mutated := map[string]interface{}{"_format_version": "3.0", "services": []interface{}{}}
+
+	tests := []struct {
+		name       string
+		command    string
+		expected   map[string]interface{}
+		violations []string
+		err        string
+	}{
+		{"no output", "cat > /dev/null", content, nil, ""},
+		{"empty array", "echo '[]'", content, nil, ""},
+		{"mutated", `echo '{"_format_version": "3.0", "services": []}'`, mutated, nil, ""},
+		{"reads the input", "cat", content, nil, ""},
+		{"violations", `echo '["route a has no rate-limiting", {"rule": "tags"}]'`, nil,
+			[]string{"route a has no rate-limiting", `{"rule":"tags"}`}, ""},
+		{"failing", "echo 'services must use https' >&2; exit 2", nil,
+			[]string{"services must use https"}, ""},
+		{"failing to stdout", "echo 'not allowed'; exit 1", nil, []string{"not allowed"}, ""},
+		{"failing silently", "exit 3", nil, []string{"exited with code 3"}, ""},
+		{"bad output", "echo 'all good'", nil, nil,
+			"expected policy 'echo 'all good'' to write a JSON object or array, got: 'all good'"},
+		{"not found", "fw-no-such-policy", nil, nil, "failed to run policy 'fw-no-such-policy': "},
+		{"scalar output", "echo 'true'", nil, nil,
+			"expected policy 'echo 'true'' to write a JSON object or array, got: 'true'"},
+	}
+
+	for _, tst := range tests {
+		result, err := Evaluate(context.Background(), tst.command, content)
+		switch {
+		case tst.violations != nil:
+			var violations *Violations
+			require.True(t, errors.As(err, &violations), tst.name)
+			assert.Equal(t, tst.command, violations.Policy, tst.name)
+			assert.Equal(t, tst.violations, violations.Messages, tst.name)
+		case tst.err != "":
+			assert.ErrorContains(t, err, tst.err, tst.name)
+		default:
+			require.NoError(t, err, tst.name)
+			assert.Equal(t, tst.expected, result, tst.name)
+		}
+	}
+}
+
+func Test_ViolationsError(t *testing.T) {
+	err := &Violations{Policy: "opa eval", Messages: []string{"a", "b"}}
+	assert.EqualError(t, err, "policy 'opa eval' rejected the configuration:\n  - a\n  - b")
+}
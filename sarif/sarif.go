@@ -0,0 +1,175 @@
+// Package sarif writes findings, eg. lint findings and conversion warnings, as a SARIF
+// 2.1.0 log (Static Analysis Results Interchange Format). CI tools, like GitHub code
+// scanning, use it to annotate the spec files with the findings, at their lines.
+package sarif
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+const (
+	schemaURI = "https://json.schemastore.org/sarif-2.1.0.json"
+	version   = "2.1.0"
+)
+
+// The levels of a finding.
+const (
+	LevelError   = "error"
+	LevelWarning = "warning"
+	LevelNote    = "note"
+)
+
+// Finding is a single result to report.
+type Finding struct {
+	RuleID  string // the rule, see AddRule
+	Level   string // LevelError, LevelWarning, or LevelNote
+	Message string
+	// URI is the file (a relative path, with forward slashes) or URL the finding is
+	// in. If empty, the finding has no location.
+	URI string
+	// Pointer is the JSON pointer to the element in the file, eg. "/paths/~1users/get".
+	Pointer string
+	// Line and Column (1-based) are the position of the element in the file, if known.
+	Line   int
+	Column int
+}
+
+// Log is a SARIF log with a single run, of the tool given to New.
+type Log struct {
+	Schema  string `json:"$schema"`
+	Version string `json:"version"`
+	Runs    []*run `json:"runs"`
+}
+
+type run struct {
+	Tool    tool      `json:"tool"`
+	Results []*result `json:"results"`
+}
+
+type tool struct {
+	Driver driver `json:"driver"`
+}
+
+type driver struct {
+	Name           string  `json:"name"`
+	Version        string  `json:"version,omitempty"`
+	InformationURI string  `json:"informationUri,omitempty"`
+	Rules          []*rule `json:"rules"`
+}
+
+type rule struct {
+	ID               string  `json:"id"`
+	ShortDescription message `json:"shortDescription"`
+}
+
+type message struct {
+	Text string `json:"text"`
+}
+
+type result struct {
+	RuleID    string      `json:"ruleId"`
+	RuleIndex int         `json:"ruleIndex"`
+	Level     string      `json:"level"`
+	Message   message     `json:"message"`
+	Locations []*location `json:"locations,omitempty"`
+}
+
+type location struct {
+	PhysicalLocation *physicalLocation  `json:"physicalLocation,omitempty"`
+	LogicalLocations []*logicalLocation `json:"logicalLocations,omitempty"`
+}
+
+type physicalLocation struct {
+	ArtifactLocation artifactLocation `json:"artifactLocation"`
+	Region           *region          `json:"region,omitempty"`
+}
+
+type artifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type region struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn,omitempty"`
+}
+
+type logicalLocation struct {
+	FullyQualifiedName string `json:"fullyQualifiedName"`
+	Kind               string `json:"kind"`
+}
+
+// New returns an empty log, for the tool with the given name, version, and URI of its
+// documentation.
+func New(name string, toolVersion string, informationURI string) *Log {
+	return &Log{
+		Schema:  schemaURI,
+		Version: version,
+		Runs: []*run{{
+			Tool: tool{Driver: driver{
+				Name:           name,
+				Version:        toolVersion,
+				InformationURI: informationURI,
+				Rules:          make([]*rule, 0),
+			}},
+			Results: make([]*result, 0),
+		}},
+	}
+}
+
+// AddRule adds a rule that findings can refer to, with a short description. Adding an
+// existing rule does nothing.
+func (l *Log) AddRule(id string, description string) {
+	if l.ruleIndex(id) < 0 {
+		l.Runs[0].Tool.Driver.Rules = append(l.Runs[0].Tool.Driver.Rules,
+			&rule{ID: id, ShortDescription: message{description}})
+	}
+}
+
+// ruleIndex returns the index of the rule, or -1 if it was not added.
+func (l *Log) ruleIndex(id string) int {
+	for i, r := range l.Runs[0].Tool.Driver.Rules {
+		if r.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// Add adds a finding to the log. A rule not added before is added, with the rule ID as
+// its description.
+func (l *Log) Add(finding Finding) {
+	l.AddRule(finding.RuleID, finding.RuleID)
+	res := &result{
+		RuleID:    finding.RuleID,
+		RuleIndex: l.ruleIndex(finding.RuleID),
+		Level:     finding.Level,
+		Message:   message{finding.Message},
+	}
+	if finding.URI != "" || finding.Pointer != "" {
+		loc := &location{}
+		if finding.URI != "" {
+			loc.PhysicalLocation = &physicalLocation{ArtifactLocation: artifactLocation{finding.URI}}
+			if finding.Line > 0 {
+				loc.PhysicalLocation.Region = &region{StartLine: finding.Line, StartColumn: finding.Column}
+			}
+		}
+		if finding.Pointer != "" {
+			loc.LogicalLocations = []*logicalLocation{{FullyQualifiedName: finding.Pointer, Kind: "element"}}
+		}
+		res.Locations = []*location{loc}
+	}
+	l.Runs[0].Results = append(l.Runs[0].Results, res)
+}
+
+// Marshal returns the log as indented JSON.
+func (l *Log) Marshal() ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(l); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,80 @@
+package sarif
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Log(t *testing.T) {
+	log := New("fw", "v1.2.3", "https://github.com/Kong/fw")
+	log.AddRule("conversion-error", "The spec could not be converted")
+	log.Add(Finding{
+		RuleID:  "conversion-warning",
+		Level:   LevelWarning,
+		Message: "callback URL is a runtime expression, skipped",
+		URI:     "specs/api.yaml",
+		Pointer: "/paths/~1users/post/callbacks",
+		Line:    12,
+		Column:  7,
+	})
+	log.Add(Finding{RuleID: "conversion-error", Level: LevelError, Message: "no paths", URI: "specs/api.yaml"})
+	log.Add(Finding{RuleID: "conversion-error", Level: LevelError, Message: "from stdin"})
+
+	data, err := log.Marshal()
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+  "$schema": "https://json.schemastore.org/sarif-2.1.0.json",
+  "version": "2.1.0",
+  "runs": [{
+    "tool": {"driver": {
+      "name": "fw",
+      "version": "v1.2.3",
+      "informationUri": "https://github.com/Kong/fw",
+      "rules": [
+        {"id": "conversion-error", "shortDescription": {"text": "The spec could not be converted"}},
+        {"id": "conversion-warning", "shortDescription": {"text": "conversion-warning"}}
+      ]
+    }},
+    "results": [
+      {
+        "ruleId": "conversion-warning",
+        "ruleIndex": 1,
+        "level": "warning",
+        "message": {"text": "callback URL is a runtime expression, skipped"},
+        "locations": [{
+          "physicalLocation": {
+            "artifactLocation": {"uri": "specs/api.yaml"},
+            "region": {"startLine": 12, "startColumn": 7}
+          },
+          "logicalLocations": [{"fullyQualifiedName": "/paths/~1users/post/callbacks", "kind": "element"}]
+        }]
+      },
+      {
+        "ruleId": "conversion-error",
+        "ruleIndex": 0,
+        "level": "error",
+        "message": {"text": "no paths"},
+        "locations": [{"physicalLocation": {"artifactLocation": {"uri": "specs/api.yaml"}}}]
+      },
+      {
+        "ruleId": "conversion-error",
+        "ruleIndex": 0,
+        "level": "error",
+        "message": {"text": "from stdin"}
+      }
+    ]
+  }]
+}`, string(data))
+}
+
+func Test_LogEmpty(t *testing.T) {
+	data, err := New("fw", "", "").Marshal()
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+  "$schema": "https://json.schemastore.org/sarif-2.1.0.json",
+  "version": "2.1.0",
+  "runs": [{"tool": {"driver": {"name": "fw", "rules": []}}, "results": []}]
+}`, string(data))
+}